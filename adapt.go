@@ -0,0 +1,172 @@
+package slab
+
+import "sort"
+
+// AdaptOptions bound the class layout chosen by Adapt.
+type AdaptOptions struct {
+	MinClasses    int     // fewest classes to keep, the default is 1
+	MaxClasses    int     // most classes to have, the default is the current number of classes
+	Fragmentation float64 // a class whose fragmentation is higher is split, the default is 0.25
+	MinRequests   uint64  // a class is split only if it served this many requests since the last Adapt, the default is 100
+}
+
+// Adapt change the size classes to fit the requests served since the pool is created or adapted last time.
+// A class with high internal fragmentation is split by a class of the median size of its requests,
+// a class which served no request is merged into the next larger one, the largest class is always kept.
+// Classes are split only if the pool is created WithSizeHistogram, which records the sizes of the requests.
+// It must be called at a safe point, when no buffer alloc from the pool is used any more, including the ones held by a Cache,
+// and no other goroutine is using the pool, like Reset. It returns false and changes nothing if a chunk is still in use,
+// or a goroutine started by StartReclaimer, StartScrubber, StartReporter, WatchFallbacks or TrimOnMemoryPressure is not stopped.
+// The pages of the old classes are released like Trim and the new classes start with the statistics cleared.
+// It must not be called on the shards of a ShardedPool, which share the same class layout.
+func (pool *AtomPool) Adapt(opts AdaptOptions) bool {
+	if pool.watchers.Load() != 0 {
+		return false
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].drain()
+	}
+	stats := pool.Stats()
+	for _, s := range stats {
+		if s.InUse != 0 {
+			return false
+		}
+	}
+
+	sizes := pool.adaptSizes(stats, opts)
+	changed := len(sizes) != len(stats)
+	for i := 0; !changed && i < len(sizes); i++ {
+		changed = sizes[i] != stats[i].Size
+	}
+	if !changed {
+		return false
+	}
+	cfg := configure(append(append([]Option{}, pool.opts...), WithClasses(sizes...)))
+	if cfg.validate() != nil {
+		return false
+	}
+
+	// 新的 class 从零开始统计，直方图也从现在开始计算
+	pool.adapted = pool.SizeHistogram()
+	releasePages(pool.classes)
+	classes, err := pool.newClasses(&cfg, sizes)
+	if err != nil {
+		// 新的 class 预分配的 page 超出了内存预算，恢复原来的 class，原来的 page 刚被释放，不会超出预算
+		sizes = make([]int, len(stats))
+		for i, s := range stats {
+			sizes[i] = s.Size
+		}
+		cfg.classes = sizes
+		classes, _ = pool.newClasses(&cfg, sizes)
+		changed = false
+	}
+	pool.classes = classes
+	pool.lookup = newLookup(classes)
+	pool.minSize = sizes[0]
+	// 替换上次 Adapt 加上的 WithClasses，opts 不随 Adapt 的次数增长
+	if pool.adaptedOpt {
+		pool.opts[len(pool.opts)-1] = WithClasses(sizes...)
+	} else {
+		pool.opts = append(pool.opts, WithClasses(sizes...))
+		pool.adaptedOpt = true
+	}
+	return changed
+}
+
+// adaptSizes 根据各个 class 的统计和请求大小的直方图计算新的 class 大小
+func (pool *AtomPool) adaptSizes(stats []ClassStats, opts AdaptOptions) []int {
+	if opts.MinClasses < 1 {
+		opts.MinClasses = 1
+	}
+	if opts.MaxClasses < 1 {
+		opts.MaxClasses = len(stats)
+	}
+	if opts.Fragmentation <= 0 {
+		opts.Fragmentation = 0.25
+	}
+	if opts.MinRequests == 0 {
+		opts.MinRequests = 100
+	}
+
+	// 合并：没有请求的 class 并入下一个更大的 class，最大的 class 保留
+	var kept []ClassStats
+	merged := 0
+	for i, s := range stats {
+		if i < len(stats)-1 && s.Allocs+s.Fallbacks == 0 && len(stats)-merged > opts.MinClasses {
+			merged++
+			continue
+		}
+		kept = append(kept, s)
+	}
+
+	// 拆分：内部碎片高的 class 按碎片从高到低拆分，新的大小为区间内请求大小的中位数
+	type split struct {
+		at   int
+		size int
+		frag float64
+	}
+	var splits []split
+	for i, s := range kept {
+		if s.Requests < opts.MinRequests || s.Fragmentation() <= opts.Fragmentation {
+			continue
+		}
+		lower := 0
+		if i > 0 {
+			lower = kept[i-1].Size
+		}
+		if size := pool.medianSize(lower, s.Size); size > lower && size < s.Size {
+			splits = append(splits, split{i, size, s.Fragmentation()})
+		}
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].frag > splits[j].frag
+	})
+	if n := opts.MaxClasses - len(kept); len(splits) > n {
+		if n < 0 {
+			n = 0
+		}
+		splits = splits[:n]
+	}
+
+	sizes := make([]int, 0, len(kept)+len(splits))
+	for i, s := range kept {
+		for _, sp := range splits {
+			if sp.at == i {
+				sizes = append(sizes, sp.size)
+			}
+		}
+		sizes = append(sizes, s.Size)
+	}
+	return sizes
+}
+
+// medianSize 返回 Adapt 上次调用以来大小在 (lower, upper] 之间的请求的中位数所在桶的上界，
+// 按对齐要求向上取整，没有请求时返回 0
+func (pool *AtomPool) medianSize(lower, upper int) int {
+	var buckets []SizeBucket
+	var total uint64
+	for _, b := range pool.SizeHistogram() {
+		for _, p := range pool.adapted {
+			if p.Size == b.Size {
+				b.Count -= p.Count
+				break
+			}
+		}
+		// 桶的上界在区间内才完全属于这个 class
+		if b.Size > lower && b.Size <= upper && b.Count > 0 {
+			buckets = append(buckets, b)
+			total += b.Count
+		}
+	}
+	var n uint64
+	for _, b := range buckets {
+		if n += b.Count; 2*n >= total {
+			align := 8
+			if pool.align > align {
+				align = pool.align
+			}
+			return (b.Size + align - 1) / align * align
+		}
+	}
+	return 0
+}
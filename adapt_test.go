@@ -0,0 +1,96 @@
+package slab
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func classSizesOf(pool *AtomPool) string {
+	var sizes []int
+	for _, s := range pool.Stats() {
+		sizes = append(sizes, s.Size)
+	}
+	return fmt.Sprint(sizes)
+}
+
+func Test_AtomPool_Adapt(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(64, 1024), WithPageSize(4096), WithSizeHistogram())
+	utest.IsNilNow(t, err)
+
+	// day: requests of 600 bytes are served by the 1024 class
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(600))
+	}
+	mem := pool.Alloc(600)
+	utest.Assert(t, !pool.Adapt(AdaptOptions{}))
+	utest.EqualNow(t, classSizesOf(pool), "[64 128 256 512 1024]")
+	pool.Free(mem)
+
+	// the unused classes are kept by MinClasses
+	utest.Assert(t, pool.Adapt(AdaptOptions{MinClasses: 5, MaxClasses: 6}))
+	utest.EqualNow(t, classSizesOf(pool), "[64 128 256 512 640 1024]")
+	utest.Assert(t, !pool.Adapt(AdaptOptions{MinClasses: 6}))
+
+	// the classes without requests are merged
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(600))
+	}
+	utest.Assert(t, pool.Adapt(AdaptOptions{}))
+	utest.EqualNow(t, classSizesOf(pool), "[640 1024]")
+	mem = pool.Alloc(600)
+	utest.EqualNow(t, cap(mem), 640)
+	utest.EqualNow(t, pool.Stats()[0].Allocs, uint64(1))
+	pool.Free(mem)
+
+	// night: small requests are split from the 640 class, MaxClasses bounds the number of classes
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(100))
+		pool.Free(pool.Alloc(1000))
+	}
+	utest.Assert(t, !pool.Adapt(AdaptOptions{MaxClasses: 2}))
+	utest.Assert(t, pool.Adapt(AdaptOptions{MaxClasses: 3}))
+	utest.EqualNow(t, classSizesOf(pool), "[112 640 1024]")
+	utest.EqualNow(t, cap(pool.Alloc(100)), 112)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_AdaptBudget(t *testing.T) {
+	pool, err := NewPool(WithClasses(128, 1024), WithPageSize(1024), WithMaxMemory(2048), WithSizeHistogram())
+	utest.IsNilNow(t, err)
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(1000))
+		pool.Free(pool.Alloc(600))
+	}
+	// the 640 class would exceed the memory budget with its preallocated page
+	utest.Assert(t, !pool.Adapt(AdaptOptions{MinClasses: 2, MaxClasses: 3}))
+	utest.EqualNow(t, classSizesOf(pool), "[128 1024]")
+	utest.EqualNow(t, cap(pool.Alloc(600)), 1024)
+}
+
+func Test_AtomPool_AdaptWatched(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(64, 1024), WithPageSize(4096), WithSizeHistogram())
+	utest.IsNilNow(t, err)
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(600))
+	}
+
+	// the watcher reads the classes, they are kept until it is stopped
+	w := pool.WatchFallbacks(0.5, time.Millisecond, func(FallbackAlert) {})
+	utest.Assert(t, !pool.Adapt(AdaptOptions{}))
+	utest.EqualNow(t, classSizesOf(pool), "[64 128 256 512 1024]")
+	w.Stop()
+	utest.Assert(t, pool.Adapt(AdaptOptions{}))
+	utest.EqualNow(t, classSizesOf(pool), "[640 1024]")
+	n := len(pool.opts)
+
+	// the classes of the last Adapt replace the previous ones in the options
+	for i := 0; i < 200; i++ {
+		pool.Free(pool.Alloc(100))
+	}
+	utest.Assert(t, pool.Adapt(AdaptOptions{MaxClasses: 3}))
+	utest.EqualNow(t, len(pool.opts), n)
+	utest.EqualNow(t, fmt.Sprint(configure(pool.opts).classes), classSizesOf(pool))
+}
@@ -0,0 +1,53 @@
+package slab
+
+import "fmt"
+
+// SetClassEnabled take the slab class of chunkSize out of service or put it back, e.g. when it misbehaves during an incident.
+// Requests for a disabled class are served by the next larger enabled class, or fall back like requests larger than the largest chunk size
+// when there is none. Chunks of a disabled class still in use are freed back to it, and Trim releases its pages once they are free.
+// Adapt and Reconfigure create new classes, which are all enabled. It returns an error if no class has chunks of chunkSize bytes.
+func (pool *AtomPool) SetClassEnabled(chunkSize int, enabled bool) error {
+	c, err := pool.classOf(chunkSize)
+	if err != nil {
+		return err
+	}
+	c.disabled.Store(!enabled)
+	return nil
+}
+
+// SetClassPinned pin the slab class of chunkSize or unpin it. Trim, Reclaim and the automatic trims never release the pages
+// of a pinned class, e.g. to guarantee the capacity of the MTU-sized class, only Close and Migrate do.
+// It returns an error if no class has chunks of chunkSize bytes.
+func (pool *AtomPool) SetClassPinned(chunkSize int, pinned bool) error {
+	c, err := pool.classOf(chunkSize)
+	if err != nil {
+		return err
+	}
+	c.pinned.Store(pinned)
+	return nil
+}
+
+// classOf 返回 chunk 大小为 chunkSize 的 class
+func (pool *AtomPool) classOf(chunkSize int) (*class, error) {
+	for i := 0; i < len(pool.classes); i++ {
+		if pool.classes[i].size == chunkSize {
+			return &pool.classes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("slab: no class of chunk size %d", chunkSize)
+}
+
+// enabledFrom 返回 classes[i:] 中第一个没有停用的 class 的下标，都停用时返回 -1
+func enabledFrom(classes []class, i int) int {
+	for ; i < len(classes); i++ {
+		if !classes[i].disabled.Load() {
+			return i
+		}
+	}
+	return -1
+}
+
+// keeps 判断因 reason 释放内存时是否保留 class 的 page
+func (c *class) keeps(reason TrimReason) bool {
+	return c.pinned.Load() && reason != TrimClose && reason != TrimMigrate
+}
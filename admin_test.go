@@ -0,0 +1,63 @@
+package slab
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_SetClassEnabled(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	utest.IsNilNow(t, pool.SetClassEnabled(256, false))
+	utest.Assert(t, pool.Stats()[1].Disabled)
+
+	// 停用的 class 的请求交给下一个 class
+	mem := pool.Alloc(200)
+	utest.EqualNow(t, cap(mem), 512)
+	utest.EqualNow(t, pool.Stats()[1].Allocs, uint64(0))
+	utest.EqualNow(t, pool.Stats()[2].Allocs, uint64(1))
+	pool.Free(mem)
+
+	// 停用之前分配的 chunk 仍然回到原来的 class
+	utest.IsNilNow(t, pool.SetClassEnabled(256, true))
+	mem = pool.Alloc(256)
+	utest.IsNilNow(t, pool.SetClassEnabled(256, false))
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Stats()[1].Frees, uint64(1))
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+
+	// 更大的 class 都停用时回退到 heap
+	utest.IsNilNow(t, pool.SetClassEnabled(512, false))
+	utest.IsNilNow(t, pool.SetClassEnabled(1024, false))
+	mem, pooled := pool.AllocPooled(200)
+	utest.Assert(t, !pooled)
+	utest.EqualNow(t, len(mem), 200)
+	utest.EqualNow(t, cap(pool.Alloc(100)), 128)
+
+	data, err := json.Marshal(pool.Snapshot())
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, strings.Count(string(data), `"disabled":true`), 3)
+
+	utest.NotNilNow(t, pool.SetClassEnabled(300, false))
+}
+
+func Test_AtomPool_SetClassPinned(t *testing.T) {
+	pool := NewAtomPool(128, 512, 2, 1024)
+	utest.IsNilNow(t, pool.SetClassPinned(256, true))
+	utest.Assert(t, pool.Stats()[1].Pinned)
+	utest.EqualNow(t, pool.Trim(), 2048)
+	utest.EqualNow(t, pool.Reclaim(0), 0)
+	utest.EqualNow(t, pool.Stats()[1].Pages, 1)
+
+	utest.IsNilNow(t, pool.SetClassPinned(256, false))
+	utest.EqualNow(t, pool.Trim(), 1024)
+
+	// Close 也释放固定的 class 的 page
+	pool = NewAtomPool(128, 512, 2, 1024)
+	utest.IsNilNow(t, pool.SetClassPinned(256, true))
+	utest.IsNilNow(t, pool.Close())
+	utest.EqualNow(t, pool.Stats()[1].Pages, 0)
+	utest.NotNilNow(t, pool.SetClassPinned(300, true))
+}
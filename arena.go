@@ -0,0 +1,60 @@
+package slab
+
+// Arena bump-allocates many small buffers out of blocks alloc from a Pool and releases them all at once.
+// It is meant for request-scoped data, every buffer of the arena is valid until Release.
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	pool      Pool
+	blockSize int
+	blocks    [][]byte // 从 pool 分配的所有 block，Release 时统一归还
+	cur       []byte   // 当前 block 未被分配的部分
+}
+
+// NewArena create an Arena which alloc blocks of blockSize bytes from pool.
+// blockSize is usually a chunk size of the pool, e.g. the page size divided by a small number.
+func NewArena(pool Pool, blockSize int) *Arena {
+	return &Arena{pool: pool, blockSize: blockSize}
+}
+
+// Alloc returns a []byte of size bytes carved out of the current block.
+// Requests larger than a quarter of the block size get their own buffer from the pool.
+// The capacity of the returned slice is locked to size, so append never overwrites its neighbours.
+// It returns nil when the pool returns nil, e.g. a pool created with WithNoHeap is exhausted.
+func (a *Arena) Alloc(size int) []byte {
+	if size > a.blockSize/4 {
+		// 大块单独分配，避免浪费当前 block 剩余的空间
+		mem := a.pool.Alloc(size)
+		if mem == nil {
+			return nil
+		}
+		a.blocks = append(a.blocks, mem)
+		return mem[:size:size]
+	}
+	if size > len(a.cur) {
+		block := a.pool.Alloc(a.blockSize)
+		if block == nil {
+			return nil
+		}
+		a.blocks = append(a.blocks, block)
+		a.cur = block[:cap(block)]
+	}
+	mem := a.cur[:size:size]
+	a.cur = a.cur[size:]
+	return mem
+}
+
+// Blocks returns the number of blocks currently held by the arena.
+func (a *Arena) Blocks() int {
+	return len(a.blocks)
+}
+
+// Release return every block to the pool at once, all buffers alloc from the arena must not be used after that.
+// The arena can be reused after Release.
+func (a *Arena) Release() {
+	for i, block := range a.blocks {
+		a.pool.Free(block)
+		a.blocks[i] = nil
+	}
+	a.blocks = a.blocks[:0]
+	a.cur = nil
+}
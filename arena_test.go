@@ -0,0 +1,72 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Arena(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	arena := NewArena(pool, 1024)
+
+	a := arena.Alloc(10)
+	b := arena.Alloc(20)
+	utest.EqualNow(t, len(a), 10)
+	utest.EqualNow(t, cap(a), 10)
+	utest.EqualNow(t, &arena.blocks[0][10], &b[0])
+	utest.EqualNow(t, arena.Blocks(), 1)
+
+	// the current block is full
+	for i := 0; i < 1024/200; i++ {
+		arena.Alloc(200)
+	}
+	utest.EqualNow(t, arena.Blocks(), 2)
+
+	// large request
+	c := arena.Alloc(512)
+	utest.EqualNow(t, cap(c), 512)
+	utest.EqualNow(t, arena.Blocks(), 3)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 2)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 1)
+
+	arena.Release()
+	utest.EqualNow(t, arena.Blocks(), 0)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	arena.Alloc(10)
+	utest.EqualNow(t, arena.Blocks(), 1)
+	arena.Release()
+}
+
+func Test_Arena_NoHeap(t *testing.T) {
+	pool, err := NewPool(WithClasses(1024), WithPageSize(1024), WithNoHeap())
+	utest.IsNilNow(t, err)
+	arena := NewArena(pool, 1024)
+
+	utest.NotNilNow(t, arena.Alloc(1000))
+	utest.IsNilNow(t, arena.Alloc(1000))
+	utest.IsNilNow(t, arena.Alloc(300))
+	utest.EqualNow(t, arena.Blocks(), 1)
+
+	arena.Release()
+	utest.NotNilNow(t, arena.Alloc(10))
+	utest.IsNilNow(t, arena.Alloc(300))
+	arena.Release()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Benchmark_Arena_AllocAndRelease_16(b *testing.B) {
+	pool := NewAtomPool(128, 64*1024, 2, 1024*1024)
+	arena := NewArena(pool, 4096)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			arena.Alloc(16)
+		}
+		arena.Release()
+	}
+}
@@ -1,183 +1,1592 @@
 package slab
 
 import (
-	"reflect"
-	"runtime"
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"os"
+	"runtime/pprof"
+	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
+// ErrDoubleFree is returned by TryFree when the chunk is already free.
+var ErrDoubleFree = errors.New("slab: double free")
+
+// ErrNotPooled is returned by TryFree when the buffer doesn't belong to any slab class.
+var ErrNotPooled = errors.New("slab: buffer not pooled")
+
+// ErrPoolExhausted is returned by TryAlloc in strict budget mode when a request can't be served without exceeding the memory budget,
+// and in no heap mode when a request can't be served by the pool at all.
+var ErrPoolExhausted = errors.New("slab: pool exhausted")
+
 // AtomPool is a lock-free slab allocation memory pool.
 type AtomPool struct {
-	classes []class
-	minSize int
-	maxSize int
+	classes  []class
+	lookup   []uint16 // 请求大小到 class 下标的查找表
+	minSize  int
+	maxSize  int
+	fallback func(size int) []byte
+	src      source
+	opts     []Option
+	strict   bool
+	noHeap   bool
+	realTime int // 实时模式下 pop 的 CAS 尝试次数上限，0 表示关闭
+	align    int
+	overflow *overflow
+	buddy    *buddy
+	oversize [bits.UintSize]oversizeBucket
+
+	onDoubleFree func(mem []byte)
+	onCorruption func(err error)
+	onAlloc      func(e AllocEvent)
+	onFree       func(e AllocEvent)
+	onRelease    func(e ReleaseEvent)
+	tracer       *Tracer
+	hooks        bool // 设置了 hook 或 tracer
+	policy       []Policy
+	fit          Fit
+	latency      *latencyStats // WithLatency 模式下 Alloc 和 Free 的耗时
+	secondary    Pool
+	tags         tags
+	trims        trimLog
+	sizes        *sizeHistogram           // WithSizeHistogram 模式下请求大小的直方图
+	name         string                   // 在 registry 中注册的名字
+	adapted      []SizeBucket             // Adapt 上次改变 class 时的请求大小直方图
+	adaptedOpt   bool                     // opts 的最后一个是 Adapt 加上的 WithClasses
+	watchers     atomic.Int32             // 正在运行的读取 class 的后台 goroutine 数
+	file         *os.File                 // WithFile 的文件
+	views        *stringViews             // WithStringGuard 记录的字符串视图
+	usage        usage                    // 所有 class 合计的用量和峰值
+	migrated     atomic.Pointer[AtomPool] // Migrate 之后接手分配的 pool
+}
+
+// budget 限制所有 class 的 page 占用的内存总量
+type budget struct {
+	used  atomic.Int64
+	limit atomic.Int64 // 0 表示不限制，SetMaxMemory 可以随时修改
+}
+
+func (b *budget) reserve(n int) bool {
+	for {
+		used := b.used.Load()
+		if limit := b.limit.Load(); limit > 0 && used+int64(n) > limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+func (b *budget) release(n int) {
+	b.used.Add(-int64(n))
+}
+
+// source 为 pool 的 page 和 buddy arena 提供内存并计入预算，子 pool 的 source 从父 pool 借用内存
+type source struct {
+	budget budget
+	parent *source
+	pages  PageAllocator
+	mu     sync.Mutex
+	spares [][]byte // 子 pool 归还的内存，仍计入本 pool 及其祖先的预算
+	closed atomic.Bool
+	done   chan struct{} // Close 时关闭，唤醒 AllocContext 中的等待者
+}
+
+// get 取得 size 字节的内存，overBudget 表示受本 pool 或祖先的内存预算限制
+func (s *source) get(size int) (raw []byte, overBudget bool, err error) {
+	if s.closed.Load() {
+		return nil, false, nil
+	}
+	s.mu.Lock()
+	for i := len(s.spares) - 1; i >= 0; i-- {
+		if len(s.spares[i]) == size {
+			raw = s.spares[i]
+			s.spares[i] = s.spares[len(s.spares)-1]
+			s.spares[len(s.spares)-1] = nil
+			s.spares = s.spares[:len(s.spares)-1]
+			s.mu.Unlock()
+			return raw, false, nil
+		}
+	}
+	s.mu.Unlock()
+
+	if !s.budget.reserve(size) {
+		return nil, true, nil
+	}
+	switch {
+	case s.parent != nil:
+		raw, overBudget, err = s.parent.get(size)
+	default:
+		raw, err = s.pages.AllocPage(size)
+	}
+	if raw == nil {
+		s.budget.release(size)
+	}
+	return raw, overBudget, err
+}
+
+// put 归还 get 得到的内存，子 pool 的内存放回父 pool 的 spares 供其它 pool 复用
+func (s *source) put(raw []byte) {
+	s.budget.release(len(raw))
+	if s.parent != nil {
+		s.parent.mu.Lock()
+		if !s.parent.closed.Load() {
+			s.parent.spares = append(s.parent.spares, raw)
+			s.parent.mu.Unlock()
+			return
+		}
+		// 父 pool 已经关闭，不再保留 spares，直接交给它释放
+		s.parent.mu.Unlock()
+		s.parent.put(raw)
+		return
+	}
+	s.pages.FreePage(raw)
+}
+
+// drop 释放 spares 中的全部内存，返回释放的字节数
+func (s *source) drop() int {
+	s.mu.Lock()
+	spares := s.spares
+	s.spares = nil
+	s.mu.Unlock()
+	released := 0
+	for _, raw := range spares {
+		s.put(raw)
+		released += len(raw)
+	}
+	return released
 }
 
 // NewAtomPool create a lock-free slab allocation memory pool.
 // minSize is the smallest chunk size.
 // maxSize is the lagest chunk size.
 // factor is used to control growth of chunk size.
-// pageSize is the memory size of each slab class.
-func NewAtomPool(minSize, maxSize, factor, pageSize int) *AtomPool {
+// pageSize is the memory size of each slab page.
+// NewAtomPool panics if the configuration is invalid, use NewPool to get an error instead.
+func NewAtomPool(minSize, maxSize, factor, pageSize int, opts ...Option) *AtomPool {
+	opts = append([]Option{
+		WithSizeRange(minSize, maxSize),
+		WithGrowthFactor(factor),
+		WithPageSize(pageSize),
+	}, opts...)
+	pool, err := NewPool(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+// NewPool create a lock-free slab allocation memory pool configured by opts.
+// It returns an error if the configuration is invalid.
+func NewPool(opts ...Option) (*AtomPool, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.realTime > 0 {
+		// 实时模式下 class 不再增长，page 在创建时全部分配并写入一遍
+		cfg.noHeap = true
+		cfg.prealloc = cfg.maxPages
+		cfg.pretouch = true
+	}
 
+	// 为每种大小的 chunk: minSize, minSize * factor, minSize * factor * factor, ... , maxSize 创建一个 class
+	sizes := cfg.classSizes()
 	pool := &AtomPool{
-		make([]class, 0, 10), // 每种 class 对应一种大小的 chunk
-		minSize,              // 最小 chunk 的大小
-		maxSize,              // 最大 chunk 的大小
+		minSize:  sizes[0],     // 最小 chunk 的大小
+		maxSize:  cfg.maxSize,  // 最大 chunk 的大小
+		fallback: cfg.fallback, // 无法从 class 分配时的后备分配函数
+		src:      source{parent: cfg.parent, pages: cfg.pages, done: make(chan struct{})},
+		opts:     append([]Option{}, opts...),
+		strict:   cfg.strict,
+		noHeap:   cfg.noHeap,
+		realTime: cfg.realTime,
+		align:    cfg.align,
+
+		onDoubleFree: cfg.onDoubleFree,
+		onCorruption: cfg.onCorruption,
+		onAlloc:      cfg.onAlloc,
+		onFree:       cfg.onFree,
+		onRelease:    cfg.onRelease,
+		tracer:       cfg.tracer,
+		hooks:        cfg.onAlloc != nil || cfg.onFree != nil || cfg.tracer != nil,
+		policy:       cfg.policy,
+		fit:          cfg.fit,
+		secondary:    cfg.secondary,
+		name:         cfg.name,
+	}
+	pool.src.budget.limit.Store(int64(cfg.maxMemory))
+	if cfg.buddyMax > 0 {
+		pool.buddy = newBuddy(cfg.maxSize, cfg.buddyMax, cfg.buddyArenas, &pool.src, cfg.align)
+	}
+	if cfg.overflow > 0 {
+		pool.overflow = newOverflow(cfg.maxSize, cfg.overflow, cfg.overflowCap)
+	}
+	if cfg.latency {
+		pool.latency = &latencyStats{}
+	}
+	if cfg.sizeHistogram {
+		pool.sizes = &sizeHistogram{}
+	}
+	if cfg.stringGuard {
+		pool.views = &stringViews{views: make(map[uintptr]stringView)}
 	}
 
-	// 为每种大小的 chunk: minSize, minSize * factor, minSize * factor * factor, ... , maxSize 创建一个 class 
-	for chunkSize := minSize; chunkSize <= maxSize && chunkSize <= pageSize; chunkSize *= factor {
-		
-		// 为每种 chunkSize 大小的 chunk 创建一个 class 
-		c := class{
-			size:   chunkSize,
-			page:   make([]byte, pageSize),             // 每个 class 的总大小为 pageSize，默认 64KB
-			chunks: make([]chunk, pageSize/chunkSize),  // 每个 class 包含的 chunk 总数为 pageSize/chunkSize 个
-			head:   (1 << 32),                          // ???
+	if cfg.file != "" {
+		f, err := os.OpenFile(cfg.file, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
 		}
+		pool.file = f
+	}
+	var err error
+	if pool.classes, err = pool.newClasses(&cfg, sizes); err != nil {
+		if pool.file != nil {
+			pool.file.Close()
+		}
+		return nil, err
+	}
+	pool.lookup = newLookup(pool.classes)
+	if pool.name != "" {
+		RegisterPool(pool.name, pool)
+	}
+	return pool, nil
+}
 
-		// 初始化 class 中所含的 chunks
-		for i := 0; i < len(c.chunks); i++ {
+// newClasses 为每种大小的 chunk 创建一个 class，并按配置预先分配 page，失败时已分配的 page 被释放
+func (pool *AtomPool) newClasses(cfg *config, sizes []int) ([]class, error) {
+	classes := make([]class, len(sizes)) // 每种 class 对应一种大小的 chunk
+	var fileSize int64
+	for n, chunkSize := range sizes {
+		c := &classes[n]
+		c.id = n
+		c.size = chunkSize
+		c.pageSize = cfg.classPageSize(chunkSize)      // 每个 page 的大小为 pageSize，默认 1MB
+		c.guard = cfg.guardSize()                      // 开启 guard 时每个 chunk 前后各保留 guard 字节
+		c.stride = chunkSize + 2*c.guard               // 相邻 chunk 起始地址的间隔
+		c.perPage = c.pageSize / c.stride              // 每个 page 包含的 chunk 总数为 pageSize/stride 个
+		c.pages = make([]unsafe.Pointer, cfg.maxPages) // class 最多可以增长到 maxPages 个 page
+		c.growBy = cfg.growBy
+		c.spins = cfg.spins
+		c.pauses = cfg.pauses
+		c.listMu = newListLock(cfg.classSync(chunkSize))
+		c.freeCap = cfg.classFreeCap(chunkSize)
+		c.abaBase = make([]uint32, cfg.maxPages)
+		c.src = &pool.src
+		c.usage = &pool.usage
+		c.wake = make(chan struct{}, 1)
+		c.align = cfg.align
+		c.leak = cfg.leak
+		c.sample.Store(int64(cfg.sample))
+		if cfg.chaos {
+			c.chaos = newChaos(cfg.chaosSeed + int64(n))
+		}
+		if cfg.shuffle {
+			c.shuffle = rand.New(rand.NewSource(cfg.shuffleSeed + int64(n)))
+		}
+		c.pretouch = cfg.pretouch
+		c.fixed = cfg.realTime > 0
+		c.meta = cfg.meta
+		c.profile = cfg.profile
+		c.onPage = cfg.onPage
+		if cfg.routing {
+			c.owner = pool
+		}
+		c.poison = cfg.poison
+		c.poisonBy = cfg.poisonBy
+		if cfg.fifo {
+			c.fifo = &fifo{}
+		}
+		c.counting = cfg.peaks || cfg.onMark != nil
+		if cfg.onMark != nil {
+			c.marks = &watermarks{high: cfg.highMark, low: cfg.lowMark, fn: cfg.onMark}
+		}
+		if cfg.quarantine > 0 {
+			c.quarantine = &quarantine{ring: make([]uint64, cfg.quarantine)}
+		}
+		if pool.file != nil {
+			// 每个 class 在文件中占用 maxPages 个 page 的区域，page 按操作系统内存页对齐
+			c.file = pool.file
+			c.fileBase = fileSize
+			c.fileStride = int64((c.rawSize() + osPageSize - 1) / osPageSize * osPageSize)
+			fileSize += int64(cfg.maxPages) * c.fileStride
+			if err := growFile(pool.file, fileSize); err != nil {
+				releasePages(classes[:n])
+				return nil, err
+			}
+		}
+		for i := 0; i < cfg.prealloc; i++ {
+			raw, overBudget, err := c.getPage(i)
+			if overBudget {
+				releasePages(classes[:n+1])
+				return nil, fmt.Errorf("slab: preallocated pages exceed max memory %d", cfg.maxMemory)
+			}
+			if err != nil {
+				releasePages(classes[:n+1])
+				return nil, err
+			}
+			c.addPage(i, raw, false)
+		}
+	}
+	return classes, nil
+}
 
-			chk := &c.chunks[i]
+// releasePages 释放 classes 的所有 page，调用者需保证其中没有 chunk 被使用
+func releasePages(classes []class) {
+	for i := range classes {
+		classes[i].Trim()
+	}
+}
 
-			// 把字节数组 c.page 按序切分成一个个 chunk，起始地址保存到变量 chk.mem 上
-			chk.mem = c.page[i*chunkSize : (i+1)*chunkSize : (i+1)*chunkSize] // lock down the capacity to protect append operation
-			
-			// 如果是最后一个 chunk，
-			if i < len(c.chunks)-1 {
-				chk.next = uint64(i+1+1 /* index start from 1 */) << 32
-			} else {
-				c.pageBegin = uintptr(unsafe.Pointer(&c.page[0]))
-				c.pageEnd = uintptr(unsafe.Pointer(&chk.mem[0]))
+// emptyBuf 是 Alloc(0) 返回的共享空切片，不占用 chunk，Free 按首地址识别它，
+// 重新切片成零容量的 chunk 首地址不同，仍然按 chunk 回收
+var emptyBuf = make([]byte, 0)
+
+// Alloc try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
+// Alloc(0) returns an empty slice with zero capacity which takes no chunk, Free ignores it.
+// Sizes below the smallest chunk size are served by the smallest class, see WithTinyClass.
+func (pool *AtomPool) Alloc(size int) []byte {
+	mem, _, _ := pool.alloc(size, false)
+	return mem
+}
+
+// TryAlloc is like Alloc, but in strict budget mode it returns ErrPoolExhausted
+// instead of falling back to make when the memory budget stops the slab class from growing,
+// and in no heap mode it returns ErrPoolExhausted whenever Alloc would return nil.
+// In real-time mode it returns ErrWouldBlock when the retries of WithRealTime are used up.
+// It returns ErrPoolClosed after Close.
+func (pool *AtomPool) TryAlloc(size int) ([]byte, error) {
+	if pool.src.closed.Load() {
+		return nil, ErrPoolClosed
+	}
+	mem, _, err := pool.alloc(size, pool.strict)
+	return mem, err
+}
+
+// AllocPooled is like Alloc, but also reports whether the buffer came from the pool.
+// pooled is false when the buffer is a heap fallback, which the caller may drop instead of passing it to Free.
+func (pool *AtomPool) AllocPooled(size int) (mem []byte, pooled bool) {
+	mem, pooled, _ = pool.alloc(size, false)
+	return
+}
+
+// alloc 分配 size 字节，pooled 表示分配自 class、buddy 层或 overflow 层而不是 heap
+func (pool *AtomPool) alloc(size int, strict bool) (mem []byte, pooled bool, err error) {
+	if dst := pool.migrated.Load(); dst != nil {
+		return dst.alloc(size, strict)
+	}
+	if pool.hooks {
+		defer func() { pool.allocated(size, mem, pooled) }()
+	}
+	if pool.latency != nil {
+		start := time.Now()
+		defer func() { pool.latency.alloc(start, pooled) }()
+	}
+	pool.recordSize(1, size)
+	if size == 0 {
+		return emptyBuf, false, nil
+	}
+	if pool.src.closed.Load() {
+		return pool.heap(size), false, ErrPoolClosed
+	}
+	if i := pool.classIndex(size); i >= 0 && (pool.fit != FitExact || pool.classes[i].size == size) {
+		c := &pool.classes[i]
+		if pool.fit == FitBest {
+			if mem := pool.allocBest(i, size); mem != nil {
+				return mem, true, nil
 			}
+		}
+		if pool.policy != nil {
+			return pool.allocChain(i, size, strict)
+		}
+		if pool.realTime > 0 {
+			return pool.allocRealTime(c, size)
+		}
+		mem, overBudget := c.pop(size, true)
+		if mem != nil {
+			c.allocs.Add(1)
+			c.request(1, size)
+			return mem[:size], true, nil
+		}
+		if overBudget {
+			c.overBudget.Add(1)
+			if strict {
+				return nil, false, ErrPoolExhausted
+			}
+		}
+		c.fallbacks.Add(1)
+	} else if i >= 0 {
+		// FitExact 模式下大小不等于 chunk 大小的请求
+		pool.classes[i].fallbacks.Add(1)
+	}
+	var over *oversizeBucket
+	if size > pool.maxSize {
+		over = pool.oversized(size)
+		over.requests.Add(1)
+	}
+	if size > pool.maxSize && pool.buddy != nil && size <= pool.buddy.maxBlock {
+		mem, overBudget := pool.buddy.alloc(size)
+		if mem != nil {
+			return mem, true, nil
+		}
+		if overBudget && strict {
+			return nil, false, ErrPoolExhausted
+		}
+	}
+	if size > pool.maxSize && pool.overflow != nil {
+		if mem := pool.overflow.alloc(size); mem != nil {
+			return mem, true, nil
+		}
+	}
+	if over != nil {
+		over.fallbacks.Add(1)
+	}
+	if pool.noHeap {
+		return nil, false, ErrPoolExhausted
+	}
+	return pool.heap(size), false, nil
+}
 
+// heap 在 class 无法分配时用后备分配函数或 make 分配
+func (pool *AtomPool) heap(size int) []byte {
+	if pool.noHeap {
+		return nil
+	}
+	if pool.fallback != nil {
+		return pool.fallback(size)
+	}
+	return make([]byte, size)
+}
 
+// AllocAligned alloc a []byte whose first byte address is a multiple of align, align must be a power of 2.
+// Chunks of the pool are aligned to the alignment set by WithAlignment,
+// larger alignments can't be served by the slab classes and fall back to make.
+func (pool *AtomPool) AllocAligned(size, align int) []byte {
+	if align <= pool.align || align <= 1 {
+		mem := pool.Alloc(size)
+		if isAligned(mem, align) {
+			return mem
 		}
+		pool.Free(mem)
+	}
+	if pool.noHeap {
+		return nil
+	}
+	return alignSlice(make([]byte, size+align), align)[:size:size]
+}
+
+// AllocZeroed is like Alloc but the whole chunk is cleared before it is returned,
+// so no data of the previous user can be read from it, even after reslicing up to its capacity.
+func (pool *AtomPool) AllocZeroed(size int) []byte {
+	mem := pool.Alloc(size)
+	memclr(mem[:cap(mem)])
+	return mem
+}
 
-		pool.classes = append(pool.classes, c)
+// AllocCap alloc a []byte of length size whose capacity is at least capacity, so the caller can append up to it without reallocation.
+// The slab class is chosen by capacity, a capacity smaller than size is ignored. It returns nil when Alloc does, e.g. WithNoHeap.
+func (pool *AtomPool) AllocCap(size, capacity int) []byte {
+	if capacity < size {
+		capacity = size
 	}
-	return pool
+	mem := pool.Alloc(capacity)
+	if mem == nil {
+		return nil
+	}
+	return mem[:size]
 }
 
-// Alloc try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
-func (pool *AtomPool) Alloc(size int) []byte {
-	if size <= pool.maxSize {
-		for i := 0; i < len(pool.classes); i++ {
-			if pool.classes[i].size >= size {
-				mem := pool.classes[i].Pop()
-				if mem != nil {
-					return mem[:size]
-				}
+// Realloc resize a []byte that alloc from Pool.Alloc to newSize.
+// If newSize fits in the capacity of mem it is resliced in place,
+// otherwise a larger chunk is allocated, the data is copied to it and mem is freed.
+// If the larger chunk can't be allocated, e.g. WithNoHeap, it returns nil and mem is not freed.
+func (pool *AtomPool) Realloc(mem []byte, newSize int) []byte {
+	if newSize <= cap(mem) {
+		return mem[:newSize]
+	}
+	newMem := pool.Alloc(newSize)
+	if newMem == nil {
+		return nil
+	}
+	copy(newMem, mem)
+	pool.Free(mem)
+	return newMem
+}
+
+// Grow make room for n more bytes after len(mem) and returns mem with the same length.
+// If they fit in the capacity of mem it's returned as is, otherwise a larger chunk of at least twice the capacity is allocated,
+// the data is copied to it and mem is freed, so growing a buffer step by step copies it only a few times.
+// Unlike append, the grown buffer still belongs to the pool and must be passed to Free.
+// If the larger chunk can't be allocated, e.g. WithNoHeap, mem is returned unchanged and not freed.
+func (pool *AtomPool) Grow(mem []byte, n int) []byte {
+	need := len(mem) + n
+	if need <= cap(mem) {
+		return mem
+	}
+	size := 2 * cap(mem)
+	if size < need {
+		size = need
+	}
+	newMem := pool.Alloc(size)
+	if newMem == nil {
+		return mem
+	}
+	newMem = newMem[:len(mem)]
+	copy(newMem, mem)
+	if cap(mem) > 0 {
+		pool.Free(mem)
+	}
+	return newMem
+}
+
+// Append appends data to mem like append does, but grows mem with Grow so the result is still a buffer of the pool.
+// If mem can't grow it returns nil and mem is not freed.
+func (pool *AtomPool) Append(mem []byte, data ...byte) []byte {
+	mem = pool.Grow(mem, len(data))
+	if cap(mem)-len(mem) < len(data) {
+		return nil
+	}
+	return append(mem, data...)
+}
+
+// Shrink resize a []byte that alloc from Pool.Alloc to newSize, which must not be larger than len(mem).
+// If a smaller slab class can hold newSize bytes the data is moved into one of its chunks and mem is freed,
+// e.g. a 64KB read buffer that got 300 bytes doesn't pin the large chunk while the message lives.
+// Otherwise, or if the smaller class has no free chunk, mem is resliced in place.
+func (pool *AtomPool) Shrink(mem []byte, newSize int) []byte {
+	if i := pool.classIndex(newSize); i >= 0 && pool.classes[i].size < cap(mem) {
+		if newMem, pooled := pool.AllocPooled(newSize); pooled {
+			copy(newMem, mem)
+			pool.Free(mem)
+			return newMem
+		}
+	}
+	return mem[:newSize]
+}
+
+// AllocBatchAtomic try alloc n []byte of the same size from internal slab class.
+// It either returns all n chunks from the pool, or returns false and reserves none of them.
+func (pool *AtomPool) AllocBatchAtomic(size, n int) ([][]byte, bool) {
+	pool.recordSize(n, size)
+	if i := pool.classIndex(size); i >= 0 {
+		c := &pool.classes[i]
+		bufs := make([][]byte, 0, n)
+		for j := 0; j < n; j++ {
+			mem := c.Pop()
+			if mem == nil {
+				// 不足 n 个，把已取出的 chunk 按原来的顺序挂回空闲链表
+				c.unpop(bufs)
+				return nil, false
+			}
+			bufs = append(bufs, mem[:size])
+		}
+		c.allocs.Add(uint64(n))
+		c.request(n, size)
+		if pool.hooks {
+			for _, mem := range bufs {
+				pool.allocated(size, mem, true)
+			}
+		}
+		return bufs, true
+	}
+	return nil, false
+}
+
+// AllocBatch alloc n []byte of the same size, popping runs of the free list with one CAS each instead of one CAS per chunk.
+// Chunks the slab class can't serve are allocated like Alloc does.
+func (pool *AtomPool) AllocBatch(size, n int) [][]byte {
+	if dst := pool.migrated.Load(); dst != nil {
+		return dst.AllocBatch(size, n)
+	}
+	bufs := make([][]byte, 0, n)
+	if i := pool.classIndex(size); i >= 0 {
+		c := &pool.classes[i]
+		pool.recordSize(n, size)
+		overBudget := false
+		for len(bufs) < n {
+			m := len(bufs)
+			bufs, overBudget = c.popRun(bufs, n-len(bufs), size)
+			if len(bufs) == m {
 				break
 			}
 		}
+		c.allocs.Add(uint64(len(bufs)))
+		c.request(len(bufs), size)
+		pooled := len(bufs)
+		if len(bufs) < n {
+			if overBudget {
+				c.overBudget.Add(uint64(n - len(bufs)))
+			}
+			c.fallbacks.Add(uint64(n - len(bufs)))
+			for len(bufs) < n {
+				bufs = append(bufs, pool.heap(size))
+			}
+		}
+		if pool.hooks {
+			for j, mem := range bufs {
+				pool.allocated(size, mem, j < pooled)
+			}
+		}
+		return bufs
 	}
-	return make([]byte, size)
+	// 交给 Alloc 分配，大小由 Alloc 计入直方图
+	for len(bufs) < n {
+		bufs = append(bufs, pool.Alloc(size))
+	}
+	return bufs
+}
+
+// FreeBatch release the []byte alloc from Pool.Alloc or Pool.AllocBatch like Free does,
+// consecutive chunks of the same slab class are linked up and pushed to the free list with one CAS.
+func (pool *AtomPool) FreeBatch(bufs [][]byte) {
+	var (
+		run   *class // 当前正在拼接的 chunk 串所属的 class
+		first uint64 // 串首 chunk 的编码
+		last  *chunk // 串尾 chunk
+		count int
+	)
+	flush := func() {
+		if run != nil {
+			run.release(first, last)
+			run.frees.Add(uint64(count))
+			pool.spilled(run)
+			if pool.migrated.Load() != nil {
+				run.shrink()
+			}
+			run, count = nil, 0
+		}
+	}
+	for _, mem := range bufs {
+		c := pool.class(cap(mem))
+		if c != run {
+			flush()
+		}
+		if c == nil {
+			pool.Free(mem)
+			continue
+		}
+		chk, idx, ok, err := c.prepare(mem)
+		if !ok {
+			// 可能是重新切片过的 chunk，交给 Free 按首指针查找
+			pool.Free(mem)
+			continue
+		}
+		if err == ErrDoubleFree {
+			flush()
+			c.doubleFrees.Add(1)
+			pool.report(mem, err)
+			continue
+		}
+		pool.tags.release(mem)
+		v := makeLink(idx, chk.aba)
+		if run == nil {
+			run, first = c, v
+		} else {
+			last.next.Store(v)
+		}
+		// 先把 next 置为非 0，同一批次中重复出现的 chunk 也能被识别为重复回收
+		chk.next.Store(v)
+		last = chk
+		count++
+		pool.freed(mem, c.size, true)
+		if err != nil {
+			flush()
+			pool.report(mem, err)
+		}
+	}
+	flush()
+}
+
+// class 返回 chunk 大小恰好为 size 的 class
+func (pool *AtomPool) class(size int) *class {
+	for i := 0; i < len(pool.classes); i++ {
+		if pool.classes[i].size == size {
+			return &pool.classes[i]
+		}
+	}
+	return nil
+}
+
+// Prealloc grow the slab class serving size until it owns enough pages for count chunks to be allocated at the same time,
+// so the first wave of requests doesn't fall back to make. The pages it adds are written to fault them in before traffic arrives.
+// It returns ErrNotPooled if no slab class serves size,
+// or ErrPoolExhausted if the class can't grow that much because of the max pages or the memory budget.
+func (pool *AtomPool) Prealloc(size, count int) error {
+	if i := pool.classIndex(size); i >= 0 {
+		c := &pool.classes[i]
+		if !c.reserve((count + c.perPage - 1) / c.perPage) {
+			return ErrPoolExhausted
+		}
+		return nil
+	}
+	return ErrNotPooled
 }
 
 // Free release a []byte that alloc from Pool.Alloc.
+// The chunk is located by the address of the first byte, so mem can be resliced to any length and capacity as long as it starts at the chunk.
+// Free panics with a *FreeError on double free, unless a handler is set by WithDoubleFreeHandler,
+// and on guard corruption when the pool is created with WithGuards.
+// With WithLeakDetection it also panics with a *FreeError when mem starts in the middle of a chunk.
 func (pool *AtomPool) Free(mem []byte) {
+	pool.report(mem, pool.free(mem))
+}
+
+// report 按 Free 的约定处理回收 mem 时遇到的错误
+func (pool *AtomPool) report(mem []byte, err error) {
+	if err == ErrDoubleFree {
+		if pool.onDoubleFree != nil {
+			pool.onDoubleFree(mem)
+			return
+		}
+		panic(pool.freeError(mem, err))
+	}
+	if err == ErrNotPooled && pool.interior(mem) {
+		panic(pool.freeError(mem, err))
+	}
+	if err, ok := err.(*CorruptionError); ok {
+		if pool.onCorruption != nil {
+			pool.onCorruption(err)
+			return
+		}
+		panic(err)
+	}
+}
+
+// TryFree is like Free but reports what happened to the buffer.
+// It returns nil if the buffer is returned to a slab class, the buddy tier or the overflow tier, ErrNotPooled if it doesn't belong to any class
+// (e.g. a heap fallback or a slice not starting at a chunk), or ErrDoubleFree instead of panicking if the chunk is already free.
+func (pool *AtomPool) TryFree(mem []byte) error {
+	return pool.free(mem)
+}
+
+func (pool *AtomPool) free(mem []byte) (err error) {
+	if pool.latency != nil {
+		start := time.Now()
+		defer func() { pool.latency.free(start, err == nil) }()
+	}
+	if cap(mem) == 0 && dataPtr(mem) == dataPtr(emptyBuf) {
+		pool.freed(mem, 0, false)
+		return nil
+	}
+	pool.tags.release(mem)
+
+	// 按首指针查找 mem 所属的 chunk，重新切片过的 mem 容量只会变小，
+	// 因此只需查找 chunk 大小不小于 cap(mem) 的 class，未重新切片的 mem 第一次就能找到
 	size := cap(mem)
+	var exact *class
 	for i := 0; i < len(pool.classes); i++ {
-		if pool.classes[i].size == size {
-			pool.classes[i].Push(mem)
-			break
+		c := &pool.classes[i]
+		if c.size < size {
+			continue
+		}
+		ok, err := c.Push(mem)
+		if err == ErrDoubleFree {
+			c.doubleFrees.Add(1)
+			return err
+		}
+		if ok {
+			c.frees.Add(1)
+			pool.freed(mem, c.size, true)
+			pool.spilled(c)
+			if pool.migrated.Load() != nil {
+				pool.releasedBy(TrimMigrate, "class", c.size, c.shrink())
+			}
+			return err
+		}
+		if c.size == size {
+			exact = c
+		}
+	}
+	if exact != nil {
+		exact.rejects.Add(1)
+	}
+	if pool.buddy != nil {
+		if ok, err := pool.buddy.free(mem); ok {
+			if err == nil {
+				pool.freed(mem, 0, true)
+			}
+			return err
 		}
 	}
+	if pool.overflow != nil && pool.overflow.free(mem) {
+		pool.freed(mem, 0, true)
+		return nil
+	}
+	if dst := pool.migrated.Load(); dst != nil {
+		return dst.free(mem)
+	}
+	pool.freed(mem, 0, false)
+	if pool.secondary != nil {
+		pool.secondary.Free(mem)
+		return nil
+	}
+	return ErrNotPooled
+}
+
+// ClassStats is the statistics of a slab class.
+type ClassStats struct {
+	Size        int    // chunk size of the class
+	Pages       int    // number of pages owned by the class
+	Allocs      uint64 // allocations served from the class
+	Fallbacks   uint64 // allocations fall back to make because the class is exhausted
+	OverBudget  uint64 // allocations the class could not serve because the memory budget is reached
+	Frees       uint64 // chunks returned to the class by Free
+	Rejects     uint64 // buffers passed to Free that do not belong to the class
+	DoubleFrees uint64 // chunks passed to Free while they are already free
+	Requests    uint64 // allocations served from the class whose requested size is counted in Requested
+	Requested   uint64 // bytes requested by these allocations, each of them is handed out Size bytes
+	Grows       uint64 // times the class grows when it's exhausted, see WithGrowIncrement
+	InUse       int    // chunks currently allocated
+	Free        int    // chunks currently free
+	Quarantined int    // free chunks held in quarantine which can't be alloc yet
+	Resident    int    // bytes of pages owned by the class
+	PageSize    int    // size of each page of the class
+	TailWaste   int    // bytes at the end of each page too small for one more chunk, never alloc
+	Retries     uint64 // compare-and-swap attempts on the free list lost to other goroutines and retried
+	Yields      uint64 // retries which yield the processor first, see WithBackoff
+	Spins       uint64 // rounds of busy waiting before retries, see WithBackoff
+	Spills      uint64 // free chunks beyond WithClassFreeCap released with their pages
+	Disabled    bool   // the class is taken out of service by SetClassEnabled
+	Pinned      bool   // the pages of the class are kept by Trim, see SetClassPinned
+}
+
+// Fragmentation returns the internal fragmentation of the class, the fraction of the handed out bytes
+// which were not requested, e.g. 0.3 if requests of 90 bytes are served by chunks of 128 bytes on average.
+func (s ClassStats) Fragmentation() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return 1 - float64(s.Requested)/float64(s.Requests*uint64(s.Size))
+}
+
+// Stats returns the statistics of each slab class.
+func (pool *AtomPool) Stats() []ClassStats {
+	stats := make([]ClassStats, len(pool.classes))
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		s := &stats[i]
+		s.Size = c.size
+		s.Pages = int(atomic.LoadInt32(&c.npages))
+		s.Allocs = c.allocs.Load()
+		s.Fallbacks = c.fallbacks.Load()
+		s.OverBudget = c.overBudget.Load()
+		s.Frees = c.frees.Load()
+		s.Rejects = c.rejects.Load()
+		s.DoubleFrees = c.doubleFrees.Load()
+		s.Requests = c.requests.Load()
+		s.Requested = c.requested.Load()
+		s.Grows = c.grows.Load()
+		s.InUse = int(s.Allocs - s.Frees)
+		s.Free = s.Pages*c.perPage - s.InUse
+		s.Resident = s.Pages * c.pageSize
+		s.PageSize = c.pageSize
+		s.TailWaste = c.pageSize - c.perPage*c.stride
+		s.Retries = c.retries.Load()
+		s.Yields = c.yields.Load()
+		s.Spins = c.spinRounds.Load()
+		s.Spills = c.spills.Load()
+		s.Disabled = c.disabled.Load()
+		s.Pinned = c.pinned.Load()
+		if q := c.quarantine; q != nil {
+			q.mu.Lock()
+			s.Quarantined = q.n
+			q.mu.Unlock()
+		}
+	}
+	return stats
+}
+
+// Owns reports whether the backing array of mem lies inside a slab page of the pool.
+func (pool *AtomPool) Owns(mem []byte) bool {
+	if cap(mem) == 0 {
+		return false
+	}
+	ptr := dataPtr(mem)
+	for i := 0; i < len(pool.classes); i++ {
+		if pool.classes[i].Owns(ptr) {
+			return true
+		}
+	}
+	return pool.buddy != nil && pool.buddy.owns(ptr)
+}
+
+// ScrubFree zeroes every free chunk in every slab class, or fills it with the pattern of WithPoison.
+// It must be called in a quiescent window when no other goroutine is calling Alloc or Free.
+// Buffers still in use are not in any free list and are left untouched.
+func (pool *AtomPool) ScrubFree() {
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].Scrub()
+	}
+}
+
+// Trim release the slab pages whose chunks are all free and returns the number of bytes released.
+// Released pages are dropped for the garbage collector, or unmapped when the pool is mmap-backed,
+// a slab class can grow again later if it needs more chunks, a class pinned by SetClassPinned keeps its pages.
+// The buffers kept by WithOverflowCap are released too.
+// It can be called while other goroutines Alloc and Free, they wait for the slab class being trimmed,
+// and the memory of a page is released only after every Alloc and Free in flight when the page was removed has returned.
+func (pool *AtomPool) Trim() int {
+	return pool.trim(TrimExplicit)
 }
 
 type class struct {
-	size      int
-	page      []byte
-	pageBegin uintptr
-	pageEnd   uintptr
-	chunks    []chunk
-	head      uint64
+	id         int // 在 pool.classes 中的下标
+	size       int
+	pageSize   int
+	perPage    int
+	guard      int
+	stride     int
+	pages      []unsafe.Pointer // *page, 下标 >= nslots 的部分未分配，Trim 之后中间也可能为 nil
+	npages     int32            // 已分配的 page 数
+	nslots     int32            // 曾经使用过的 page 下标上限
+	growBy     int              // 每次增长的 page 数，0 表示 1
+	spins      int              // CAS 失败后立即重试的次数
+	pauses     int              // CAS 失败后指数退避忙等的最大轮数
+	listMu     sync.Locker      // 加锁模式下 pop 和 push 空闲链表时持有的锁，无锁模式为 nil
+	growMu     sync.Mutex
+	reclaiming int32         // reclaim 摘下了空闲链表，正在统计和重建
+	waiters    int32         // 在 AllocContext 中等待空闲 chunk 的 goroutine 数
+	wake       chan struct{} // 有等待者时回收 chunk 发出的唤醒信号
+	abaBase    []uint32      // 每个 page 下标上新建 chunk 的初始 ABA 计数，由 growMu 保护
+	src        *source
+	align      int
+	leak       bool
+	sample     atomic.Int64 // 开启采样时每 sample 次分配记录一次调用栈，SetSampling 可以随时修改
+	pretouch   bool
+	fixed      bool        // 实时模式下 page 不再增长和释放
+	disabled   atomic.Bool // SetClassEnabled 停用了 class，请求交给更大的 class
+	pinned     atomic.Bool // SetClassPinned 固定了 class，只有 Close 和 Migrate 释放它的 page
+	freeCap    int         // WithClassFreeCap 设置的空闲 chunk 数上限，0 表示没有上限
+	meta       bool        // 每个 page 为 chunk 保留用户数据
+	profile    *pprof.Profile
+	poison     bool
+	poisonBy   byte
+	quarantine *quarantine // 开启隔离时回收的 chunk 先进入隔离区
+	fifo       *fifo       // 开启 FIFO 时回收的 chunk 先进入后备链表
+	marks      *watermarks // 设置了水位线时检查已分配的 chunk 数
+	counting   bool        // 统计已分配的 chunk 数
+	usage      *usage      // pool 所有 class 合计的用量
+	peakInUse  peak        // 已分配的 chunk 数的峰值
+	peakPages  peak        // page 字节数的峰值
+	chaos      *chaos      // 测试用的随机化模式
+	file       *os.File    // WithFile 的文件，第 n 个 page 映射文件中 fileBase+n*fileStride 开始的区域
+	fileBase   int64
+	fileStride int64
+	onPage     func(e PageEvent) // WithPageHook 设置的 hook
+	owner      *AtomPool         // 开启 WithRouting 时 page 登记在全局登记表中，归属于 owner
+	shuffle    *rand.Rand        // 打乱新 page 和 Reset 之后 chunk 的顺序，由 growMu 保护
+
+	// 以下字段都用 64 位原子操作访问，atomic.Uint64 保证它们在 32 位平台上也按 8 字节对齐
+	head atomic.Uint64
+
+	epoch   epoch         // reclaim 释放 page 之前等待的宽限期
+	inUse   atomic.Int64  // 开启 WithPeaks 或 WithWatermarks 时统计已分配的 chunk 数
+	samples atomic.Uint64 // 采样计数
+
+	// 统计计数
+	allocs      atomic.Uint64
+	fallbacks   atomic.Uint64
+	overBudget  atomic.Uint64
+	frees       atomic.Uint64
+	rejects     atomic.Uint64
+	doubleFrees atomic.Uint64
+	requests    atomic.Uint64 // 记录了请求大小的分配次数
+	requested   atomic.Uint64 // 这些分配请求的字节数
+	grows       atomic.Uint64 // 增长的次数
+	drainMark   atomic.Int64  // 迁移之后上次 Trim 时已分配的 chunk 数
+	retries     atomic.Uint64 // CAS 失败后重试的次数
+	yields      atomic.Uint64 // 重试前让出处理器的次数
+	spinRounds  atomic.Uint64 // 重试前忙等的轮数
+	spills      atomic.Uint64 // 超过空闲 chunk 数上限而随 page 释放的 chunk 数
+	spillMark   atomic.Uint64 // 上次检查空闲 chunk 数上限时的回收次数
+}
+
+// request 记录 n 次请求 size 字节、由本 class 分配的请求，用于统计内部碎片
+func (c *class) request(n, size int) {
+	c.requests.Add(uint64(n))
+	c.requested.Add(uint64(n * size))
+}
+
+type page struct {
+	raw    []byte // 分配得到的原始内存，对齐后的 page 为 mem
+	mem    []byte
+	begin  uintptr
+	end    uintptr
+	chunks []chunk
+	freed  []unsafe.Pointer // *allocInfo, 开启泄漏检测时记录每个 chunk 上一次回收的调用栈
+	meta   []atomic.Uint64  // WithMetadata 为每个 chunk 保留的用户数据
+	idle   time.Time        // 所有 chunk 开始空闲的时间，由 growMu 保护
 }
 
 type chunk struct {
 	mem  []byte
-	aba  uint32 // reslove ABA problem
-	next uint64
+	aba  uint32         // resolve ABA problem, see link.go
+	next atomic.Uint64  // chunk 在 []chunk 中，只有 atomic.Uint64 能保证它在 32 位平台上的对齐
+	info unsafe.Pointer // *allocInfo, 开启泄漏检测时记录分配信息
+}
+
+// page 返回 class 的第 n 个 page
+func (c *class) page(n int) *page {
+	return (*page)(atomic.LoadPointer(&c.pages[n]))
+}
+
+// chunk 返回全局下标为 i 的 chunk，下标 i 在第 i/perPage 个 page 中，page 已被释放时返回 nil
+func (c *class) chunk(i uint64) *chunk {
+	p := c.page(int(i / uint64(c.perPage)))
+	if p == nil {
+		return nil
+	}
+	return &p.chunks[i%uint64(c.perPage)]
 }
 
-func (c *class) Push(mem []byte) {
+// rawSize 返回每个 page 需要分配的原始内存大小
+func (c *class) rawSize() int {
+	if c.align > 1 {
+		// 多分配 align 字节，以便把 page 的起始地址对齐
+		return c.pageSize + c.align
+	}
+	return c.pageSize
+}
 
-	// 获取切片 mem 的底层数组的首指针 ptr
-	ptr := (*reflect.SliceHeader)(unsafe.Pointer(&mem)).Data
+// addPage 用 raw 作为第 n 个 page，并把它的所有 chunk 挂到空闲链表首部，touch 为 true 时预先触碰 page 的内存
+func (c *class) addPage(n int, raw []byte, touch bool) {
+	p := &page{
+		raw:    raw,
+		chunks: make([]chunk, c.perPage),
+	}
+	if c.leak {
+		p.freed = make([]unsafe.Pointer, c.perPage)
+	}
+	if c.meta {
+		p.meta = make([]atomic.Uint64, c.perPage)
+	}
+	p.mem = alignSlice(p.raw, c.align)[:c.pageSize:c.pageSize]
+	if c.pretouch || touch {
+		// 每个操作系统内存页写一个字节，让内核立即分配物理内存
+		for i := 0; i < len(p.mem); i += osPageSize {
+			p.mem[i] = 0
+		}
+	}
+	base := n * c.perPage
+	aba := c.abaBase[n]
 
-	// 判断 ptr 是否属于本 class 管辖的内存范围，若属于则进行回收，否则不予处理
-	if c.pageBegin <= ptr && ptr <= c.pageEnd {
+	// 初始化 page 中所含的 chunks
+	for i := 0; i < len(p.chunks); i++ {
+		chk := &p.chunks[i]
 
-		// 计算 ptr 属于当前 class 内的第几个 chunk 
-		i := (ptr - c.pageBegin) / uintptr(c.size)
+		// 把字节数组 p.mem 按序切分成一个个 chunk，起始地址保存到变量 chk.mem 上
+		off := i*c.stride + c.guard
+		chk.mem = p.mem[off : off+c.size : off+c.size] // lock down the capacity to protect append operation
+		chk.aba = aba
+		if c.guard > 0 {
+			fillGuards(p.mem[off-c.guard:off+c.size+c.guard], c.guard)
+		}
+		if c.poison {
+			// 新的 chunk 也带有毒化内容，Audit 才能检查从未分配过的 chunk
+			memset(chk.mem, c.poisonBy)
+		}
+	}
+	p.begin = uintptr(unsafe.Pointer(&p.chunks[0].mem[0]))
+	p.end = uintptr(unsafe.Pointer(&p.chunks[len(p.chunks)-1].mem[0]))
 
-		// 取出 ptr 所属 chunk
-		chk := &c.chunks[i]
+	// 按下标顺序把 chunk 链接起来，随机化或打乱模式下按随机顺序
+	order := c.order(len(p.chunks))
+	for k := 0; k < len(order)-1; k++ {
+		p.chunks[order[k]].next.Store(makeLink(uint64(base+order[k+1]), aba))
+	}
 
-		// 已分配的 chunk 的 chk.next 值应为 0，若非 0，则意味着此前已被回收，报错
-		if chk.next != 0 {
-			panic("slab.AtomPool: Double Free")
+	// page 必须在 chunk 下标对其它 goroutine 可见之前发布
+	atomic.StorePointer(&c.pages[n], unsafe.Pointer(p))
+	c.resized(1)
+	if int(atomic.LoadInt32(&c.nslots)) < n+1 {
+		atomic.StoreInt32(&c.nslots, int32(n+1))
+	}
+	c.pageChanged(n, p, true)
+
+	// 把新 page 的 chunk 链表整体挂到空闲链表首部
+	last := &p.chunks[order[len(order)-1]]
+	new := makeLink(uint64(base+order[0]), aba)
+	c.lockList()
+	for retry := 0; ; retry++ {
+		old := c.head.Load()
+		last.next.Store(tailLink(old))
+		c.chaos.delay()
+		if c.head.CompareAndSwap(old, new) {
+			break
 		}
+		c.backoff(retry)
+	}
+	c.unlockList()
+}
 
-		chk.aba++
+// grow 在空闲链表为空时为 class 增加 growBy 个 page，达到上限时少增加一些，
+// 返回 false 表示已达到 page 数上限，overBudget 表示受内存预算限制无法增长
+func (c *class) grow() (ok, overBudget bool) {
+	if int(atomic.LoadInt32(&c.npages)) >= len(c.pages) && atomic.LoadInt32(&c.reclaiming) == 0 {
+		return false, false
+	}
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
 
-		// 被回收的 chunk 放到 class 空闲链表首部，因此：
-		// 
-		// chk := &c.chunks[i]
-		// chk.next = c.head
-		// c.head = i
-		// 
-		// 备注，这里第三步的 i 实际上是 new = f(i) = uint64(i+1)<<32 + uint64(chk.aba++)
-		new := uint64(i+1)<<32 + uint64(chk.aba)
-
-		for {
-			// 相当于 chk.next = c.head
-			old := atomic.LoadUint64(&c.head)
-			atomic.StoreUint64(&chk.next, old)
-			// 相当于 c.head = i
-			if atomic.CompareAndSwapUint64(&c.head, old, new) {
-				break
+	// 等待锁期间其它 goroutine 可能已经增长过或者归还了 chunk
+	if c.head.Load() != 0 {
+		return true, false
+	}
+	ok, overBudget = c.addSlot(false)
+	if !ok {
+		return false, overBudget
+	}
+	for i := 1; i < c.growBy; i++ {
+		if more, _ := c.addSlot(false); !more {
+			break
+		}
+	}
+	c.grows.Add(1)
+	return true, false
+}
+
+// addSlot 在第一个空闲的 page 下标上增加一个 page，调用者需持有 growMu
+func (c *class) addSlot(touch bool) (ok, overBudget bool) {
+	if int(atomic.LoadInt32(&c.npages)) >= len(c.pages) {
+		return false, false
+	}
+	// 找到第一个空闲的 page 下标，Trim 释放过的下标可以复用
+	n := 0
+	for c.pages[n] != nil {
+		n++
+	}
+	raw, overBudget, _ := c.getPage(n)
+	if raw == nil {
+		return false, overBudget
+	}
+	c.addPage(n, raw, touch)
+	return true, false
+}
+
+// unlinkPage 摘下第 n 个 page 并返回它，page 的内存由调用者归还，调用者需持有 growMu
+func (c *class) unlinkPage(n int) *page {
+	p := c.page(n)
+	// 之后在同一个下标上新建的 chunk 的 ABA 计数从旧 chunk 的最大值之后开始，
+	// 被挂起的 pop 持有的旧 head 值才不会和新 page 的 chunk 匹配
+	for i := range p.chunks {
+		if p.chunks[i].aba >= c.abaBase[n] {
+			c.abaBase[n] = p.chunks[i].aba + 1
+		}
+	}
+	atomic.StorePointer(&c.pages[n], nil)
+	c.resized(-1)
+	c.pageChanged(n, p, false)
+	return p
+}
+
+// reserve 增长到至少 npages 个 page，新增的 page 会被预先触碰
+func (c *class) reserve(npages int) bool {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+	for int(atomic.LoadInt32(&c.npages)) < npages {
+		if ok, _ := c.addSlot(true); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Owns 判断 ptr 是否位于本 class 的某个 page 内，page 末尾放不下一个 chunk 的部分不算在内
+func (c *class) Owns(ptr uintptr) bool {
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p != nil {
+			begin := uintptr(unsafe.Pointer(&p.mem[0]))
+			if begin <= ptr && ptr < begin+uintptr(c.perPage*c.stride) {
+				return true
 			}
-			runtime.Gosched()
 		}
+	}
+	return false
+}
 
+// Push 返回 mem 是否属于本 class，重复回收时返回 ErrDoubleFree
+func (c *class) Push(mem []byte) (bool, error) {
+	chk, idx, ok, err := c.prepare(mem)
+	if !ok || err == ErrDoubleFree {
+		return ok, err
 	}
 
+	// 被回收的 chunk 放到 class 空闲链表首部，因此：
+	//
+	// chk.next = c.head
+	// c.head = i
+	//
+	// 备注，这里第二步的 i 实际上是链接值 new = makeLink(n*perPage+i, chk.aba)
+	c.release(makeLink(idx, chk.aba), chk)
+	return true, err
+}
+
+// prepare 找到 mem 所属的 chunk 并做回收前的检查和清理，返回 chunk 及其全局下标，
+// ok 为 false 表示 mem 不属于本 class
+func (c *class) prepare(mem []byte) (chk *chunk, idx uint64, ok bool, err error) {
+
+	// 获取切片 mem 的底层数组的首指针 ptr，找到它所属的 chunk，若不属于本 class 则不予处理。
+	// 查找期间进入宽限期，chunk 确认是已分配的之后，它所在的 page 就不会被 reclaim 释放
+	e := c.epoch.enter()
+	p, n, i := c.locate(dataPtr(mem))
+	if p == nil {
+		c.epoch.exit(e)
+		return nil, 0, false, nil
+	}
+
+	// 取出 ptr 所属 chunk
+	chk = &p.chunks[i]
+
+	// 已分配的 chunk 的 chk.next 值应为 0，若非 0，则意味着此前已被回收，报错，
+	// 空闲链表的尾 chunk 的 next 为 linkEnd，同样非 0
+	if chk.next.Load() != 0 {
+		c.epoch.exit(e)
+		return chk, 0, true, ErrDoubleFree
+	}
+	c.epoch.exit(e)
+
+	// 检查 chunk 前后的 guard 是否被改写，若被改写则修复并报告
+	if c.guard > 0 {
+		err = c.checkGuards(p, i, chk)
+	}
+
+	c.clearInfo(chk)
+	if p.meta != nil {
+		p.meta[i].Store(0)
+	}
+	if p.freed != nil {
+		atomic.StorePointer(&p.freed[i], unsafe.Pointer(newFreeInfo()))
+	}
+	if c.profile != nil {
+		c.profile.Remove(chk)
+	}
+	if c.poison {
+		memset(chk.mem, c.poisonBy)
+	}
+
+	chk.aba++
+	c.used(-1)
+	return chk, uint64(n*c.perPage + i), true, err
+}
+
+// locate 返回 ptr 所在的 page、page 的下标和 chunk 在 page 中的下标，ptr 不是本 class 某个 chunk 的开头时 p 为 nil
+func (c *class) locate(ptr uintptr) (p *page, n, i int) {
+	// 判断 ptr 是否属于本 class 某个 page 管辖的内存范围
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p != nil && p.begin <= ptr && ptr <= p.end {
+
+			// 计算 ptr 属于当前 page 内的第几个 chunk，ptr 必须指向 chunk 的开头
+			if (ptr-p.begin)%uintptr(c.stride) != 0 {
+				return nil, 0, 0
+			}
+			return p, n, int((ptr - p.begin) / uintptr(c.stride))
+		}
+	}
+	return nil, 0, 0
+}
+
+// unpop 把 pop 取出、还没有交给调用者的 chunk 按原来的顺序用一次 CAS 挂回空闲链表首部，并撤销 pop 记录的分配信息。
+// 和 Push 不同，chunk 不经过回收检查、毒化和隔离区，也不计入回收次数
+func (c *class) unpop(bufs [][]byte) {
+	var first uint64
+	var last *chunk
+	for _, mem := range bufs {
+		p, n, i := c.locate(dataPtr(mem))
+		chk := &p.chunks[i]
+		c.clearInfo(chk)
+		if c.profile != nil {
+			c.profile.Remove(chk)
+		}
+
+		// 和回收时一样增加 ABA 计数，被挂起的 pop 持有的旧 head 值才不会再次匹配
+		chk.aba++
+		e := makeLink(uint64(n*c.perPage+i), chk.aba)
+		if last == nil {
+			first = e
+		} else {
+			last.next.Store(e)
+		}
+		last = chk
+	}
+	if last != nil {
+		c.pushRun(first, last)
+	}
+	c.used(-len(bufs))
+}
+
+// pushRun 把以 first 为首、last 为尾且已经链接好的一串 chunk 用一次 CAS 整体挂到空闲链表首部
+func (c *class) pushRun(first uint64, last *chunk) {
+	c.lockList()
+	for retry := 0; ; retry++ {
+		// 相当于 last.next = c.head
+		old := c.head.Load()
+		last.next.Store(tailLink(old))
+		// 相当于 c.head = first
+		c.chaos.delay()
+		if c.head.CompareAndSwap(old, first) {
+			break
+		}
+		c.backoff(retry)
+	}
+	c.unlockList()
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		c.signal()
+	}
 }
 
 func (c *class) Pop() []byte {
+	mem, _ := c.pop(c.size, true)
+	return mem
+}
+
+// pop 弹出一个空闲 chunk，size 是请求的大小，记录在分配信息中，空闲链表为空时 grow 为 true 才增长，
+// 若返回 nil 且 overBudget 为 true 表示是受内存预算限制而无法增长
+func (c *class) pop(size int, grow bool) (mem []byte, overBudget bool) {
 
 	// 从本 class 空闲链表推出首部 chunk :
-	// 
-	// chk := &c.chunks[c.head] // 取出首元素
+	//
+	// chk := c.chunk(c.head)   // 取出首元素
 	// c.head = chk.next        // 更新首指针
 	// chk.next = 0             // 重置取出元素的next指针
 	// return chk.mem           // 返回已取出的首元素
-	// 
+	//
+	retry := 0 // CAS 失败的次数
 	for {
 
 		// 获取当前 class 的空闲列表的首 chunk 的下标
-		old := atomic.LoadUint64(&c.head)
+		c.lockList()
+		old := c.head.Load()
 		if old == 0 {
-			return nil
+			c.unlockList()
+			if c.takeFIFO() {
+				continue
+			}
+			if !grow {
+				return nil, false
+			}
+			// 空闲链表为空，尝试增长一个 page
+			ok, overBudget := c.grow()
+			if ok {
+				continue
+			}
+			return nil, overBudget
 		}
 
 		// 取出 head 对应的 chunk: chk, 同时取出其下个 chunk 的坐标: nxt
-		chk := &c.chunks[old>>32-1]
-		nxt := atomic.LoadUint64(&chk.next)
+		// head 被读取之后所在的 page 可能已被 reclaim 释放，此时 head 必然已改变，重试即可
+		e := c.epoch.enter()
+		chk := c.chunk(linkIndex(old))
+		if chk == nil {
+			c.epoch.exit(e)
+			c.unlockList()
+			continue
+		}
+		nxt := nextLink(chk.next.Load())
 
 		// 把 nxt 设置为当前 class 的空闲列表的首 chunk 下标
-		if atomic.CompareAndSwapUint64(&c.head, old, nxt) {
+		c.chaos.delay()
+		ok := c.head.CompareAndSwap(old, nxt)
+		c.epoch.exit(e)
+		c.unlockList()
+		if ok {
 			// 把 chk 的 next 指针置零
-			atomic.StoreUint64(&chk.next, 0)
+			chk.next.Store(0)
+			if c.leak || c.sampled() {
+				atomic.StorePointer(&chk.info, unsafe.Pointer(newAllocInfo(size)))
+			}
+			if c.profile != nil {
+				// skip class.pop, AtomPool.alloc and AtomPool.Alloc
+				c.profile.Add(chk, 3)
+			}
+			c.used(1)
 			// 返回 chk.mem
-			return chk.mem
+			return chk.mem, false
 		}
 
-		runtime.Gosched()
+		c.backoff(retry)
+		retry++
+	}
+}
+
+// popRun 用一次 CAS 从空闲链表首部摘下最多 n 个 chunk，切成 size 大小追加到 bufs，
+// 空闲链表为空且无法增长时不追加，overBudget 表示受内存预算限制
+func (c *class) popRun(bufs [][]byte, n, size int) ([][]byte, bool) {
+	retry := 0
+	for {
+		c.lockList()
+		old := c.head.Load()
+		if old == 0 {
+			c.unlockList()
+			if c.takeFIFO() {
+				continue
+			}
+			ok, overBudget := c.grow()
+			if ok {
+				continue
+			}
+			return bufs, overBudget
+		}
+
+		// 沿空闲链表向后走最多 n 个 chunk，nxt 为摘下这一串之后的新首部，
+		// 期间链表若被其它 goroutine 修改，head 的 ABA 计数必然变化，下面的 CAS 会失败
+		k := 0
+		nxt := old
+		e := c.epoch.enter()
+		for k < n && nxt != 0 {
+			chk := c.chunk(linkIndex(nxt))
+			if chk == nil {
+				// 所在 page 已被 reclaim 释放，head 必然已改变
+				break
+			}
+			nxt = nextLink(chk.next.Load())
+			k++
+		}
+
+		c.chaos.delay()
+		ok := k > 0 && c.head.CompareAndSwap(old, nxt)
+		c.epoch.exit(e)
+		c.unlockList()
+		if ok {
+			c.used(k)
+			for v := old; k > 0; k-- {
+				chk := c.chunk(linkIndex(v))
+				v = nextLink(chk.next.Load())
+				chk.next.Store(0)
+				if c.leak || c.sampled() {
+					atomic.StorePointer(&chk.info, unsafe.Pointer(newAllocInfo(size)))
+				}
+				if c.profile != nil {
+					// skip class.popRun and AtomPool.AllocBatch
+					c.profile.Add(chk, 2)
+				}
+				bufs = append(bufs, chk.mem[:size])
+			}
+			return bufs, false
+		}
+
+		c.backoff(retry)
+		retry++
+	}
+}
+
+func (c *class) Scrub() {
+	c.takeFIFO()
+	// 沿空闲链表遍历，free list 中的 chunk 都是未被使用的，逐个清零，毒化模式下填充毒化内容
+	for v := c.head.Load(); v != 0; {
+		chk := c.chunk(linkIndex(v))
+		if c.poison {
+			memset(chk.mem, c.poisonBy)
+		} else {
+			memclr(chk.mem)
+		}
+		v = nextLink(chk.next.Load())
+	}
+}
+
+func (c *class) Trim() int {
+	return c.reclaim(time.Now(), 0)
+}
+
+// reclaim 释放所有 chunk 都空闲了至少 ttl 的 page，返回释放的字节数。
+// 先把整条空闲链表摘下来，统计和重建都在私有的链表上进行，因此可以和 Alloc、Free 并发执行
+func (c *class) reclaim(now time.Time, ttl time.Duration) int {
+	return c.reclaimKeep(now, ttl, 0)
+}
+
+// reclaimKeep 和 reclaim 相同，但释放 page 之后至少保留 keep 个空闲 chunk
+func (c *class) reclaimKeep(now time.Time, ttl time.Duration, keep int) int {
+	if c.fixed {
+		return 0
+	}
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	// 期间空闲链表为空，让 grow 等待 growMu 而不是直接失败
+	atomic.StoreInt32(&c.reclaiming, 1)
+	defer atomic.StoreInt32(&c.reclaiming, 0)
+	c.takeFIFO()
+	head := c.head.Swap(0)
+
+	// 统计每个 page 中空闲 chunk 的数量
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	free := make([]int, nslots)
+	total := 0
+	for v := head; v != 0; v = nextLink(c.chunk(linkIndex(v)).next.Load()) {
+		free[int(linkIndex(v))/c.perPage]++
+		total++
+	}
+
+	release := make([]bool, nslots)
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p == nil {
+			continue
+		}
+		if free[n] != c.perPage {
+			p.idle = time.Time{}
+			continue
+		}
+		// 记录 page 开始完全空闲的时间
+		if p.idle.IsZero() {
+			p.idle = now
+		}
+		if now.Sub(p.idle) >= ttl && total-c.perPage >= keep {
+			release[n] = true
+			total -= c.perPage
+		}
+	}
+
+	// 重建空闲链表，跳过要释放的 page，保留其余 chunk 的顺序。
+	// 每个 chunk 的 ABA 计数都要增加，被挂起的 pop 持有的旧 head 值才不会再次匹配
+	var first uint64
+	var last *chunk
+	for v := head; v != 0; {
+		idx := linkIndex(v)
+		chk := c.chunk(idx)
+		nxt := nextLink(chk.next.Load())
+		if !release[int(idx)/c.perPage] {
+			chk.aba++
+			e := makeLink(idx, chk.aba)
+			if last == nil {
+				first = e
+			} else {
+				last.next.Store(e)
+			}
+			last = chk
+		}
+		v = nxt
+	}
+
+	// 先摘下要释放的 page，pop 之后再也拿不到它们的 chunk，
+	// 等到此前开始的操作全部退出，才归还它们的内存
+	released := 0
+	pages := make([]*page, 0, nslots)
+	for n := 0; n < nslots; n++ {
+		if release[n] {
+			pages = append(pages, c.unlinkPage(n))
+		}
+	}
+	if len(pages) > 0 {
+		c.epoch.synchronize()
+	}
+	for _, p := range pages {
+		c.putPage(p.raw)
+		released += c.pageSize
+	}
+	if last != nil {
+		c.pushRun(first, last)
 	}
+	return released
 }
@@ -1,7 +1,10 @@
 package slab
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"unsafe"
 
 	"github.com/funny/utest"
 )
@@ -10,7 +13,7 @@ func Test_AtomPool_AllocAndFree(t *testing.T) {
 	pool := NewAtomPool(128, 64*1024, 2, 1024*1024)
 	for i := 0; i < len(pool.classes); i++ {
 
-		temp := make([][]byte, len(pool.classes[i].chunks))
+		temp := make([][]byte, pool.classes[i].perPage)
 
 		for j := 0; j < len(temp); j++ {
 			mem := pool.Alloc(pool.classes[i].size)
@@ -19,12 +22,12 @@ func Test_AtomPool_AllocAndFree(t *testing.T) {
 		}
 
 		
-		utest.Assert(t, pool.classes[i].head == 0)
+		utest.Assert(t, pool.classes[i].head.Load() == 0)
 
 		for j := 0; j < len(temp); j++ {
 			pool.Free(temp[j])
 		}
-		utest.Assert(t, pool.classes[i].head != 0)
+		utest.Assert(t, pool.classes[i].head.Load() != 0)
 	}
 }
 
@@ -44,6 +47,22 @@ func Test_AtomPool_AllocLarge(t *testing.T) {
 	pool.Free(mem)
 }
 
+func Test_AtomPool_AllocZero(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(0)
+	utest.NotNilNow(t, mem)
+	utest.EqualNow(t, cap(mem), 0)
+	utest.EqualNow(t, pool.Stats()[0].Allocs, uint64(0))
+
+	_, pooled := pool.AllocPooled(0)
+	utest.Assert(t, !pooled)
+	utest.IsNilNow(t, pool.TryFree(mem))
+	pool.Free(mem)
+	pool.Free(nil)
+	utest.EqualNow(t, pool.Stats()[0].Frees, uint64(0))
+	utest.EqualNow(t, pool.Stats()[0].Rejects, uint64(0))
+}
+
 func Test_AtomPool_DoubleFree(t *testing.T) {
 	pool := NewAtomPool(128, 1024, 2, 1024)
 	mem := pool.Alloc(64)
@@ -56,16 +75,630 @@ func Test_AtomPool_DoubleFree(t *testing.T) {
 	}()
 }
 
+func Test_AtomPool_TryFree(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(64)
+	pool.Alloc(64)
+	utest.IsNilNow(t, pool.TryFree(mem))
+	utest.Assert(t, pool.TryFree(mem) == ErrDoubleFree)
+	utest.EqualNow(t, pool.Stats()[0].DoubleFrees, uint64(1))
+	utest.EqualNow(t, pool.Stats()[0].Frees, uint64(1))
+
+	utest.Assert(t, pool.TryFree(make([]byte, 128)) == ErrNotPooled)
+	utest.Assert(t, pool.TryFree(make([]byte, 100)) == ErrNotPooled)
+	utest.Assert(t, pool.TryFree(pool.Alloc(2048)) == ErrNotPooled)
+	mem = pool.Alloc(128)
+	utest.Assert(t, pool.TryFree(mem[1:]) == ErrNotPooled)
+	utest.IsNilNow(t, pool.TryFree(mem[:64:64]))
+	utest.Assert(t, pool.TryFree(mem) == ErrDoubleFree)
+}
+
+func Test_AtomPool_DoubleFreeTail(t *testing.T) {
+	// both chunks of the page are allocated, the freed one is the only and last chunk of the free list
+	pool := NewAtomPool(128, 128, 2, 256)
+	a := pool.Alloc(128)
+	b := pool.Alloc(128)
+	utest.IsNilNow(t, pool.TryFree(a))
+	utest.Assert(t, pool.TryFree(a) == ErrDoubleFree)
+	utest.IsNilNow(t, pool.Verify())
+
+	c := pool.Alloc(128)
+	utest.Assert(t, dataPtr(c) == dataPtr(a))
+	utest.Assert(t, !pool.Owns(pool.Alloc(128)))
+	utest.IsNilNow(t, pool.TryFree(b))
+	utest.IsNilNow(t, pool.TryFree(c))
+	utest.Assert(t, pool.TryFree(c) == ErrDoubleFree)
+	utest.Assert(t, pool.TryFree(b) == ErrDoubleFree)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_FreeResliced(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	for _, size := range []int{128, 256, 1024} {
+		mem := pool.Alloc(size)
+		pool.Free(mem[:0:0])
+		mem = pool.Alloc(size)
+		pool.Free(mem[:100:100])
+		mem = pool.Alloc(size)
+		pool.FreeBatch([][]byte{mem[:size/2:size/2]})
+	}
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+		utest.EqualNow(t, s.Rejects, uint64(0))
+	}
+
+	cache := pool.NewCache(4)
+	mem := cache.Alloc(256)
+	cache.Free(mem[:128:128])
+	cache.Flush()
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_AtomPool_DoubleFreeHandler(t *testing.T) {
+	var handled []byte
+	pool := NewAtomPool(128, 1024, 2, 1024, WithDoubleFreeHandler(func(mem []byte) {
+		handled = mem
+	}))
+	mem := pool.Alloc(64)
+	pool.Alloc(64)
+	pool.Free(mem)
+	pool.Free(mem)
+	utest.Assert(t, &handled[0] == &mem[0])
+}
+
+func Test_AtomPool_Owns(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(100)
+	utest.Assert(t, pool.Owns(mem))
+	utest.Assert(t, pool.Owns(mem[50:]))
+	utest.Assert(t, !pool.Owns(mem[:0:0]))
+	utest.Assert(t, !pool.Owns(make([]byte, 128)))
+	utest.Assert(t, !pool.Owns(pool.Alloc(2048)))
+	utest.Assert(t, !pool.Owns(nil))
+}
+
+func Test_AtomPool_Poison(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithPoison(0xDD))
+	mem := pool.Alloc(64)
+	copy(mem, "secret")
+	pool.Free(mem)
+	mem = mem[:cap(mem)]
+	for i := range mem {
+		utest.EqualNow(t, mem[i], byte(0xDD))
+	}
+}
+
 func Test_AtomPool_AllocSlow(t *testing.T) {
 	pool := NewAtomPool(128, 1024, 2, 1024)
 	mem := pool.classes[len(pool.classes)-1].Pop()
 	utest.EqualNow(t, cap(mem), 1024)
-	utest.Assert(t, pool.classes[len(pool.classes)-1].head == 0)
+	utest.Assert(t, pool.classes[len(pool.classes)-1].head.Load() == 0)
 
 	mem = pool.Alloc(1024)
 	utest.EqualNow(t, cap(mem), 1024)
 }
 
+func Test_AtomPool_ScrubFree(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	live := pool.Alloc(128)
+	dead := pool.Alloc(128)
+	for i := range live {
+		live[i] = 0xff
+		dead[i] = 0xff
+	}
+	pool.Free(dead)
+
+	pool.ScrubFree()
+
+	for i := range live {
+		utest.EqualNow(t, live[i], byte(0xff))
+		utest.EqualNow(t, dead[i], byte(0))
+	}
+}
+
+func Test_AtomPool_AllocBatchAtomic(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	bufs, ok := pool.AllocBatchAtomic(100, 8)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, len(bufs), 8)
+	for _, mem := range bufs {
+		utest.EqualNow(t, len(mem), 100)
+		utest.EqualNow(t, cap(mem), 128)
+	}
+	for _, mem := range bufs {
+		pool.Free(mem)
+	}
+}
+
+func Test_AtomPool_AllocBatchAtomic_Rollback(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	first := pool.Alloc(128)
+
+	bufs, ok := pool.AllocBatchAtomic(128, 8)
+	utest.Assert(t, !ok)
+	utest.Assert(t, bufs == nil)
+
+	// the 7 free chunks must still be available after rollback
+	bufs, ok = pool.AllocBatchAtomic(128, 7)
+	utest.Assert(t, ok)
+	utest.Assert(t, pool.classes[0].head.Load() == 0)
+
+	pool.Free(first)
+	for _, mem := range bufs {
+		pool.Free(mem)
+	}
+}
+
+func Test_AtomPool_AllocBatchAtomic_RollbackRelinks(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 1024, WithPoison(0xDD), WithQuarantine(4), WithLeakDetection())
+	c := &pool.classes[0]
+	first := pool.Alloc(128)
+	order := func() (idx []uint64) {
+		for v := c.head.Load(); v != 0; v = nextLink(c.chunk(linkIndex(v)).next.Load()) {
+			idx = append(idx, linkIndex(v))
+		}
+		return
+	}
+	before := fmt.Sprint(order())
+	// new pages are poisoned too, clear the free chunks to tell whether the rollback poisons them
+	for _, idx := range order() {
+		memclr(c.chunk(idx).mem)
+	}
+
+	// the rolled back chunks are relinked in the same order, they are not freed, poisoned nor quarantined
+	_, ok := pool.AllocBatchAtomic(128, 8)
+	utest.Assert(t, !ok)
+	utest.EqualNow(t, fmt.Sprint(order()), before)
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Frees, uint64(0))
+	utest.EqualNow(t, s.Quarantined, 0)
+	utest.EqualNow(t, s.Free, 7)
+	utest.EqualNow(t, len(pool.Leaks(0)), 1)
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, mem[0], byte(0))
+	utest.IsNilNow(t, pool.Verify())
+
+	pool.Free(mem)
+	pool.Free(first)
+}
+
+func Test_AtomPool_AllocBatch(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	bufs := pool.AllocBatch(100, 20)
+	utest.EqualNow(t, len(bufs), 20)
+	for _, mem := range bufs[:16] {
+		utest.EqualNow(t, len(mem), 100)
+		utest.Assert(t, pool.Owns(mem))
+	}
+	for _, mem := range bufs[16:] {
+		utest.Assert(t, !pool.Owns(mem))
+	}
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Allocs, uint64(16))
+	utest.EqualNow(t, s.Fallbacks, uint64(4))
+	utest.EqualNow(t, s.Pages, 2)
+
+	pool.FreeBatch(bufs)
+	s = pool.Stats()[0]
+	utest.EqualNow(t, s.Frees, uint64(16))
+	utest.EqualNow(t, s.InUse, 0)
+
+	// every chunk is back in the free list
+	n := 0
+	for v := pool.classes[0].head.Load(); v != 0; v = nextLink(pool.classes[0].chunk(linkIndex(v)).next.Load()) {
+		n++
+	}
+	utest.EqualNow(t, n, 16)
+
+	bufs = pool.AllocBatch(2000, 2)
+	utest.EqualNow(t, len(bufs[1]), 2000)
+}
+
+func Test_AtomPool_FreeBatch_DoubleFree(t *testing.T) {
+	var doubleFrees int
+	pool := NewAtomPool(128, 1024, 2, 1024, WithDoubleFreeHandler(func([]byte) {
+		doubleFrees++
+	}))
+	a := pool.Alloc(128)
+	b := pool.Alloc(256)
+	pool.FreeBatch([][]byte{a, a, b, a})
+	utest.EqualNow(t, doubleFrees, 2)
+	utest.EqualNow(t, pool.Stats()[0].Frees, uint64(1))
+	utest.EqualNow(t, pool.Stats()[1].Frees, uint64(1))
+}
+
+func Test_AtomPool_BatchParallel(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithMaxPages(4))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				bufs := pool.AllocBatch(128, 10)
+				for _, mem := range bufs {
+					mem[0] = byte(j)
+				}
+				pool.FreeBatch(bufs)
+			}
+		}()
+	}
+	wg.Wait()
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.InUse, 0)
+	utest.EqualNow(t, s.DoubleFrees, uint64(0))
+}
+
+func Test_AtomPool_Grow(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(3))
+	c := &pool.classes[0]
+
+	temp := make([][]byte, c.perPage*3)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+	}
+	utest.EqualNow(t, int(c.npages), 3)
+	utest.Assert(t, c.head.Load() == 0)
+
+	// no more pages can be added, fall back to make
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, int(c.npages), 3)
+
+	for i := 0; i < len(temp); i++ {
+		pool.Free(temp[i])
+	}
+	utest.Assert(t, c.head.Load() != 0)
+
+	for i := 0; i < len(temp); i++ {
+		pool.Alloc(128)
+	}
+	utest.Assert(t, c.head.Load() == 0)
+	utest.EqualNow(t, int(c.npages), 3)
+}
+
+func Test_AtomPool_Prealloc(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4))
+	utest.IsNilNow(t, pool.Prealloc(100, 20))
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+	utest.EqualNow(t, pool.Stats()[0].Free, 24)
+
+	// already large enough
+	utest.IsNilNow(t, pool.Prealloc(128, 10))
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+
+	utest.EqualNow(t, pool.Prealloc(128, 40), ErrPoolExhausted)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 4)
+	utest.EqualNow(t, pool.Prealloc(2048, 1), ErrNotPooled)
+
+	for i := 0; i < 32; i++ {
+		utest.Assert(t, pool.Owns(pool.Alloc(128)))
+	}
+}
+
+func Test_AtomPool_GrowParallel(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(64))
+	var allocated, freed sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		allocated.Add(1)
+		freed.Add(1)
+		go func() {
+			defer freed.Done()
+			temp := make([][]byte, 0, 64)
+			for j := 0; j < 64; j++ {
+				temp = append(temp, pool.Alloc(128))
+			}
+			allocated.Done()
+			allocated.Wait()
+			for _, mem := range temp {
+				pool.Free(mem)
+			}
+		}()
+	}
+	freed.Wait()
+	utest.EqualNow(t, int(pool.classes[0].npages), 64)
+}
+
+// Test_AtomPool_Align64 matters on 32-bit platforms, run it with GOARCH=386 too
+func Test_AtomPool_Align64(t *testing.T) {
+	aligned := func(p unsafe.Pointer) bool { return uintptr(p)%8 == 0 }
+	pool := NewAtomPool(128, 1024, 2, 1024, WithOverflow(4096))
+	for i := range pool.classes {
+		c := &pool.classes[i]
+		utest.Assert(t, aligned(unsafe.Pointer(&c.head)))
+		utest.Assert(t, aligned(unsafe.Pointer(&c.allocs)))
+		for j := range c.page(0).chunks {
+			utest.Assert(t, aligned(unsafe.Pointer(&c.page(0).chunks[j].next)))
+		}
+	}
+	utest.Assert(t, aligned(unsafe.Pointer(&pool.src.budget.used)))
+	for _, b := range pool.overflow.buckets {
+		utest.Assert(t, aligned(unsafe.Pointer(&b.allocs)))
+	}
+
+	// every code path doing 64-bit atomics
+	pool.FreeBatch(pool.AllocBatch(128, 10))
+	pool.Free(pool.Alloc(2048))
+	pool.Trim()
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_Stats(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	temp := make([][]byte, 9)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(100)
+	}
+	pool.Free(temp[0])
+	pool.Free(make([]byte, 128))
+
+	stats := pool.Stats()
+	utest.EqualNow(t, len(stats), 4)
+	utest.EqualNow(t, stats[0].Size, 128)
+	utest.EqualNow(t, stats[0].Pages, 1)
+	utest.EqualNow(t, stats[0].Allocs, uint64(8))
+	utest.EqualNow(t, stats[0].Fallbacks, uint64(1))
+	utest.EqualNow(t, stats[0].Frees, uint64(1))
+	utest.EqualNow(t, stats[0].Rejects, uint64(1))
+	utest.EqualNow(t, stats[0].InUse, 7)
+	utest.EqualNow(t, stats[0].Resident, 1024)
+	utest.EqualNow(t, stats[1].Allocs, uint64(0))
+}
+
+func Test_AtomPool_Fragmentation(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	utest.EqualNow(t, pool.Stats()[0].Fragmentation(), 0.0)
+	pool.Free(pool.Alloc(64))
+	pool.Free(pool.Alloc(128))
+	pool.FreeBatch(pool.AllocBatch(96, 2))
+
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Requests, uint64(4))
+	utest.EqualNow(t, s.Requested, uint64(64+128+96*2))
+	utest.EqualNow(t, s.Fragmentation(), 0.25)
+
+	cache := pool.NewCache(4)
+	for i := 0; i < 4; i++ {
+		cache.Free(cache.Alloc(32))
+	}
+	cache.Flush()
+	s = pool.Stats()[0]
+	utest.EqualNow(t, s.Requests, uint64(8))
+	utest.EqualNow(t, s.Requested, uint64(64+128+96*2+32*4))
+}
+
+func Test_AtomPool_TailWaste(t *testing.T) {
+	pool := NewAtomPool(96, 1024, 2, 1000)
+	stats := pool.Stats()
+	utest.EqualNow(t, stats[0].TailWaste, 1000-10*96)
+	utest.EqualNow(t, stats[1].TailWaste, 1000-5*192)
+	utest.EqualNow(t, stats[2].TailWaste, 1000-2*384)
+	utest.EqualNow(t, stats[3].TailWaste, 1000-768)
+
+	// the tail of a page is not a chunk of the pool
+	p := pool.classes[0].page(0)
+	utest.Assert(t, pool.Owns(p.mem[9*96:]))
+	utest.Assert(t, !pool.Owns(p.mem[10*96:]))
+
+	// the guards around each chunk are not waste
+	guarded := NewAtomPool(96, 1024, 2, 1000, WithGuards(nil))
+	utest.EqualNow(t, guarded.Stats()[0].TailWaste, 1000%(96+2*guardSize))
+}
+
+func Test_AtomPool_AllocZeroed(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(128)
+	for i := range mem {
+		mem[i] = 0xff
+	}
+	pool.Free(mem)
+
+	mem = pool.AllocZeroed(64)
+	utest.EqualNow(t, len(mem), 64)
+	mem = mem[:cap(mem)]
+	for i := range mem {
+		utest.EqualNow(t, mem[i], byte(0))
+	}
+}
+
+func Test_AtomPool_AllocPooled(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithOverflow(4096))
+	for i := 0; i < 8; i++ {
+		mem, pooled := pool.AllocPooled(100)
+		utest.EqualNow(t, len(mem), 100)
+		utest.Assert(t, pooled)
+	}
+	mem, pooled := pool.AllocPooled(100)
+	utest.EqualNow(t, len(mem), 100)
+	utest.Assert(t, !pooled)
+
+	mem, pooled = pool.AllocPooled(2048)
+	utest.Assert(t, pooled)
+	utest.IsNilNow(t, pool.TryFree(mem))
+	_, pooled = pool.AllocPooled(8192)
+	utest.Assert(t, !pooled)
+}
+
+func Test_AtomPool_AllocCap(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.AllocCap(10, 200)
+	utest.EqualNow(t, len(mem), 10)
+	utest.EqualNow(t, cap(mem), 256)
+	mem = append(mem, make([]byte, 246)...)
+	utest.IsNilNow(t, pool.TryFree(mem))
+
+	mem = pool.AllocCap(100, 10)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, cap(mem), 128)
+	pool.Free(mem)
+
+	mem = pool.AllocCap(10, 2000)
+	utest.EqualNow(t, len(mem), 10)
+	utest.EqualNow(t, cap(mem), 2000)
+
+	pool = NewAtomPool(128, 1024, 2, 1024, WithNoHeap())
+	utest.Assert(t, pool.AllocCap(10, 2000) == nil)
+}
+
+func Test_AtomPool_Realloc(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(100)
+	copy(mem, "hello")
+
+	mem = pool.Realloc(mem, 120)
+	utest.EqualNow(t, len(mem), 120)
+	utest.EqualNow(t, cap(mem), 128)
+
+	mem = pool.Realloc(mem, 200)
+	utest.EqualNow(t, len(mem), 200)
+	utest.EqualNow(t, cap(mem), 256)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 1)
+
+	// the larger chunk can't be allocated, mem is kept
+	pool = NewAtomPool(128, 1024, 2, 1024, WithNoHeap())
+	mem = pool.Alloc(100)
+	copy(mem, "hello")
+	utest.Assert(t, pool.Realloc(mem, 2000) == nil)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+}
+
+func Test_AtomPool_GrowBuffer(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(100)
+	copy(mem, "hello")
+
+	mem = pool.Grow(mem, 28)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, cap(mem), 128)
+
+	mem = pool.Grow(mem, 29)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, cap(mem), 256)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 1)
+
+	var buf []byte
+	for i := 0; i < 1000; i++ {
+		buf = pool.Append(buf, byte(i))
+	}
+	utest.EqualNow(t, len(buf), 1000)
+	utest.EqualNow(t, cap(buf), 1024)
+	for i := range buf {
+		utest.EqualNow(t, buf[i], byte(i))
+	}
+	utest.Assert(t, pool.Owns(buf))
+	utest.EqualNow(t, pool.Stats()[3].InUse, 1)
+	pool.Free(buf)
+	pool.Free(mem)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// the larger chunk can't be allocated, mem is kept
+	pool = NewAtomPool(128, 1024, 2, 1024, WithNoHeap())
+	mem = pool.Alloc(1000)
+	copy(mem, "hello")
+	grown := pool.Grow(mem, 100)
+	utest.EqualNow(t, len(grown), 1000)
+	utest.EqualNow(t, cap(grown), 1024)
+	utest.Assert(t, &grown[0] == &mem[0])
+	utest.Assert(t, pool.Append(mem, make([]byte, 100)...) == nil)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	utest.EqualNow(t, pool.Stats()[3].InUse, 1)
+}
+
+func Test_AtomPool_Shrink(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(1000)
+	copy(mem, "hello")
+
+	mem = pool.Shrink(mem, 600)
+	utest.EqualNow(t, len(mem), 600)
+	utest.EqualNow(t, cap(mem), 1024)
+
+	mem = pool.Shrink(mem, 300)
+	utest.EqualNow(t, len(mem), 300)
+	utest.EqualNow(t, cap(mem), 512)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 1)
+
+	// the smaller class is exhausted
+	temp := pool.AllocBatch(128, 8)
+	mem = pool.Shrink(mem, 5)
+	utest.EqualNow(t, len(mem), 5)
+	utest.EqualNow(t, cap(mem), 512)
+	pool.FreeBatch(temp)
+
+	mem = pool.Shrink(mem, 5)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, string(mem), "hello")
+
+	big := pool.Shrink(make([]byte, 4096), 2000)
+	utest.EqualNow(t, cap(big), 4096)
+}
+
+func Test_AtomPool_Trim(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4))
+	c := &pool.classes[0]
+
+	temp := make([][]byte, c.perPage*3)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+	}
+	utest.EqualNow(t, int(c.npages), 3)
+
+	// keep the first chunk of the first page in use
+	for i := 1; i < len(temp); i++ {
+		pool.Free(temp[i])
+	}
+	// the untouched pages of the other 3 classes are released too
+	utest.EqualNow(t, pool.Trim(), 2*1024+3*1024)
+	utest.EqualNow(t, int(c.npages), 1)
+	utest.EqualNow(t, pool.Stats()[0].Resident, 1024)
+	utest.EqualNow(t, pool.Trim(), 0)
+
+	// the rest of the first page is still allocatable, then the class grows again
+	for i := 1; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+		utest.EqualNow(t, cap(temp[i]), 128)
+	}
+	utest.EqualNow(t, int(c.npages), 3)
+	utest.Assert(t, c.head.Load() == 0)
+
+	for i := 0; i < len(temp); i++ {
+		pool.Free(temp[i])
+	}
+	utest.EqualNow(t, pool.Trim(), 3*1024)
+	utest.EqualNow(t, int(c.npages), 0)
+	utest.Assert(t, c.head.Load() == 0)
+
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, int(c.npages), 1)
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Stats()[0].Rejects, uint64(0))
+}
+
+func Test_AtomPool_AllocAligned(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithAlignment(64))
+	mem := pool.AllocAligned(100, 64)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.Assert(t, isAligned(mem, 64))
+
+	// larger than the pool alignment, fall back to make
+	mem = pool.AllocAligned(100, 4096)
+	utest.EqualNow(t, len(mem), 100)
+	utest.Assert(t, isAligned(mem, 4096))
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+}
+
 func Benchmark_AtomPool_AllocAndFree_128(b *testing.B) {
 	pool := NewAtomPool(128, 1024, 2, 64*1024)
 	b.ResetTimer()
@@ -86,6 +719,24 @@ func Benchmark_AtomPool_AllocAndFree_256(b *testing.B) {
 	})
 }
 
+func Benchmark_AtomPool_AllocBatch_128(b *testing.B) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.FreeBatch(pool.AllocBatch(128, 64))
+	}
+}
+
+func Benchmark_AtomPool_AllocZeroed_512(b *testing.B) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Free(pool.AllocZeroed(512))
+		}
+	})
+}
+
 func Benchmark_AtomPool_AllocAndFree_512(b *testing.B) {
 	pool := NewAtomPool(128, 1024, 2, 64*1024)
 	b.ResetTimer()
@@ -95,3 +746,23 @@ func Benchmark_AtomPool_AllocAndFree_512(b *testing.B) {
 		}
 	})
 }
+
+func benchmarkGrowIncrement(b *testing.B, pages int) {
+	bufs := make([][]byte, 0, 512)
+	for i := 0; i < b.N; i++ {
+		pool, _ := NewPool(WithClasses(1024), WithPageSize(8*1024), WithMaxPages(64), WithLazy(), WithGrowIncrement(pages))
+		for j := 0; j < cap(bufs); j++ {
+			bufs = append(bufs, pool.Alloc(1024))
+		}
+		pool.FreeBatch(bufs)
+		bufs = bufs[:0]
+	}
+}
+
+func Benchmark_AtomPool_GrowIncrement_1(b *testing.B) {
+	benchmarkGrowIncrement(b, 1)
+}
+
+func Benchmark_AtomPool_GrowIncrement_8(b *testing.B) {
+	benchmarkGrowIncrement(b, 8)
+}
@@ -0,0 +1,53 @@
+package slab
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// autoFreed 是被垃圾回收归还给 pool 的 AutoBuffer 的个数
+var autoFreed atomic.Uint64
+
+// AutoBuffer is a chunk of Pool which is returned to the pool by the garbage collector if its owner drops it without Free,
+// insurance for callers which may forget to free on some path, e.g. an error return. Free is still the fast path,
+// the finalizer only runs for the forgotten buffers and AutoFreed counts them.
+// The AutoBuffer must stay reachable while its bytes are used, e.g. by runtime.KeepAlive(b) after the last use,
+// otherwise the chunk may be returned to the pool while the bytes are still used.
+type AutoBuffer struct {
+	pool Pool
+	buf  []byte
+}
+
+// NewAutoBuffer create an AutoBuffer with a chunk of size bytes alloc from pool.
+func NewAutoBuffer(pool Pool, size int) *AutoBuffer {
+	b := &AutoBuffer{pool: pool, buf: pool.Alloc(size)}
+	runtime.SetFinalizer(b, (*AutoBuffer).finalize)
+	return b
+}
+
+// Bytes returns the chunk, see AutoBuffer about keeping b reachable while it's used.
+func (b *AutoBuffer) Bytes() []byte { return b.buf }
+
+// Free release the chunk to the pool and removes the finalizer, the bytes must not be used after that.
+// It panics if the buffer is already freed.
+func (b *AutoBuffer) Free() {
+	if b.buf == nil {
+		panic("slab.AutoBuffer: Double Free")
+	}
+	runtime.SetFinalizer(b, nil)
+	b.pool.Free(b.buf)
+	b.buf = nil
+}
+
+// finalize 在 b 不可达且没有 Free 时由垃圾回收调用
+func (b *AutoBuffer) finalize() {
+	autoFreed.Add(1)
+	b.pool.Free(b.buf)
+	b.buf = nil
+}
+
+// AutoFreed returns the number of AutoBuffers returned to their pool by the garbage collector because they were not freed,
+// a growing count means some code path forgets to call Free.
+func AutoFreed() uint64 {
+	return autoFreed.Load()
+}
@@ -0,0 +1,46 @@
+package slab
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AutoBuffer_Free(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	n := AutoFreed()
+	b := NewAutoBuffer(pool, 100)
+	utest.EqualNow(t, len(b.Bytes()), 100)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+	b.Free()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.IsNilNow(t, b.Bytes())
+	func() {
+		defer func() {
+			utest.NotNilNow(t, recover())
+		}()
+		b.Free()
+	}()
+	runtime.GC()
+	utest.EqualNow(t, AutoFreed(), n)
+}
+
+func Test_AutoBuffer_Forgotten(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	n := AutoFreed()
+	for i := 0; i < 4; i++ {
+		NewAutoBuffer(pool, 128)
+	}
+	utest.EqualNow(t, pool.Stats()[0].InUse, 4)
+
+	// finalizer 在单独的 goroutine 中执行，多等几轮垃圾回收
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Stats()[0].InUse > 0 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, AutoFreed()-n, uint64(4))
+}
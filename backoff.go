@@ -0,0 +1,30 @@
+package slab
+
+import "runtime"
+
+// backoff 在第 n 次（从 0 开始计数）CAS 失败之后等待：前 spins 次立即重试，之后忙等 1、2、4 ... 轮，
+// 超过 pauses 轮之后每次都让出处理器。默认的 spins 和 pauses 都为 0，即每次失败都让出处理器
+func (c *class) backoff(n int) {
+	c.retries.Add(1)
+	if n < c.spins {
+		return
+	}
+	if k := n - c.spins; k < 31 && 1<<k <= c.pauses {
+		c.spinRounds.Add(1 << k)
+		spin(1 << k)
+		return
+	}
+	c.yields.Add(1)
+	runtime.Gosched()
+}
+
+// spin 忙等 n 轮，不能内联，循环才不会被优化掉
+//
+//go:noinline
+func spin(n int) int {
+	x := 0
+	for i := 0; i < n; i++ {
+		x += i
+	}
+	return x
+}
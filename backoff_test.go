@@ -0,0 +1,59 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Class_Backoff(t *testing.T) {
+	c := &class{spins: 2, pauses: 4}
+	for n := 0; n < 7; n++ {
+		c.backoff(n)
+	}
+	// 0、1 立即重试，2、3、4 忙等 1、2、4 轮，之后让出处理器
+	utest.EqualNow(t, c.retries.Load(), uint64(7))
+	utest.EqualNow(t, c.yields.Load(), uint64(2))
+	utest.EqualNow(t, c.spinRounds.Load(), uint64(1+2+4))
+
+	c = &class{}
+	c.backoff(0)
+	utest.EqualNow(t, c.yields.Load(), uint64(1))
+}
+
+func Test_AtomPool_Backoff(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithBackoff(4, 64)}} {
+		// 随机化模式在 load 和 CAS 之间让出处理器，制造 CAS 失败
+		opts = append(opts, WithSizeRange(128, 128), WithPageSize(4096), WithChaos(1))
+		pool, err := NewPool(opts...)
+		utest.IsNilNow(t, err)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					pool.Free(pool.Alloc(128))
+				}
+			}()
+		}
+		wg.Wait()
+		s := pool.Stats()[0]
+		utest.Assert(t, s.Retries > 0)
+		if len(opts) == 3 {
+			utest.EqualNow(t, s.Yields, s.Retries)
+			utest.EqualNow(t, s.Spins, uint64(0))
+		} else {
+			utest.Assert(t, s.Yields < s.Retries)
+		}
+		utest.EqualNow(t, s.InUse, 0)
+	}
+}
+
+func Test_NewPool_BackoffInvalid(t *testing.T) {
+	_, err := NewPool(WithBackoff(-1, 0))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithBackoff(0, -1))
+	utest.NotNilNow(t, err)
+}
@@ -0,0 +1,256 @@
+package slab
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// buddy 是服务于大于 maxSize 的请求的伙伴分配器，
+// 每个 arena 的大小为 maxBlock，可以按 2 的幂逐级对半切分到 minBlock，回收时与相邻的伙伴块合并
+type buddy struct {
+	mu        sync.Mutex
+	minBlock  int
+	maxBlock  int
+	levels    int // maxBlock = minBlock << (levels-1)
+	maxArenas int
+	arenas    []*buddyArena
+	src       *source
+	align     int
+
+	// 统计计数，由 mu 保护
+	allocs     uint64
+	fallbacks  uint64
+	overBudget uint64
+	frees      uint64
+	rejects    uint64
+	inUse      int
+}
+
+type buddyArena struct {
+	raw   []byte
+	mem   []byte
+	begin uintptr
+	free  []map[int]struct{} // 每一级空闲块的偏移
+	used  map[int]int        // 已分配块的偏移 -> 级别
+}
+
+// newBuddy 创建块大小从大于 minSize 的最小的 2 的幂到 maxBlock 的伙伴分配器
+func newBuddy(minSize, maxBlock, maxArenas int, src *source, align int) *buddy {
+	b := &buddy{
+		minBlock:  1,
+		maxBlock:  maxBlock,
+		maxArenas: maxArenas,
+		src:       src,
+		align:     align,
+	}
+	for b.minBlock <= minSize {
+		b.minBlock <<= 1
+	}
+	for b.minBlock<<b.levels <= maxBlock {
+		b.levels++
+	}
+	return b
+}
+
+// newArena 用 raw 创建一个 arena
+func (b *buddy) newArena(raw []byte) *buddyArena {
+	a := &buddyArena{
+		raw:  raw,
+		free: make([]map[int]struct{}, b.levels),
+		used: make(map[int]int),
+	}
+	for i := range a.free {
+		a.free[i] = make(map[int]struct{})
+	}
+	a.mem = alignSlice(a.raw, b.align)[:b.maxBlock:b.maxBlock]
+	a.begin = uintptr(unsafe.Pointer(&a.mem[0]))
+	a.free[b.levels-1][0] = struct{}{}
+	return a
+}
+
+// rawSize 返回每个 arena 需要分配的原始内存大小
+func (b *buddy) rawSize() int {
+	if b.align > 1 {
+		return b.maxBlock + b.align
+	}
+	return b.maxBlock
+}
+
+// take 从 arena 中取出一个 level 级的块，没有足够大的空闲块时返回 false
+func (a *buddyArena) take(b *buddy, level int) (int, bool) {
+	k := level
+	for k < b.levels && len(a.free[k]) == 0 {
+		k++
+	}
+	if k == b.levels {
+		return 0, false
+	}
+	off := 0
+	for off = range a.free[k] {
+		break
+	}
+	delete(a.free[k], off)
+	// 逐级对半切分，后一半放回对应级别的空闲集合
+	for k > level {
+		k--
+		a.free[k][off+b.minBlock<<k] = struct{}{}
+	}
+	a.used[off] = level
+	return off, true
+}
+
+// alloc 分配一个能容纳 size 字节的块，overBudget 表示受内存预算限制而无法增加 arena
+func (b *buddy) alloc(size int) (mem []byte, overBudget bool) {
+	level := 0
+	for b.minBlock<<level < size {
+		level++
+	}
+	blockSize := b.minBlock << level
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.arenas {
+		if off, ok := a.take(b, level); ok {
+			b.allocs++
+			b.inUse += blockSize
+			return a.mem[off : off+size : off+blockSize], false
+		}
+	}
+	if len(b.arenas) < b.maxArenas {
+		raw, overBudget, _ := b.src.get(b.rawSize())
+		if overBudget {
+			b.overBudget++
+			return nil, true
+		}
+		if raw != nil {
+			a := b.newArena(raw)
+			b.arenas = append(b.arenas, a)
+			off, _ := a.take(b, level)
+			b.allocs++
+			b.inUse += blockSize
+			return a.mem[off : off+size : off+blockSize], false
+		}
+	}
+	b.fallbacks++
+	return nil, false
+}
+
+// free 回收 mem，返回 mem 是否位于某个 arena 内
+func (b *buddy) free(mem []byte) (bool, error) {
+	ptr := dataPtr(mem)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.arenas {
+		if a.begin <= ptr && ptr < a.begin+uintptr(b.maxBlock) {
+			off := int(ptr - a.begin)
+			// 块按首地址查找，mem 重新切片过也能回收
+			level, ok := a.used[off]
+			if !ok {
+				// 容量和对齐都像是分配出去的块却不在已分配集合中，说明已被回收过
+				if b.isBlock(off, cap(mem)) {
+					return true, ErrDoubleFree
+				}
+				b.rejects++
+				return true, ErrNotPooled
+			}
+			delete(a.used, off)
+			b.frees++
+			b.inUse -= b.minBlock << level
+
+			// 伙伴块也空闲时合并成上一级的块
+			for level < b.levels-1 {
+				buddyOff := off ^ b.minBlock<<level
+				if _, ok := a.free[level][buddyOff]; !ok {
+					break
+				}
+				delete(a.free[level], buddyOff)
+				if buddyOff < off {
+					off = buddyOff
+				}
+				level++
+			}
+			a.free[level][off] = struct{}{}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isBlock 判断偏移 off 处是否可能存在一个大小为 size 的块
+func (b *buddy) isBlock(off, size int) bool {
+	for level := 0; level < b.levels; level++ {
+		if b.minBlock<<level == size {
+			return off%size == 0
+		}
+	}
+	return false
+}
+
+func (b *buddy) owns(ptr uintptr) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.arenas {
+		if a.begin <= ptr && ptr < a.begin+uintptr(b.maxBlock) {
+			return true
+		}
+	}
+	return false
+}
+
+// trim 释放完全空闲的 arena，返回释放的字节数
+func (b *buddy) trim() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	released := 0
+	arenas := b.arenas[:0]
+	for _, a := range b.arenas {
+		if len(a.used) == 0 {
+			b.src.put(a.raw)
+			released += b.maxBlock
+			continue
+		}
+		arenas = append(arenas, a)
+	}
+	for i := len(arenas); i < len(b.arenas); i++ {
+		b.arenas[i] = nil
+	}
+	b.arenas = arenas
+	return released
+}
+
+// BuddyStats is the statistics of the buddy tier.
+type BuddyStats struct {
+	MinBlock   int    // smallest block size
+	MaxBlock   int    // largest block size, which is also the size of each arena
+	Arenas     int    // number of arenas owned by the tier
+	Allocs     uint64 // allocations served by the tier
+	Fallbacks  uint64 // allocations fall back because all arenas are full
+	OverBudget uint64 // allocations the tier could not serve because the memory budget is reached
+	Frees      uint64 // blocks returned to the tier by Free
+	Rejects    uint64 // buffers passed to Free that point into an arena but are not an allocated block
+	InUse      int    // bytes of blocks currently allocated
+	Resident   int    // bytes of arenas owned by the tier
+}
+
+// BuddyStats returns the statistics of the buddy tier, or the zero value if the pool is created without WithBuddy.
+func (pool *AtomPool) BuddyStats() BuddyStats {
+	b := pool.buddy
+	if b == nil {
+		return BuddyStats{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BuddyStats{
+		MinBlock:   b.minBlock,
+		MaxBlock:   b.maxBlock,
+		Arenas:     len(b.arenas),
+		Allocs:     b.allocs,
+		Fallbacks:  b.fallbacks,
+		OverBudget: b.overBudget,
+		Frees:      b.frees,
+		Rejects:    b.rejects,
+		InUse:      b.inUse,
+		Resident:   len(b.arenas) * b.maxBlock,
+	}
+}
@@ -0,0 +1,65 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Buddy(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithBuddy(8192, 1))
+	stats := pool.BuddyStats()
+	utest.EqualNow(t, stats.MinBlock, 2048)
+	utest.EqualNow(t, stats.MaxBlock, 8192)
+	utest.EqualNow(t, stats.Arenas, 0)
+
+	a := pool.Alloc(3000)
+	utest.EqualNow(t, len(a), 3000)
+	utest.EqualNow(t, cap(a), 4096)
+	b := pool.Alloc(2000)
+	utest.EqualNow(t, cap(b), 2048)
+	c := pool.Alloc(2048)
+	utest.EqualNow(t, cap(c), 2048)
+	utest.Assert(t, pool.Owns(a) && pool.Owns(b) && pool.Owns(c))
+
+	stats = pool.BuddyStats()
+	utest.EqualNow(t, stats.Arenas, 1)
+	utest.EqualNow(t, stats.InUse, 8192)
+
+	// the arena is full
+	d := pool.Alloc(2048)
+	utest.Assert(t, !pool.Owns(d))
+	utest.EqualNow(t, pool.BuddyStats().Fallbacks, uint64(1))
+
+	utest.EqualNow(t, pool.TryFree(b[1:]), ErrNotPooled)
+	utest.IsNilNow(t, pool.TryFree(b[:10:10]))
+	utest.EqualNow(t, pool.TryFree(b), ErrDoubleFree)
+	utest.IsNilNow(t, pool.TryFree(c))
+	utest.IsNilNow(t, pool.TryFree(a))
+
+	// freed blocks are coalesced into the whole arena
+	e := pool.Alloc(8000)
+	utest.EqualNow(t, cap(e), 8192)
+	utest.EqualNow(t, &e[0], &a[0])
+	pool.Free(e)
+
+	stats = pool.BuddyStats()
+	utest.EqualNow(t, stats.Allocs, uint64(4))
+	utest.EqualNow(t, stats.Frees, uint64(4))
+	utest.EqualNow(t, stats.Rejects, uint64(1))
+	utest.EqualNow(t, stats.InUse, 0)
+
+	// every slab page is free too
+	utest.EqualNow(t, pool.Trim(), 8192+4*1024)
+	utest.EqualNow(t, pool.BuddyStats().Arenas, 0)
+}
+
+func Test_AtomPool_BuddyBudget(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithBuddy(8192, 2), WithMaxMemory(4*1024+8192), WithStrictBudget())
+	a, err := pool.TryAlloc(8192)
+	utest.IsNilNow(t, err)
+	_, err = pool.TryAlloc(8192)
+	utest.EqualNow(t, err, ErrPoolExhausted)
+	utest.EqualNow(t, pool.BuddyStats().OverBudget, uint64(1))
+	pool.Free(a)
+}
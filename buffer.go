@@ -0,0 +1,116 @@
+package slab
+
+import "io"
+
+const minRead = 512
+
+// Buffer is a variable-sized buffer of bytes backed by a chunk of Pool.
+// It implements io.Reader, io.Writer, io.ReaderFrom and io.Closer,
+// the chunk is returned to the pool when the Buffer is closed.
+type Buffer struct {
+	pool Pool
+	buf  []byte
+	off  int
+}
+
+// NewBuffer create a Buffer with a chunk of at least size bytes alloc from pool.
+func NewBuffer(pool Pool, size int) *Buffer {
+	return &Buffer{pool: pool, buf: pool.Alloc(size)[:0]}
+}
+
+// Bytes returns the unread portion of the buffer.
+// The slice is valid only until the next write or Close.
+func (b *Buffer) Bytes() []byte { return b.buf[b.off:] }
+
+// Len returns the number of bytes of the unread portion of the buffer.
+func (b *Buffer) Len() int { return len(b.buf) - b.off }
+
+// Cap returns the capacity of the underlying chunk.
+func (b *Buffer) Cap() int { return cap(b.buf) }
+
+// Reset empty the buffer but keep the underlying chunk for future writes.
+func (b *Buffer) Reset() {
+	b.buf = b.buf[:0]
+	b.off = 0
+}
+
+// grow make room for n more bytes, when the chunk is too small a larger one is alloc from the pool.
+func (b *Buffer) grow(n int) {
+	l := b.Len()
+	if len(b.buf)+n <= cap(b.buf) {
+		return
+	}
+	if l+n <= cap(b.buf) {
+		// 已读部分腾出的空间足够，把未读数据移到开头
+		copy(b.buf, b.buf[b.off:])
+		b.buf = b.buf[:l]
+		b.off = 0
+		return
+	}
+	size := 2 * cap(b.buf)
+	if size < l+n {
+		size = l + n
+	}
+	buf := b.pool.Alloc(size)
+	copy(buf, b.buf[b.off:])
+	if b.buf != nil {
+		b.pool.Free(b.buf)
+	}
+	b.buf = buf[:l]
+	b.off = 0
+}
+
+// Write appends the contents of p to the buffer, growing the buffer as needed.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.grow(len(p))
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// Read reads the next len(p) bytes from the buffer or until the buffer is drained.
+// If the buffer has no data to return, err is io.EOF (unless len(p) is zero).
+func (b *Buffer) Read(p []byte) (int, error) {
+	if b.off >= len(b.buf) {
+		b.Reset()
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf[b.off:])
+	b.off += n
+	return n, nil
+}
+
+// ReadFrom reads data from r until EOF and appends it to the buffer, growing the buffer as needed.
+func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		b.grow(minRead)
+		n, err := r.Read(b.buf[len(b.buf):cap(b.buf)])
+		b.buf = b.buf[:len(b.buf)+n]
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Close return the underlying chunk to the pool.
+// The buffer must not be used after Close, closing it again is a no-op.
+func (b *Buffer) Close() error {
+	if b.buf != nil {
+		b.pool.Free(b.buf)
+		b.buf = nil
+		b.off = 0
+	}
+	return nil
+}
+
+var _ io.Reader = (*Buffer)(nil)
+var _ io.Writer = (*Buffer)(nil)
+var _ io.ReaderFrom = (*Buffer)(nil)
+var _ io.Closer = (*Buffer)(nil)
@@ -0,0 +1,28 @@
+package slab
+
+// BufferPool adapts a Pool to httputil.BufferPool, e.g. for the BufferPool of httputil.ReverseProxy.
+type BufferPool struct {
+	pool Pool
+	size int
+}
+
+// NewBufferPool create a BufferPool whose Get returns buffers of size bytes alloc from pool.
+// If size <= 0 it's 32KB, the buffer size ReverseProxy uses without a BufferPool.
+func NewBufferPool(pool Pool, size int) *BufferPool {
+	if size <= 0 {
+		size = 32 * 1024
+	}
+	return &BufferPool{pool: pool, size: size}
+}
+
+// Get alloc a buffer of the configured size.
+func (p *BufferPool) Get() []byte {
+	return p.pool.Alloc(p.size)[:p.size]
+}
+
+// Put return a buffer got by Get to the pool, the buffer may be resliced.
+func (p *BufferPool) Put(b []byte) {
+	if cap(b) > 0 {
+		p.pool.Free(b)
+	}
+}
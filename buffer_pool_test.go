@@ -0,0 +1,43 @@
+package slab
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+var _ httputil.BufferPool = (*BufferPool)(nil)
+
+func Test_BufferPool(t *testing.T) {
+	pool := NewAtomPool(1024, 64*1024, 2, 64*1024)
+	bp := NewBufferPool(pool, 0)
+	b := bp.Get()
+	utest.EqualNow(t, len(b), 32*1024)
+	bp.Put(b[:10])
+	utest.EqualNow(t, pool.Stats()[5].Frees, uint64(1))
+	bp.Put(nil)
+
+	body := strings.Repeat("slab", 50*1024)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer backend.Close()
+	target, err := url.Parse(backend.URL)
+	utest.IsNilNow(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.BufferPool = NewBufferPool(pool, 4096)
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	utest.EqualNow(t, rec.Body.String(), body)
+	s := pool.Stats()[2]
+	utest.EqualNow(t, s.Size, 4096)
+	utest.Assert(t, s.Allocs > 0)
+	utest.EqualNow(t, s.InUse, 0)
+}
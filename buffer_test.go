@@ -0,0 +1,60 @@
+package slab
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Buffer_WriteAndRead(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewBuffer(pool, 100)
+	utest.EqualNow(t, b.Cap(), 128)
+
+	n, err := b.Write([]byte("hello "))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 6)
+	b.Write([]byte("world"))
+	utest.EqualNow(t, string(b.Bytes()), "hello world")
+
+	p := make([]byte, 6)
+	n, err = b.Read(p)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(p[:n]), "hello ")
+	utest.EqualNow(t, b.Len(), 5)
+
+	n, _ = b.Read(p)
+	utest.EqualNow(t, string(p[:n]), "world")
+	_, err = b.Read(p)
+	utest.EqualNow(t, err, io.EOF)
+
+	utest.IsNilNow(t, b.Close())
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.IsNilNow(t, b.Close())
+}
+
+func Test_Buffer_Grow(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewBuffer(pool, 128)
+	data := bytes.Repeat([]byte("x"), 300)
+	b.Write(data)
+	utest.EqualNow(t, b.Cap(), 512)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 1)
+	utest.Assert(t, bytes.Equal(b.Bytes(), data))
+	b.Close()
+	utest.EqualNow(t, pool.Stats()[2].InUse, 0)
+}
+
+func Test_Buffer_ReadFrom(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewBuffer(pool, 128)
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	n, err := b.ReadFrom(bytes.NewReader(data))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, int64(len(data)))
+	utest.Assert(t, bytes.Equal(b.Bytes(), data))
+	b.Close()
+}
@@ -0,0 +1,344 @@
+package slab
+
+import (
+	"bufio"
+	"io"
+)
+
+// Reader is a buffered reader like bufio.Reader whose buffer is a chunk of Pool.
+// The chunk is returned to the pool by Release.
+type Reader struct {
+	pool     Pool
+	buf      []byte
+	rd       io.Reader
+	r, w     int // buf[r:w] 为尚未读取的数据
+	err      error
+	lastByte int
+}
+
+// NewReaderSize create a Reader reading from rd with a buffer of at least size bytes alloc from pool.
+func NewReaderSize(pool Pool, rd io.Reader, size int) *Reader {
+	mem := pool.Alloc(size)
+	return &Reader{pool: pool, buf: mem[:cap(mem)], rd: rd, lastByte: -1}
+}
+
+// Reset discard any buffered data and make the Reader read from rd, keeping the buffer.
+func (b *Reader) Reset(rd io.Reader) {
+	b.rd = rd
+	b.r, b.w = 0, 0
+	b.err = nil
+	b.lastByte = -1
+}
+
+// Release return the buffer to the pool. The Reader must not be used after Release, releasing it again is a no-op.
+func (b *Reader) Release() {
+	if b.buf != nil {
+		b.pool.Free(b.buf)
+		b.buf = nil
+		b.r, b.w = 0, 0
+	}
+}
+
+// Size returns the size of the underlying buffer in bytes.
+func (b *Reader) Size() int { return len(b.buf) }
+
+// Buffered returns the number of bytes that can be read from the current buffer.
+func (b *Reader) Buffered() int { return b.w - b.r }
+
+// fill 读入一次新数据到缓冲区
+func (b *Reader) fill() {
+	// 把未读数据移到开头
+	if b.r > 0 {
+		copy(b.buf, b.buf[b.r:b.w])
+		b.w -= b.r
+		b.r = 0
+	}
+	for i := 0; i < 100; i++ {
+		n, err := b.rd.Read(b.buf[b.w:])
+		if n < 0 {
+			panic("slab: reader returned negative count from Read")
+		}
+		b.w += n
+		if err != nil {
+			b.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
+	}
+	b.err = io.ErrNoProgress
+}
+
+func (b *Reader) readErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// Read reads data into p like bufio.Reader.Read.
+// It calls Read at most once on the underlying reader, large reads bypass the buffer.
+func (b *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		if b.Buffered() > 0 {
+			return 0, nil
+		}
+		return 0, b.readErr()
+	}
+	if b.r == b.w {
+		if b.err != nil {
+			return 0, b.readErr()
+		}
+		if len(p) >= len(b.buf) {
+			// 直接读到 p 中，避免一次复制
+			n, err := b.rd.Read(p)
+			if n > 0 {
+				b.lastByte = int(p[n-1])
+			}
+			return n, err
+		}
+		b.r, b.w = 0, 0
+		b.fill()
+		if b.r == b.w {
+			return 0, b.readErr()
+		}
+	}
+	n := copy(p, b.buf[b.r:b.w])
+	b.r += n
+	b.lastByte = int(b.buf[b.r-1])
+	return n, nil
+}
+
+// ReadByte reads and returns a single byte.
+func (b *Reader) ReadByte() (byte, error) {
+	for b.r == b.w {
+		if b.err != nil {
+			return 0, b.readErr()
+		}
+		b.fill()
+	}
+	c := b.buf[b.r]
+	b.r++
+	b.lastByte = int(c)
+	return c, nil
+}
+
+// UnreadByte unreads the last byte read.
+func (b *Reader) UnreadByte() error {
+	if b.lastByte < 0 || b.r == 0 && b.w > 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	if b.r > 0 {
+		b.r--
+	} else {
+		b.w = 1
+	}
+	b.buf[b.r] = byte(b.lastByte)
+	b.lastByte = -1
+	return nil
+}
+
+// Peek returns the next n bytes without advancing the reader.
+// The bytes stop being valid at the next read call. If n is larger than the buffer size, Peek returns bufio.ErrBufferFull.
+func (b *Reader) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, bufio.ErrNegativeCount
+	}
+	b.lastByte = -1
+	for b.w-b.r < n && b.w-b.r < len(b.buf) && b.err == nil {
+		b.fill()
+	}
+	if n > len(b.buf) {
+		return b.buf[b.r:b.w], bufio.ErrBufferFull
+	}
+	var err error
+	if avail := b.w - b.r; avail < n {
+		n = avail
+		err = b.readErr()
+		if err == nil {
+			err = bufio.ErrBufferFull
+		}
+	}
+	return b.buf[b.r : b.r+n], err
+}
+
+// Discard skips the next n bytes, returning the number of bytes discarded.
+func (b *Reader) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, bufio.ErrNegativeCount
+	}
+	b.lastByte = -1
+	remain := n
+	for {
+		skip := b.Buffered()
+		if skip == 0 {
+			b.fill()
+			skip = b.Buffered()
+		}
+		if skip > remain {
+			skip = remain
+		}
+		b.r += skip
+		remain -= skip
+		if remain == 0 {
+			return n, nil
+		}
+		if b.err != nil {
+			return n - remain, b.readErr()
+		}
+	}
+}
+
+// ReadSlice reads until the first occurrence of delim in the input,
+// returning a slice pointing at the bytes in the buffer like bufio.Reader.ReadSlice.
+func (b *Reader) ReadSlice(delim byte) ([]byte, error) {
+	s := 0 // 已经查找过的部分
+	for {
+		for i := b.r + s; i < b.w; i++ {
+			if b.buf[i] == delim {
+				line := b.buf[b.r : i+1]
+				b.r = i + 1
+				b.lastByte = int(delim)
+				return line, nil
+			}
+		}
+		if b.err != nil {
+			line := b.buf[b.r:b.w]
+			b.r = b.w
+			return line, b.readErr()
+		}
+		if b.Buffered() >= len(b.buf) {
+			b.r = b.w
+			return b.buf, bufio.ErrBufferFull
+		}
+		s = b.w - b.r
+		b.fill()
+	}
+}
+
+// Writer is a buffered writer like bufio.Writer whose buffer is a chunk of Pool.
+// The chunk is returned to the pool by Release.
+type Writer struct {
+	pool Pool
+	buf  []byte
+	n    int
+	wr   io.Writer
+	err  error
+}
+
+// NewWriterSize create a Writer writing to wr with a buffer of at least size bytes alloc from pool.
+func NewWriterSize(pool Pool, wr io.Writer, size int) *Writer {
+	mem := pool.Alloc(size)
+	return &Writer{pool: pool, buf: mem[:cap(mem)], wr: wr}
+}
+
+// Reset discard any unflushed data and make the Writer write to wr, keeping the buffer.
+func (b *Writer) Reset(wr io.Writer) {
+	b.wr = wr
+	b.n = 0
+	b.err = nil
+}
+
+// Release return the buffer to the pool without flushing it, call Flush first to keep the buffered data.
+// The Writer must not be used after Release, releasing it again is a no-op.
+func (b *Writer) Release() {
+	if b.buf != nil {
+		b.pool.Free(b.buf)
+		b.buf = nil
+		b.n = 0
+	}
+}
+
+// Size returns the size of the underlying buffer in bytes.
+func (b *Writer) Size() int { return len(b.buf) }
+
+// Available returns how many bytes are unused in the buffer.
+func (b *Writer) Available() int { return len(b.buf) - b.n }
+
+// Buffered returns the number of bytes that have been written into the current buffer.
+func (b *Writer) Buffered() int { return b.n }
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (b *Writer) Flush() error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.n == 0 {
+		return nil
+	}
+	n, err := b.wr.Write(b.buf[:b.n])
+	if n < b.n && err == nil {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		if n > 0 && n < b.n {
+			copy(b.buf[:b.n-n], b.buf[n:b.n])
+		}
+		b.n -= n
+		b.err = err
+		return err
+	}
+	b.n = 0
+	return nil
+}
+
+// Write writes the contents of p into the buffer, large writes bypass the buffer when it is empty.
+func (b *Writer) Write(p []byte) (int, error) {
+	nn := 0
+	for len(p) > b.Available() && b.err == nil {
+		var n int
+		if b.n == 0 {
+			// 缓冲区为空时直接写出，避免一次复制
+			n, b.err = b.wr.Write(p)
+		} else {
+			n = copy(b.buf[b.n:], p)
+			b.n += n
+			b.Flush()
+		}
+		nn += n
+		p = p[n:]
+	}
+	if b.err != nil {
+		return nn, b.err
+	}
+	n := copy(b.buf[b.n:], p)
+	b.n += n
+	return nn + n, nil
+}
+
+// WriteByte writes a single byte.
+func (b *Writer) WriteByte(c byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.Available() <= 0 && b.Flush() != nil {
+		return b.err
+	}
+	b.buf[b.n] = c
+	b.n++
+	return nil
+}
+
+// WriteString writes a string.
+func (b *Writer) WriteString(s string) (int, error) {
+	nn := 0
+	for len(s) > b.Available() && b.err == nil {
+		n := copy(b.buf[b.n:], s)
+		b.n += n
+		nn += n
+		s = s[n:]
+		b.Flush()
+	}
+	if b.err != nil {
+		return nn, b.err
+	}
+	n := copy(b.buf[b.n:], s)
+	b.n += n
+	return nn + n, nil
+}
+
+var _ io.Reader = (*Reader)(nil)
+var _ io.ByteScanner = (*Reader)(nil)
+var _ io.Writer = (*Writer)(nil)
+var _ io.ByteWriter = (*Writer)(nil)
+var _ io.StringWriter = (*Writer)(nil)
@@ -0,0 +1,75 @@
+package slab
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Reader(t *testing.T) {
+	pool := NewAtomPool(16, 1024, 2, 1024)
+	r := NewReaderSize(pool, strings.NewReader("hello world\nsecond line\nend"), 16)
+	utest.EqualNow(t, r.Size(), 16)
+
+	p, err := r.Peek(5)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(p), "hello")
+
+	c, err := r.ReadByte()
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, c, byte('h'))
+	utest.IsNilNow(t, r.UnreadByte())
+	utest.EqualNow(t, r.UnreadByte(), bufio.ErrInvalidUnreadByte)
+
+	line, err := r.ReadSlice('\n')
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(line), "hello world\n")
+
+	n, err := r.Discard(7)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 7)
+
+	rest, err := io.ReadAll(r)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(rest), "line\nend")
+
+	_, err = r.Peek(17)
+	utest.EqualNow(t, err, bufio.ErrBufferFull)
+
+	r.Reset(strings.NewReader(strings.Repeat("x", 20)))
+	_, err = r.ReadSlice('\n')
+	utest.EqualNow(t, err, bufio.ErrBufferFull)
+
+	r.Release()
+	r.Release()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_Writer(t *testing.T) {
+	pool := NewAtomPool(16, 1024, 2, 1024)
+	var out bytes.Buffer
+	w := NewWriterSize(pool, &out, 16)
+
+	w.WriteString("hello ")
+	w.WriteByte('w')
+	w.Write([]byte("orld"))
+	utest.EqualNow(t, w.Buffered(), 11)
+	utest.EqualNow(t, w.Available(), 5)
+	utest.EqualNow(t, out.Len(), 0)
+
+	// larger than the buffer
+	n, err := w.Write([]byte(strings.Repeat("x", 40)))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 40)
+	w.WriteString(strings.Repeat("y", 20))
+	utest.IsNilNow(t, w.Flush())
+	utest.EqualNow(t, out.String(), "hello world"+strings.Repeat("x", 40)+strings.Repeat("y", 20))
+
+	w.Release()
+	w.Release()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
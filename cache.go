@@ -0,0 +1,119 @@
+package slab
+
+// Cache is a private front-end of an AtomPool holding a small magazine of free chunks for each slab class.
+// Alloc and Free work on the magazines without atomic operations, which are refilled from and flushed to
+// the shared free lists in batches. A Cache is not safe for concurrent use, give each worker goroutine its own one.
+// Chunks held by magazines are counted as in use by the pool, call Flush to return them.
+// The requested sizes of the allocations are counted in the stats of the pool when a magazine is refilled or flushed.
+// Double frees are not detected by a Cache, so in the debug modes of the pool it passes every call to the pool.
+type Cache struct {
+	pool   *AtomPool
+	size   int
+	mags   [][][]byte // 每个 class 一个 magazine，保存完整容量的空闲 chunk
+	reqs   []cacheRequests
+	direct bool // 开启调试模式时不缓存，直接调用 pool
+}
+
+// cacheRequests 在本地累计从 magazine 分配的请求，refill 和 Flush 时再计入 class
+type cacheRequests struct {
+	n    int
+	size int
+}
+
+// NewCache create a Cache whose magazines hold up to size chunks of each slab class.
+func (pool *AtomPool) NewCache(size int) *Cache {
+	if size < 2 {
+		size = 2
+	}
+	cache := &Cache{
+		pool: pool,
+		size: size,
+		mags: make([][][]byte, len(pool.classes)),
+		reqs: make([]cacheRequests, len(pool.classes)),
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if c.guard > 0 || c.leak || c.sample.Load() > 0 || c.profile != nil || c.poison || c.quarantine != nil || c.fifo != nil || pool.hooks {
+			cache.direct = true
+		}
+	}
+	return cache
+}
+
+// Alloc try alloc a []byte from the magazine of the slab class,
+// an empty magazine is refilled with half of its size from the pool with one CAS.
+func (cache *Cache) Alloc(size int) []byte {
+	pool := cache.pool
+	if i := pool.classIndex(size); i >= 0 && !cache.direct {
+		mag := cache.mags[i]
+		if len(mag) == 0 {
+			c := &pool.classes[i]
+			cache.report(i)
+			var overBudget bool
+			mag, overBudget = c.popRun(mag, cache.size/2, c.size)
+			if len(mag) == 0 {
+				if overBudget {
+					c.overBudget.Add(1)
+				}
+				c.fallbacks.Add(1)
+				return pool.heap(size)
+			}
+			c.allocs.Add(uint64(len(mag)))
+		}
+		mem := mag[len(mag)-1]
+		mag[len(mag)-1] = nil
+		cache.mags[i] = mag[:len(mag)-1]
+		cache.reqs[i].n++
+		cache.reqs[i].size += size
+		return mem[:size]
+	}
+	return pool.Alloc(size)
+}
+
+// Free release a []byte that alloc from Cache.Alloc or Pool.Alloc into the magazine of the slab class,
+// a full magazine flushes half of its chunks to the pool with one CAS.
+func (cache *Cache) Free(mem []byte) {
+	pool := cache.pool
+	if !cache.direct {
+		ptr := dataPtr(mem)
+		for i := 0; i < len(pool.classes); i++ {
+			// 重新切片过的 chunk 容量可能恰好等于更小的 class，需要确认它确实位于该 class 的 page 内
+			if pool.classes[i].size == cap(mem) && pool.classes[i].Owns(ptr) {
+				mag := cache.mags[i]
+				if len(mag) == cache.size {
+					mag = cache.flush(mag, cache.size/2)
+				}
+				cache.mags[i] = append(mag, mem[:cap(mem)])
+				return
+			}
+		}
+	}
+	pool.Free(mem)
+}
+
+// Flush return every chunk held by the magazines to the pool.
+func (cache *Cache) Flush() {
+	for i, mag := range cache.mags {
+		cache.mags[i] = cache.flush(mag, 0)
+		cache.report(i)
+	}
+}
+
+// report 把本地累计的第 i 个 class 的请求计入 class
+func (cache *Cache) report(i int) {
+	if r := &cache.reqs[i]; r.n > 0 {
+		c := &cache.pool.classes[i]
+		c.requests.Add(uint64(r.n))
+		c.requested.Add(uint64(r.size))
+		*r = cacheRequests{}
+	}
+}
+
+// flush 把 mag 中下标 n 之后的 chunk 归还给 pool
+func (cache *Cache) flush(mag [][]byte, n int) [][]byte {
+	cache.pool.FreeBatch(mag[n:])
+	for j := n; j < len(mag); j++ {
+		mag[j] = nil
+	}
+	return mag[:n]
+}
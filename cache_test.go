@@ -0,0 +1,91 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Cache(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	cache := pool.NewCache(4)
+
+	mem := cache.Alloc(100)
+	utest.EqualNow(t, len(mem), 100)
+	utest.Assert(t, pool.Owns(mem))
+	utest.EqualNow(t, len(cache.mags[0]), 1)
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Allocs, uint64(2))
+	utest.EqualNow(t, s.InUse, 2)
+
+	cache.Free(mem)
+	utest.EqualNow(t, len(cache.mags[0]), 2)
+	utest.EqualNow(t, &cache.Alloc(128)[0], &mem[0])
+
+	// a full magazine flushes half of it
+	bufs := make([][]byte, 0, 5)
+	for i := 0; i < 5; i++ {
+		bufs = append(bufs, pool.Alloc(128))
+	}
+	for _, mem := range bufs {
+		cache.Free(mem)
+	}
+	utest.EqualNow(t, len(cache.mags[0]), 4)
+
+	cache.Flush()
+	utest.EqualNow(t, len(cache.mags[0]), 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+
+	// not pooled
+	utest.EqualNow(t, cap(cache.Alloc(2000)), 2000)
+	cache.Free(make([]byte, 2000))
+}
+
+func Test_Cache_Direct(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithPoison(0xDD))
+	cache := pool.NewCache(4)
+	mem := cache.Alloc(128)
+	utest.EqualNow(t, len(cache.mags[0]), 0)
+	cache.Free(mem)
+	utest.EqualNow(t, mem[0], byte(0xDD))
+}
+
+func Test_Cache_Parallel(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithMaxPages(4))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache := pool.NewCache(16)
+			var bufs [][]byte
+			for j := 0; j < 1000; j++ {
+				bufs = append(bufs, cache.Alloc(128))
+				if len(bufs) == 10 {
+					for _, mem := range bufs {
+						cache.Free(mem)
+					}
+					bufs = bufs[:0]
+				}
+			}
+			for _, mem := range bufs {
+				cache.Free(mem)
+			}
+			cache.Flush()
+		}()
+	}
+	wg.Wait()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Benchmark_Cache_AllocAndFree_128(b *testing.B) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	b.RunParallel(func(pb *testing.PB) {
+		cache := pool.NewCache(64)
+		for pb.Next() {
+			cache.Free(cache.Alloc(128))
+		}
+		cache.Flush()
+	})
+}
@@ -52,6 +52,19 @@ func (pool *ChanPool) Alloc(size int) []byte {
 	return make([]byte, size)
 }
 
+// AllocWait alloc a []byte from internal slab class, if no free chunk in slab class AllocWait will block until one is freed.
+// Sizes larger than the largest chunk size are allocated by make.
+func (pool *ChanPool) AllocWait(size int) []byte {
+	if size <= pool.maxSize {
+		for i := 0; i < len(pool.classes); i++ {
+			if pool.classes[i].size >= size {
+				return (<-pool.classes[i].chunks)[:size]
+			}
+		}
+	}
+	return make([]byte, size)
+}
+
 // Free release a []byte that alloc from Pool.Alloc.
 func (pool *ChanPool) Free(mem []byte) {
 	size := cap(mem)
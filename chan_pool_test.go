@@ -2,6 +2,7 @@ package slab
 
 import (
 	"testing"
+	"time"
 
 	"github.com/funny/utest"
 )
@@ -55,6 +56,32 @@ func Test_ChanPool_AllocSlow(t *testing.T) {
 	utest.EqualNow(t, cap(mem), 1024)
 }
 
+func Test_ChanPool_AllocWait(t *testing.T) {
+	pool := NewChanPool(128, 1024, 2, 1024)
+	c := &pool.classes[len(pool.classes)-1]
+	mem := pool.AllocWait(1024)
+	utest.EqualNow(t, len(c.chunks), 0)
+
+	done := make(chan []byte)
+	go func() {
+		done <- pool.AllocWait(1000)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("AllocWait should block when the class is exhausted")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	pool.Free(mem)
+	mem = <-done
+	utest.EqualNow(t, len(mem), 1000)
+	utest.EqualNow(t, cap(mem), 1024)
+
+	mem = pool.AllocWait(2048)
+	utest.EqualNow(t, cap(mem), 2048)
+}
+
 func Benchmark_ChanPool_AllocAndFree_128(b *testing.B) {
 	pool := NewChanPool(128, 1024, 2, 64*1024)
 	b.ResetTimer()
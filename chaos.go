@@ -0,0 +1,56 @@
+package slab
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// chaos 是 WithChaos 的随机化模式，打乱 page 中 chunk 链入空闲链表的顺序，并在 CAS 之前随机让出处理器
+type chaos struct {
+	seed uint64
+	n    atomic.Uint64 // delay 的调用次数
+	rng  *rand.Rand    // 由 growMu 保护
+}
+
+func newChaos(seed int64) *chaos {
+	return &chaos{seed: uint64(seed), rng: rand.New(rand.NewSource(seed))}
+}
+
+// delay 按种子和调用次数决定是否让出处理器或短暂休眠，扩大 load 和 CAS 之间的窗口
+func (x *chaos) delay() {
+	if x == nil {
+		return
+	}
+	v := splitmix64(x.seed + x.n.Add(1))
+	switch v % 8 {
+	case 0, 1:
+		runtime.Gosched()
+	case 2:
+		time.Sleep(time.Duration(v>>8%50) * time.Microsecond)
+	}
+}
+
+// splitmix64 把连续的输入散列成分布均匀的伪随机数
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// order 返回 page 中 n 个 chunk 链入空闲链表的顺序，随机化或打乱模式下调用者需持有 growMu
+func (c *class) order(n int) []int {
+	if c.chaos != nil {
+		return c.chaos.rng.Perm(n)
+	}
+	if c.shuffle != nil {
+		return c.shuffle.Perm(n)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
@@ -0,0 +1,64 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_ChaosOrder(t *testing.T) {
+	order := func(seed int64) []uintptr {
+		pool := NewAtomPool(128, 128, 2, 4096, WithChaos(seed))
+		var ptrs []uintptr
+		for _, mem := range pool.AllocBatch(128, 32) {
+			ptrs = append(ptrs, dataPtr(mem))
+		}
+		return ptrs
+	}
+	a, b, c := order(1), order(1), order(2)
+	same, sequential := true, true
+	for i := range a {
+		utest.EqualNow(t, a[i]-a[0], b[i]-b[0])
+		same = same && a[i]-a[0] == c[i]-c[0]
+		sequential = sequential && a[i]-a[0] == uintptr(i*128)
+	}
+	utest.Assert(t, !same)
+	utest.Assert(t, !sequential)
+
+	// reset pages are shuffled too
+	pool := NewAtomPool(128, 128, 2, 4096, WithChaos(3))
+	pool.AllocBatch(128, 32)
+	pool.Reset()
+	utest.IsNilNow(t, pool.Verify())
+	utest.EqualNow(t, len(pool.AllocBatch(128, 32)), 32)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+}
+
+func Test_AtomPool_Chaos(t *testing.T) {
+	const seed = 42
+	t.Logf("seed %d", seed)
+	pool := NewAtomPool(128, 256, 2, 1024, WithMaxPages(4), WithChaos(seed))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 300; j++ {
+				bufs := pool.AllocBatch(128, 3)
+				mem := pool.Alloc(256)
+				mem[0] = byte(i)
+				for _, b := range bufs {
+					b[0] = byte(i)
+				}
+				for _, b := range bufs {
+					utest.EqualNow(t, b[0], byte(i))
+				}
+				pool.Free(mem)
+				pool.FreeBatch(bufs)
+			}
+		}(i)
+	}
+	wg.Wait()
+	utest.IsNilNow(t, pool.Verify())
+}
@@ -0,0 +1,209 @@
+package slab
+
+import (
+	"errors"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ErrCheckpointPages is returned by RestoreCheckpoint when a page of the checkpoint has been released since, e.g. by Trim.
+var ErrCheckpointPages = errors.New("slab: page of the checkpoint released")
+
+// Checkpoint is the state of the slab classes of an AtomPool saved by AtomPool.Checkpoint:
+// which chunks are free and in which order, and the statistics of the classes.
+type Checkpoint struct {
+	pool    *AtomPool
+	classes []classCheckpoint
+}
+
+// classCheckpoint 是一个 class 的状态，free、fifo 和 quarantined 都是 chunk 的全局下标
+type classCheckpoint struct {
+	pages       []*page // 每个 page 下标上的 page
+	free        []uint64
+	fifo        []uint64
+	quarantined []uint64
+	infos       []unsafe.Pointer // 开启泄漏检测或采样时每个 chunk 的分配信息，按全局下标
+	inUse       int64
+	above       bool
+	counters    []uint64
+}
+
+// counters 返回 class 的统计计数，顺序和 setCounters 一致
+func (c *class) counters() []uint64 {
+	return []uint64{
+		c.allocs.Load(), c.fallbacks.Load(), c.overBudget.Load(), c.frees.Load(), c.rejects.Load(),
+		c.doubleFrees.Load(), c.requests.Load(), c.requested.Load(), c.grows.Load(), c.samples.Load(),
+	}
+}
+
+func (c *class) setCounters(v []uint64) {
+	for i, x := range []*atomic.Uint64{
+		&c.allocs, &c.fallbacks, &c.overBudget, &c.frees, &c.rejects,
+		&c.doubleFrees, &c.requests, &c.requested, &c.grows, &c.samples,
+	} {
+		x.Store(v[i])
+	}
+}
+
+// Checkpoint saves which chunks of the slab classes are free and the statistics of the classes,
+// so RestoreCheckpoint can bring the pool back to this state, e.g. between the cases of a table-driven test,
+// without creating a new pool and faulting its pages in again.
+// The buddy and overflow tiers and the tags are not part of the checkpoint.
+// It must be called when no other goroutine is calling Alloc or Free.
+func (pool *AtomPool) Checkpoint() *Checkpoint {
+	cp := &Checkpoint{pool: pool, classes: make([]classCheckpoint, len(pool.classes))}
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].checkpoint(&cp.classes[i])
+	}
+	return cp
+}
+
+func (c *class) checkpoint(cc *classCheckpoint) {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	cc.pages = make([]*page, nslots)
+	for n := 0; n < nslots; n++ {
+		cc.pages[n] = c.page(n)
+	}
+	for v := c.head.Load(); v != 0; v = nextLink(c.chunk(linkIndex(v)).next.Load()) {
+		cc.free = append(cc.free, linkIndex(v))
+	}
+	for _, v := range c.fifoLinks() {
+		cc.fifo = append(cc.fifo, linkIndex(v))
+	}
+	if c.quarantine != nil {
+		for _, v := range c.quarantine.quarantined() {
+			cc.quarantined = append(cc.quarantined, linkIndex(v))
+		}
+	}
+	if c.leak || c.sample.Load() > 0 {
+		cc.infos = make([]unsafe.Pointer, nslots*c.perPage)
+		for n, p := range cc.pages {
+			for i := 0; p != nil && i < len(p.chunks); i++ {
+				cc.infos[n*c.perPage+i] = atomic.LoadPointer(&p.chunks[i].info)
+			}
+		}
+	}
+	cc.inUse = c.inUse.Load()
+	if c.marks != nil {
+		cc.above = c.marks.above.Load()
+	}
+	cc.counters = c.counters()
+}
+
+// RestoreCheckpoint brings the slab classes back to the state saved by Checkpoint: the chunks free at that time are free again
+// in the same order, the other chunks are allocated, and the statistics are restored. The pages added since are released.
+// It returns ErrCheckpointPages without changing the pool if a page of the checkpoint has been released since.
+// Like Reset it must be called when no buffer alloc after the checkpoint is used any more and no other goroutine is calling Alloc or Free.
+// The buffers alloc before the checkpoint stay valid, the ones freed since must be used again as if they were never freed.
+func (pool *AtomPool) RestoreCheckpoint(cp *Checkpoint) error {
+	if cp.pool != pool {
+		return errors.New("slab: checkpoint of another pool")
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		for n, p := range cp.classes[i].pages {
+			if p != nil && c.page(n) != p {
+				return ErrCheckpointPages
+			}
+		}
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].restore(&cp.classes[i])
+	}
+	return nil
+}
+
+func (c *class) restore(cc *classCheckpoint) {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	// 释放 checkpoint 之后增加的 page
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	for n := 0; n < nslots; n++ {
+		if p := c.page(n); p != nil && (n >= len(cc.pages) || cc.pages[n] == nil) {
+			c.putPage(c.unlinkPage(n).raw)
+		}
+	}
+
+	// 先把所有 chunk 标记为已分配，再按 checkpoint 中的顺序重建空闲链表、后备链表和隔离区
+	for n, p := range cc.pages {
+		for i := 0; p != nil && i < len(p.chunks); i++ {
+			chk := &p.chunks[i]
+			chk.next.Store(0)
+			if cc.infos != nil {
+				atomic.StorePointer(&chk.info, cc.infos[n*c.perPage+i])
+			}
+			if c.guard > 0 {
+				off := i*c.stride + c.guard
+				fillGuards(p.mem[off-c.guard:off+c.size+c.guard], c.guard)
+			}
+		}
+	}
+	c.head.Store(c.relink(cc.free, linkEnd))
+	if f := c.fifo; f != nil {
+		f.clear()
+		if len(cc.fifo) > 0 {
+			f.head = c.relink(cc.fifo, linkEnd)
+			f.tail = c.chunk(cc.fifo[len(cc.fifo)-1])
+		}
+	}
+	if q := c.quarantine; q != nil {
+		q.clear()
+		q.mu.Lock()
+		for _, idx := range cc.quarantined {
+			chk := c.freeChunk(idx)
+			// 隔离中的 chunk 的 next 指向自己
+			v := makeLink(idx, chk.aba)
+			chk.next.Store(v)
+			q.ring[q.n] = v
+			q.n++
+		}
+		q.mu.Unlock()
+	}
+
+	c.usage.inUse.Add((cc.inUse - c.inUse.Swap(cc.inUse)) * int64(c.size))
+	if c.marks != nil {
+		c.marks.above.Store(cc.above)
+	}
+	c.setCounters(cc.counters)
+}
+
+// relink 把下标为 idxs 的 chunk 按顺序链接起来，最后一个 chunk 的 next 为 tail，返回首 chunk 的链接值，idxs 为空时返回 0
+func (c *class) relink(idxs []uint64, tail uint64) uint64 {
+	var first uint64
+	var last *chunk
+	for _, idx := range idxs {
+		chk := c.freeChunk(idx)
+		e := makeLink(idx, chk.aba)
+		if last == nil {
+			first = e
+		} else {
+			last.next.Store(e)
+		}
+		last = chk
+	}
+	if last != nil {
+		last.next.Store(tail)
+	}
+	return first
+}
+
+// freeChunk 像回收时一样清除下标为 idx 的 chunk 的分配信息和用户数据、毒化并增加 ABA 计数，返回这个 chunk
+func (c *class) freeChunk(idx uint64) *chunk {
+	chk := c.chunk(idx)
+	c.clearInfo(chk)
+	if p := c.page(int(idx) / c.perPage); p.meta != nil {
+		p.meta[int(idx)%c.perPage].Store(0)
+	}
+	if c.profile != nil {
+		c.profile.Remove(chk)
+	}
+	if c.poison {
+		memset(chk.mem, c.poisonBy)
+	}
+	chk.aba++
+	return chk
+}
@@ -0,0 +1,70 @@
+package slab
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Checkpoint(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithLeakDetection())
+	held := pool.Alloc(128)
+	a := pool.Alloc(128)
+	pool.Free(a)
+	cp := pool.Checkpoint()
+	before := pool.Stats()
+
+	for _, n := range []int{3, 20} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			var bufs [][]byte
+			for i := 0; i < n; i++ {
+				bufs = append(bufs, pool.Alloc(128))
+			}
+			pool.Free(held)
+			utest.EqualNow(t, dataPtr(bufs[0]), dataPtr(a))
+
+			utest.IsNilNow(t, pool.RestoreCheckpoint(cp))
+			utest.IsNilNow(t, pool.Verify())
+			utest.EqualNow(t, fmt.Sprint(pool.Stats()), fmt.Sprint(before))
+			utest.EqualNow(t, len(pool.Leaks(0)), 1)
+
+			// the free chunks are alloc again in the same order
+			utest.EqualNow(t, dataPtr(pool.Alloc(128)), dataPtr(a))
+			utest.IsNilNow(t, pool.RestoreCheckpoint(cp))
+		})
+	}
+	pool.Free(held)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_AtomPool_CheckpointQuarantine(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(1), WithQuarantine(2), WithFIFO())
+	bufs := pool.AllocBatch(128, 5)
+	pool.FreeBatch(bufs[:4])
+	cp := pool.Checkpoint()
+	before := pool.Stats()
+
+	pool.Free(bufs[4])
+	for i := 0; i < 3; i++ {
+		pool.Alloc(128)
+	}
+	utest.IsNilNow(t, pool.RestoreCheckpoint(cp))
+	utest.IsNilNow(t, pool.Verify())
+	utest.EqualNow(t, fmt.Sprint(pool.Stats()), fmt.Sprint(before))
+
+	// chunks still quarantined can't be freed again
+	utest.EqualNow(t, pool.TryFree(bufs[3]), ErrDoubleFree)
+	utest.IsNilNow(t, pool.TryFree(bufs[4]))
+}
+
+func Test_AtomPool_CheckpointTrimmed(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	pool.Free(pool.Alloc(128))
+	cp := pool.Checkpoint()
+	pool.Trim()
+	utest.EqualNow(t, pool.RestoreCheckpoint(cp), ErrCheckpointPages)
+
+	other := NewAtomPool(128, 1024, 2, 1024)
+	utest.NotNilNow(t, other.RestoreCheckpoint(cp))
+}
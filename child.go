@@ -0,0 +1,25 @@
+package slab
+
+// NewChild create a pool which borrows its pages from pool instead of allocating them,
+// configured like pool and then by opts, e.g. WithMaxMemory to give a subsystem its own limit and accounting.
+// The pages of a child count against the memory budget of pool too, Trim of the child
+// returns them to pool, where they are reused by pool and its other children until pool is trimmed.
+func (pool *AtomPool) NewChild(opts ...Option) (*AtomPool, error) {
+	parent := &pool.src
+	opts = append(append(append([]Option{}, pool.opts...), WithMaxMemory(0), WithName(""), WithFile("")), opts...)
+	opts = append(opts, func(cfg *config) {
+		cfg.parent = parent
+	})
+	return NewPool(opts...)
+}
+
+// spareBytes 返回子 pool 归还给 pool、尚未被复用的内存字节数
+func (pool *AtomPool) spareBytes() int {
+	pool.src.mu.Lock()
+	defer pool.src.mu.Unlock()
+	n := 0
+	for _, raw := range pool.src.spares {
+		n += len(raw)
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Child(t *testing.T) {
+	parent := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2), WithMaxMemory(8*1024))
+	child, err := parent.NewChild(WithLazy(), WithMaxMemory(2*1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 4*1024)
+
+	a := child.Alloc(128)
+	b := child.Alloc(256)
+	utest.Assert(t, child.Owns(a) && child.Owns(b))
+	utest.Assert(t, !parent.Owns(a))
+	utest.EqualNow(t, int(child.src.budget.used.Load()), 2*1024)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 6*1024)
+
+	// over the limit of the child
+	c := child.Alloc(512)
+	utest.Assert(t, !child.Owns(c))
+	utest.EqualNow(t, child.Stats()[2].OverBudget, uint64(1))
+
+	// pages go back to the parent
+	child.Reset()
+	utest.EqualNow(t, child.Trim(), 2*1024)
+	utest.EqualNow(t, int(child.src.budget.used.Load()), 0)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 6*1024)
+	utest.EqualNow(t, parent.spareBytes(), 2*1024)
+	for _, s := range child.Stats() {
+		utest.EqualNow(t, s.Pages, 0)
+		utest.EqualNow(t, s.Allocs, uint64(0))
+	}
+
+	// the parent grows with a spare page
+	for i := 0; i < 9; i++ {
+		utest.Assert(t, parent.Owns(parent.Alloc(128)))
+	}
+	utest.EqualNow(t, parent.spareBytes(), 1024)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 6*1024)
+
+	// the child can grow again
+	a = child.Alloc(128)
+	utest.Assert(t, child.Owns(a))
+	utest.EqualNow(t, parent.spareBytes(), 0)
+	child.Free(a)
+
+	// trimming the child parks its pages, trimming the parent releases them
+	utest.EqualNow(t, child.Trim(), 1024)
+	utest.EqualNow(t, parent.spareBytes(), 1024)
+	utest.EqualNow(t, parent.Trim(), 3*1024+1024)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 2*1024)
+}
+
+func Test_AtomPool_ChildBudget(t *testing.T) {
+	parent := NewAtomPool(128, 1024, 2, 1024, WithMaxMemory(5*1024))
+	child, err := parent.NewChild(WithLazy())
+	utest.IsNilNow(t, err)
+	utest.Assert(t, child.Owns(child.Alloc(128)))
+
+	// the parent's budget is shared
+	utest.Assert(t, !child.Owns(child.Alloc(256)))
+	utest.EqualNow(t, child.Stats()[1].OverBudget, uint64(1))
+
+	_, err = parent.NewChild()
+	utest.NotNilNow(t, err)
+}
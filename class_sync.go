@@ -0,0 +1,54 @@
+package slab
+
+import "sync"
+
+// Sync is the way a slab class synchronizes the goroutines popping and pushing chunks of its free list, set by WithClassSync.
+type Sync int
+
+const (
+	// SyncLockFree updates the free list with compare-and-swap, goroutines which lose the race retry after WithBackoff.
+	// It's the fastest under contention for small hot classes.
+	SyncLockFree Sync = iota
+	// SyncMutex updates the free list holding a sync.Mutex, goroutines which wait for it are parked instead of spinning,
+	// which suits classes whose chunks are alloc rarely, e.g. huge buffers.
+	SyncMutex
+	// SyncChannel is like SyncMutex with a channel as the lock, so waiting goroutines get the free list in the order they came,
+	// a goroutine that has just freed a chunk can't take the lock again ahead of them.
+	SyncChannel
+)
+
+// chanLock 是容量为 1 的 channel 实现的锁，channel 按等待的先后交出锁
+type chanLock chan struct{}
+
+func (l chanLock) Lock() {
+	l <- struct{}{}
+}
+
+func (l chanLock) Unlock() {
+	<-l
+}
+
+// newListLock 返回 s 模式下空闲链表的锁，无锁模式返回 nil
+func newListLock(s Sync) sync.Locker {
+	switch s {
+	case SyncMutex:
+		return new(sync.Mutex)
+	case SyncChannel:
+		return make(chanLock, 1)
+	}
+	return nil
+}
+
+// lockList 在加锁模式下锁住空闲链表。加锁时 pop 和 push 的 CAS 仍然保留，
+// reclaim 等在 growMu 下用 Swap 摘下整条链表的操作不加这把锁，CAS 会因此失败并重试
+func (c *class) lockList() {
+	if c.listMu != nil {
+		c.listMu.Lock()
+	}
+}
+
+func (c *class) unlockList() {
+	if c.listMu != nil {
+		c.listMu.Unlock()
+	}
+}
@@ -0,0 +1,76 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_ClassSync(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 512), WithPageSize(4096), WithMaxPages(4), WithClassSync(func(chunkSize int) Sync {
+		switch chunkSize {
+		case 256:
+			return SyncMutex
+		case 512:
+			return SyncChannel
+		}
+		return SyncLockFree
+	}))
+	utest.IsNilNow(t, err)
+	utest.Assert(t, pool.classes[0].listMu == nil)
+	utest.Assert(t, pool.classes[1].listMu != nil)
+	utest.Assert(t, pool.classes[2].listMu != nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				mem := pool.Alloc(128 << (i % 3))
+				mem[0] = byte(g)
+				bufs := pool.AllocBatch(256, 3)
+				pool.Free(mem)
+				pool.FreeBatch(bufs)
+			}
+		}(g)
+	}
+	wg.Wait()
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+	utest.EqualNow(t, len(pool.Audit()), 0)
+	utest.EqualNow(t, pool.Trim(), 3*4096)
+}
+
+func Test_AtomPool_ClassSyncBlocks(t *testing.T) {
+	pool, err := NewPool(WithClasses(1024), WithPageSize(4096), WithClassSync(func(int) Sync { return SyncChannel }))
+	utest.IsNilNow(t, err)
+
+	// 持有锁时分配的 goroutine 阻塞等待，锁交出之后继续
+	pool.classes[0].listMu.Lock()
+	done := make(chan []byte)
+	go func() {
+		done <- pool.Alloc(1024)
+	}()
+	select {
+	case <-done:
+		t.Fatal("alloc didn't wait for the lock")
+	case <-time.After(10 * time.Millisecond):
+	}
+	pool.classes[0].listMu.Unlock()
+	mem := <-done
+	utest.EqualNow(t, pool.Stats()[0].Allocs, uint64(1))
+	pool.Free(mem)
+}
+
+func Test_NewPool_InvalidClassSync(t *testing.T) {
+	_, err := NewPool(WithClassSync(func(int) Sync { return SyncChannel + 1 }))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithRealTime(3), WithClassSync(func(int) Sync { return SyncMutex }))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithRealTime(3), WithClassSync(func(int) Sync { return SyncLockFree }))
+	utest.IsNilNow(t, err)
+}
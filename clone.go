@@ -0,0 +1,16 @@
+package slab
+
+// Clone alloc a chunk of len(b) bytes and copies b into it, e.g. to keep data parsed from a transient read buffer.
+// The result must be released by Free.
+func (pool *AtomPool) Clone(b []byte) []byte {
+	mem := pool.Alloc(len(b))
+	copy(mem, b)
+	return mem
+}
+
+// CloneString is like Clone, but copies the bytes of s.
+func (pool *AtomPool) CloneString(s string) []byte {
+	mem := pool.Alloc(len(s))
+	copy(mem, s)
+	return mem
+}
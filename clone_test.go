@@ -0,0 +1,29 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Clone(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	src := []byte("hello world")
+
+	b := pool.Clone(src)
+	utest.EqualNow(t, string(b), "hello world")
+	utest.EqualNow(t, cap(b), 128)
+	src[0] = 'H'
+	utest.EqualNow(t, string(b), "hello world")
+
+	s := pool.CloneString("hello")
+	utest.EqualNow(t, string(s), "hello")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+
+	pool.Free(b)
+	pool.Free(s)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	big := pool.CloneString(string(make([]byte, 2000)))
+	utest.EqualNow(t, len(big), 2000)
+}
@@ -0,0 +1,64 @@
+package slab
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPoolClosed is returned by TryAlloc and AllocContext after the pool is closed.
+var ErrPoolClosed = errors.New("slab: pool closed")
+
+// CloseError is returned by Close when buffers alloc from the pool are still not freed.
+type CloseError struct {
+	Chunks int    // chunks of the slab classes not freed, including the ones held by a Cache
+	Blocks int    // blocks of the buddy tier not freed
+	Leaks  []Leak // allocation stacks of the chunks, only recorded when the pool is created with WithLeakDetection
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("slab: pool closed with %d chunks and %d blocks not freed", e.Chunks, e.Blocks)
+}
+
+// Close mark the pool closed and release its memory like Trim, including the pages returned by its child pools.
+// Chunks held in quarantine are released too.
+// After Close no slab class or buddy arena grows again, Alloc falls back to make, TryAlloc and AllocContext return ErrPoolClosed,
+// and the child pools can't borrow pages from it any more. A pool created WithName is removed from the registry.
+// Buffers alloc before can still be passed to Free, but the pages and arenas holding them are not released since they may still be used.
+// In that case Close returns a *CloseError reporting them, closing again after they are freed releases the rest.
+func (pool *AtomPool) Close() error {
+	pool.src.mu.Lock()
+	if !pool.src.closed.Load() {
+		pool.src.closed.Store(true)
+		// 唤醒 AllocContext 中的等待者
+		close(pool.src.done)
+	}
+	pool.src.mu.Unlock()
+	if pool.name != "" {
+		unregister(pool.name, pool)
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].drain()
+	}
+	pool.trim(TrimClose)
+	if pool.file != nil {
+		// 不会再增长，已经映射的 page 不需要文件保持打开
+		pool.file.Close()
+	}
+
+	e := &CloseError{}
+	for _, s := range pool.Stats() {
+		e.Chunks += s.InUse
+	}
+	if pool.buddy != nil {
+		pool.buddy.mu.Lock()
+		for _, a := range pool.buddy.arenas {
+			e.Blocks += len(a.used)
+		}
+		pool.buddy.mu.Unlock()
+	}
+	if e.Chunks == 0 && e.Blocks == 0 {
+		return nil
+	}
+	e.Leaks = pool.Leaks(0)
+	return e
+}
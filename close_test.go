@@ -0,0 +1,88 @@
+package slab
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Close(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithBuddy(16*1024, 1))
+	mem := pool.Alloc(128)
+	pool.Free(mem)
+	pool.Free(pool.Alloc(4096))
+	utest.IsNilNow(t, pool.Close())
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 0)
+	}
+	utest.EqualNow(t, pool.BuddyStats().Arenas, 0)
+	utest.EqualNow(t, int(pool.src.budget.used.Load()), 0)
+
+	// the pool doesn't grow any more
+	mem, pooled := pool.AllocPooled(128)
+	utest.Assert(t, !pooled)
+	utest.EqualNow(t, len(mem), 128)
+	_, err := pool.TryAlloc(128)
+	utest.EqualNow(t, err, ErrPoolClosed)
+	_, err = pool.AllocContext(context.Background(), 128)
+	utest.EqualNow(t, err, ErrPoolClosed)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+	utest.IsNilNow(t, pool.Close())
+}
+
+func Test_AtomPool_CloseOutstanding(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithBuddy(16*1024, 1), WithLeakDetection())
+	held := pool.Alloc(128)
+	block := pool.Alloc(4096)
+
+	err := pool.Close()
+	e, ok := err.(*CloseError)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, e.Chunks, 1)
+	utest.EqualNow(t, e.Blocks, 1)
+	utest.EqualNow(t, len(e.Leaks), 1)
+	utest.EqualNow(t, e.Error(), "slab: pool closed with 1 chunks and 1 blocks not freed")
+
+	// the pages holding the buffers are kept until they are freed
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+	held[0] = 1
+	pool.Free(held)
+	pool.Free(block)
+	utest.IsNilNow(t, pool.Close())
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+	utest.EqualNow(t, pool.BuddyStats().Arenas, 0)
+}
+
+func Test_AtomPool_CloseWakesWaiters(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 128, WithMaxPages(1))
+	held := pool.Alloc(128)
+	done := make(chan error)
+	go func() {
+		_, err := pool.AllocContext(context.Background(), 128)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pool.Close()
+	utest.EqualNow(t, <-done, ErrPoolClosed)
+	pool.Free(held)
+}
+
+func Test_AtomPool_CloseParent(t *testing.T) {
+	parent := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4))
+	child, err := parent.NewChild()
+	utest.IsNilNow(t, err)
+	mem := child.Alloc(128)
+	utest.IsNilNow(t, parent.Close())
+
+	// pages returned by the child are released instead of kept as spares
+	child.Free(mem)
+	utest.EqualNow(t, child.Trim(), 4*1024)
+	utest.EqualNow(t, parent.spareBytes(), 0)
+	utest.EqualNow(t, int(parent.src.budget.used.Load()), 0)
+
+	// the child can't borrow new pages
+	_, pooled := child.AllocPooled(128)
+	utest.Assert(t, !pooled)
+}
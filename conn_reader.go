@@ -0,0 +1,171 @@
+package slab
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+)
+
+// ConnReader reads a net.Conn into a chunk of Pool and splits the input into messages by a bufio.SplitFunc,
+// e.g. bufio.ScanLines. Each message is copied into its own chunk, which the caller passes to Done when it's done with it.
+// Close frees the read buffer and every message not passed to Done yet.
+// ReadMessage must be called by one goroutine at a time, Done and Close can be called by any goroutine.
+type ConnReader struct {
+	pool  Pool
+	conn  net.Conn
+	split bufio.SplitFunc
+
+	buf  []byte
+	r, w int // buf[r:w] 为尚未切分的数据
+	err  error
+
+	mu      sync.Mutex
+	pending map[uintptr][]byte // 交给调用者、还没有 Done 的消息
+	reading bool               // ReadMessage 正在使用 buf
+	closed  bool
+}
+
+// NewConnReader create a ConnReader reading conn with a buffer of at least maxMessage bytes alloc from pool,
+// so a message can be up to the size of the buffer.
+func NewConnReader(pool Pool, conn net.Conn, split bufio.SplitFunc, maxMessage int) *ConnReader {
+	mem := pool.Alloc(maxMessage)
+	return &ConnReader{
+		pool:    pool,
+		conn:    conn,
+		split:   split,
+		buf:     mem[:cap(mem)],
+		pending: make(map[uintptr][]byte),
+	}
+}
+
+// ReadMessage returns the next message in a chunk of the pool, which must be passed to Done.
+// It returns bufio.ErrTooLong if a message doesn't fit the buffer, io.EOF at the end of the input,
+// and net.ErrClosed after Close.
+func (cr *ConnReader) ReadMessage() ([]byte, error) {
+	cr.mu.Lock()
+	if cr.closed {
+		cr.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	cr.reading = true
+	cr.mu.Unlock()
+
+	token, err := cr.next()
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.reading = false
+	if cr.closed {
+		// Close 在读取过程中被调用，buf 由这里归还
+		cr.release()
+		return nil, net.ErrClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg := cr.pool.Alloc(len(token))
+	copy(msg, token)
+	if cap(msg) > 0 {
+		cr.pending[dataPtr(msg)] = msg
+	}
+	return msg, nil
+}
+
+// next 从 buf 中切分出下一条消息，数据不足时从 conn 读入
+func (cr *ConnReader) next() ([]byte, error) {
+	for {
+		if cr.r < cr.w || cr.err != nil {
+			advance, token, err := cr.split(cr.buf[cr.r:cr.w], cr.err != nil)
+			if err != nil {
+				if err == bufio.ErrFinalToken {
+					// 之后的数据都被丢弃
+					cr.r, cr.w = 0, 0
+					cr.err = io.EOF
+					if token == nil {
+						return nil, io.EOF
+					}
+					return token, nil
+				}
+				return nil, err
+			}
+			if advance < 0 || advance > cr.w-cr.r {
+				return nil, bufio.ErrBadReadCount
+			}
+			cr.r += advance
+			if token != nil {
+				return token, nil
+			}
+			if advance > 0 {
+				continue
+			}
+		}
+		if cr.err != nil {
+			cr.r, cr.w = 0, 0
+			return nil, cr.err
+		}
+		if cr.r == 0 && cr.w == len(cr.buf) {
+			return nil, bufio.ErrTooLong
+		}
+
+		// 把未切分的数据移到开头再读入
+		if cr.r > 0 {
+			copy(cr.buf, cr.buf[cr.r:cr.w])
+			cr.w -= cr.r
+			cr.r = 0
+		}
+		n, err := cr.conn.Read(cr.buf[cr.w:])
+		cr.w += n
+		if err != nil {
+			cr.err = err
+		}
+	}
+}
+
+// Done frees a message returned by ReadMessage, messages already freed by Close are ignored.
+func (cr *ConnReader) Done(msg []byte) {
+	if cap(msg) == 0 {
+		return
+	}
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if m, ok := cr.pending[dataPtr(msg)]; ok {
+		delete(cr.pending, dataPtr(msg))
+		cr.pool.Free(m)
+	}
+}
+
+// Pending returns the number of messages returned by ReadMessage and not passed to Done yet.
+func (cr *ConnReader) Pending() int {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	return len(cr.pending)
+}
+
+// Close closes the connection, frees the read buffer and every message not passed to Done yet,
+// those messages must not be used after that.
+func (cr *ConnReader) Close() error {
+	cr.mu.Lock()
+	if cr.closed {
+		cr.mu.Unlock()
+		return nil
+	}
+	cr.closed = true
+	for ptr, msg := range cr.pending {
+		delete(cr.pending, ptr)
+		cr.pool.Free(msg)
+	}
+	if !cr.reading {
+		cr.release()
+	}
+	cr.mu.Unlock()
+	return cr.conn.Close()
+}
+
+// release 归还 buf，调用者需持有 mu
+func (cr *ConnReader) release() {
+	if cr.buf != nil {
+		cr.pool.Free(cr.buf)
+		cr.buf = nil
+	}
+}
@@ -0,0 +1,87 @@
+package slab
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_ConnReader(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	client, server := net.Pipe()
+	go func() {
+		client.Write([]byte("hello\nwor"))
+		client.Write([]byte("ld\n\n" + strings.Repeat("x", 200) + "\nlast"))
+		client.Close()
+	}()
+
+	cr := NewConnReader(pool, server, bufio.ScanLines, 128)
+	var msgs []string
+	for {
+		msg, err := cr.ReadMessage()
+		if err == io.EOF {
+			break
+		}
+		if err == bufio.ErrTooLong {
+			break
+		}
+		utest.IsNilNow(t, err)
+		msgs = append(msgs, string(msg))
+		if len(msgs) == 1 {
+			// the first message is kept until Close
+			continue
+		}
+		cr.Done(msg)
+	}
+	utest.EqualNow(t, strings.Join(msgs, ","), "hello,world,")
+	utest.EqualNow(t, cr.Pending(), 1)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+
+	utest.IsNilNow(t, cr.Close())
+	utest.EqualNow(t, cr.Pending(), 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	_, err := cr.ReadMessage()
+	utest.EqualNow(t, err, net.ErrClosed)
+}
+
+func Test_ConnReader_CloseWhileReading(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	client, server := net.Pipe()
+	defer client.Close()
+
+	cr := NewConnReader(pool, server, bufio.ScanLines, 128)
+	done := make(chan error)
+	go func() {
+		_, err := cr.ReadMessage()
+		done <- err
+	}()
+	client.Write([]byte("no newline yet"))
+	cr.Close()
+	utest.EqualNow(t, <-done, net.ErrClosed)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_ConnReader_EOF(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	client, server := net.Pipe()
+	go func() {
+		client.Write([]byte("a\nb"))
+		client.Close()
+	}()
+
+	cr := NewConnReader(pool, server, bufio.ScanLines, 128)
+	defer cr.Close()
+	for _, want := range []string{"a", "b"} {
+		msg, err := cr.ReadMessage()
+		utest.IsNilNow(t, err)
+		utest.EqualNow(t, string(msg), want)
+		cr.Done(msg)
+	}
+	_, err := cr.ReadMessage()
+	utest.EqualNow(t, err, io.EOF)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+}
@@ -0,0 +1,78 @@
+package slab
+
+import "io"
+
+// copyBufferSize 和 io.Copy 使用的缓冲区大小一致
+const copyBufferSize = 32 * 1024
+
+// Copy copies from src to dst like io.Copy, using a chunk of the pool as the buffer.
+// The chunk is freed before Copy returns, even if dst or src panics.
+func (pool *AtomPool) Copy(dst io.Writer, src io.Reader) (int64, error) {
+	return pool.copyBuffer(dst, src, copyBufferSize)
+}
+
+// CopyN copies n bytes, or until an error, from src to dst like io.CopyN, using a chunk of the pool as the buffer.
+func (pool *AtomPool) CopyN(dst io.Writer, src io.Reader, n int64) (int64, error) {
+	size := copyBufferSize
+	if n < int64(size) {
+		size = int(n)
+	}
+	written, err := pool.copyBuffer(dst, io.LimitReader(src, n), size)
+	if written < n && err == nil {
+		// src 提前结束
+		err = io.EOF
+	}
+	return written, err
+}
+
+func (pool *AtomPool) copyBuffer(dst io.Writer, src io.Reader, size int) (int64, error) {
+	if size > pool.maxSize {
+		size = pool.maxSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	buf := pool.Alloc(size)
+	defer pool.Free(buf)
+	return io.CopyBuffer(dst, src, buf)
+}
+
+// ReadAll reads from r until EOF or an error like io.ReadAll, into chunks of the pool instead of a slice
+// doubled and copied as it grows. The first chunk is of the smallest class, and each time a chunk is full the next one
+// is of the next larger class, up to the largest, so the data read is never copied and a short body fits a single small chunk.
+// bufs holds the data read in order, each of them filled but the last, and release frees their chunks, also when err is not nil.
+// A successful ReadAll returns err == nil, not io.EOF. It returns ErrPoolExhausted if the pool returns nil.
+func (pool *AtomPool) ReadAll(r io.Reader) (bufs [][]byte, release func(), err error) {
+	release = func() {
+		pool.FreeBatch(bufs)
+		bufs = nil
+	}
+	classes := pool.classes
+	for {
+		if n := len(bufs); n == 0 || len(bufs[n-1]) == cap(bufs[n-1]) {
+			size := pool.maxSize
+			if n < len(classes) {
+				size = classes[n].size
+			}
+			mem := pool.Alloc(size)
+			if cap(mem) == 0 {
+				return bufs, release, ErrPoolExhausted
+			}
+			bufs = append(bufs, mem[:0])
+		}
+		last := bufs[len(bufs)-1]
+		m, e := r.Read(last[len(last):cap(last)])
+		bufs[len(bufs)-1] = last[:len(last)+m]
+		if e != nil {
+			if len(bufs[len(bufs)-1]) == 0 {
+				// 最后一个 chunk 没有读到数据
+				pool.Free(bufs[len(bufs)-1])
+				bufs = bufs[:len(bufs)-1]
+			}
+			if e != io.EOF {
+				err = e
+			}
+			return bufs, release, err
+		}
+	}
+}
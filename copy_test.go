@@ -0,0 +1,105 @@
+package slab
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/funny/utest"
+)
+
+// onlyReader 隐藏 strings.Reader 的 WriteTo，使 io.CopyBuffer 使用传入的缓冲区
+type onlyReader struct{ io.Reader }
+
+// onlyWriter 隐藏 bytes.Buffer 的 ReadFrom
+type onlyWriter struct{ io.Writer }
+
+func Test_AtomPool_Copy(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	data := strings.Repeat("0123456789", 500)
+
+	var out bytes.Buffer
+	n, err := pool.Copy(onlyWriter{&out}, onlyReader{strings.NewReader(data)})
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, int64(len(data)))
+	utest.EqualNow(t, out.String(), data)
+	utest.EqualNow(t, pool.Stats()[3].Allocs, uint64(1))
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+
+	out.Reset()
+	n, err = pool.CopyN(onlyWriter{&out}, onlyReader{strings.NewReader(data)}, 100)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, int64(100))
+	utest.EqualNow(t, out.String(), data[:100])
+	utest.EqualNow(t, pool.Stats()[0].Allocs, uint64(1))
+
+	out.Reset()
+	n, err = pool.CopyN(onlyWriter{&out}, onlyReader{strings.NewReader("hello")}, 100)
+	utest.EqualNow(t, err, io.EOF)
+	utest.EqualNow(t, n, int64(5))
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+}
+
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) { panic("write") }
+
+func Test_AtomPool_CopyPanic(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	defer func() {
+		utest.NotNilNow(t, recover())
+		utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+	}()
+	pool.Copy(panicWriter{}, onlyReader{strings.NewReader("hello")})
+}
+
+func Test_AtomPool_ReadAll(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 8192)
+	data := strings.Repeat("0123456789", 500)
+
+	bufs, release, err := pool.ReadAll(onlyReader{strings.NewReader(data)})
+	utest.IsNilNow(t, err)
+	var sizes []int
+	for _, b := range bufs {
+		sizes = append(sizes, cap(b))
+	}
+	utest.Assert(t, reflect.DeepEqual(sizes, []int{128, 256, 512, 1024, 1024, 1024, 1024, 1024}), sizes)
+	utest.EqualNow(t, string(bytes.Join(bufs, nil)), data)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 5)
+	release()
+	release()
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// 刚好写满一个 chunk 时不保留空 chunk
+	bufs, release, err = pool.ReadAll(strings.NewReader(data[:128]))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(bufs), 1)
+	utest.EqualNow(t, string(bufs[0]), data[:128])
+	release()
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+
+	bufs, release, err = pool.ReadAll(strings.NewReader(""))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(bufs), 0)
+	release()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	// 读取出错时返回已读到的数据
+	bufs, release, err = pool.ReadAll(io.MultiReader(strings.NewReader(data[:200]), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	utest.EqualNow(t, err, io.ErrUnexpectedEOF)
+	utest.EqualNow(t, string(bytes.Join(bufs, nil)), data[:200])
+	release()
+
+	pool = NewAtomPool(128, 1024, 2, 1024, WithNoHeap())
+	bufs, release, err = pool.ReadAll(strings.NewReader(data))
+	utest.EqualNow(t, err, ErrPoolExhausted)
+	utest.Assert(t, len(bufs) > 0)
+	release()
+}
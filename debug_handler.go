@@ -0,0 +1,147 @@
+package slab
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// DebugHandler returns an http.Handler which renders the configuration, the per-class utilization, the fallback counters
+// and the recent trim events of the pools in the registry, see RegisterPool, as an HTML page,
+// or as JSON if the request has the query format=json. The query pool=name shows only that pool.
+// It can be mounted next to /debug/pprof:
+//
+//	http.Handle("/debug/slab", slab.DebugHandler())
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(serveDebug)
+}
+
+// debugPool 是调试页面上一个 pool 的内容
+type debugPool struct {
+	Name     string
+	Config   debugConfig
+	Classes  []ClassStats
+	Oversize []OversizeStats
+	Buddy    *BuddyStats     `json:",omitempty"`
+	Overflow []OverflowStats `json:",omitempty"`
+	Trims    []TrimEvent
+}
+
+type debugConfig struct {
+	MinSize   int
+	MaxSize   int
+	Classes   int
+	MaxPages  int
+	MaxMemory int // 0 means no limit
+	Align     int
+	Strict    bool
+	NoHeap    bool
+	Mmap      bool
+}
+
+func newDebugPool(name string, pool *AtomPool) debugPool {
+	d := debugPool{
+		Name: name,
+		Config: debugConfig{
+			MinSize:   pool.minSize,
+			MaxSize:   pool.maxSize,
+			Classes:   len(pool.classes),
+			MaxMemory: int(pool.src.budget.limit.Load()),
+			Align:     pool.align,
+			Strict:    pool.strict,
+			NoHeap:    pool.noHeap,
+			Mmap:      isMmap(pool.src.pages),
+		},
+		Classes:  pool.Stats(),
+		Oversize: pool.OversizeStats(),
+		Overflow: pool.OverflowStats(),
+		Trims:    pool.TrimEvents(),
+	}
+	if len(pool.classes) > 0 {
+		d.Config.MaxPages = len(pool.classes[0].pages)
+	}
+	if pool.buddy != nil {
+		s := pool.BuddyStats()
+		d.Buddy = &s
+	}
+	return d
+}
+
+func serveDebug(w http.ResponseWriter, r *http.Request) {
+	var pools []debugPool
+	only := r.URL.Query().Get("pool")
+	for name, pool := range RegisteredPools() {
+		if only == "" || only == name {
+			pools = append(pools, newDebugPool(name, pool))
+		}
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(pools)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	debugTemplate.Execute(w, pools)
+}
+
+var debugTemplate = template.Must(template.New("slab").Funcs(template.FuncMap{
+	"utilization": func(s ClassStats) string {
+		if s.InUse+s.Free == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(s.InUse)/float64(s.InUse+s.Free))
+	},
+	"fragmentation": func(s ClassStats) string {
+		return fmt.Sprintf("%.1f%%", 100*s.Fragmentation())
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>/debug/slab</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: right; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.Name}}</h2>
+{{with .Config}}
+<p>sizes {{.MinSize}}-{{.MaxSize}} in {{.Classes}} classes, up to {{.MaxPages}} pages per class,
+max memory {{if .MaxMemory}}{{.MaxMemory}}{{else}}unlimited{{end}}{{if .Align}}, aligned to {{.Align}}{{end}}{{if .Strict}}, strict budget{{end}}{{if .NoHeap}}, no heap{{end}}{{if .Mmap}}, mmap{{end}}</p>
+{{end}}
+<table>
+<tr><th>size</th><th>page size</th><th>pages</th><th>resident</th><th>in use</th><th>free</th><th>utilization</th><th>allocs</th><th>frees</th><th>fallbacks</th><th>over budget</th><th>double frees</th><th>fragmentation</th></tr>
+{{range .Classes}}<tr><td>{{.Size}}</td><td>{{.PageSize}}</td><td>{{.Pages}}</td><td>{{.Resident}}</td><td>{{.InUse}}</td><td>{{.Free}}</td><td>{{utilization .}}</td><td>{{.Allocs}}</td><td>{{.Frees}}</td><td>{{.Fallbacks}}</td><td>{{.OverBudget}}</td><td>{{.DoubleFrees}}</td><td>{{fragmentation .}}</td></tr>
+{{end}}
+</table>
+{{if .Oversize}}
+<table>
+<tr><th>oversize up to</th><th>requests</th><th>fallbacks</th></tr>
+{{range .Oversize}}<tr><td>{{.Size}}</td><td>{{.Requests}}</td><td>{{.Fallbacks}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{with .Buddy}}
+<p>buddy blocks {{.MinBlock}}-{{.MaxBlock}} in {{.Arenas}} arenas, {{.InUse}} of {{.Resident}} bytes in use, {{.Allocs}} allocs, {{.Fallbacks}} fallbacks</p>
+{{end}}
+{{if .Trims}}
+<table>
+<tr><th>trimmed at</th><th>ttl</th><th>released</th></tr>
+{{range .Trims}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.TTL}}</td><td>{{.Released}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{else}}
+<p>no pool registered</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,44 @@
+package slab
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_DebugHandler(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	pool.AllocBatch(128, 9)
+	pool.Alloc(4096)
+	pool.Trim()
+	RegisterPool("debug", pool)
+	defer UnregisterPool("debug")
+
+	w := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/slab?pool=debug", nil))
+	utest.EqualNow(t, w.Code, 200)
+	body := w.Body.String()
+	utest.Assert(t, strings.Contains(body, "<h2>debug</h2>"))
+	utest.Assert(t, strings.Contains(body, "up to 2 pages per class"))
+	utest.Assert(t, strings.Contains(body, "<td>128</td><td>1024</td><td>2</td><td>2048</td><td>9</td><td>7</td><td>56.2%</td>"))
+	utest.Assert(t, strings.Contains(body, "<td>4096</td><td>1</td><td>1</td>"))
+	utest.Assert(t, strings.Contains(body, "<td>3072</td></tr>"))
+
+	w = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/slab?pool=debug&format=json", nil))
+	utest.EqualNow(t, w.Header().Get("Content-Type"), "application/json")
+	var pools []debugPool
+	utest.IsNilNow(t, json.Unmarshal(w.Body.Bytes(), &pools))
+	utest.EqualNow(t, len(pools), 1)
+	utest.EqualNow(t, pools[0].Config.MaxPages, 2)
+	utest.EqualNow(t, pools[0].Classes[0].InUse, 9)
+	utest.EqualNow(t, len(pools[0].Trims), 1)
+	utest.EqualNow(t, pools[0].Trims[0].Released, 3*1024)
+
+	w = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/slab?pool=none", nil))
+	utest.Assert(t, strings.Contains(w.Body.String(), "no pool registered"))
+}
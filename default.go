@@ -0,0 +1,42 @@
+package slab
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	defaultOnce sync.Once
+	defaultPool atomic.Value // holder
+)
+
+type holder struct{ pool Pool }
+
+// DefaultPool returns the pool used by Alloc and Free.
+// Unless SetDefaultPool is called first it is an AtomPool created on first use,
+// with the default size range, lazily allocated pages and up to 16 pages per slab class.
+func DefaultPool() Pool {
+	defaultOnce.Do(func() {
+		if defaultPool.Load() == nil {
+			pool, _ := NewPool(WithLazy(), WithMaxPages(16))
+			defaultPool.Store(holder{pool})
+		}
+	})
+	return defaultPool.Load().(holder).pool
+}
+
+// SetDefaultPool replace the pool used by Alloc and Free.
+// It should be called during initialization, buffers alloc from the previous pool must not be passed to Free after that.
+func SetDefaultPool(pool Pool) {
+	defaultPool.Store(holder{pool})
+}
+
+// Alloc alloc a []byte from the default pool.
+func Alloc(size int) []byte {
+	return DefaultPool().Alloc(size)
+}
+
+// Free release a []byte that alloc from Alloc to the default pool.
+func Free(mem []byte) {
+	DefaultPool().Free(mem)
+}
@@ -0,0 +1,20 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_DefaultPool(t *testing.T) {
+	pool := DefaultPool().(*AtomPool)
+	mem := Alloc(100)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.Assert(t, pool.Owns(mem))
+	Free(mem)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+
+	SetDefaultPool(&NoPool{})
+	defer SetDefaultPool(pool)
+	utest.EqualNow(t, cap(Alloc(100)), 100)
+}
@@ -0,0 +1,73 @@
+package slab
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+// DebugDump writes the state of each slab class to w as a human-readable table:
+// the chunk size, the page count, the length of the free list, the encoding of its head and the range of the ABA counters of each page.
+// The free lists are walked without synchronization, call it in a quiescent window like ScrubFree to get an exact picture.
+func (pool *AtomPool) DebugDump(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLASS\tSIZE\tPAGES\tFREE\tHEAD\tPAGE\tCHUNKS\tABA")
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].dump(tw, i)
+	}
+	if b := pool.buddy; b != nil {
+		s := pool.BuddyStats()
+		fmt.Fprintf(tw, "buddy\t%d-%d\t%d\t%d\t-\t\t\t\n", s.MinBlock, s.MaxBlock, s.Arenas, s.Resident-s.InUse)
+	}
+	return tw.Flush()
+}
+
+// dump 输出第 i 个 class 的一行汇总，以及每个 page 一行的空闲 chunk 数和 ABA 计数范围
+func (c *class) dump(w io.Writer, i int) {
+	// 持有 growMu，避免遍历期间 page 被 reclaim 释放
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	free := make([]int, nslots)
+	length := 0
+	broken := ""
+	head := c.head.Load()
+	for v := head; v != 0; {
+		idx := linkIndex(v)
+		if idx >= uint64(nslots*c.perPage) || c.chunk(idx) == nil {
+			broken = fmt.Sprintf(" (bad index %d)", idx)
+			break
+		}
+		if length >= nslots*c.perPage {
+			broken = " (cycle)"
+			break
+		}
+		free[int(idx)/c.perPage]++
+		length++
+		v = nextLink(c.chunk(idx).next.Load())
+	}
+
+	headStr := "-"
+	if head != 0 {
+		headStr = fmt.Sprintf("%d/%d", linkIndex(head), linkABA(head))
+	}
+	fmt.Fprintf(w, "%d\t%d\t%d\t%d%s\t%s\t\t\t\n", i, c.size, atomic.LoadInt32(&c.npages), length, broken, headStr)
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p == nil {
+			fmt.Fprintf(w, "\t\t\t\t\t%d\treleased\t\n", n)
+			continue
+		}
+		lo, hi := p.chunks[0].aba, p.chunks[0].aba
+		for j := range p.chunks {
+			if aba := p.chunks[j].aba; aba < lo {
+				lo = aba
+			} else if aba > hi {
+				hi = aba
+			}
+		}
+		fmt.Fprintf(w, "\t\t\t%d\t\t%d\t%d\t%d-%d\n", free[n], n, len(p.chunks), lo, hi)
+	}
+}
@@ -0,0 +1,31 @@
+package slab
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_DebugDump(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	temp := make([][]byte, 10)
+	for i := range temp {
+		temp[i] = pool.Alloc(128)
+	}
+	pool.Free(temp[9])
+	pool.Free(temp[3])
+
+	var out bytes.Buffer
+	utest.IsNilNow(t, pool.DebugDump(&out))
+	if testing.Verbose() {
+		os.Stdout.Write(out.Bytes())
+	}
+	lines := bytes.Split(out.Bytes(), []byte("\n"))
+	utest.EqualNow(t, string(bytes.Fields(lines[0])[0]), "CLASS")
+	// class 0 owns 2 pages with 8 free chunks, the head is the last freed chunk
+	utest.EqualNow(t, string(bytes.Join(bytes.Fields(lines[1]), []byte(" "))), "0 128 2 8 3/1")
+	utest.EqualNow(t, string(bytes.Join(bytes.Fields(lines[2]), []byte(" "))), "1 0 8 0-1")
+	utest.EqualNow(t, string(bytes.Join(bytes.Fields(lines[3]), []byte(" "))), "7 1 8 0-1")
+}
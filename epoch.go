@@ -0,0 +1,62 @@
+package slab
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// epochStripes 是每个周期的计数分散到的槽数，减少不同 goroutine 在同一个缓存行上的争用
+const epochStripes = 8
+
+// epoch 是 class 的宽限期计数，相当于一个简化的 RCU：
+// 不持锁读取 page 的操作（pop、popRun、Push）执行期间进入当前周期，reclaim 摘下 page 之后推进周期，
+// 等到上一个周期中的操作全部退出，才把 page 的内存归还给 source 或者解除映射。
+// 此时仍在执行的操作都是在 page 摘下之后开始的，不可能再拿到它的 chunk
+type epoch struct {
+	current atomic.Uint64
+	active  [2][epochStripes]epochStripe // 按周期的奇偶记录尚未退出的操作数
+}
+
+type epochStripe struct {
+	n atomic.Int64
+	_ [56]byte // 独占一个缓存行
+}
+
+// enter 进入当前周期，返回值需传给 exit
+func (e *epoch) enter() uint64 {
+	// 按 goroutine 栈的地址选择计数槽，同一个 goroutine 的 enter 和 exit 不必落在同一个槽上
+	s := uint64(stackHint()) % epochStripes
+	for {
+		v := e.current.Load()
+		e.active[v&1][s].n.Add(1)
+		// 计数之前周期可能已被推进，此时 synchronize 未必能看到这次计数，退出后重新进入
+		if e.current.Load() == v {
+			return v*epochStripes + s
+		}
+		e.active[v&1][s].n.Add(-1)
+	}
+}
+
+// stackHint 返回由调用者 goroutine 的栈地址得到的一个数，不同 goroutine 的结果通常不同，用于把它们分散到不同的槽上
+func stackHint() uintptr {
+	var x byte
+	// goroutine 的栈按 8KB 的整数倍分配，地址的低位在不同 goroutine 之间往往相同，散列之后再用
+	return uintptr(splitmix64(uint64(uintptr(unsafe.Pointer(&x)) >> 10)))
+}
+
+// exit 退出 enter 进入的周期
+func (e *epoch) exit(v uint64) {
+	e.active[v/epochStripes&1][v%epochStripes].n.Add(-1)
+}
+
+// synchronize 推进周期并等待上一个周期的操作全部退出，调用者需持有 growMu。
+// 进入周期的操作不会等待 growMu，因此这里不会死锁
+func (e *epoch) synchronize() {
+	v := e.current.Add(1) - 1
+	for s := 0; s < epochStripes; s++ {
+		for e.active[v&1][s].n.Load() > 0 {
+			runtime.Gosched()
+		}
+	}
+}
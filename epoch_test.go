@@ -0,0 +1,86 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_Epoch_Synchronize(t *testing.T) {
+	var e epoch
+	v := e.enter()
+
+	done := make(chan struct{})
+	go func() {
+		e.synchronize()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("synchronize returned while an operation is in the epoch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// operations entering after the epoch moved on are not waited for
+	for e.current.Load() == v/epochStripes {
+		time.Sleep(time.Millisecond)
+	}
+	w := e.enter()
+	e.exit(v)
+	<-done
+	e.exit(w)
+	e.synchronize()
+}
+
+func Test_AtomPool_TrimGracePeriod(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	c := &pool.classes[0]
+	pool.Free(pool.Alloc(128))
+
+	// an operation in flight holds the memory of the removed page
+	v := c.epoch.enter()
+	released := make(chan int)
+	go func() {
+		released <- pool.Trim()
+	}()
+	select {
+	case <-released:
+		t.Fatal("Trim returned before the operation in flight exited")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.epoch.exit(v)
+	utest.EqualNow(t, <-released, 1024+3*1024)
+	utest.EqualNow(t, int(c.npages), 0)
+}
+
+func Test_AtomPool_TrimParallelMmap(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithMaxPages(8), WithMmap())
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				bufs := pool.AllocBatch(256, 4)
+				mem := pool.Alloc(128)
+				mem[0] = byte(j)
+				pool.FreeBatch(bufs)
+				pool.Free(mem)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		pool.Trim()
+	}
+	wg.Wait()
+
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+		utest.EqualNow(t, s.DoubleFrees, uint64(0))
+		utest.EqualNow(t, s.Rejects, uint64(0))
+	}
+}
@@ -0,0 +1,12 @@
+package slab
+
+import "expvar"
+
+// PublishExpvar publish the live statistics of the pool as an expvar variable named name,
+// so they are exported as JSON by the /debug/vars endpoint.
+// Like expvar.Publish it panics if name is already registered.
+func (pool *AtomPool) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return pool.Stats()
+	}))
+}
@@ -0,0 +1,23 @@
+package slab
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_PublishExpvar(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	pool.PublishExpvar("slab_test_pool")
+	pool.Alloc(128)
+
+	v := expvar.Get("slab_test_pool")
+	utest.NotNilNow(t, v)
+
+	var stats []ClassStats
+	utest.IsNilNow(t, json.Unmarshal([]byte(v.String()), &stats))
+	utest.EqualNow(t, len(stats), 4)
+	utest.EqualNow(t, stats[0].InUse, 1)
+}
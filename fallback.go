@@ -0,0 +1,90 @@
+package slab
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// oversizeBucket 统计大小在 (2^(k-1), 2^k] 之间、大于最大 chunk 的请求
+type oversizeBucket struct {
+	requests  atomic.Uint64
+	fallbacks atomic.Uint64
+}
+
+// oversized 返回大于最大 chunk 的 size 所属的统计桶
+func (pool *AtomPool) oversized(size int) *oversizeBucket {
+	return &pool.oversize[bits.Len(uint(size-1))]
+}
+
+// OversizeStats is the statistics of the requests larger than the largest chunk size, bucketed by powers of 2.
+type OversizeStats struct {
+	Size      int    // upper bound of the bucket, which counts requests of (Size/2, Size] bytes
+	Requests  uint64 // requests of the bucket
+	Fallbacks uint64 // requests served by make or the fallback function instead of the buddy or overflow tier
+}
+
+// OversizeStats returns the statistics of the buckets which have seen any request.
+func (pool *AtomPool) OversizeStats() []OversizeStats {
+	var stats []OversizeStats
+	for k := range pool.oversize {
+		b := &pool.oversize[k]
+		if n := b.requests.Load(); n > 0 {
+			stats = append(stats, OversizeStats{Size: 1 << k, Requests: n, Fallbacks: b.fallbacks.Load()})
+		}
+	}
+	return stats
+}
+
+// FallbackAlert reports a slab class or an oversize bucket whose heap fallbacks crossed the threshold of WatchFallbacks.
+type FallbackAlert struct {
+	Size      int    // chunk size of the class, or upper bound of the oversize bucket
+	Oversize  bool   // the requests are larger than the largest chunk size
+	Requests  uint64 // requests during the interval
+	Fallbacks uint64 // requests fall back to the heap during the interval
+}
+
+// Rate returns the fraction of the requests which fall back to the heap.
+func (a FallbackAlert) Rate() float64 {
+	return float64(a.Fallbacks) / float64(a.Requests)
+}
+
+// WatchFallbacks start a goroutine which checks the heap fallbacks of every slab class and oversize bucket every interval,
+// and calls fn for each one whose fallbacks during the interval are more than ratio of its requests, e.g. to page someone.
+// Call Stop to end the goroutine.
+func (pool *AtomPool) WatchFallbacks(ratio float64, interval time.Duration, fn func(FallbackAlert)) *Reclaimer {
+	return pool.startReclaimer(interval, pool.fallbackCheck(ratio, fn))
+}
+
+// fallbackCheck 返回 WatchFallbacks 每个周期执行的检查，每次调用检查自上次调用以来的 fallback
+func (pool *AtomPool) fallbackCheck(ratio float64, fn func(FallbackAlert)) func() {
+	var (
+		last     = make([]ClassStats, len(pool.classes))
+		lastOver [len(pool.oversize)]OversizeStats
+	)
+	check := func(a FallbackAlert) {
+		if a.Requests > 0 && a.Rate() > ratio {
+			fn(a)
+		}
+	}
+	return func() {
+		for i, s := range pool.Stats() {
+			check(FallbackAlert{
+				Size:      s.Size,
+				Requests:  s.Allocs + s.Fallbacks - last[i].Allocs - last[i].Fallbacks,
+				Fallbacks: s.Fallbacks - last[i].Fallbacks,
+			})
+			last[i] = s
+		}
+		for _, s := range pool.OversizeStats() {
+			k := bits.Len(uint(s.Size - 1))
+			check(FallbackAlert{
+				Size:      s.Size,
+				Oversize:  true,
+				Requests:  s.Requests - lastOver[k].Requests,
+				Fallbacks: s.Fallbacks - lastOver[k].Fallbacks,
+			})
+			lastOver[k] = s
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package slab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_OversizeStats(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithBuddy(4096, 1))
+	utest.EqualNow(t, len(pool.OversizeStats()), 0)
+	pool.Alloc(2048)
+	pool.Alloc(2048)
+	pool.Alloc(1500)
+	pool.Alloc(3000)
+	pool.Alloc(10000)
+
+	stats := pool.OversizeStats()
+	utest.EqualNow(t, len(stats), 3)
+	utest.EqualNow(t, stats[0], OversizeStats{Size: 2048, Requests: 3, Fallbacks: 1})
+	utest.EqualNow(t, stats[1], OversizeStats{Size: 4096, Requests: 1, Fallbacks: 1})
+	utest.EqualNow(t, stats[2], OversizeStats{Size: 16384, Requests: 1, Fallbacks: 1})
+}
+
+func Test_AtomPool_WatchFallbacks(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	var alerts []FallbackAlert
+	tick := pool.fallbackCheck(0.5, func(a FallbackAlert) {
+		alerts = append(alerts, a)
+	})
+
+	// every request is served by the class
+	for i := 0; i < 8; i++ {
+		pool.Alloc(128)
+	}
+	tick()
+	utest.EqualNow(t, len(alerts), 0)
+
+	// the class is exhausted and every request falls back to make
+	for i := 0; i < 10; i++ {
+		pool.Alloc(128)
+	}
+	pool.Alloc(4096)
+	tick()
+	utest.EqualNow(t, len(alerts), 2)
+	utest.EqualNow(t, alerts[0], FallbackAlert{Size: 128, Requests: 10, Fallbacks: 10})
+	utest.EqualNow(t, alerts[1], FallbackAlert{Size: 4096, Oversize: true, Requests: 1, Fallbacks: 1})
+
+	// nothing happened since the last check
+	tick()
+	utest.EqualNow(t, len(alerts), 2)
+
+	// the goroutine runs the same check every interval
+	ch := make(chan FallbackAlert, 10)
+	w := pool.WatchFallbacks(0.5, time.Millisecond, func(a FallbackAlert) {
+		ch <- a
+	})
+	defer w.Stop()
+	for i := 0; i < 2; i++ {
+		<-ch
+	}
+	pool.Alloc(4096)
+	a := <-ch
+	utest.EqualNow(t, a, FallbackAlert{Size: 4096, Oversize: true, Requests: 1, Fallbacks: 1})
+}
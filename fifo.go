@@ -0,0 +1,74 @@
+package slab
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// fifo 是 WithFIFO 模式下回收的 chunk 的后备链表，回收的 chunk 按回收顺序追加到尾部，
+// 空闲链表为空时整条后备链表才挂到空闲链表上，因此 chunk 按回收的先后被重新分配
+type fifo struct {
+	mu   sync.Mutex
+	head uint64 // 后备链表首 chunk 的链接值，0 表示为空
+	tail *chunk
+}
+
+// pushFree 把以 first 为首、last 为尾且已经链接好的一串回收的 chunk 挂到 FIFO 后备链表尾部，没有开启 FIFO 时挂到空闲链表首部
+func (c *class) pushFree(first uint64, last *chunk) {
+	f := c.fifo
+	if f == nil {
+		c.pushRun(first, last)
+		return
+	}
+	f.mu.Lock()
+	last.next.Store(linkEnd)
+	if f.tail == nil {
+		f.head = first
+	} else {
+		f.tail.next.Store(first)
+	}
+	f.tail = last
+	f.mu.Unlock()
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		c.signal()
+	}
+}
+
+// takeFIFO 把整条 FIFO 后备链表挂到空闲链表上，后备链表为空时返回 false
+func (c *class) takeFIFO() bool {
+	f := c.fifo
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	first, last := f.head, f.tail
+	f.head, f.tail = 0, nil
+	f.mu.Unlock()
+	if last == nil {
+		return false
+	}
+	c.pushRun(first, last)
+	return true
+}
+
+// clear 丢弃后备链表中的 chunk，调用者负责重建空闲链表
+func (f *fifo) clear() {
+	f.mu.Lock()
+	f.head, f.tail = 0, nil
+	f.mu.Unlock()
+}
+
+// fifoLinks 返回后备链表中的 chunk 的链接值
+func (c *class) fifoLinks() []uint64 {
+	f := c.fifo
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var links []uint64
+	for v := f.head; v != 0; v = nextLink(c.chunk(linkIndex(v)).next.Load()) {
+		links = append(links, v)
+	}
+	return links
+}
@@ -0,0 +1,73 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_NewPool_FIFO(t *testing.T) {
+	for _, fifo := range []bool{false, true} {
+		opts := []Option{WithClasses(128), WithPageSize(384)}
+		if fifo {
+			opts = append(opts, WithFIFO())
+		}
+		pool, err := NewPool(opts...)
+		utest.IsNilNow(t, err)
+
+		a, b, c := pool.Alloc(128), pool.Alloc(128), pool.Alloc(128)
+		pool.Free(a)
+		pool.Free(b)
+		pool.Free(c)
+		utest.EqualNow(t, pool.TryFree(b), ErrDoubleFree)
+		utest.IsNilNow(t, pool.Verify())
+		utest.EqualNow(t, pool.Stats()[0].Free, 3)
+
+		first := pool.Alloc(128)
+		if fifo {
+			utest.EqualNow(t, &first[0], &a[0])
+			utest.EqualNow(t, &pool.Alloc(128)[0], &b[0])
+		} else {
+			utest.EqualNow(t, &first[0], &c[0])
+		}
+		utest.IsNilNow(t, pool.Verify())
+	}
+}
+
+func Test_NewPool_FIFO_Trim(t *testing.T) {
+	pool, err := NewPool(WithClasses(128), WithPageSize(384), WithMaxPages(2), WithFIFO())
+	utest.IsNilNow(t, err)
+	var bufs [][]byte
+	for i := 0; i < 6; i++ {
+		bufs = append(bufs, pool.Alloc(128))
+	}
+	for _, mem := range bufs {
+		pool.Free(mem)
+	}
+
+	// the queued chunks are free for Trim
+	utest.EqualNow(t, pool.Trim(), 2*384)
+	utest.IsNilNow(t, pool.Verify())
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+}
+
+func Test_NewPool_FIFO_Concurrent(t *testing.T) {
+	pool, err := NewPool(WithClasses(128), WithPageSize(1024), WithFIFO())
+	utest.IsNilNow(t, err)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				a, b := pool.Alloc(100), pool.Alloc(100)
+				pool.Free(a)
+				pool.Free(b)
+			}
+		}()
+	}
+	wg.Wait()
+	utest.IsNilNow(t, pool.Verify())
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
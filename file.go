@@ -0,0 +1,140 @@
+package slab
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// growFile 把文件扩展到至少 size 字节，未写入的部分不占用磁盘空间
+func growFile(f *os.File, size int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= size {
+		return nil
+	}
+	return f.Truncate(size)
+}
+
+// getPage 取得第 n 个 page 的内存，WithFile 时映射文件中固定的区域
+func (c *class) getPage(n int) (raw []byte, overBudget bool, err error) {
+	if c.file == nil {
+		return c.src.get(c.rawSize())
+	}
+	if c.src.closed.Load() {
+		return nil, false, nil
+	}
+	size := c.rawSize()
+	if !c.src.budget.reserve(size) {
+		return nil, true, nil
+	}
+	raw, err = mmapFile(c.file, c.fileBase+int64(n)*c.fileStride, size)
+	if err != nil {
+		c.src.budget.release(size)
+		return nil, false, err
+	}
+	return raw, false, nil
+}
+
+// putPage 归还 getPage 得到的内存
+func (c *class) putPage(raw []byte) {
+	if c.file == nil {
+		c.src.put(raw)
+		return
+	}
+	c.src.budget.release(len(raw))
+	munmapPage(raw)
+}
+
+// FileOffset returns the offset in the file of a pool created WithFile of the chunk mem starts at,
+// which Recover turns back into the chunk after the process restarts. ok is false if mem doesn't start at a chunk.
+func (pool *AtomPool) FileOffset(mem []byte) (off int64, ok bool) {
+	if pool.file == nil || cap(mem) == 0 {
+		return 0, false
+	}
+	ptr := dataPtr(mem)
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if p, n, _ := c.locate(ptr); p != nil {
+			return c.fileBase + int64(n)*c.fileStride + int64(ptr-dataPtr(p.raw)), true
+		}
+	}
+	return 0, false
+}
+
+// Recover returns the chunk at offset off of the file of a pool created WithFile, sliced to size bytes,
+// and marks it allocated, e.g. to get back the cached buffers written by the process before it restarted.
+// The chunk keeps the data of the file and is passed to Free like the buffers from Alloc.
+// Like Reset it must be called before the pool is used by other goroutines.
+func (pool *AtomPool) Recover(off int64, size int) ([]byte, error) {
+	for i := 0; pool.file != nil && i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if off >= c.fileBase && off < c.fileBase+int64(len(c.pages))*c.fileStride {
+			if size > c.size {
+				return nil, fmt.Errorf("slab: size %d is larger than the chunk at offset %d", size, off)
+			}
+			mem, err := c.recover(off - c.fileBase)
+			if err != nil {
+				return nil, err
+			}
+			c.allocs.Add(1)
+			c.request(1, size)
+			return mem[:size], nil
+		}
+	}
+	return nil, fmt.Errorf("slab: offset %d is not in a slab class", off)
+}
+
+// recover 把距离 class 在文件中的区域起点 off 字节的 chunk 从空闲链表中摘下，page 没有映射时先映射它
+func (c *class) recover(off int64) ([]byte, error) {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+	c.takeFIFO()
+
+	n := int(off / c.fileStride)
+	if c.page(n) == nil {
+		raw, overBudget, err := c.getPage(n)
+		if overBudget {
+			return nil, ErrPoolExhausted
+		}
+		if raw == nil {
+			return nil, fmt.Errorf("slab: can't map the page at offset %d: %v", c.fileBase+int64(n)*c.fileStride, err)
+		}
+		c.addPage(n, raw, false)
+	}
+	p := c.page(n)
+	pos := off%c.fileStride - int64(dataPtr(p.mem)-dataPtr(p.raw)) - int64(c.guard)
+	if pos < 0 || pos%int64(c.stride) != 0 || pos/int64(c.stride) >= int64(c.perPage) {
+		return nil, fmt.Errorf("slab: no chunk at offset %d", c.fileBase+off)
+	}
+	i := int(pos / int64(c.stride))
+	chk := &p.chunks[i]
+	idx := uint64(n*c.perPage + i)
+
+	// 没有并发的 Alloc 和 Free，可以像普通链表一样删除
+	var prev *chunk
+	for v := c.head.Load(); v != 0; v = nextLink(prev.next.Load()) {
+		if linkIndex(v) == idx {
+			if prev == nil {
+				c.head.Store(nextLink(chk.next.Load()))
+			} else {
+				prev.next.Store(chk.next.Load())
+			}
+			chk.next.Store(0)
+			if c.leak || c.sampled() {
+				atomic.StorePointer(&chk.info, unsafe.Pointer(newAllocInfo(c.size)))
+			}
+			if c.profile != nil {
+				// skip class.recover and AtomPool.Recover
+				c.profile.Add(chk, 2)
+			}
+			c.used(1)
+			return chk.mem, nil
+		}
+		prev = c.chunk(linkIndex(v))
+	}
+	return nil, fmt.Errorf("slab: chunk at offset %d is not free", c.fileBase+off)
+}
@@ -0,0 +1,67 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package slab
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pages")
+	_, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1000), WithFile(path))
+	utest.NotNilNow(t, err)
+
+	opts := []Option{WithSizeRange(128, 1024), WithPageSize(4096), WithMaxPages(4), WithLazy(), WithFile(path)}
+	pool, err := NewPool(opts...)
+	utest.IsNilNow(t, err)
+	var offs []int64
+	for i := 0; i < 16; i++ {
+		mem := pool.Alloc(1000)
+		mem[0] = byte(i)
+		off, ok := pool.FileOffset(mem)
+		utest.Assert(t, ok)
+		offs = append(offs, off)
+	}
+	mem := pool.Alloc(100)
+	copy(mem, "hello")
+	off, ok := pool.FileOffset(mem)
+	utest.Assert(t, ok)
+	_, ok = pool.FileOffset(make([]byte, 100))
+	utest.Assert(t, !ok)
+
+	// the restarted process creates the pool with the same options and recovers the buffers by offset
+	restarted, err := NewPool(opts...)
+	utest.IsNilNow(t, err)
+	defer restarted.Close()
+	mem, err = restarted.Recover(off, 5)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(mem), "hello")
+	for i, off := range offs {
+		mem, err := restarted.Recover(off, 1000)
+		utest.IsNilNow(t, err)
+		utest.EqualNow(t, mem[0], byte(i))
+	}
+	s := restarted.Stats()
+	utest.EqualNow(t, s[0].InUse, 1)
+	utest.EqualNow(t, s[3].InUse, 16)
+	utest.EqualNow(t, s[3].Pages, 4)
+
+	_, err = restarted.Recover(off, 5)
+	utest.NotNilNow(t, err)
+	_, err = restarted.Recover(off+1, 5)
+	utest.NotNilNow(t, err)
+	_, err = restarted.Recover(off, 200)
+	utest.NotNilNow(t, err)
+	_, err = restarted.Recover(1<<40, 5)
+	utest.NotNilNow(t, err)
+
+	// the recovered chunks are not alloc again and can be freed
+	utest.Assert(t, !restarted.Owns(restarted.Alloc(1000)))
+	restarted.Free(mem)
+	utest.IsNilNow(t, restarted.Verify())
+	utest.NotNilNow(t, pool.Close())
+}
@@ -0,0 +1,44 @@
+package slab
+
+import "sync/atomic"
+
+// Fit is the way Alloc, TryAlloc and AllocPooled choose the slab class of a request, set by WithFit.
+type Fit int
+
+const (
+	// FitFirst serves a request from the smallest slab class whose chunks are large enough, growing it when it has no free chunk.
+	FitFirst Fit = iota
+	// FitExact serves only the requests whose size is exactly the chunk size of a slab class,
+	// the others fall back like the requests larger than the largest chunk and are counted in ClassStats.Fallbacks
+	// of the class which would serve them, so no chunk is handed out with unused bytes.
+	FitExact
+	// FitBest is like FitFirst, but when the smallest class has no free chunk it takes one of the smallest larger class
+	// which has at least half a page of free chunks before growing the class,
+	// trading internal fragmentation for fewer pages while other classes sit mostly idle.
+	FitBest
+)
+
+// allocBest 按 FitBest 分配：classes[i] 有空闲 chunk 时直接取，否则从空闲 chunk 多于半个 page 的最小的更大 class 取，
+// 都没有时返回 nil，由调用者按默认路径增长 classes[i]
+func (pool *AtomPool) allocBest(i, size int) []byte {
+	c := &pool.classes[i]
+	if mem, _ := c.pop(size, false); mem != nil {
+		return c.served(mem, size)
+	}
+	for j := i + 1; j < len(pool.classes); j++ {
+		l := &pool.classes[j]
+		if l.freeChunks() < (l.perPage+1)/2 {
+			continue
+		}
+		if mem, _ := l.pop(size, false); mem != nil {
+			return l.served(mem, size)
+		}
+	}
+	return nil
+}
+
+// freeChunks 返回 class 当前空闲 chunk 数的估计值，并发分配和释放时可能略有偏差
+func (c *class) freeChunks() int {
+	inUse := int(c.allocs.Load() - c.frees.Load())
+	return int(atomic.LoadInt32(&c.npages))*c.perPage - inUse
+}
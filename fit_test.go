@@ -0,0 +1,75 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_FitExact(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 512), WithPageSize(1024), WithFit(FitExact))
+	utest.IsNilNow(t, err)
+	mem, pooled := pool.AllocPooled(256)
+	utest.Assert(t, pooled)
+	utest.EqualNow(t, cap(mem), 256)
+	pool.Alloc(256)
+	pool.Alloc(256)
+	pool.Alloc(256)
+
+	// 256 的 class 用完了也不会取更大的 class
+	mem = pool.Alloc(256)
+	utest.EqualNow(t, cap(mem), 256)
+	utest.EqualNow(t, pool.Stats()[2].Allocs, uint64(0))
+
+	// 200 字节会浪费 256 字节 chunk 的一部分，退回 heap
+	mem, pooled = pool.AllocPooled(200)
+	utest.Assert(t, !pooled)
+	utest.EqualNow(t, len(mem), 200)
+	utest.EqualNow(t, pool.Stats()[1].Fallbacks, uint64(2))
+	utest.EqualNow(t, pool.Stats()[1].Allocs, uint64(4))
+
+	pool, err = NewPool(WithSizeRange(128, 512), WithPageSize(1024), WithFit(FitExact), WithNoHeap())
+	utest.IsNilNow(t, err)
+	_, err = pool.TryAlloc(200)
+	utest.EqualNow(t, err, ErrPoolExhausted)
+}
+
+func Test_AtomPool_FitBest(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 512), WithPageSize(1024), WithMaxPages(2), WithFit(FitBest))
+	utest.IsNilNow(t, err)
+	bufs := pool.AllocBatch(128, 8)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+
+	// 256 的 class 有一整个 page 空闲，不增长 128 的 class
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, len(mem), 128)
+	utest.EqualNow(t, cap(mem), 256)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+	utest.EqualNow(t, pool.Stats()[1].Allocs, uint64(1))
+	pool.Alloc(128)
+	mem = pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 256)
+
+	// 256 的 class 只剩 1 个空闲，少于半个 page，取 512 的 class
+	mem = pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 512)
+	pool.Alloc(128)
+
+	// 都不够空闲时增长 128 的 class
+	mem = pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 2)
+
+	pool.Free(mem)
+	pool.FreeBatch(bufs)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_NewPool_FitInvalid(t *testing.T) {
+	_, err := NewPool(WithFit(FitBest + 1))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithFit(FitBest), WithRealTime(4))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithFit(FitExact), WithRealTime(4))
+	utest.IsNilNow(t, err)
+}
@@ -0,0 +1,89 @@
+package slab
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// PageInfo describes a slab page, e.g. to register it once as an io_uring fixed buffer or a DMA region
+// and refer to the chunks in it by the page and an offset after that.
+// The page doesn't move until it's released, pages allocated by make are in the Go heap, WithMmap keeps them out of it.
+type PageInfo struct {
+	Class int            // index of the slab class, as in AtomPool.Stats
+	Size  int            // chunk size of the class
+	Index int            // index of the page in the class, reused by a page added after the page is released
+	Addr  unsafe.Pointer // address of the first byte of the page
+	Len   int            // bytes of the page
+}
+
+// PageEvent is a slab page added to or about to be released by a class, passed to the hook set by WithPageHook.
+type PageEvent struct {
+	PageInfo
+	Added bool // the page is added, false if it's about to be released
+}
+
+// ChunkLocation is where a chunk is in the slab pages, returned by AllocLocated and Locate.
+type ChunkLocation struct {
+	Class  int // index of the slab class, as in AtomPool.Stats
+	Page   int // index of the page in the class, as PageInfo.Index
+	Offset int // bytes between the start of the page and the start of the chunk
+}
+
+// Pages returns the pages currently owned by the slab classes of the pool.
+func (pool *AtomPool) Pages() []PageInfo {
+	var pages []PageInfo
+	for k := 0; k < len(pool.classes); k++ {
+		c := &pool.classes[k]
+		nslots := int(atomic.LoadInt32(&c.nslots))
+		for n := 0; n < nslots; n++ {
+			if p := c.page(n); p != nil {
+				pages = append(pages, c.pageInfo(n, p))
+			}
+		}
+	}
+	return pages
+}
+
+// AllocLocated is like AllocPooled, and also returns where the chunk is when it's alloc from a slab class.
+// ok is false when the buffer doesn't come from a slab class, e.g. a heap fallback or a block of the buddy tier.
+func (pool *AtomPool) AllocLocated(size int) (mem []byte, loc ChunkLocation, ok bool) {
+	mem, pooled := pool.AllocPooled(size)
+	if !pooled {
+		return mem, ChunkLocation{}, false
+	}
+	loc, ok = pool.Locate(mem)
+	return mem, loc, ok
+}
+
+// Locate returns where the chunk starting at mem is in the slab pages, ok is false if mem doesn't start at a chunk in use.
+func (pool *AtomPool) Locate(mem []byte) (loc ChunkLocation, ok bool) {
+	if cap(mem) == 0 {
+		return ChunkLocation{}, false
+	}
+	for k := 0; k < len(pool.classes); k++ {
+		c := &pool.classes[k]
+		if c.size < cap(mem) {
+			continue
+		}
+		if p, n, i := c.locate(dataPtr(mem)); p != nil {
+			if p.chunks[i].next.Load() != 0 {
+				return ChunkLocation{}, false
+			}
+			return ChunkLocation{k, n, int(dataPtr(mem) - dataPtr(p.mem))}, true
+		}
+	}
+	return ChunkLocation{}, false
+}
+
+// pageInfo 返回第 n 个 page 的信息
+func (c *class) pageInfo(n int, p *page) PageInfo {
+	return PageInfo{c.id, c.size, n, unsafe.Pointer(unsafe.SliceData(p.mem)), len(p.mem)}
+}
+
+// pageChanged 在新增第 n 个 page 之后或者摘下它时更新 WithRouting 的登记表，并调用 WithPageHook 设置的 hook，调用者持有 growMu
+func (c *class) pageChanged(n int, p *page, added bool) {
+	c.route(p, added)
+	if c.onPage != nil {
+		c.onPage(PageEvent{c.pageInfo(n, p), added})
+	}
+}
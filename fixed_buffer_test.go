@@ -0,0 +1,56 @@
+package slab
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Pages(t *testing.T) {
+	var events []PageEvent
+	pool, err := NewPool(WithSizeRange(128, 256), WithPageSize(1024), WithMaxPages(2), WithLazy(), WithPageHook(func(e PageEvent) {
+		events = append(events, e)
+	}))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Pages()), 0)
+
+	mem, loc, ok := pool.AllocLocated(200)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, len(mem), 200)
+	utest.EqualNow(t, len(events), 1)
+	utest.Assert(t, events[0].Added)
+	utest.EqualNow(t, events[0].Class, 1)
+	utest.EqualNow(t, events[0].Size, 256)
+	utest.EqualNow(t, events[0].Index, 0)
+	utest.EqualNow(t, events[0].Len, 1024)
+
+	// 按 page 和偏移找到的就是分配到的 chunk
+	utest.EqualNow(t, loc.Class, 1)
+	utest.EqualNow(t, loc.Page, 0)
+	pages := pool.Pages()
+	utest.EqualNow(t, len(pages), 1)
+	utest.EqualNow(t, pages[0], events[0].PageInfo)
+	utest.EqualNow(t, unsafe.Add(pages[0].Addr, loc.Offset), unsafe.Pointer(&mem[0]))
+
+	bufs := pool.AllocBatch(256, 4)
+	utest.EqualNow(t, len(events), 2)
+	utest.EqualNow(t, events[1].Index, 1)
+	loc, ok = pool.Locate(bufs[3])
+	utest.Assert(t, ok)
+	utest.EqualNow(t, unsafe.Add(pool.Pages()[loc.Page].Addr, loc.Offset), unsafe.Pointer(&bufs[3][0]))
+
+	_, ok = pool.Locate(mem[1:])
+	utest.Assert(t, !ok)
+	pool.FreeBatch(bufs)
+	pool.Free(mem)
+	_, ok = pool.Locate(mem)
+	utest.Assert(t, !ok)
+	_, _, ok = pool.AllocLocated(4096)
+	utest.Assert(t, !ok)
+
+	utest.EqualNow(t, pool.Trim(), 2048)
+	utest.EqualNow(t, len(events), 4)
+	utest.Assert(t, !events[2].Added && !events[3].Added)
+	utest.EqualNow(t, len(pool.Pages()), 0)
+}
@@ -0,0 +1,71 @@
+package slab
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFrameTooLarge is returned by FrameReader.ReadFrame when the length of a frame is larger than the limit of the reader.
+var ErrFrameTooLarge = errors.New("slab: frame too large")
+
+// FrameReader reads frames prefixed by their length from an io.Reader, each frame body in its own buffer of a Pool.
+// The caller owns the frames returned by ReadFrame and passes each of them to the Free of the pool when it's done.
+// The length prefix is read with a small read, wrap a raw connection in a bufio.Reader to save syscalls.
+type FrameReader struct {
+	pool     Pool
+	r        io.Reader
+	order    binary.ByteOrder
+	lenSize  int
+	maxFrame int
+	hdr      [8]byte
+}
+
+// NewFrameReader create a FrameReader reading frames whose length is encoded in the lenSize bytes before them,
+// lenSize is 1, 2, 4 or 8, in the byte order order. Frames longer than maxFrame bytes are rejected.
+func NewFrameReader(pool Pool, r io.Reader, lenSize int, order binary.ByteOrder, maxFrame int) *FrameReader {
+	switch lenSize {
+	case 1, 2, 4, 8:
+	default:
+		panic(fmt.Sprintf("slab: invalid frame length size %d", lenSize))
+	}
+	return &FrameReader{pool: pool, r: r, order: order, lenSize: lenSize, maxFrame: maxFrame}
+}
+
+// ReadFrame reads the next frame into a buffer alloc from the pool, which the caller must Free.
+// It returns io.EOF when the input ends between frames, io.ErrUnexpectedEOF when it ends inside one,
+// ErrFrameTooLarge for a frame longer than the limit, whose body is left unread, and ErrPoolExhausted if the pool returns nil.
+// On every error no buffer is held.
+func (fr *FrameReader) ReadFrame() ([]byte, error) {
+	hdr := fr.hdr[:fr.lenSize]
+	if _, err := io.ReadFull(fr.r, hdr); err != nil {
+		return nil, err
+	}
+	var n uint64
+	switch fr.lenSize {
+	case 1:
+		n = uint64(hdr[0])
+	case 2:
+		n = uint64(fr.order.Uint16(hdr))
+	case 4:
+		n = uint64(fr.order.Uint32(hdr))
+	case 8:
+		n = fr.order.Uint64(hdr)
+	}
+	if n > uint64(fr.maxFrame) {
+		return nil, ErrFrameTooLarge
+	}
+	frame := fr.pool.Alloc(int(n))
+	if frame == nil {
+		return nil, ErrPoolExhausted
+	}
+	if _, err := io.ReadFull(fr.r, frame); err != nil {
+		fr.pool.Free(frame)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,69 @@
+package slab
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_FrameReader(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	var input bytes.Buffer
+	for _, s := range []string{"hello", "", strings.Repeat("x", 300)} {
+		binary.Write(&input, binary.BigEndian, uint16(len(s)))
+		input.WriteString(s)
+	}
+
+	fr := NewFrameReader(pool, &input, 2, binary.BigEndian, 1024)
+	var frames []string
+	for {
+		frame, err := fr.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		utest.IsNilNow(t, err)
+		frames = append(frames, string(frame))
+		pool.Free(frame)
+	}
+	utest.EqualNow(t, strings.Join(frames, ","), "hello,,"+strings.Repeat("x", 300))
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+}
+
+func Test_FrameReader_Errors(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+
+	// the input ends inside the body, the buffer is freed
+	fr := NewFrameReader(pool, bytes.NewReader([]byte{0, 0, 0, 10, 'a', 'b'}), 4, binary.BigEndian, 1024)
+	_, err := fr.ReadFrame()
+	utest.EqualNow(t, err, io.ErrUnexpectedEOF)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	// the input ends inside the length
+	fr = NewFrameReader(pool, bytes.NewReader([]byte{1}), 2, binary.LittleEndian, 1024)
+	_, err = fr.ReadFrame()
+	utest.EqualNow(t, err, io.ErrUnexpectedEOF)
+
+	fr = NewFrameReader(pool, bytes.NewReader([]byte{0xff, 0xff}), 2, binary.LittleEndian, 1024)
+	_, err = fr.ReadFrame()
+	utest.EqualNow(t, err, ErrFrameTooLarge)
+
+	noHeap, _ := NewPool(WithSizeRange(128, 1024), WithNoHeap())
+	fr = NewFrameReader(noHeap, bytes.NewReader([]byte{0xd0, 0x07, 0, 0, 0, 0, 0, 0}), 8, binary.LittleEndian, 4096)
+	_, err = fr.ReadFrame()
+	utest.EqualNow(t, err, ErrPoolExhausted)
+	fr = NewFrameReader(noHeap, bytes.NewReader([]byte{200}), 1, nil, 4096)
+	frame, err := fr.ReadFrame()
+	utest.EqualNow(t, err, io.ErrUnexpectedEOF)
+	utest.IsNilNow(t, frame)
+
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	NewFrameReader(pool, nil, 3, binary.BigEndian, 1024)
+}
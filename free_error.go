@@ -0,0 +1,91 @@
+package slab
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// FreeError describes a buffer Free can't return to the pool, it's the panic value of a double free,
+// and with WithLeakDetection also of a pointer into the middle of a chunk. errors.Is matches it with its Err.
+type FreeError struct {
+	Err        error     // ErrDoubleFree, or ErrNotPooled for a pointer into the middle of a chunk
+	Addr       uintptr   // address of the first byte of the buffer
+	Class      int       // index of the slab class whose pages hold the address, -1 if none does
+	Size       int       // chunk size of that class
+	Chunk      int       // index of the chunk in the class, counting the chunks of the previous pages
+	Offset     int       // bytes between the start of the chunk and the address
+	Generation uint32    // ABA counter of the chunk, incremented every time it is freed
+	Stack      []uintptr // stack of the previous Free of the chunk, recorded by WithLeakDetection
+}
+
+func (e *FreeError) Error() string {
+	var buf bytes.Buffer
+	op := "free"
+	if e.Err == ErrDoubleFree {
+		op = "double free"
+	}
+	if e.Class < 0 {
+		fmt.Fprintf(&buf, "slab: %s of %#x outside the slab classes", op, e.Addr)
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "slab: %s of %#x", op, e.Addr)
+	if e.Offset > 0 {
+		fmt.Fprintf(&buf, " at offset %d", e.Offset)
+	}
+	fmt.Fprintf(&buf, " of chunk %d in class %d (%d bytes) at generation %d", e.Chunk, e.Class, e.Size, e.Generation)
+	if e.Stack != nil {
+		buf.WriteString(", the chunk was previously freed at\n")
+		writeStack(&buf, e.Stack)
+	}
+	return buf.String()
+}
+
+func (e *FreeError) Unwrap() error {
+	return e.Err
+}
+
+// newFreeInfo 记录回收的调用栈，跳过 runtime.Callers、newFreeInfo、class.prepare 和 class.Push
+func newFreeInfo() *allocInfo {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(4, pcs[:])
+	return &allocInfo{time: time.Now(), stack: append([]uintptr(nil), pcs[:n]...)}
+}
+
+// freeError 返回回收 mem 时遇到的错误 err 的详细信息。
+// 只在报告错误时调用，其间 chunk 可能被其它 goroutine 分配或回收，信息仅供诊断
+func (pool *AtomPool) freeError(mem []byte, err error) *FreeError {
+	e := &FreeError{Err: err, Addr: dataPtr(mem), Class: -1, Chunk: -1}
+	for k := 0; k < len(pool.classes); k++ {
+		c := &pool.classes[k]
+		nslots := int(atomic.LoadInt32(&c.nslots))
+		for n := 0; n < nslots; n++ {
+			p := c.page(n)
+			if p == nil || e.Addr < p.begin || e.Addr >= p.end+uintptr(c.stride) {
+				continue
+			}
+			i := int((e.Addr - p.begin) / uintptr(c.stride))
+			e.Class, e.Size = k, c.size
+			e.Chunk = n*c.perPage + i
+			e.Offset = int((e.Addr - p.begin) % uintptr(c.stride))
+			e.Generation = p.chunks[i].aba
+			if p.freed != nil {
+				if info := (*allocInfo)(atomic.LoadPointer(&p.freed[i])); info != nil {
+					e.Stack = info.stack
+				}
+			}
+			return e
+		}
+	}
+	return e
+}
+
+// interior 判断开启泄漏检测时 mem 是否指向某个 chunk 的中间，这样的 mem 不属于任何 class，Free 会把它当作错误报告
+func (pool *AtomPool) interior(mem []byte) bool {
+	if len(pool.classes) == 0 || !pool.classes[0].leak || cap(mem) == 0 {
+		return false
+	}
+	return pool.freeError(mem, ErrNotPooled).Class >= 0
+}
@@ -0,0 +1,56 @@
+package slab
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+// recoverFree 返回 fn 中 panic 的 *FreeError
+func recoverFree(fn func()) (err *FreeError) {
+	defer func() {
+		err, _ = recover().(*FreeError)
+	}()
+	fn()
+	return nil
+}
+
+func Test_AtomPool_DoubleFreeError(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	pool.Alloc(256)
+	mem := pool.Alloc(256)
+	pool.Free(mem)
+	err := recoverFree(func() { pool.Free(mem) })
+	utest.NotNilNow(t, err)
+	utest.Assert(t, errors.Is(err, ErrDoubleFree))
+	utest.EqualNow(t, err.Class, 1)
+	utest.EqualNow(t, err.Size, 256)
+	utest.EqualNow(t, err.Chunk, 1)
+	utest.EqualNow(t, err.Offset, 0)
+	utest.EqualNow(t, err.Generation, uint32(1))
+	utest.IsNilNow(t, err.Stack)
+	utest.Assert(t, strings.Contains(err.Error(), "double free"))
+	utest.Assert(t, strings.Contains(err.Error(), "chunk 1 in class 1 (256 bytes) at generation 1"))
+}
+
+func Test_AtomPool_DoubleFreeStack(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithLeakDetection())
+	mem := pool.Alloc(128)
+	pool.Free(mem)
+	err := recoverFree(func() { pool.Free(mem) })
+	utest.NotNilNow(t, err)
+	utest.Assert(t, err.Stack != nil)
+	utest.Assert(t, strings.Contains(err.Error(), "Test_AtomPool_DoubleFreeStack"))
+
+	// 指向 chunk 中间的 mem 也被报告，heap 上的 mem 不会
+	mem = pool.Alloc(128)
+	err = recoverFree(func() { pool.Free(mem[8:]) })
+	utest.NotNilNow(t, err)
+	utest.Assert(t, errors.Is(err, ErrNotPooled))
+	utest.EqualNow(t, err.Offset, 8)
+	utest.IsNilNow(t, recoverFree(func() { pool.Free(make([]byte, 128)) }))
+	utest.Assert(t, pool.TryFree(mem[8:]) == ErrNotPooled)
+	pool.Free(mem)
+}
@@ -0,0 +1,73 @@
+package slab
+
+import "sync"
+
+// Generational allocates buffers for phase-structured lifetimes, e.g. the requests and responses of a batch pipeline,
+// out of blocks alloc from a Pool, like an Arena per generation. Buffers are alloc into the active generation,
+// and FlipAndFree makes the oldest generation the active one after freeing all its blocks at once,
+// so a buffer stays valid until n-1 more calls of FlipAndFree, e.g. until the end of the next phase with 2 generations.
+// A Generational is safe for concurrent use.
+type Generational struct {
+	mu     sync.Mutex
+	gens   []*Arena // 每一代的缓冲区都从自己的 Arena 分配
+	active int      // 当前分配的代在 gens 中的下标
+	flips  uint64   // FlipAndFree 的调用次数
+}
+
+// NewGenerational create a Generational of n generations, at least 2, which alloc blocks of blockSize bytes from pool.
+func NewGenerational(pool Pool, blockSize, n int) *Generational {
+	if n < 2 {
+		n = 2
+	}
+	g := &Generational{gens: make([]*Arena, n)}
+	for i := range g.gens {
+		g.gens[i] = NewArena(pool, blockSize)
+	}
+	return g
+}
+
+// Alloc returns a []byte of size bytes of the active generation, see Arena.Alloc.
+// It returns nil when the pool returns nil, e.g. a pool created with WithNoHeap is exhausted.
+func (g *Generational) Alloc(size int) []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gens[g.active].Alloc(size)
+}
+
+// FlipAndFree end the current phase: it frees every block of the oldest generation, whose buffers must not be used after that,
+// and makes it the active generation. The buffers of the other generations are still valid.
+func (g *Generational) FlipAndFree() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active = (g.active + 1) % len(g.gens)
+	g.gens[g.active].Release()
+	g.flips++
+}
+
+// Generation returns the number of calls of FlipAndFree, i.e. the phase the buffers alloc now belong to.
+func (g *Generational) Generation() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.flips
+}
+
+// Blocks returns the number of blocks currently held by all generations.
+func (g *Generational) Blocks() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := 0
+	for _, a := range g.gens {
+		n += a.Blocks()
+	}
+	return n
+}
+
+// Release free the blocks of every generation, all buffers alloc before must not be used after that.
+// The Generational can be reused after Release.
+func (g *Generational) Release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, a := range g.gens {
+		a.Release()
+	}
+}
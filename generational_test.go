@@ -0,0 +1,46 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Generational(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	g := NewGenerational(pool, 1024, 2)
+
+	// 第 0 代
+	a := g.Alloc(200)
+	big := g.Alloc(512)
+	utest.EqualNow(t, len(a), 200)
+	utest.EqualNow(t, g.Blocks(), 2)
+
+	// 第 1 代，第 0 代的缓冲区仍然有效
+	g.FlipAndFree()
+	utest.EqualNow(t, g.Generation(), uint64(1))
+	b := g.Alloc(300)
+	utest.EqualNow(t, g.Blocks(), 3)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 2)
+	copy(a, "still valid")
+	copy(big, "still valid")
+
+	// 第 2 代重用第 0 代的空间，第 0 代的 block 一次归还
+	g.FlipAndFree()
+	utest.EqualNow(t, g.Blocks(), 1)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 1)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+	copy(b, "still valid")
+	g.Alloc(100)
+	utest.EqualNow(t, g.Blocks(), 2)
+
+	g.Release()
+	utest.EqualNow(t, g.Blocks(), 0)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// 至少两代
+	g = NewGenerational(pool, 1024, 1)
+	utest.EqualNow(t, len(g.gens), 2)
+}
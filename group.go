@@ -0,0 +1,73 @@
+package slab
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a Pool tracking every buffer it alloc from another Pool, so they are all freed at once when the group is closed
+// or its context is done, e.g. everything allocated while serving one HTTP request dies with the request.
+// Buffers can still be freed before that by Free. A Group is safe for concurrent use.
+type Group struct {
+	pool Pool
+	mu   sync.Mutex
+	bufs map[uintptr][]byte // 尚未归还的缓冲区，按首地址索引
+	stop func() bool        // 取消 context 结束时的回调
+}
+
+// NewGroup create a Group alloc from pool, which is closed when ctx is done.
+func NewGroup(ctx context.Context, pool Pool) *Group {
+	g := &Group{pool: pool, bufs: make(map[uintptr][]byte)}
+	g.stop = context.AfterFunc(ctx, func() { g.Close() })
+	return g
+}
+
+// Alloc alloc a []byte from the pool and tracks it until Free or Close.
+// After the group is closed the buffers fall back to make, so late allocations can't leak chunks of the pool.
+func (g *Group) Alloc(size int) []byte {
+	mem := g.pool.Alloc(size)
+	if cap(mem) == 0 {
+		return mem
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.bufs == nil {
+		g.pool.Free(mem)
+		return make([]byte, size)
+	}
+	g.bufs[dataPtr(mem)] = mem
+	return mem
+}
+
+// Free release a []byte that alloc from Alloc before the group is closed, other buffers are ignored.
+func (g *Group) Free(mem []byte) {
+	if cap(mem) == 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if m, ok := g.bufs[dataPtr(mem)]; ok {
+		delete(g.bufs, dataPtr(mem))
+		g.pool.Free(m)
+	}
+}
+
+// Len returns the number of buffers alloc from the group and not freed yet.
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.bufs)
+}
+
+// Close free every buffer of the group not freed yet, they must not be used after that.
+// Closing it again is a no-op.
+func (g *Group) Close() error {
+	g.stop()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, mem := range g.bufs {
+		g.pool.Free(mem)
+	}
+	g.bufs = nil
+	return nil
+}
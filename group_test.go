@@ -0,0 +1,57 @@
+package slab
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_Group(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	g := NewGroup(context.Background(), pool)
+
+	a := g.Alloc(100)
+	g.Alloc(200)
+	g.Alloc(100)
+	utest.EqualNow(t, g.Len(), 3)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+
+	// freed early
+	g.Free(a)
+	g.Free(a)
+	utest.EqualNow(t, g.Len(), 2)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+
+	utest.IsNilNow(t, g.Close())
+	utest.EqualNow(t, g.Len(), 0)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// late allocations are not pooled
+	mem := g.Alloc(100)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	g.Free(mem)
+	utest.IsNilNow(t, g.Close())
+}
+
+func Test_Group_Context(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewGroup(ctx, pool)
+	g.Alloc(100)
+	g.Alloc(1000)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+
+	// context.AfterFunc 在另一个 goroutine 中关闭 group
+	cancel()
+	for deadline := time.Now().Add(5 * time.Second); g.Len() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	utest.EqualNow(t, g.Len(), 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+}
@@ -0,0 +1,68 @@
+package slab
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+)
+
+const (
+	guardSize    = 16
+	guardPattern = 0xCA
+)
+
+// CorruptionError is reported when the guard bytes around a chunk are overwritten,
+// which means the chunk or its neighbor was written out of its bounds.
+type CorruptionError struct {
+	Size      int       // chunk size of the class
+	Underflow bool      // the guard before the chunk is overwritten
+	Overflow  bool      // the guard after the chunk is overwritten
+	Stack     []uintptr // allocation stack of the chunk
+}
+
+func (e *CorruptionError) Error() string {
+	var buf bytes.Buffer
+	side := "after"
+	if e.Underflow && e.Overflow {
+		side = "before and after"
+	} else if e.Underflow {
+		side = "before"
+	}
+	fmt.Fprintf(&buf, "slab: guard %s %d bytes chunk overwritten, the chunk was allocated at\n", side, e.Size)
+	writeStack(&buf, e.Stack)
+	return buf.String()
+}
+
+// fillGuards fill the first and the last n bytes of b with the canary pattern.
+func fillGuards(b []byte, n int) {
+	for i := 0; i < n; i++ {
+		b[i] = guardPattern
+		b[len(b)-1-i] = guardPattern
+	}
+}
+
+func intact(b []byte) bool {
+	for _, v := range b {
+		if v != guardPattern {
+			return false
+		}
+	}
+	return true
+}
+
+// checkGuards 检查第 i 个 chunk 前后的 guard，若被改写则修复并返回 *CorruptionError
+func (c *class) checkGuards(p *page, i int, chk *chunk) error {
+	off := i * c.stride
+	b := p.mem[off : off+c.stride]
+	underflow := !intact(b[:c.guard])
+	overflow := !intact(b[c.guard+c.size:])
+	if !underflow && !overflow {
+		return nil
+	}
+	fillGuards(b, c.guard)
+	err := &CorruptionError{Size: c.size, Underflow: underflow, Overflow: overflow}
+	if info := (*allocInfo)(atomic.LoadPointer(&chk.info)); info != nil {
+		err.Stack = info.stack
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package slab
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/funny/utest"
+)
+
+// outOfBounds returns n bytes around mem, starting off bytes from its first byte.
+func outOfBounds(mem []byte, off, n int) []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(&mem[0]), off)), n)
+}
+
+func Test_AtomPool_Guards(t *testing.T) {
+	var reported []*CorruptionError
+	pool := NewAtomPool(128, 1024, 2, 4096, WithGuards(func(err error) {
+		reported = append(reported, err.(*CorruptionError))
+	}))
+	utest.EqualNow(t, pool.classes[0].perPage, 4096/(128+2*guardSize))
+
+	mem := pool.Alloc(128)
+	for i := range mem {
+		mem[i] = 1
+	}
+	pool.Free(mem)
+	utest.EqualNow(t, len(reported), 0)
+
+	mem = pool.Alloc(128)
+	outOfBounds(mem, 128, 1)[0] = 1
+	pool.Free(mem)
+	utest.EqualNow(t, len(reported), 1)
+	utest.Assert(t, reported[0].Overflow)
+	utest.Assert(t, !reported[0].Underflow)
+	utest.Assert(t, strings.Contains(reported[0].Error(), "Test_AtomPool_Guards"), reported[0].Error())
+
+	// the guards are repaired
+	mem = pool.Alloc(128)
+	outOfBounds(mem, -1, 1)[0] = 1
+	pool.Free(mem)
+	utest.EqualNow(t, len(reported), 2)
+	utest.Assert(t, reported[1].Underflow)
+	utest.Assert(t, !reported[1].Overflow)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_AtomPool_GuardsPanic(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithGuards(nil))
+	mem := pool.Alloc(100)
+	outOfBounds(mem, 128, 1)[0] = 1
+	defer func() {
+		_, ok := recover().(*CorruptionError)
+		utest.Assert(t, ok)
+	}()
+	pool.Free(mem)
+}
+
+func Test_AtomPool_GuardsTryFree(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithGuards(nil), WithAlignment(64))
+	mem := pool.Alloc(100)
+	utest.Assert(t, isAligned(mem, 64))
+	outOfBounds(mem, 128, 1)[0] = 1
+	_, ok := pool.TryFree(mem).(*CorruptionError)
+	utest.Assert(t, ok)
+	utest.IsNilNow(t, pool.TryFree(pool.Alloc(100)))
+}
@@ -0,0 +1,66 @@
+package slab
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// sizeHistogram 按对数刻度统计请求的大小，每个 2 的幂区间再均分为 4 个桶，
+// 桶的上界依次为 1, 2, 3, 4, 5, 6, 7, 8, 10, 12, 14, 16, 20, 24, 28, 32, ...
+type sizeHistogram [4 * bits.UintSize]atomic.Uint64
+
+// sizeBucket 返回 size 所属桶的下标
+func sizeBucket(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	x := uint(size - 1)
+	if x < 4 {
+		return int(x)
+	}
+	e := bits.Len(x) - 3
+	return 4*e + int(x>>e)
+}
+
+// bucketSize 返回第 i 个桶的上界
+func bucketSize(i int) int {
+	if i < 4 {
+		return i + 1
+	}
+	e := i/4 - 1
+	return (i%4 + 5) << e
+}
+
+func (h *sizeHistogram) add(n, size int) {
+	h[sizeBucket(size)].Add(uint64(n))
+}
+
+// recordSize 在 WithSizeHistogram 模式下统计 n 个 size 字节的请求
+func (pool *AtomPool) recordSize(n, size int) {
+	if pool.sizes != nil {
+		pool.sizes.add(n, size)
+	}
+}
+
+// SizeBucket is a bucket of the histogram of requested sizes, reported by SizeHistogram.
+type SizeBucket struct {
+	Size  int    // upper bound of the bucket, which counts the sizes larger than the upper bound of the previous bucket
+	Count uint64 // number of requests
+}
+
+// SizeHistogram returns the histogram of the sizes passed to Alloc and the other allocation methods, in ascending order,
+// with only the buckets which have seen any request. The buckets are log-scale, each power of 2 is split into 4 of them,
+// e.g. the requests of 97 to 112 bytes are counted in the bucket of size 112, which is precise enough to choose the size classes.
+// It returns nil unless the pool is created WithSizeHistogram.
+func (pool *AtomPool) SizeHistogram() []SizeBucket {
+	if pool.sizes == nil {
+		return nil
+	}
+	var buckets []SizeBucket
+	for i := range pool.sizes {
+		if n := pool.sizes[i].Load(); n > 0 {
+			buckets = append(buckets, SizeBucket{bucketSize(i), n})
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,60 @@
+package slab
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_SizeBucket(t *testing.T) {
+	var bounds []int
+	for i := 0; i < 16; i++ {
+		bounds = append(bounds, bucketSize(i))
+	}
+	utest.EqualNow(t, fmt.Sprint(bounds), "[1 2 3 4 5 6 7 8 10 12 14 16 20 24 28 32]")
+	for size := 1; size < 1<<16; size++ {
+		i := sizeBucket(size)
+		utest.Assert(t, size <= bucketSize(i))
+		if i > 0 {
+			utest.Assert(t, size > bucketSize(i-1))
+		}
+	}
+	utest.EqualNow(t, sizeBucket(0), 0)
+	utest.Assert(t, sizeBucket(math.MaxInt) < len(sizeHistogram{}))
+}
+
+func Test_AtomPool_SizeHistogram(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	pool.Alloc(100)
+	utest.Assert(t, pool.SizeHistogram() == nil)
+
+	pool = NewAtomPool(128, 1024, 2, 1024, WithSizeHistogram())
+	pool.Alloc(100)
+	pool.Alloc(112)
+	pool.AllocBatch(100, 3)
+	pool.AllocBatch(4096, 2)
+	bufs, _ := pool.AllocBatchAtomic(1000, 1)
+	pool.FreeBatch(bufs)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := pool.AllocContext(ctx, 1000)
+	utest.IsNilNow(t, err)
+	pool.Alloc(5000)
+
+	h := pool.SizeHistogram()
+	utest.EqualNow(t, len(h), 4)
+	utest.EqualNow(t, h[0], SizeBucket{112, 5})
+	utest.EqualNow(t, h[1], SizeBucket{1024, 2})
+	utest.EqualNow(t, h[2], SizeBucket{4096, 2})
+	utest.EqualNow(t, h[3], SizeBucket{5120, 1})
+
+	prev := pool.Snapshot()
+	pool.Alloc(100)
+	d := pool.Snapshot().Delta(prev)
+	utest.EqualNow(t, len(d.Sizes), 1)
+	utest.EqualNow(t, d.Sizes[0], SizeBucket{112, 1})
+}
@@ -0,0 +1,33 @@
+package slab
+
+// AllocEvent describes an allocation or a free passed to the hooks set by WithAllocHook and WithFreeHook.
+type AllocEvent struct {
+	Size   int  // size requested by the allocation, or capacity of the buffer passed to Free
+	Class  int  // chunk size of the slab class serving the buffer, 0 if the buffer is not a chunk
+	Pooled bool // whether the buffer is alloc from or returned to the pool, false for heap fallbacks
+}
+
+// allocated 调用分配的 hook 和 tracer，pooled 为 true 且 size 不大于 maxSize 时 mem 是 chunk
+func (pool *AtomPool) allocated(size int, mem []byte, pooled bool) {
+	e := AllocEvent{Size: size, Pooled: pooled}
+	if pooled && size <= pool.maxSize {
+		e.Class = cap(mem)
+	}
+	if pool.tracer != nil {
+		pool.tracer.record(TraceAlloc, e, mem)
+	}
+	if pool.onAlloc != nil {
+		pool.onAlloc(e)
+	}
+}
+
+// freed 调用回收的 hook 和 tracer，class 为回收 mem 的 class 的 chunk 大小
+func (pool *AtomPool) freed(mem []byte, class int, pooled bool) {
+	e := AllocEvent{Size: cap(mem), Class: class, Pooled: pooled}
+	if pool.tracer != nil {
+		pool.tracer.record(TraceFree, e, mem)
+	}
+	if pool.onFree != nil {
+		pool.onFree(e)
+	}
+}
@@ -0,0 +1,65 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Hooks(t *testing.T) {
+	var allocs, frees []AllocEvent
+	pool := NewAtomPool(128, 1024, 2, 1024, WithBuddy(16*1024, 1),
+		WithAllocHook(func(e AllocEvent) { allocs = append(allocs, e) }),
+		WithFreeHook(func(e AllocEvent) { frees = append(frees, e) }))
+
+	a := pool.Alloc(100)
+	b := pool.Alloc(4096)
+	c := pool.Alloc(64 * 1024)
+	equalEvents(t, allocs, []AllocEvent{{100, 128, true}, {4096, 0, true}, {64 * 1024, 0, false}})
+	pool.Free(a)
+	pool.Free(b)
+	pool.Free(c)
+	equalEvents(t, frees, []AllocEvent{{128, 128, true}, {4096, 0, true}, {64 * 1024, 0, false}})
+
+	// double frees are not reported
+	utest.EqualNow(t, pool.TryFree(a), ErrDoubleFree)
+	utest.EqualNow(t, len(frees), 3)
+
+	allocs, frees = nil, nil
+	bufs := pool.AllocBatch(256, 5)
+	utest.EqualNow(t, len(allocs), 5)
+	utest.EqualNow(t, allocs[4], AllocEvent{256, 0, false})
+	pool.FreeBatch(bufs)
+	utest.EqualNow(t, len(frees), 5)
+	utest.EqualNow(t, frees[0], AllocEvent{256, 256, true})
+	utest.EqualNow(t, frees[4], AllocEvent{256, 0, false})
+
+	// a Cache calls the pool directly
+	allocs = nil
+	cache := pool.NewCache(8)
+	cache.Free(cache.Alloc(128))
+	utest.EqualNow(t, len(allocs), 1)
+}
+
+func Test_ShardedPool_Hooks(t *testing.T) {
+	var mu sync.Mutex
+	var allocs, frees []AllocEvent
+	pool, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(1024),
+		WithAllocHook(func(e AllocEvent) { mu.Lock(); allocs = append(allocs, e); mu.Unlock() }),
+		WithFreeHook(func(e AllocEvent) { mu.Lock(); frees = append(frees, e); mu.Unlock() }))
+	utest.IsNilNow(t, err)
+	a := pool.Alloc(500)
+	b := pool.Alloc(4096)
+	pool.Free(a)
+	pool.Free(b)
+	equalEvents(t, allocs, []AllocEvent{{500, 512, true}, {4096, 0, false}})
+	equalEvents(t, frees, []AllocEvent{{512, 512, true}, {4096, 0, false}})
+}
+
+func equalEvents(t *testing.T, events, want []AllocEvent) {
+	utest.EqualNow(t, len(events), len(want))
+	for i := range want {
+		utest.EqualNow(t, events[i], want[i])
+	}
+}
@@ -0,0 +1,60 @@
+package slab
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	hugePageOnce sync.Once
+	hugePageMem  int
+)
+
+// hugePageSize 返回内核默认的大页大小，读取失败时为 2MB
+func hugePageSize() int {
+	hugePageOnce.Do(func() {
+		hugePageMem = readHugePageSize()
+	})
+	return hugePageMem
+}
+
+// readHugePageSize 从 /proc/meminfo 读取大页大小
+func readHugePageSize() int {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 2 << 20
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Hugepagesize:       2048 kB
+		if fields := strings.Fields(s.Text()); len(fields) == 3 && fields[0] == "Hugepagesize:" {
+			if kb, err := strconv.Atoi(fields[1]); err == nil && kb > 0 {
+				return kb << 10
+			}
+		}
+	}
+	return 2 << 20
+}
+
+// mmapHuge 优先用预留的大页映射 size 字节，没有可用的大页或 size 不是大页的整数倍时退回普通的映射，
+// 并建议内核用透明大页合并它
+func mmapHuge(size int) ([]byte, error) {
+	if size%hugePageSize() == 0 {
+		mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE|mapHugeTLB)
+		if err == nil {
+			return mem, nil
+		}
+	}
+	mem, err := mmapPage(size)
+	if err != nil {
+		return nil, err
+	}
+	// 内核不支持透明大页时忽略错误
+	syscall.Madvise(mem, syscall.MADV_HUGEPAGE)
+	return mem, nil
+}
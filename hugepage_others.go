@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package slab
+
+// mmapHuge 在没有大页支持的平台上就是普通的映射
+func mmapHuge(size int) ([]byte, error) {
+	return mmapPage(size)
+}
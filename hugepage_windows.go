@@ -0,0 +1,63 @@
+//go:build windows
+// +build windows
+
+package slab
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procLookupPrivilegeValue  = advapi32.NewProc("LookupPrivilegeValueW")
+	procAdjustTokenPrivileges = advapi32.NewProc("AdjustTokenPrivileges")
+	procGetLargePageMinimum   = kernel32.NewProc("GetLargePageMinimum")
+)
+
+const errorNotAllAssigned = syscall.Errno(1300)
+
+// tokenPrivileges 是只含一项特权的 TOKEN_PRIVILEGES
+type tokenPrivileges struct {
+	count      uint32
+	luid       [2]uint32
+	attributes uint32
+}
+
+// largePageSize 返回大页的大小，进程没有 SeLockMemoryPrivilege 特权或系统不支持大页时为 0。
+// 大页要求进程令牌中启用该特权，账户需要在本地安全策略中被授予“锁定内存页”的权限
+var largePageSize = sync.OnceValue(func() int {
+	size, _, _ := procGetLargePageMinimum.Call()
+	if size == 0 {
+		return 0
+	}
+	var token syscall.Token
+	process, _ := syscall.GetCurrentProcess()
+	if syscall.OpenProcessToken(process, syscall.TOKEN_ADJUST_PRIVILEGES|syscall.TOKEN_QUERY, &token) != nil {
+		return 0
+	}
+	defer token.Close()
+	name, _ := syscall.UTF16PtrFromString("SeLockMemoryPrivilege")
+	tp := tokenPrivileges{count: 1, attributes: 0x2} // SE_PRIVILEGE_ENABLED
+	if r, _, _ := procLookupPrivilegeValue.Call(0, uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&tp.luid))); r == 0 {
+		return 0
+	}
+	// 没有被授予特权时 AdjustTokenPrivileges 也返回成功，但错误码为 ERROR_NOT_ALL_ASSIGNED
+	r, _, err := procAdjustTokenPrivileges.Call(uintptr(token), 0, uintptr(unsafe.Pointer(&tp)), 0, 0, 0)
+	if r == 0 || err == errorNotAllAssigned {
+		return 0
+	}
+	return int(size)
+})
+
+// mmapHuge 在进程可以使用大页且 size 是大页的整数倍时分配大页，大页不会被换出，
+// 物理内存不足或不满足条件时退回普通的 VirtualAlloc
+func mmapHuge(size int) ([]byte, error) {
+	if huge := largePageSize(); huge > 0 && size%huge == 0 {
+		if mem, err := virtualAlloc(size, memLargePages); err == nil {
+			return mem, nil
+		}
+	}
+	return mmapPage(size)
+}
@@ -0,0 +1,7 @@
+//go:build linux && !arm
+
+package slab
+
+import "syscall"
+
+const mapHugeTLB = syscall.MAP_HUGETLB
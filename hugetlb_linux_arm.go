@@ -0,0 +1,4 @@
+package slab
+
+// syscall 包在 linux/arm 上没有定义 MAP_HUGETLB，取值和其它非 mips 平台相同
+const mapHugeTLB = 0x40000
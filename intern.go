@@ -0,0 +1,92 @@
+package slab
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Interner stores one copy of each distinct byte string in a chunk of Pool and hands out the same string for repeated values,
+// e.g. header or topic names, so they are not copied to the heap again and again. Each Intern holds a reference
+// which must be dropped by Release, the chunk is returned to the pool when the last reference is released.
+// An Interner is safe for concurrent use.
+type Interner struct {
+	mu      sync.Mutex
+	pool    Pool
+	entries map[string]*internEntry // 键就是 chunk 上的字符串视图，释放 chunk 之前先删除
+}
+
+// internEntry 是 Interner 中的一个值，mem 是保存它的 chunk
+type internEntry struct {
+	mem  []byte
+	refs int
+}
+
+// NewInterner create an Interner which stores the values in chunks of pool.
+func NewInterner(pool Pool) *Interner {
+	return &Interner{pool: pool, entries: make(map[string]*internEntry)}
+}
+
+// Intern returns the stored string equal to b, storing a copy of b in a chunk of the pool first if there is none,
+// and adds a reference to it. The string is valid until its references are all released.
+// If the pool returns nil the result is a heap copy not stored in the interner, Release ignores it.
+func (in *Interner) Intern(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if e, ok := in.entries[string(b)]; ok {
+		e.refs++
+		return unsafe.String(unsafe.SliceData(e.mem), len(e.mem))
+	}
+	mem := in.pool.Alloc(len(b))
+	if mem == nil {
+		return string(b)
+	}
+	copy(mem, b)
+	s := unsafe.String(unsafe.SliceData(mem), len(mem))
+	in.entries[s] = &internEntry{mem: mem, refs: 1}
+	return s
+}
+
+// InternString is like Intern for a string.
+func (in *Interner) InternString(s string) string {
+	return in.Intern(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Release drop a reference to s returned by Intern, the string must not be used by the caller after that.
+// When it was the last reference the chunk is returned to the pool. Strings not returned by Intern are ignored.
+func (in *Interner) Release(s string) {
+	if s == "" {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	e, ok := in.entries[s]
+	if !ok || unsafe.SliceData(e.mem) != unsafe.StringData(s) {
+		// 内容相同但不是 Intern 返回的字符串，例如 pool 返回 nil 时的堆上副本
+		return
+	}
+	e.refs--
+	if e.refs == 0 {
+		delete(in.entries, s)
+		in.pool.Free(e.mem)
+	}
+}
+
+// Refs returns the number of references to the stored string equal to s, 0 if there is none.
+func (in *Interner) Refs(s string) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if e, ok := in.entries[s]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+// Len returns the number of distinct strings stored.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.entries)
+}
@@ -0,0 +1,51 @@
+package slab
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/funny/utest"
+)
+
+func Test_Interner(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	in := NewInterner(pool)
+	a := in.Intern([]byte("content-type"))
+	b := in.InternString("content-type")
+	c := in.Intern([]byte("host"))
+	utest.EqualNow(t, a, "content-type")
+	utest.Assert(t, unsafe.StringData(a) == unsafe.StringData(b))
+	utest.EqualNow(t, in.Len(), 2)
+	utest.EqualNow(t, in.Refs("content-type"), 2)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+	utest.EqualNow(t, in.Intern(nil), "")
+
+	// 内容相同的其它字符串不会释放引用
+	in.Release(string([]byte("content-type")))
+	utest.EqualNow(t, in.Refs("content-type"), 2)
+
+	in.Release(a)
+	utest.EqualNow(t, in.Refs("content-type"), 1)
+	in.Release(b)
+	in.Release(c)
+	utest.EqualNow(t, in.Len(), 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		in.Release(in.Intern([]byte("host")))
+	})
+	utest.EqualNow(t, allocs, 1.0)
+}
+
+func Test_Interner_Exhausted(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 128), WithPageSize(128), WithNoHeap())
+	utest.IsNilNow(t, err)
+	in := NewInterner(pool)
+	a := in.InternString("a")
+	b := in.InternString("b")
+	utest.EqualNow(t, b, "b")
+	utest.EqualNow(t, in.Len(), 1)
+	in.Release(b)
+	in.Release(a)
+	utest.EqualNow(t, in.Len(), 0)
+}
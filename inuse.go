@@ -0,0 +1,87 @@
+package slab
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ChunkInfo describes a chunk in use reported by RangeInUse.
+type ChunkInfo struct {
+	Size  int           // chunk size of the class
+	Index int           // global index of the chunk in its class
+	Age   time.Duration // time since the chunk was allocated, 0 if unknown
+	Stack []uintptr     // program counters of the allocation stack, nil if unknown
+}
+
+// RangeInUse calls fn for each chunk currently allocated, with the index of its slab class in Stats and the whole chunk,
+// until fn returns false. Age and Stack are only known in leak detection mode, or for the chunks recorded by WithSampling.
+// It's a best-effort snapshot: without leak detection a chunk is in use if it's not in the free list,
+// so chunks allocated or freed during the walk may be reported or missed, and chunks held by a Cache are reported too.
+// Each class is locked against growth, Trim and Reclaim while fn is called, fn must not alloc from the pool or trim it.
+func (pool *AtomPool) RangeInUse(fn func(class int, chunk []byte, meta ChunkInfo) bool) {
+	now := time.Now()
+	for i := 0; i < len(pool.classes); i++ {
+		if !pool.classes[i].rangeInUse(i, now, fn) {
+			return
+		}
+	}
+}
+
+// rangeInUse 对第 i 个 class 中每个已分配的 chunk 调用 fn，fn 返回 false 时返回 false
+func (c *class) rangeInUse(i int, now time.Time, fn func(class int, chunk []byte, meta ChunkInfo) bool) bool {
+	// 持有 growMu，避免遍历期间 page 被 reclaim 释放
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	total := nslots * c.perPage
+	var free []bool
+	if !c.leak {
+		// 空闲链表在遍历期间仍在变化，遇到越界或者走得比 chunk 总数还长就停下
+		free = make([]bool, total)
+		length := 0
+		for v := c.head.Load(); v != 0 && length < total; length++ {
+			idx := linkIndex(v)
+			if idx >= uint64(total) || c.chunk(idx) == nil {
+				break
+			}
+			free[idx] = true
+			v = nextLink(c.chunk(idx).next.Load())
+		}
+		for _, v := range c.fifoLinks() {
+			if idx := linkIndex(v); idx < uint64(total) {
+				free[idx] = true
+			}
+		}
+		if c.quarantine != nil {
+			for _, v := range c.quarantine.quarantined() {
+				if idx := linkIndex(v); idx < uint64(total) {
+					free[idx] = true
+				}
+			}
+		}
+	}
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p == nil {
+			continue
+		}
+		for j := range p.chunks {
+			chk := &p.chunks[j]
+			idx := n*c.perPage + j
+			info := (*allocInfo)(atomic.LoadPointer(&chk.info))
+			if (c.leak && info == nil) || (!c.leak && free[idx]) {
+				continue
+			}
+			meta := ChunkInfo{Size: c.size, Index: idx}
+			if info != nil {
+				meta.Age = now.Sub(info.time)
+				meta.Stack = info.stack
+			}
+			if !fn(i, chk.mem, meta) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_RangeInUse(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithLeakDetection()}, {WithQuarantine(2)}} {
+		pool := NewAtomPool(128, 1024, 2, 1024, opts...)
+		a := pool.AllocBatch(128, 3)
+		b := pool.Alloc(200)
+		pool.Free(a[1])
+
+		var classes, sizes []int
+		var ptrs []uintptr
+		pool.RangeInUse(func(class int, chunk []byte, meta ChunkInfo) bool {
+			classes = append(classes, class)
+			sizes = append(sizes, meta.Size)
+			ptrs = append(ptrs, dataPtr(chunk))
+			utest.EqualNow(t, len(chunk), meta.Size)
+			if pool.classes[0].leak {
+				utest.Assert(t, meta.Age > 0)
+				utest.Assert(t, len(meta.Stack) > 0)
+			} else {
+				utest.IsNilNow(t, meta.Stack)
+			}
+			return true
+		})
+		utest.EqualNow(t, len(classes), 3)
+		utest.EqualNow(t, classes[2], 1)
+		utest.EqualNow(t, sizes[2], 256)
+		for _, mem := range [][]byte{a[0], a[2], b} {
+			found := false
+			for _, ptr := range ptrs {
+				found = found || ptr == dataPtr(mem)
+			}
+			utest.Assert(t, found)
+		}
+
+		n := 0
+		pool.RangeInUse(func(class int, chunk []byte, meta ChunkInfo) bool {
+			n++
+			return false
+		})
+		utest.EqualNow(t, n, 1)
+	}
+}
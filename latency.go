@@ -0,0 +1,112 @@
+package slab
+
+import "time"
+
+// latencyStats 记录 WithLatency 模式下 Alloc 和 Free 的耗时，以纳秒为单位，桶的划分和请求大小的直方图相同
+type latencyStats struct {
+	allocHit      sizeHistogram
+	allocFallback sizeHistogram
+	freeHit       sizeHistogram
+	freeFallback  sizeHistogram
+}
+
+func (l *latencyStats) alloc(start time.Time, pooled bool) {
+	if pooled {
+		l.allocHit.add(1, int(time.Since(start)))
+	} else {
+		l.allocFallback.add(1, int(time.Since(start)))
+	}
+}
+
+func (l *latencyStats) free(start time.Time, pooled bool) {
+	if pooled {
+		l.freeHit.add(1, int(time.Since(start)))
+	} else {
+		l.freeFallback.add(1, int(time.Since(start)))
+	}
+}
+
+// LatencyBucket is a bucket of a LatencyHistogram.
+type LatencyBucket struct {
+	Latency time.Duration // upper bound of the bucket, which counts the calls slower than the upper bound of the previous bucket
+	Count   uint64        // number of calls
+}
+
+// LatencyHistogram is a histogram of the time taken by calls in ascending order, with only the buckets which have seen any call.
+// The buckets are log-scale like the histogram of requested sizes, each power of 2 nanoseconds is split into 4 of them.
+type LatencyHistogram []LatencyBucket
+
+// Count returns the number of calls in the histogram.
+func (h LatencyHistogram) Count() uint64 {
+	var n uint64
+	for _, b := range h {
+		n += b.Count
+	}
+	return n
+}
+
+// Quantile returns the upper bound of the bucket holding the q quantile, e.g. 0.999 for p99.9, 0 if the histogram is empty.
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	rank := uint64(q * float64(h.Count()))
+	var n uint64
+	for _, b := range h {
+		n += b.Count
+		if n > rank {
+			return b.Latency
+		}
+	}
+	if len(h) > 0 {
+		return h[len(h)-1].Latency
+	}
+	return 0
+}
+
+// delta 返回 h 和更早的直方图 prev 之间的差，只保留区间内有调用的桶
+func (h LatencyHistogram) delta(prev LatencyHistogram) LatencyHistogram {
+	var d LatencyHistogram
+	for _, b := range h {
+		for _, p := range prev {
+			if p.Latency == b.Latency {
+				b.Count -= p.Count
+				break
+			}
+		}
+		if b.Count > 0 {
+			d = append(d, b)
+		}
+	}
+	return d
+}
+
+// LatencyStats is the time taken by Alloc, TryAlloc, AllocPooled, Free and TryFree of a pool created WithLatency,
+// split by whether the buffer is served by or returned to the pool, or falls back, e.g. to the heap.
+type LatencyStats struct {
+	AllocHit      LatencyHistogram // allocations served by the pool
+	AllocFallback LatencyHistogram // allocations served by the fallback or failed
+	FreeHit       LatencyHistogram // buffers returned to the pool
+	FreeFallback  LatencyHistogram // buffers the pool doesn't own and double frees
+}
+
+// Latency returns the latency histograms of the pool, the zero value if the pool is created without WithLatency.
+func (pool *AtomPool) Latency() LatencyStats {
+	l := pool.latency
+	if l == nil {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		AllocHit:      latencyHistogram(&l.allocHit),
+		AllocFallback: latencyHistogram(&l.allocFallback),
+		FreeHit:       latencyHistogram(&l.freeHit),
+		FreeFallback:  latencyHistogram(&l.freeFallback),
+	}
+}
+
+func latencyHistogram(h *sizeHistogram) LatencyHistogram {
+	var buckets LatencyHistogram
+	for i := range h {
+		if n := h[i].Load(); n > 0 {
+			buckets = append(buckets, LatencyBucket{time.Duration(bucketSize(i)), n})
+		}
+	}
+	return buckets
+}
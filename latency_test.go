@@ -0,0 +1,51 @@
+package slab
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Latency(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithLatency())
+	utest.IsNilNow(t, err)
+	for i := 0; i < 100; i++ {
+		pool.Free(pool.Alloc(128))
+	}
+	pool.Free(pool.Alloc(4096))
+	l := pool.Latency()
+	utest.EqualNow(t, l.AllocHit.Count(), uint64(100))
+	utest.EqualNow(t, l.AllocFallback.Count(), uint64(1))
+	utest.EqualNow(t, l.FreeHit.Count(), uint64(100))
+	utest.EqualNow(t, l.FreeFallback.Count(), uint64(1))
+	utest.Assert(t, l.AllocHit.Quantile(0.5) > 0)
+	utest.Assert(t, l.AllocHit.Quantile(0.5) <= l.AllocHit.Quantile(0.999))
+
+	// 快照的差只包含区间内的调用
+	prev := pool.Snapshot()
+	pool.Free(pool.Alloc(128))
+	d := pool.Snapshot().Delta(prev)
+	utest.EqualNow(t, d.Latency.AllocHit.Count(), uint64(1))
+	utest.EqualNow(t, d.Latency.AllocFallback.Count(), uint64(0))
+
+	data, err := json.Marshal(prev)
+	utest.IsNilNow(t, err)
+	var s Snapshot
+	utest.IsNilNow(t, json.Unmarshal(data, &s))
+	utest.Assert(t, reflect.DeepEqual(s.Latency, prev.Latency))
+
+	utest.Assert(t, reflect.DeepEqual(NewAtomPool(128, 1024, 2, 1024).Latency(), LatencyStats{}))
+}
+
+func Test_LatencyHistogram_Quantile(t *testing.T) {
+	h := LatencyHistogram{{10, 90}, {100, 9}, {1000, 1}}
+	utest.EqualNow(t, h.Quantile(0), time.Duration(10))
+	utest.EqualNow(t, h.Quantile(0.5), time.Duration(10))
+	utest.EqualNow(t, h.Quantile(0.95), time.Duration(100))
+	utest.EqualNow(t, h.Quantile(0.999), time.Duration(1000))
+	utest.EqualNow(t, h.Quantile(1), time.Duration(1000))
+	utest.EqualNow(t, LatencyHistogram(nil).Quantile(0.5), time.Duration(0))
+}
@@ -0,0 +1,78 @@
+package slab
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const maxStackDepth = 32
+
+type allocInfo struct {
+	time  time.Time
+	stack []uintptr
+	size  int // 请求的字节数
+}
+
+func newAllocInfo(size int) *allocInfo {
+	var pcs [maxStackDepth]uintptr
+	// skip runtime.Callers, newAllocInfo and class.pop
+	n := runtime.Callers(3, pcs[:])
+	return &allocInfo{time.Now(), append([]uintptr(nil), pcs[:n]...), size}
+}
+
+// Leak is a chunk which has been allocated but not freed, reported by AtomPool.Leaks.
+type Leak struct {
+	Size  int           // chunk size of the class
+	Age   time.Duration // time since the chunk was allocated
+	Stack []uintptr     // program counters of the allocation stack
+}
+
+// String returns the allocation stack in the same format as panics.
+func (l Leak) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "slab: %d bytes chunk allocated %s ago not freed\n", l.Size, l.Age)
+	writeStack(&buf, l.Stack)
+	return buf.String()
+}
+
+// writeStack writes the frames of pcs in the same format as panics.
+func writeStack(buf *bytes.Buffer, pcs []uintptr) {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+}
+
+// Leaks returns the chunks allocated longer than age ago and still not freed.
+// It only works when the pool is created with WithLeakDetection and can be called periodically by a sweeper goroutine.
+func (pool *AtomPool) Leaks(age time.Duration) []Leak {
+	var leaks []Leak
+	now := time.Now()
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if !c.leak {
+			continue
+		}
+		nslots := int(atomic.LoadInt32(&c.nslots))
+		for n := 0; n < nslots; n++ {
+			p := c.page(n)
+			if p == nil {
+				continue
+			}
+			for j := 0; j < len(p.chunks); j++ {
+				info := (*allocInfo)(atomic.LoadPointer(&p.chunks[j].info))
+				if info != nil && now.Sub(info.time) >= age {
+					leaks = append(leaks, Leak{c.size, now.Sub(info.time), info.stack})
+				}
+			}
+		}
+	}
+	return leaks
+}
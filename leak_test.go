@@ -0,0 +1,52 @@
+package slab
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Leaks(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithLeakDetection())
+	leaked := pool.Alloc(100)
+	freed := pool.Alloc(200)
+	pool.Free(freed)
+
+	leaks := pool.Leaks(0)
+	utest.EqualNow(t, len(leaks), 1)
+	utest.EqualNow(t, leaks[0].Size, 128)
+	utest.Assert(t, strings.Contains(leaks[0].String(), "Test_AtomPool_Leaks"), leaks[0].String())
+
+	utest.EqualNow(t, len(pool.Leaks(time.Hour)), 0)
+
+	pool.Free(leaked)
+	utest.EqualNow(t, len(pool.Leaks(0)), 0)
+}
+
+func Test_AtomPool_NoLeakDetection(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	pool.Alloc(100)
+	utest.EqualNow(t, len(pool.Leaks(0)), 0)
+}
+
+func Test_AtomPool_Profile(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithProfile("slab.test.inuse"))
+	profile := pprof.Lookup("slab.test.inuse")
+	utest.NotNilNow(t, profile)
+
+	mem1 := pool.Alloc(100)
+	mem2 := pool.Alloc(1000)
+	utest.EqualNow(t, profile.Count(), 2)
+
+	var buf bytes.Buffer
+	profile.WriteTo(&buf, 1)
+	utest.Assert(t, strings.Contains(buf.String(), "Test_AtomPool_Profile"), buf.String())
+
+	pool.Free(mem1)
+	pool.Free(mem2)
+	utest.EqualNow(t, profile.Count(), 0)
+}
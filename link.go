@@ -0,0 +1,52 @@
+package slab
+
+// 空闲链表的 head 和 chunk.next 都是带标签的链接值：
+//
+//	高 32 位为 chunk 的全局下标 +1，head 为 0 表示链表为空，chunk.next 为 0 表示 chunk 已被分配，为 linkEnd 表示链表结束
+//	低 32 位为 chunk 的 ABA 计数，每次回收加一
+//
+// 因此一个 class 的所有 page 合计最多只能有 maxChunks 个 chunk，NewPool 会检查这个上限
+//
+// ABA 计数是每个 chunk 各自的，只有在一个 goroutine 读出 head 到执行 CAS 的窗口内，
+// 同一个 chunk 恰好被分配和回收了 2^32 次，计数回绕到原值，CAS 才会误判成功。
+// 即使每秒回收同一个 chunk 一千万次，这也需要窗口持续 7 分钟以上，被抢占的 goroutine 不会停顿这么久。
+// Go 的 sync/atomic 没有 128 位 CAS，把计数放宽到 64 位需要双字 CAS 或 epoch 回收，会让每次 Alloc 和 Free 都变慢，因此没有这样做
+const (
+	linkShift = 32
+	maxChunks = 1<<linkShift - 1
+)
+
+// makeLink 返回指向全局下标为 idx、ABA 计数为 aba 的 chunk 的链接值
+func makeLink(idx uint64, aba uint32) uint64 {
+	return (idx+1)<<linkShift | uint64(aba)
+}
+
+// linkEnd 是空闲链表尾 chunk 的 next 值。它的高 32 位为 0，不会和任何链接值混淆，
+// 又不为 0，使尾 chunk 和已分配的 chunk 区分开，重复回收尾 chunk 也能被识别
+const linkEnd = 1
+
+// tailLink 返回挂在 head 值 v 之前的 chunk 的 next 值，v 为 0 即链表为空时返回 linkEnd
+func tailLink(v uint64) uint64 {
+	if v == 0 {
+		return linkEnd
+	}
+	return v
+}
+
+// nextLink 返回 next 值 v 指向的链接值，v 为 linkEnd 即链表结束时返回 0
+func nextLink(v uint64) uint64 {
+	if v == linkEnd {
+		return 0
+	}
+	return v
+}
+
+// linkIndex 返回非 0 链接值 v 指向的 chunk 的全局下标
+func linkIndex(v uint64) uint64 {
+	return v>>linkShift - 1
+}
+
+// linkABA 返回链接值 v 中的 ABA 计数
+func linkABA(v uint64) uint32 {
+	return uint32(v)
+}
@@ -0,0 +1,91 @@
+package slab
+
+// LocalPool is a slab allocation memory pool without locks or atomic operations for code already confined to one goroutine,
+// e.g. a per-connection state machine. Each slab class keeps its free chunks in a plain stack of indexes.
+// A LocalPool is not safe for concurrent use.
+type LocalPool struct {
+	classes []localClass
+	minSize int
+	maxSize int
+}
+
+type localClass struct {
+	size      int
+	page      []byte
+	pageBegin uintptr
+	pageEnd   uintptr
+	free      []int32 // 空闲 chunk 下标组成的栈
+	used      []bool  // chunk 是否已分配，用于识别重复回收
+}
+
+// NewLocalPool create a single goroutine slab allocation memory pool.
+// minSize is the smallest chunk size.
+// maxSize is the lagest chunk size.
+// factor is used to control growth of chunk size.
+// pageSize is the memory size of each slab class.
+func NewLocalPool(minSize, maxSize, factor, pageSize int) *LocalPool {
+	pool := &LocalPool{minSize: minSize, maxSize: maxSize}
+	for chunkSize := minSize; chunkSize <= maxSize && chunkSize <= pageSize; chunkSize *= factor {
+		n := pageSize / chunkSize
+		c := localClass{
+			size: chunkSize,
+			page: make([]byte, n*chunkSize),
+			free: make([]int32, n),
+			used: make([]bool, n),
+		}
+		// 栈顶为下标 0，按地址顺序分配
+		for i := range c.free {
+			c.free[i] = int32(n - 1 - i)
+		}
+		c.pageBegin = dataPtr(c.page)
+		c.pageEnd = c.pageBegin + uintptr((n-1)*chunkSize)
+		pool.classes = append(pool.classes, c)
+	}
+	return pool
+}
+
+// Alloc try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
+func (pool *LocalPool) Alloc(size int) []byte {
+	if size <= pool.maxSize {
+		for i := 0; i < len(pool.classes); i++ {
+			c := &pool.classes[i]
+			if c.size < size {
+				continue
+			}
+			if n := len(c.free); n > 0 {
+				idx := int(c.free[n-1])
+				c.free = c.free[:n-1]
+				c.used[idx] = true
+				off := idx * c.size
+				return c.page[off : off+size : off+c.size]
+			}
+			break
+		}
+	}
+	return make([]byte, size)
+}
+
+// Free release a []byte that alloc from Pool.Alloc, it panics on double free.
+// Like AtomPool.Free the chunk is located by the address of the first byte.
+func (pool *LocalPool) Free(mem []byte) {
+	if cap(mem) == 0 {
+		return
+	}
+	ptr := dataPtr(mem)
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if ptr < c.pageBegin || ptr > c.pageEnd {
+			continue
+		}
+		if (ptr-c.pageBegin)%uintptr(c.size) != 0 {
+			return
+		}
+		idx := int((ptr - c.pageBegin) / uintptr(c.size))
+		if !c.used[idx] {
+			panic("slab.LocalPool: Double Free")
+		}
+		c.used[idx] = false
+		c.free = append(c.free, int32(idx))
+		return
+	}
+}
@@ -0,0 +1,66 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_LocalPool_AllocAndFree(t *testing.T) {
+	pool := NewLocalPool(128, 64*1024, 2, 1024*1024)
+	for i := 0; i < len(pool.classes); i++ {
+		temp := make([][]byte, len(pool.classes[i].used))
+
+		for j := 0; j < len(temp); j++ {
+			mem := pool.Alloc(pool.classes[i].size)
+			utest.EqualNow(t, cap(mem), pool.classes[i].size)
+			temp[j] = mem
+		}
+		utest.EqualNow(t, len(pool.classes[i].free), 0)
+
+		for j := 0; j < len(temp); j++ {
+			pool.Free(temp[j])
+		}
+		utest.EqualNow(t, len(pool.classes[i].free), len(temp))
+	}
+}
+
+func Test_LocalPool_AllocSmallAndLarge(t *testing.T) {
+	pool := NewLocalPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(64)
+	utest.EqualNow(t, len(mem), 64)
+	utest.EqualNow(t, cap(mem), 128)
+
+	// resliced buffers are found by their first byte
+	pool.Free(mem[:10:20])
+	utest.EqualNow(t, len(pool.classes[0].free), 8)
+
+	mem = pool.Alloc(2048)
+	utest.EqualNow(t, cap(mem), 2048)
+	pool.Free(mem)
+	pool.Free(nil)
+}
+
+func Test_LocalPool_DoubleFree(t *testing.T) {
+	pool := NewLocalPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(64)
+	pool.Free(mem)
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	pool.Free(mem)
+}
+
+func Benchmark_LocalPool_AllocAndFree_128(b *testing.B) {
+	pool := NewLocalPool(128, 1024, 2, 64*1024)
+	for i := 0; i < b.N; i++ {
+		pool.Free(pool.Alloc(128))
+	}
+}
+
+func Benchmark_LocalPool_AllocAndFree_512(b *testing.B) {
+	pool := NewLocalPool(128, 1024, 2, 64*1024)
+	for i := 0; i < b.N; i++ {
+		pool.Free(pool.Alloc(512))
+	}
+}
@@ -1,24 +1,35 @@
 package slab
 
 import (
-	"reflect"
+	"runtime"
 	"sync"
 	"unsafe"
 )
 
-// LockPool is a lock-free slab allocation memory pool.
+// LockPool is a mutex based slab allocation memory pool.
 type LockPool struct {
 	classes []lockClass
 	minSize int
 	maxSize int
 }
 
-// NewLockPool create a lock-free slab allocation memory pool.
+// NewLockPool create a mutex based slab allocation memory pool.
 // minSize is the smallest chunk size.
 // maxSize is the lagest chunk size.
 // factor is used to control growth of chunk size.
 // pageSize is the memory size of each slab class.
 func NewLockPool(minSize, maxSize, factor, pageSize int) *LockPool {
+	return NewStripedLockPool(minSize, maxSize, factor, pageSize, 1)
+}
+
+// NewStripedLockPool create a mutex based slab allocation memory pool whose slab classes split their chunks into stripes
+// free lists, each guarded by its own mutex, so goroutines hitting one hot class don't all wait on the same mutex.
+// Alloc picks a stripe by the calling goroutine and tries the others when it's empty, Free returns a chunk to its own stripe.
+// stripes <= 0 means runtime.GOMAXPROCS(0), a class never has more stripes than chunks.
+func NewStripedLockPool(minSize, maxSize, factor, pageSize, stripes int) *LockPool {
+	if stripes <= 0 {
+		stripes = runtime.GOMAXPROCS(0)
+	}
 	n := 0
 	for chunkSize := minSize; chunkSize <= maxSize && chunkSize <= pageSize; chunkSize *= factor {
 		n++
@@ -31,8 +42,7 @@ func NewLockPool(minSize, maxSize, factor, pageSize int) *LockPool {
 		c.size = chunkSize
 		c.page = make([]byte, pageSize)
 		c.chunks = make([][]byte, pageSize/chunkSize)
-		c.head = 0
-		c.tail = pageSize/chunkSize - 1
+		c.stripes = make([]lockStripe, min(stripes, len(c.chunks)))
 
 		for i := 0; i < len(c.chunks); i++ {
 			// lock down the capacity to protect append operation
@@ -41,6 +51,14 @@ func NewLockPool(minSize, maxSize, factor, pageSize int) *LockPool {
 				c.pageBegin = uintptr(unsafe.Pointer(&c.page[0]))
 				c.pageEnd = uintptr(unsafe.Pointer(&c.chunks[i][0]))
 			}
+			// 第 i 个 chunk 属于第 i % stripes 个 stripe
+			st := &c.stripes[i%len(c.stripes)]
+			st.chunks = append(st.chunks, c.chunks[i])
+		}
+		for i := range c.stripes {
+			st := &c.stripes[i]
+			st.head = 0
+			st.tail = len(st.chunks) - 1
 		}
 
 		n++
@@ -48,7 +66,7 @@ func NewLockPool(minSize, maxSize, factor, pageSize int) *LockPool {
 	return pool
 }
 
-// LockPool try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
+// Alloc try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
 func (pool *LockPool) Alloc(size int) []byte {
 	if size <= pool.maxSize {
 		for i := 0; i < len(pool.classes); i++ {
@@ -76,39 +94,63 @@ func (pool *LockPool) Free(mem []byte) {
 }
 
 type lockClass struct {
-	sync.Mutex
 	size      int
 	page      []byte
 	pageBegin uintptr
 	pageEnd   uintptr
-	chunks    [][]byte
-	head      int
-	tail      int
+	chunks    [][]byte // page 中的所有 chunk
+	stripes   []lockStripe
+}
+
+// lockStripe 是 class 的一个空闲链表，用环形队列保存它的 chunk 中空闲的那些
+type lockStripe struct {
+	sync.Mutex
+	chunks [][]byte
+	head   int
+	tail   int
+	_      [16]byte // 独占一个缓存行
 }
 
 func (c *lockClass) Push(mem []byte) {
-	ptr := (*reflect.SliceHeader)(unsafe.Pointer(&mem)).Data
+	ptr := dataPtr(mem)
 	if c.pageBegin <= ptr && ptr <= c.pageEnd {
-		c.Lock()
-		c.tail++
-		n := c.tail % len(c.chunks)
-		if c.chunks[n] != nil {
-			panic("slab.LockPool: Double Free")
-		}
-		c.chunks[n] = mem
-		c.Unlock()
+		i := int((ptr - c.pageBegin) / uintptr(c.size))
+		c.stripes[i%len(c.stripes)].push(mem)
 	}
 }
 
 func (c *lockClass) Pop() []byte {
+	// 从调用者对应的 stripe 开始，为空时依次尝试其它 stripe
+	s := int(stackHint() % uintptr(len(c.stripes)))
+	for i := 0; i < len(c.stripes); i++ {
+		if mem := c.stripes[(s+i)%len(c.stripes)].pop(); mem != nil {
+			return mem
+		}
+	}
+	return nil
+}
+
+func (st *lockStripe) push(mem []byte) {
+	st.Lock()
+	st.tail++
+	n := st.tail % len(st.chunks)
+	if st.chunks[n] != nil {
+		st.Unlock()
+		panic("slab.LockPool: Double Free")
+	}
+	st.chunks[n] = mem
+	st.Unlock()
+}
+
+func (st *lockStripe) pop() []byte {
 	var mem []byte
-	c.Lock()
-	if c.head <= c.tail {
-		n := c.head % len(c.chunks)
-		mem = c.chunks[n]
-		c.chunks[n] = nil
-		c.head++
+	st.Lock()
+	if st.head <= st.tail {
+		n := st.head % len(st.chunks)
+		mem = st.chunks[n]
+		st.chunks[n] = nil
+		st.head++
 	}
-	c.Unlock()
+	st.Unlock()
 	return mem
 }
@@ -1,6 +1,7 @@
 package slab
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/funny/utest"
@@ -89,3 +90,68 @@ func Benchmark_LockPool_AllocAndFree_512(b *testing.B) {
 		}
 	})
 }
+
+func Test_LockPool_Striped(t *testing.T) {
+	pool := NewStripedLockPool(128, 1024, 2, 1024, 4)
+	utest.EqualNow(t, len(pool.classes[0].stripes), 4)
+	// 1024 字节的 class 只有一个 chunk，也就只有一个 stripe
+	utest.EqualNow(t, len(pool.classes[3].stripes), 1)
+
+	// 调用者的 stripe 空了之后从其它 stripe 分配，8 个 chunk 都能分配出来
+	temp := make([][]byte, 8)
+	seen := map[uintptr]bool{}
+	for i := range temp {
+		temp[i] = pool.Alloc(128)
+		utest.Assert(t, pool.classes[0].pageBegin <= dataPtr(temp[i]) && dataPtr(temp[i]) <= pool.classes[0].pageEnd)
+		utest.Assert(t, !seen[dataPtr(temp[i])])
+		seen[dataPtr(temp[i])] = true
+	}
+	utest.IsNilNow(t, pool.classes[0].Pop())
+	for _, mem := range temp {
+		pool.Free(mem)
+	}
+	for i := range pool.classes[0].stripes {
+		st := &pool.classes[0].stripes[i]
+		utest.EqualNow(t, st.tail-st.head+1, 2)
+	}
+
+	func() {
+		defer func() {
+			utest.NotNilNow(t, recover())
+		}()
+		pool.Free(temp[0])
+	}()
+}
+
+func Test_LockPool_StripedParallel(t *testing.T) {
+	pool := NewStripedLockPool(128, 1024, 2, 4096, 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				mem := pool.Alloc(128)
+				mem[0] = 1
+				pool.Free(mem)
+			}
+		}()
+	}
+	wg.Wait()
+	n := 0
+	for i := range pool.classes[0].stripes {
+		st := &pool.classes[0].stripes[i]
+		n += st.tail - st.head + 1
+	}
+	utest.EqualNow(t, n, len(pool.classes[0].chunks))
+}
+
+func Benchmark_LockPool_Striped_AllocAndFree_128(b *testing.B) {
+	pool := NewStripedLockPool(128, 1024, 2, 64*1024, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Free(pool.Alloc(128))
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package slab
+
+const (
+	lookupShift = 4         // 查找表每一项覆盖 16 字节的请求大小
+	lookupMax   = 64 * 1024 // 查找表覆盖的最大请求大小，更大的请求二分查找
+)
+
+// newLookup 创建请求大小到 class 下标的查找表，第 g 项是能放下 g<<lookupShift+1 字节的第一个 class，
+// 同一项覆盖的大小中可能还有更小的 class 边界，classIndex 查表之后向后修正
+func newLookup(classes []class) []uint16 {
+	if len(classes) == 0 || len(classes) > 1<<16 {
+		return nil
+	}
+	n := (min(classes[len(classes)-1].size, lookupMax) + 1<<lookupShift - 1) >> lookupShift
+	lookup := make([]uint16, n)
+	i := 0
+	for g := range lookup {
+		for classes[i].size < g<<lookupShift+1 {
+			i++
+		}
+		lookup[g] = uint16(i)
+	}
+	return lookup
+}
+
+// classIndex 返回能放下 size 字节的第一个 class 的下标，没有这样的 class 时返回 -1
+func (pool *AtomPool) classIndex(size int) int {
+	if size > pool.maxSize {
+		return -1
+	}
+	classes := pool.classes
+	i := 0
+	if g := (size - 1) >> lookupShift; size > 0 && g < len(pool.lookup) {
+		i = int(pool.lookup[g])
+	} else if size > 0 {
+		// 查找表之外的大小二分查找
+		lo, hi := 0, len(classes)
+		for lo < hi {
+			mid := int(uint(lo+hi) >> 1)
+			if classes[mid].size < size {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		i = lo
+	}
+	for i < len(classes) && classes[i].size < size {
+		i++
+	}
+	if i == len(classes) {
+		return -1
+	}
+	if classes[i].disabled.Load() {
+		return enabledFrom(classes, i+1)
+	}
+	return i
+}
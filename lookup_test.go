@@ -0,0 +1,52 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_ClassIndex(t *testing.T) {
+	layouts := [][]Option{
+		{WithSizeRange(64, 64*1024), WithPageSize(64 * 1024)},
+		{WithSizeRange(100, 1000), WithPageSize(4096), WithLinearGrowth(3)},
+		{WithClasses(33, 40, 47, 48, 200, 100*1024, 300*1024), WithPageSize(512 * 1024)},
+		{WithSizeRange(128, 128), WithPageSize(1024)},
+	}
+	for _, opts := range layouts {
+		pool, err := NewPool(opts...)
+		utest.IsNilNow(t, err)
+		for size := 0; size <= pool.maxSize+1; size++ {
+			// 和逐个比较的线性查找结果相同
+			want := -1
+			if size <= pool.maxSize {
+				for i := range pool.classes {
+					if pool.classes[i].size >= size {
+						want = i
+						break
+					}
+				}
+			}
+			if got := pool.classIndex(size); got != want {
+				t.Fatalf("classIndex(%d) = %d, want %d", size, got, want)
+			}
+		}
+	}
+}
+
+func Test_AtomPool_ClassIndexAdapt(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	pool.Free(pool.Alloc(1024))
+	utest.Assert(t, pool.Adapt(AdaptOptions{}))
+	utest.EqualNow(t, len(pool.classes), 1)
+	utest.EqualNow(t, pool.classIndex(100), 0)
+	utest.EqualNow(t, cap(pool.Alloc(100)), 1024)
+}
+
+func Benchmark_AtomPool_ClassIndex(b *testing.B) {
+	pool := NewAtomPool(64, 64*1024, 2, 1024*1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.classIndex(i&(64*1024-1) + 1)
+	}
+}
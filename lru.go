@@ -0,0 +1,128 @@
+package slab
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUCache is a byte cache whose values are copied into chunks of a Pool, evicting the least recently used entries
+// when the chunks of the values exceed its capacity, and freeing the chunks of evicted and deleted values back to the pool.
+// An LRUCache is safe for concurrent use.
+type LRUCache struct {
+	mu       sync.Mutex
+	pool     Pool
+	capacity int
+	bytes    int
+	entries  map[string]*list.Element // key → *lruEntry
+	order    *list.List               // 最近使用的在前
+}
+
+// lruEntry 是 LRUCache 中的一个值，value 是从 pool 分配的 chunk
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRUCache create an LRUCache which holds values in chunks of pool, up to capacity bytes of chunks.
+func NewLRUCache(pool Pool, capacity int) *LRUCache {
+	return &LRUCache{
+		pool:     pool,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Set copies value into a chunk of the pool and stores it as the most recently used value of key, replacing the old value.
+// It returns false and stores nothing if the chunk alone is larger than the capacity or the pool returns nil.
+func (cache *LRUCache) Set(key string, value []byte) bool {
+	mem := cache.pool.Alloc(len(value))
+	if mem == nil {
+		return false
+	}
+	if cap(mem) > cache.capacity {
+		cache.pool.Free(mem)
+		return false
+	}
+	copy(mem, value)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.remove(key)
+	for cache.bytes+cap(mem) > cache.capacity {
+		cache.removeElement(cache.order.Back())
+	}
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key, mem})
+	cache.bytes += cap(mem)
+	return true
+}
+
+// Get appends the value of key to dst, marks it as the most recently used and returns the result.
+// ok is false if key is not in the cache.
+func (cache *LRUCache) Get(dst []byte, key string) (result []byte, ok bool) {
+	cache.View(key, func(value []byte) {
+		dst, ok = append(dst, value...), true
+	})
+	return dst, ok
+}
+
+// View calls fn with the value of key in the chunk, without copying it, and marks it as the most recently used.
+// The value is only valid in fn, which must not retain it nor call the cache. It returns false if key is not in the cache.
+func (cache *LRUCache) View(key string, fn func(value []byte)) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	e, ok := cache.entries[key]
+	if !ok {
+		return false
+	}
+	cache.order.MoveToFront(e)
+	fn(e.Value.(*lruEntry).value)
+	return true
+}
+
+// Delete removes the value of key and frees its chunk, it returns false if key is not in the cache.
+func (cache *LRUCache) Delete(key string) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.remove(key)
+}
+
+// Purge removes every value and frees their chunks.
+func (cache *LRUCache) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for cache.order.Len() > 0 {
+		cache.removeElement(cache.order.Back())
+	}
+}
+
+// Len returns the number of values in the cache.
+func (cache *LRUCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+// Bytes returns the total size of the chunks holding the values, which is at most the capacity.
+func (cache *LRUCache) Bytes() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.bytes
+}
+
+// remove 删除 key 的值，调用者需持有 mu
+func (cache *LRUCache) remove(key string) bool {
+	e, ok := cache.entries[key]
+	if ok {
+		cache.removeElement(e)
+	}
+	return ok
+}
+
+// removeElement 删除 e 并归还它的 chunk，调用者需持有 mu
+func (cache *LRUCache) removeElement(e *list.Element) {
+	entry := cache.order.Remove(e).(*lruEntry)
+	delete(cache.entries, entry.key)
+	cache.bytes -= cap(entry.value)
+	cache.pool.Free(entry.value)
+}
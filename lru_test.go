@@ -0,0 +1,80 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_LRUCache(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	cache := NewLRUCache(pool, 512)
+
+	value := []byte("hello")
+	utest.Assert(t, cache.Set("a", value))
+	value[0] = 'H'
+	v, ok := cache.Get(nil, "a")
+	utest.Assert(t, ok)
+	utest.EqualNow(t, string(v), "hello")
+	utest.EqualNow(t, cache.Bytes(), 128)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+
+	utest.Assert(t, cache.Set("b", make([]byte, 200)))
+	utest.Assert(t, cache.Set("c", []byte("c")))
+	utest.EqualNow(t, cache.Bytes(), 512)
+
+	// "a" is used more recently than "b", which is evicted
+	utest.Assert(t, cache.View("a", func(value []byte) {
+		utest.EqualNow(t, string(value), "hello")
+	}))
+	utest.Assert(t, cache.Set("d", []byte("d")))
+	_, ok = cache.Get(nil, "b")
+	utest.Assert(t, !ok)
+	utest.EqualNow(t, cache.Len(), 3)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+
+	// replacing a value frees the old chunk
+	utest.Assert(t, cache.Set("a", []byte("world")))
+	v, _ = cache.Get(v[:0], "a")
+	utest.EqualNow(t, string(v), "world")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 3)
+
+	// a value larger than the capacity is not stored
+	utest.Assert(t, !cache.Set("a", make([]byte, 1000)))
+	v, _ = cache.Get(nil, "a")
+	utest.EqualNow(t, string(v), "world")
+
+	utest.Assert(t, cache.Delete("c"))
+	utest.Assert(t, !cache.Delete("c"))
+	cache.Purge()
+	utest.EqualNow(t, cache.Len(), 0)
+	utest.EqualNow(t, cache.Bytes(), 0)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+}
+
+func Test_LRUCache_Concurrent(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	cache := NewLRUCache(pool, 1024)
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := keys[i%len(keys)]
+				if v, ok := cache.Get(nil, key); ok {
+					utest.EqualNow(t, string(v), key)
+				}
+				cache.Set(key, []byte(key))
+			}
+		}()
+	}
+	wg.Wait()
+	utest.Assert(t, cache.Bytes() <= 1024)
+	cache.Purge()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
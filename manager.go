@@ -0,0 +1,206 @@
+package slab
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manager owns several pools, e.g. one per subsystem with its own configuration,
+// and enforces one memory budget across all of them. The pages of a trimmed or closed pool are kept as spares
+// which the other pools reuse before allocating new pages, until the manager is trimmed.
+type Manager struct {
+	mu    sync.Mutex
+	src   source
+	pools map[string]*managedPool
+}
+
+type managedPool struct {
+	name   string
+	pool   *AtomPool
+	allocs uint64    // 上次观察到的分配次数
+	used   time.Time // 上次观察到分配次数变化的时间
+}
+
+// NewManager create a Manager whose pools share a budget of maxMemory bytes, 0 means unlimited.
+func NewManager(maxMemory int) *Manager {
+	m := &Manager{
+		src:   source{pages: heapPages{}, done: make(chan struct{})},
+		pools: make(map[string]*managedPool),
+	}
+	m.src.budget.limit.Store(int64(maxMemory))
+	return m
+}
+
+// NewPool create a pool named name configured by opts, whose pages count against the budget of the manager.
+// WithMaxMemory still limits the pool itself, WithMmap and WithPageAllocator have no effect since the pages are borrowed from the manager.
+// The pool is created WithRouting, so Manager.Free finds it from the buffers it allocated.
+func (m *Manager) NewPool(name string, opts ...Option) (*AtomPool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.pools[name]; exists {
+		return nil, fmt.Errorf("slab: pool %q already exists", name)
+	}
+	opts = append(append([]Option{}, opts...), func(cfg *config) {
+		cfg.parent = &m.src
+		cfg.routing = true
+	})
+	pool, err := NewPool(opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.pools[name] = &managedPool{name: name, pool: pool, used: time.Now()}
+	return pool, nil
+}
+
+// Free release mem to the pool which owns its slab page like FreeRouted, whichever pool of the manager it came from.
+// It returns false and does nothing if no routed pool owns mem, e.g. it's a heap fallback.
+func (m *Manager) Free(mem []byte) bool {
+	return FreeRouted(mem)
+}
+
+// Pool returns the pool named name, or nil if there is no such pool.
+func (m *Manager) Pool(name string) *AtomPool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.pools[name]; ok {
+		return p.pool
+	}
+	return nil
+}
+
+// Pools returns a copy of the pools owned by the manager by name.
+func (m *Manager) Pools() map[string]*AtomPool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pools := make(map[string]*AtomPool, len(m.pools))
+	for name, p := range m.pools {
+		pools[name] = p.pool
+	}
+	return pools
+}
+
+// ClosePool close the pool named name like AtomPool.Close and removes it from the manager.
+func (m *Manager) ClosePool(name string) error {
+	m.mu.Lock()
+	p, ok := m.pools[name]
+	delete(m.pools, name)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("slab: pool %q does not exist", name)
+	}
+	return p.pool.Close()
+}
+
+// Close close every pool owned by the manager and releases the spare pages,
+// returning the errors of the pools closed with outstanding buffers joined together.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	pools := m.pools
+	m.pools = make(map[string]*managedPool)
+	m.mu.Unlock()
+	var errs []error
+	for _, p := range pools {
+		if err := p.pool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("slab: pool %q: %w", p.name, err))
+		}
+	}
+	m.src.drop()
+	return errors.Join(errs...)
+}
+
+// PoolSummary is the memory usage of a pool owned by a Manager.
+type PoolSummary struct {
+	Name     string
+	Resident int       // bytes of slab pages and buddy arenas owned by the pool
+	InUse    int       // bytes of chunks and blocks currently allocated
+	Allocs   uint64    // allocations served by the slab classes and the buddy tier
+	LastUsed time.Time // last time the manager saw the pool serving allocations
+}
+
+// ManagerStats is the aggregated statistics of the pools owned by a Manager.
+type ManagerStats struct {
+	MaxMemory int           // the shared budget, 0 means unlimited
+	Used      int           // bytes counted against the budget, including the spare pages
+	Spare     int           // bytes of pages returned by trimmed or closed pools and not reused yet
+	Pools     []PoolSummary // from the least recently used pool to the most recently used one
+}
+
+// Stats returns the aggregated statistics of the pools.
+// The recency of each pool is observed from its allocation counters whenever the manager looks at the pools, i.e. Stats and Trim.
+func (m *Manager) Stats() ManagerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := ManagerStats{
+		MaxMemory: int(m.src.budget.limit.Load()),
+		Used:      int(m.src.budget.used.Load()),
+	}
+	for _, raw := range m.spares() {
+		s.Spare += len(raw)
+	}
+	for _, p := range m.lru() {
+		sum := p.summary()
+		sum.LastUsed = p.used
+		s.Pools = append(s.Pools, sum)
+	}
+	return s
+}
+
+// spares 返回 spares 的副本
+func (m *Manager) spares() [][]byte {
+	m.src.mu.Lock()
+	defer m.src.mu.Unlock()
+	return append([][]byte(nil), m.src.spares...)
+}
+
+// Trim release the spare pages, then trims the pools like AtomPool.Trim from the least recently used one
+// until at least n bytes are released, or every pool if n <= 0. It returns the number of bytes released.
+func (m *Manager) Trim(n int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	released := m.src.drop()
+	for _, p := range m.lru() {
+		if n > 0 && released >= n {
+			break
+		}
+		p.pool.Trim()
+		// 被 trim 的 page 先放回 spares，再真正释放
+		released += m.src.drop()
+	}
+	return released
+}
+
+// lru 更新每个 pool 的使用时间，按从最久未使用到最近使用的顺序返回，调用者需持有 mu
+func (m *Manager) lru() []*managedPool {
+	now := time.Now()
+	pools := make([]*managedPool, 0, len(m.pools))
+	for _, p := range m.pools {
+		if allocs := p.summary().Allocs; allocs != p.allocs {
+			p.allocs, p.used = allocs, now
+		}
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		if !pools[i].used.Equal(pools[j].used) {
+			return pools[i].used.Before(pools[j].used)
+		}
+		return pools[i].name < pools[j].name
+	})
+	return pools
+}
+
+func (p *managedPool) summary() PoolSummary {
+	s := PoolSummary{Name: p.name}
+	for _, c := range p.pool.Stats() {
+		s.Resident += c.Resident
+		s.InUse += c.InUse * c.Size
+		s.Allocs += c.Allocs
+	}
+	b := p.pool.BuddyStats()
+	s.Resident += b.Resident
+	s.InUse += b.InUse
+	s.Allocs += b.Allocs
+	return s
+}
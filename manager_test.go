@@ -0,0 +1,69 @@
+package slab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_Manager(t *testing.T) {
+	m := NewManager(4 * 1024)
+	a, err := m.NewPool("a", WithClasses(128), WithPageSize(1024), WithMaxPages(8))
+	utest.IsNilNow(t, err)
+	b, err := m.NewPool("b", WithClasses(256), WithPageSize(1024), WithMaxPages(8))
+	utest.IsNilNow(t, err)
+	_, err = m.NewPool("a")
+	utest.NotNilNow(t, err)
+	utest.Assert(t, m.Pool("a") == a)
+	utest.Assert(t, m.Pools()["b"] == b)
+	utest.IsNilNow(t, m.Pool("c"))
+
+	// the budget is shared by both pools
+	bufs := a.AllocBatch(128, 16)
+	utest.EqualNow(t, a.Stats()[0].Pages, 2)
+	utest.EqualNow(t, b.Stats()[0].Pages, 1)
+	mem, pooled := b.AllocPooled(256)
+	utest.Assert(t, pooled)
+	bufsB := append(b.AllocBatch(256, 7), mem)
+	utest.EqualNow(t, b.Stats()[0].Pages, 2)
+	_, pooled = b.AllocPooled(256)
+	utest.Assert(t, !pooled)
+
+	s := m.Stats()
+	utest.EqualNow(t, s.MaxMemory, 4*1024)
+	utest.EqualNow(t, s.Used, 4*1024)
+	utest.EqualNow(t, len(s.Pools), 2)
+	// b alloc last
+	utest.EqualNow(t, s.Pools[0].Name, "a")
+	utest.EqualNow(t, s.Pools[0].Resident, 2*1024)
+	utest.EqualNow(t, s.Pools[0].InUse, 16*128)
+	utest.EqualNow(t, s.Pools[1].Name, "b")
+	utest.EqualNow(t, s.Pools[1].Allocs, uint64(8))
+
+	// the least recently used pool is trimmed first
+	b.FreeBatch(bufsB)
+	a.FreeBatch(bufs)
+	time.Sleep(time.Millisecond)
+	a.Free(a.Alloc(128))
+	utest.EqualNow(t, m.Trim(1), 2*1024)
+	utest.EqualNow(t, a.Stats()[0].Pages, 2)
+	utest.EqualNow(t, b.Stats()[0].Pages, 0)
+	utest.EqualNow(t, m.Stats().Used, 2*1024)
+
+	// pages of a closed pool are reused by the other pools
+	c, err := m.NewPool("c", WithClasses(128), WithPageSize(1024), WithMaxPages(8))
+	utest.IsNilNow(t, err)
+	utest.IsNilNow(t, m.ClosePool("c"))
+	utest.NotNilNow(t, m.ClosePool("c"))
+	utest.IsNilNow(t, m.Pool("c"))
+	utest.EqualNow(t, c.Stats()[0].Pages, 0)
+	utest.EqualNow(t, m.Stats().Spare, 1024)
+	held := b.Alloc(256)
+	utest.EqualNow(t, m.Stats().Spare, 0)
+	utest.EqualNow(t, m.Stats().Used, 3*1024)
+
+	utest.NotNilNow(t, m.Close())
+	b.Free(held)
+	utest.EqualNow(t, len(m.Pools()), 0)
+}
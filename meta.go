@@ -0,0 +1,44 @@
+package slab
+
+// SetMeta set the metadata word of the chunk starting at mem to v, which Meta returns until the chunk is freed.
+// It returns false if the pool is created without WithMetadata or mem doesn't start at a chunk in use.
+// The metadata is not related to the tags of AllocTagged.
+func (pool *AtomPool) SetMeta(mem []byte, v uint64) bool {
+	p, i := pool.metaChunk(mem)
+	if p == nil {
+		return false
+	}
+	p.meta[i].Store(v)
+	return true
+}
+
+// Meta returns the metadata word set by SetMeta on the chunk starting at mem, 0 if none is set since the chunk was alloc.
+// ok is false if the pool is created without WithMetadata or mem doesn't start at a chunk in use.
+func (pool *AtomPool) Meta(mem []byte) (v uint64, ok bool) {
+	p, i := pool.metaChunk(mem)
+	if p == nil {
+		return 0, false
+	}
+	return p.meta[i].Load(), true
+}
+
+// metaChunk 和 Free 一样按首指针查找 mem 所属的已分配的 chunk，返回它所在的 page 和下标，
+// chunk 已分配时它所在的 page 不会被 reclaim 释放
+func (pool *AtomPool) metaChunk(mem []byte) (*page, int) {
+	if cap(mem) == 0 {
+		return nil, 0
+	}
+	for k := 0; k < len(pool.classes); k++ {
+		c := &pool.classes[k]
+		if !c.meta || c.size < cap(mem) {
+			continue
+		}
+		if p, _, i := c.locate(dataPtr(mem)); p != nil {
+			if p.chunks[i].next.Load() != 0 {
+				return nil, 0
+			}
+			return p, i
+		}
+	}
+	return nil, 0
+}
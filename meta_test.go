@@ -0,0 +1,42 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Meta(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMetadata())
+	a := pool.Alloc(100)
+	b := pool.Alloc(512)
+	v, ok := pool.Meta(a)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, v, uint64(0))
+
+	utest.Assert(t, pool.SetMeta(a, 42))
+	utest.Assert(t, pool.SetMeta(b[:10], 7))
+	v, _ = pool.Meta(a[:1])
+	utest.EqualNow(t, v, uint64(42))
+	v, _ = pool.Meta(b)
+	utest.EqualNow(t, v, uint64(7))
+
+	// 不是 chunk 开头、堆上的或已回收的 mem 都没有元数据
+	utest.Assert(t, !pool.SetMeta(a[1:], 1))
+	utest.Assert(t, !pool.SetMeta(make([]byte, 128), 1))
+	pool.Free(a)
+	_, ok = pool.Meta(a)
+	utest.Assert(t, !ok)
+
+	// 回收时清除，重新分配之后从 0 开始
+	a = pool.Alloc(128)
+	v, ok = pool.Meta(a)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, v, uint64(0))
+
+	plain := NewAtomPool(128, 1024, 2, 1024)
+	mem := plain.Alloc(128)
+	utest.Assert(t, !plain.SetMeta(mem, 1))
+	_, ok = plain.Meta(mem)
+	utest.Assert(t, !ok)
+}
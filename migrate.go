@@ -0,0 +1,48 @@
+package slab
+
+import "errors"
+
+// Migrate moves the pool to dst, e.g. a pool with a new class layout built for a long-running process.
+// From then on Alloc, TryAlloc, AllocPooled, AllocBatch and AllocContext of the pool are served by dst, and Free passes to dst
+// the buffers the pool doesn't own. The chunks alloc before keep being returned to the pool, and each slab class
+// releases its pages as they become free, so memory doesn't double while the old chunks drain.
+// Stats of the pool shows how many old chunks are still in use, the pool can be closed once they are all freed.
+// It returns an error if dst is nil, the pool itself or migrated to the pool, or if the pool is already migrated.
+func (pool *AtomPool) Migrate(dst *AtomPool) error {
+	if dst == nil {
+		return errors.New("slab: migrate to nil pool")
+	}
+	for p := dst; p != nil; p = p.migrated.Load() {
+		if p == pool {
+			return errors.New("slab: migrate to itself")
+		}
+	}
+	if pool.src.closed.Load() {
+		return ErrPoolClosed
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		c.drainMark.Store(int64(c.allocs.Load() - c.frees.Load()))
+	}
+	if !pool.migrated.CompareAndSwap(nil, dst) {
+		return errors.New("slab: pool already migrated")
+	}
+	// 已经完全空闲的 page 立即释放
+	pool.trim(TrimMigrate)
+	return nil
+}
+
+// Migrated returns the pool set by Migrate, or nil if the pool is not migrated.
+func (pool *AtomPool) Migrated() *AtomPool {
+	return pool.migrated.Load()
+}
+
+// shrink 在迁移之后回收了 chunk 时调用，class 每回收够一个 page 的 chunk 就 Trim 一次，释放完全空闲的 page，返回释放的字节数
+func (c *class) shrink() int {
+	n := int64(c.allocs.Load() - c.frees.Load())
+	mark := c.drainMark.Load()
+	if n <= mark-int64(c.perPage) && c.drainMark.CompareAndSwap(mark, n) || n == 0 {
+		return c.Trim()
+	}
+	return 0
+}
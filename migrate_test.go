@@ -0,0 +1,47 @@
+package slab
+
+import (
+	"context"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Migrate(t *testing.T) {
+	old := NewAtomPool(128, 256, 2, 1024, WithMaxPages(4))
+	dst := NewAtomPool(64, 512, 2, 4096)
+	bufs := old.AllocBatch(128, 24)
+	utest.EqualNow(t, old.Stats()[0].Pages, 3)
+
+	utest.IsNilNow(t, old.Migrate(dst))
+	utest.Assert(t, old.Migrated() == dst)
+	utest.NotNilNow(t, old.Migrate(dst))
+	utest.NotNilNow(t, dst.Migrate(old))
+	// 256 字节的 class 没有分配过，它的 page 在迁移时就被释放
+	utest.EqualNow(t, old.Stats()[1].Pages, 0)
+
+	// 新的分配由 dst 负责，交给 old 回收的 dst 的 chunk 也回到 dst
+	mem := old.Alloc(64)
+	utest.EqualNow(t, cap(mem), 64)
+	batch := old.AllocBatch(128, 2)
+	mem2, err := old.AllocContext(context.Background(), 300)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, cap(mem2), 512)
+	utest.EqualNow(t, old.Stats()[0].Allocs, uint64(24))
+	old.Free(mem)
+	old.FreeBatch(batch)
+	utest.IsNilNow(t, old.TryFree(mem2))
+	for _, s := range dst.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// 旧的 chunk 回收够一个 page 就释放完全空闲的 page
+	old.FreeBatch(bufs[:8])
+	utest.EqualNow(t, old.Stats()[0].Pages, 2)
+	for _, mem := range bufs[8:] {
+		old.Free(mem)
+	}
+	utest.EqualNow(t, old.Stats()[0].Pages, 0)
+	utest.IsNilNow(t, old.Close())
+	utest.EqualNow(t, cap(old.Alloc(128)), 128)
+}
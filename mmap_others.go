@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package slab
+
+import (
+	"errors"
+	"os"
+)
+
+func mmapPage(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+func munmapPage(mem []byte) error {
+	return nil
+}
+
+func mmapFile(f *os.File, off int64, size int) ([]byte, error) {
+	return nil, errors.New("slab: shared memory is not supported on this platform")
+}
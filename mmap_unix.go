@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package slab
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmapPage(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+func munmapPage(mem []byte) error {
+	return syscall.Munmap(mem)
+}
+
+func mmapFile(f *os.File, off int64, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), off, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
@@ -0,0 +1,55 @@
+//go:build windows
+// +build windows
+
+package slab
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	memCommit     = 0x1000
+	memReserve    = 0x2000
+	memRelease    = 0x8000
+	memLargePages = 0x20000000
+	pageReadWrite = 0x04
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procVirtualAlloc = kernel32.NewProc("VirtualAlloc")
+	procVirtualFree  = kernel32.NewProc("VirtualFree")
+)
+
+// virtualAlloc 用 VirtualAlloc 保留并提交 size 字节，flags 是额外的分配类型，例如 MEM_LARGE_PAGES
+func virtualAlloc(size int, flags uintptr) ([]byte, error) {
+	addr, _, err := procVirtualAlloc.Call(0, uintptr(size), memCommit|memReserve|flags, pageReadWrite)
+	if addr == 0 {
+		return nil, os.NewSyscallError("VirtualAlloc", err)
+	}
+	// addr 不在 Go 的堆中，按指针的位模式转换，避免 uintptr 直接转换为 unsafe.Pointer
+	return unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size), nil
+}
+
+func mmapPage(size int) ([]byte, error) {
+	return virtualAlloc(size, 0)
+}
+
+// munmapPage 释放 VirtualAlloc 分配的整块内存，MEM_RELEASE 要求大小为 0
+func munmapPage(mem []byte) error {
+	if len(mem) == 0 {
+		return nil
+	}
+	r, _, err := procVirtualFree.Call(uintptr(unsafe.Pointer(&mem[0])), 0, memRelease)
+	if r == 0 {
+		return os.NewSyscallError("VirtualFree", err)
+	}
+	return nil
+}
+
+func mmapFile(f *os.File, off int64, size int) ([]byte, error) {
+	return nil, errors.New("slab: shared memory is not supported on this platform")
+}
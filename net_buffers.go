@@ -0,0 +1,72 @@
+package slab
+
+import (
+	"io"
+	"net"
+)
+
+// AllocBuffers alloc a message of size bytes across chunks of chunkSize bytes from pool,
+// exposed as net.Buffers so it can be written by a single writev syscall. The last chunk holds the remaining bytes.
+// net.Buffers.WriteTo consumes the slice it is called on, so call it on a copy and pass the original to FreeBuffers.
+func AllocBuffers(pool Pool, size, chunkSize int) net.Buffers {
+	n := (size + chunkSize - 1) / chunkSize
+	var bufs [][]byte
+	if p, ok := pool.(interface{ AllocBatch(int, int) [][]byte }); ok {
+		bufs = p.AllocBatch(chunkSize, n)
+	} else {
+		bufs = make([][]byte, n)
+		for i := range bufs {
+			bufs[i] = pool.Alloc(chunkSize)
+		}
+	}
+	if n > 0 && size%chunkSize != 0 {
+		bufs[n-1] = bufs[n-1][:size%chunkSize]
+	}
+	return bufs
+}
+
+// FreeBuffers release the chunks of a message alloc by AllocBuffers to pool.
+func FreeBuffers(pool Pool, bufs net.Buffers) {
+	if p, ok := pool.(interface{ FreeBatch([][]byte) }); ok {
+		p.FreeBatch(bufs)
+		return
+	}
+	for _, mem := range bufs {
+		pool.Free(mem)
+	}
+}
+
+// ReadBuffers reads up to size bytes from r into chunks of chunkSize bytes from pool in one vectored operation,
+// a readv syscall when r is an *os.File or a net.Conn on unix, one Read per chunk until a short read otherwise.
+// It returns the chunks holding the n bytes read, the last one sliced to the remaining bytes, which must be released by FreeBuffers.
+// Like io.Reader.Read it may return fewer than size bytes, and io.EOF when it reads nothing at the end of the input.
+func ReadBuffers(pool Pool, r io.Reader, size, chunkSize int) (bufs net.Buffers, n int, err error) {
+	bufs = AllocBuffers(pool, size, chunkSize)
+	n, ok, err := readv(r, bufs)
+	if !ok {
+		n, err = 0, nil
+		for _, mem := range bufs {
+			m, e := r.Read(mem)
+			n += m
+			if e != nil || m < len(mem) {
+				err = e
+				break
+			}
+		}
+	}
+	if n == 0 && err == nil && size > 0 {
+		err = io.EOF
+	}
+
+	// 只保留装有数据的 chunk，其余的立即归还
+	used := 0
+	for left := n; used < len(bufs) && left > 0; used++ {
+		if left < len(bufs[used]) {
+			bufs[used] = bufs[used][:left]
+		}
+		left -= len(bufs[used])
+	}
+	FreeBuffers(pool, bufs[used:])
+	clear(bufs[used:])
+	return bufs[:used], n, err
+}
@@ -0,0 +1,87 @@
+package slab
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AllocBuffers(t *testing.T) {
+	pools := []Pool{
+		NewAtomPool(128, 1024, 2, 4096),
+		NewLockPool(128, 1024, 2, 4096),
+	}
+	for _, pool := range pools {
+		bufs := AllocBuffers(pool, 2500, 1024)
+		utest.EqualNow(t, len(bufs), 3)
+		utest.EqualNow(t, len(bufs[0]), 1024)
+		utest.EqualNow(t, len(bufs[2]), 452)
+		for i, mem := range bufs {
+			for j := range mem {
+				mem[j] = byte(i)
+			}
+		}
+
+		var w bytes.Buffer
+		v := append(net.Buffers(nil), bufs...)
+		n, err := v.WriteTo(&w)
+		utest.IsNilNow(t, err)
+		utest.EqualNow(t, n, int64(2500))
+		utest.EqualNow(t, w.Bytes()[2499], byte(2))
+		FreeBuffers(pool, bufs)
+	}
+
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	bufs := AllocBuffers(pool, 2048, 1024)
+	utest.EqualNow(t, len(bufs), 2)
+	utest.EqualNow(t, len(bufs[1]), 1024)
+	FreeBuffers(pool, bufs)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+}
+
+func Test_ReadBuffers(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	data := bytes.Repeat([]byte("0123456789"), 250)
+
+	r, w, err := os.Pipe()
+	utest.IsNilNow(t, err)
+	defer r.Close()
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	readers := []io.Reader{r, bytes.NewReader(data)}
+	for _, rd := range readers {
+		var got []byte
+		for {
+			bufs, n, err := ReadBuffers(pool, rd, 3000, 1024)
+			if err == io.EOF {
+				utest.EqualNow(t, len(bufs), 0)
+				break
+			}
+			utest.IsNilNow(t, err)
+			size := 0
+			for _, mem := range bufs {
+				got = append(got, mem...)
+				size += len(mem)
+			}
+			utest.EqualNow(t, size, n)
+			FreeBuffers(pool, bufs)
+		}
+		utest.EqualNow(t, string(got), string(data))
+		utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+	}
+
+	// the chunks beyond the bytes read are freed at once
+	bufs, n, err := ReadBuffers(pool, bytes.NewReader(data[:1500]), 3000, 1024)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 1500)
+	utest.EqualNow(t, len(bufs), 2)
+	utest.EqualNow(t, len(bufs[1]), 476)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 2)
+	FreeBuffers(pool, bufs)
+}
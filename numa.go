@@ -0,0 +1,99 @@
+package slab
+
+// numaNode 是一个 NUMA node 的编号和它的 cpu
+type numaNode struct {
+	id   int
+	cpus []int
+}
+
+// nodePages 是 NUMA pool 的 shard 的 PageAllocator，page 是匿名映射的内存，并在第一次写入之前用 mbind 绑定到 node，
+// 因此无论哪个线程增长或触碰 page，物理内存都从 node 分配。不支持 mbind 时退回由第一次写入的线程决定
+type nodePages struct {
+	node int
+}
+
+func (p nodePages) AllocPage(size int) ([]byte, error) {
+	mem, err := mmapPage(size)
+	if err != nil {
+		return nil, err
+	}
+	bindNode(mem, p.node)
+	return mem, nil
+}
+
+func (nodePages) FreePage(page []byte) {
+	munmapPage(page)
+}
+
+// NewNUMAPool create a sharded pool with one shard per NUMA node, configured by opts.
+// The pages of each shard are mmap-backed and bound to the node with mbind on Linux before they are touched,
+// so they are node-local whichever thread grows them, the preallocated pages are also touched by a thread running on the node.
+// Alloc prefers the shard of the node the calling thread is running on, takes the free chunks of remote nodes
+// when the local shard has none, and grows the local shard only when no node has any.
+// On platforms or machines without NUMA information it behaves like a single shard pool, unless WithNUMANodes is set.
+func NewNUMAPool(opts ...Option) (*ShardedPool, error) {
+	cfg := configure(opts)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	detected := numaNodes()
+	if len(detected) == 0 {
+		detected = []numaNode{{id: -1}}
+	}
+	n := cfg.numaNodes
+	if n == 0 {
+		n = len(detected)
+	}
+	// 第 i 个 shard 放在第 i%len(detected) 个 node 上
+	nodes := make([]numaNode, n)
+	for i := range nodes {
+		nodes[i] = detected[i%len(detected)]
+	}
+
+	// cpu 编号到 shard 编号的映射，同一个 node 上有多个 shard 时映射到第一个
+	cpuNode := []int{}
+	for node := len(nodes) - 1; node >= 0; node-- {
+		for _, cpu := range nodes[node].cpus {
+			for len(cpuNode) <= cpu {
+				cpuNode = append(cpuNode, 0)
+			}
+			cpuNode[cpu] = node
+		}
+	}
+
+	pool := &ShardedPool{shards: make([]*AtomPool, len(nodes))}
+	for i, node := range nodes {
+		var pages PageAllocator = mmapPages{}
+		if node.id >= 0 {
+			pages = nodePages{node.id}
+		}
+		shardOpts := append(shardOpts(opts, i), WithPageAllocator(pages), WithPretouch())
+		var err error
+		runOnCPUs(node.cpus, func() {
+			pool.shards[i], err = NewPool(shardOpts...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	pool.maxSize = pool.shards[0].maxSize
+	if len(nodes) > 1 {
+		pool.pick = func() int {
+			if cpu := currentCPU(); cpu >= 0 && cpu < len(cpuNode) {
+				return cpuNode[cpu]
+			}
+			return 0
+		}
+	}
+	return pool, nil
+}
+
+// AllocOnNode alloc a []byte from the shard of node, like Alloc but without stealing from the other nodes,
+// so a worker pinned to node gets node-local memory. The buffer is passed to Free like the ones from Alloc.
+// If node is not a shard of the pool AllocOnNode is the same as Alloc.
+func (pool *ShardedPool) AllocOnNode(node, size int) []byte {
+	if node < 0 || node >= len(pool.shards) {
+		return pool.Alloc(size)
+	}
+	return pool.shards[node].Alloc(size)
+}
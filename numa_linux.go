@@ -0,0 +1,147 @@
+package slab
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// getcpu 的系统调用号，syscall 包没有导出
+var sysGetcpu = map[string]uintptr{
+	"386":     318,
+	"amd64":   309,
+	"arm":     345,
+	"arm64":   168,
+	"loong64": 168,
+	"ppc64":   302,
+	"ppc64le": 302,
+	"riscv64": 168,
+	"s390x":   311,
+}[runtime.GOARCH]
+
+// mbind 的系统调用号，syscall 包没有全部导出
+var sysMbind = map[string]uintptr{
+	"386":      274,
+	"amd64":    237,
+	"arm":      319,
+	"arm64":    235,
+	"loong64":  235,
+	"mips":     4268,
+	"mipsle":   4268,
+	"mips64":   5227,
+	"mips64le": 5227,
+	"ppc64":    259,
+	"ppc64le":  259,
+	"riscv64":  235,
+	"s390x":    268,
+}[runtime.GOARCH]
+
+// mpolPreferred 优先从指定的 node 分配，node 内存不足时从其它 node 分配，而不像 MPOL_BIND 那样失败
+const mpolPreferred = 1
+
+// bindNode 用 mbind 让 mem 的物理内存从 node 分配，必须在 mem 被第一次写入之前调用
+func bindNode(mem []byte, node int) error {
+	var mask cpuSet
+	if sysMbind == 0 || node < 0 || node >= len(mask)*64 {
+		return syscall.ENOSYS
+	}
+	mask[node/64] |= 1 << uint(node%64)
+	_, _, errno := syscall.Syscall6(sysMbind, uintptr(unsafe.Pointer(unsafe.SliceData(mem))), uintptr(len(mem)),
+		mpolPreferred, uintptr(unsafe.Pointer(&mask)), uintptr(len(mask)*64), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// numaNodes returns the NUMA nodes and their cpus.
+func numaNodes() []numaNode {
+	dirs, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	ids := make([]int, 0, len(dirs))
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	var nodes []numaNode
+	for _, id := range ids {
+		data, err := os.ReadFile("/sys/devices/system/node/node" + strconv.Itoa(id) + "/cpulist")
+		if err != nil {
+			return nil
+		}
+		nodes = append(nodes, numaNode{id, parseCPUList(strings.TrimSpace(string(data)))})
+	}
+	return nodes
+}
+
+// parseCPUList parse the kernel cpu list format, e.g. "0-3,8,10-11".
+func parseCPUList(list string) []int {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		begin, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		end := begin
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		for cpu := begin; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// currentCPU returns the cpu the calling thread is running on, or -1 if unknown.
+func currentCPU() int {
+	if sysGetcpu == 0 {
+		return -1
+	}
+	var cpu uint32
+	_, _, errno := syscall.RawSyscall(sysGetcpu, uintptr(unsafe.Pointer(&cpu)), 0, 0)
+	if errno != 0 {
+		return -1
+	}
+	return int(cpu)
+}
+
+type cpuSet [16]uint64
+
+// runOnCPUs run fn on a thread bound to cpus, the affinity of the thread is restored after fn returns.
+func runOnCPUs(cpus []int, fn func()) {
+	if len(cpus) == 0 {
+		fn()
+		return
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var old, set cpuSet
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(old), uintptr(unsafe.Pointer(&old))); errno != 0 {
+		fn()
+		return
+	}
+	for _, cpu := range cpus {
+		if cpu < len(set)*64 {
+			set[cpu/64] |= 1 << uint(cpu%64)
+		}
+	}
+	syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(set), uintptr(unsafe.Pointer(&set)))
+	defer syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(old), uintptr(unsafe.Pointer(&old)))
+	fn()
+}
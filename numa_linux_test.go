@@ -0,0 +1,35 @@
+package slab
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_parseCPUList(t *testing.T) {
+	utest.EqualNow(t, fmt.Sprint(parseCPUList("0-3,8,10-11")), "[0 1 2 3 8 10 11]")
+	utest.EqualNow(t, fmt.Sprint(parseCPUList("5")), "[5]")
+	utest.EqualNow(t, len(parseCPUList("")), 0)
+}
+
+func Test_currentCPU(t *testing.T) {
+	if sysGetcpu == 0 {
+		t.Skip("getcpu is not supported on " + runtime.GOARCH)
+	}
+	utest.Assert(t, currentCPU() >= 0)
+}
+
+func Test_bindNode(t *testing.T) {
+	mem, err := mmapPage(osPageSize)
+	utest.IsNilNow(t, err)
+	defer munmapPage(mem)
+	// 容器中 mbind 可能被禁止，这时 page 按第一次写入放置
+	if err := bindNode(mem, 0); err != nil && err != syscall.ENOSYS && err != syscall.EPERM {
+		t.Fatal(err)
+	}
+	mem[0] = 1
+	utest.EqualNow(t, bindNode(mem, -1), error(syscall.ENOSYS))
+}
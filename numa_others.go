@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package slab
+
+import "syscall"
+
+func numaNodes() []numaNode { return nil }
+
+func bindNode(mem []byte, node int) error { return syscall.ENOSYS }
+
+func currentCPU() int { return -1 }
+
+func runOnCPUs(cpus []int, fn func()) { fn() }
@@ -0,0 +1,46 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_NUMAPool_AllocAndFree(t *testing.T) {
+	pool, err := NewNUMAPool(WithSizeRange(128, 1024), WithPageSize(4096))
+	utest.IsNilNow(t, err)
+	utest.Assert(t, len(pool.Shards()) >= 1)
+
+	mem := pool.Alloc(100)
+	utest.EqualNow(t, cap(mem), 128)
+	pool.Free(mem)
+
+	inUse := 0
+	for _, shard := range pool.Shards() {
+		inUse += shard.Stats()[0].InUse
+	}
+	utest.EqualNow(t, inUse, 0)
+}
+
+func Test_NUMAPool_AllocOnNode(t *testing.T) {
+	_, err := NewNUMAPool(WithNUMANodes(-1))
+	utest.NotNilNow(t, err)
+
+	pool, err := NewNUMAPool(WithSizeRange(128, 1024), WithPageSize(4096), WithNUMANodes(3))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Shards()), 3)
+
+	mem := pool.AllocOnNode(2, 100)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, pool.Shards()[2].Stats()[0].InUse, 1)
+	utest.EqualNow(t, pool.Shards()[0].Stats()[0].InUse+pool.Shards()[1].Stats()[0].InUse, 0)
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Shards()[2].Stats()[0].InUse, 0)
+
+	mem = pool.AllocOnNode(3, 100)
+	utest.EqualNow(t, cap(mem), 128)
+	pool.Free(mem)
+	for _, shard := range pool.Shards() {
+		utest.EqualNow(t, shard.Stats()[0].InUse, 0)
+	}
+}
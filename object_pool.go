@@ -0,0 +1,93 @@
+package slab
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ObjectPool is a lock-free slab allocation pool of fixed-size objects.
+// The slab page is a []T, so objects containing pointers are still visible to the garbage collector.
+type ObjectPool[T any] struct {
+	page   []T
+	begin  uintptr
+	end    uintptr
+	size   uintptr
+	chunks []objectChunk
+	head   atomic.Uint64
+}
+
+type objectChunk struct {
+	aba  uint32 // reslove ABA problem
+	next atomic.Uint64
+}
+
+// NewObjectPool create a lock-free slab allocation pool of T.
+// n is the number of objects in the slab page, up to 2^32-1.
+func NewObjectPool[T any](n int) *ObjectPool[T] {
+	if n > 0 && uint64(n) > maxChunks {
+		panic("slab.ObjectPool: more than 2^32-1 objects")
+	}
+	pool := &ObjectPool[T]{}
+	pool.size = unsafe.Sizeof(*new(T))
+	if pool.size == 0 || n <= 0 {
+		return pool
+	}
+	pool.page = make([]T, n)
+	pool.chunks = make([]objectChunk, n)
+	pool.begin = uintptr(unsafe.Pointer(&pool.page[0]))
+	pool.end = uintptr(unsafe.Pointer(&pool.page[n-1]))
+	pool.head.Store(makeLink(0, 0))
+	for i := 0; i < n-1; i++ {
+		pool.chunks[i].next.Store(makeLink(uint64(i+1), 0))
+	}
+	pool.chunks[n-1].next.Store(linkEnd)
+	return pool
+}
+
+// Get try get a *T from slab page, if no free object in slab page Get will make one.
+func (pool *ObjectPool[T]) Get() *T {
+	for {
+		old := pool.head.Load()
+		if old == 0 {
+			return new(T)
+		}
+		i := linkIndex(old)
+		chk := &pool.chunks[i]
+		nxt := nextLink(chk.next.Load())
+		if pool.head.CompareAndSwap(old, nxt) {
+			chk.next.Store(0)
+			return &pool.page[i]
+		}
+		runtime.Gosched()
+	}
+}
+
+// Put release a *T that get from ObjectPool.Get.
+// The object is reset to the zero value so it doesn't keep references alive.
+// Objects not belong to the slab page are ignored.
+func (pool *ObjectPool[T]) Put(obj *T) {
+	ptr := uintptr(unsafe.Pointer(obj))
+	if pool.page == nil || ptr < pool.begin || ptr > pool.end {
+		return
+	}
+	i := (ptr - pool.begin) / pool.size
+	chk := &pool.chunks[i]
+	if chk.next.Load() != 0 {
+		panic("slab.ObjectPool: Double Free")
+	}
+
+	var zero T
+	*obj = zero
+
+	chk.aba++
+	new := makeLink(uint64(i), chk.aba)
+	for {
+		old := pool.head.Load()
+		chk.next.Store(tailLink(old))
+		if pool.head.CompareAndSwap(old, new) {
+			break
+		}
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,76 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+type testObject struct {
+	id   int
+	name *string
+}
+
+func Test_ObjectPool_GetAndPut(t *testing.T) {
+	pool := NewObjectPool[testObject](16)
+	temp := make([]*testObject, 16)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Get()
+		temp[i].id = i
+		utest.Assert(t, temp[i] == &pool.page[i])
+	}
+	utest.Assert(t, pool.head.Load() == 0)
+
+	// exhausted, fall back to new
+	obj := pool.Get()
+	utest.Assert(t, obj != nil)
+	pool.Put(obj)
+	utest.Assert(t, pool.head.Load() == 0)
+
+	for i := 0; i < len(temp); i++ {
+		pool.Put(temp[i])
+	}
+	utest.Assert(t, pool.head.Load() != 0)
+
+	obj = pool.Get()
+	utest.EqualNow(t, obj.id, 0)
+	utest.Assert(t, obj.name == nil)
+}
+
+func Test_ObjectPool_DoubleFree(t *testing.T) {
+	pool := NewObjectPool[testObject](16)
+	obj := pool.Get()
+	pool.Put(obj)
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	pool.Put(obj)
+}
+
+func Test_ObjectPool_DoubleFreeTail(t *testing.T) {
+	pool := NewObjectPool[testObject](2)
+	a := pool.Get()
+	pool.Get()
+	pool.Put(a)
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	pool.Put(a)
+}
+
+func Test_ObjectPool_ZeroSize(t *testing.T) {
+	pool := NewObjectPool[struct{}](16)
+	obj := pool.Get()
+	utest.Assert(t, obj != nil)
+	pool.Put(obj)
+}
+
+func Benchmark_ObjectPool_GetAndPut(b *testing.B) {
+	pool := NewObjectPool[testObject](1024)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Put(pool.Get())
+		}
+	})
+}
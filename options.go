@@ -0,0 +1,886 @@
+package slab
+
+import (
+	"fmt"
+	"math"
+	"runtime/pprof"
+)
+
+type config struct {
+	minSize   int
+	maxSize   int
+	factor    int
+	ratio     float64 // 非整数的增长系数，0 表示使用 factor
+	granule   int     // ratio 增长的大小向上取整到 granule 的倍数
+	step      int
+	linearMax int
+	pageSize  int
+	pageSizes func(chunkSize int) int
+	syncs     func(chunkSize int) Sync
+	freeCaps  func(chunkSize int) int
+	classes   []int
+	tiny      int
+	maxPages  int
+	growBy    int
+	spins     int
+	pauses    int
+	prealloc  int
+	maxMemory int
+	strict    bool
+	noHeap    bool
+	realTime  int
+	pages     PageAllocator
+	align     int
+	leak      bool
+	sample    int
+	pretouch  bool
+	profile   *pprof.Profile
+	fallback  func(size int) []byte
+
+	onDoubleFree  func(mem []byte)
+	onCorruption  func(err error)
+	onAlloc       func(e AllocEvent)
+	onFree        func(e AllocEvent)
+	onRelease     func(e ReleaseEvent)
+	onPage        func(e PageEvent)
+	routing       bool
+	tracer        *Tracer
+	guards        bool
+	poison        bool
+	poisonBy      byte
+	quarantine    int
+	fifo          bool
+	highMark      float64
+	lowMark       float64
+	onMark        func(e WatermarkEvent)
+	peaks         bool
+	stringGuard   bool
+	meta          bool
+	overflow      int
+	overflowCap   int
+	buddyMax      int
+	buddyArenas   int
+	parent        *source
+	policy        []Policy
+	fit           Fit
+	latency       bool
+	sizeHistogram bool
+	secondary     Pool
+	chaos         bool
+	chaosSeed     int64
+	shuffle       bool
+	shuffleSeed   int64
+	name          string
+	numaNodes     int
+	file          string
+}
+
+func defaultConfig() config {
+	return config{
+		minSize:  64,
+		maxSize:  64 * 1024,
+		factor:   2,
+		pageSize: 1024 * 1024,
+		maxPages: 1,
+		prealloc: 1,
+		pages:    heapPages{},
+	}
+}
+
+// Option is used to configure optional behavior of AtomPool.
+type Option func(*config)
+
+// WithSizeRange set the smallest and the largest chunk size.
+// The default is 64B to 64KB.
+func WithSizeRange(minSize, maxSize int) Option {
+	return func(cfg *config) {
+		cfg.minSize = minSize
+		cfg.maxSize = maxSize
+	}
+}
+
+// WithGrowthFactor set the factor used to control growth of chunk size.
+// The default is 2.
+func WithGrowthFactor(factor int) Option {
+	return func(cfg *config) {
+		cfg.factor = factor
+		cfg.ratio = 0
+	}
+}
+
+// WithFractionalGrowth make chunk sizes grow by a factor which needs not be an integer, e.g. 1.25 or 1.5,
+// each size rounded up to a multiple of granule bytes and at least granule bytes larger than the previous one.
+// Smaller factors bound the internal fragmentation of every class, at the cost of more classes.
+// It replaces WithGrowthFactor and also applies above the linear range of WithHybridGrowth.
+func WithFractionalGrowth(factor float64, granule int) Option {
+	return func(cfg *config) {
+		cfg.ratio = factor
+		cfg.granule = granule
+	}
+}
+
+// WithLinearGrowth make chunk sizes grow by step bytes instead of multiplying by the growth factor,
+// e.g. a step of 512 gives 512, 1024, 1536, ... up to the largest chunk size.
+func WithLinearGrowth(step int) Option {
+	return func(cfg *config) {
+		cfg.step = step
+		cfg.linearMax = 0
+	}
+}
+
+// WithHybridGrowth make chunk sizes grow by step bytes up to linearMax and by the growth factor after that,
+// which keeps the internal fragmentation of small chunks bounded without creating too many large classes.
+func WithHybridGrowth(step, linearMax int) Option {
+	return func(cfg *config) {
+		cfg.step = step
+		cfg.linearMax = linearMax
+	}
+}
+
+// WithPageSize set the memory size of each slab page.
+// Chunk sizes larger than the page size have no slab class, use WithClassPageSize to give large classes larger pages.
+// The end of a page too small for one more chunk is wasted, ClassStats.TailWaste reports it.
+// The default is 1MB.
+func WithPageSize(pageSize int) Option {
+	return func(cfg *config) {
+		cfg.pageSize = pageSize
+	}
+}
+
+// WithClassPageSize set the page size of each slab class by its chunk size, overriding WithPageSize,
+// e.g. 64KB pages for 256B chunks and 1MB pages for 64KB chunks.
+// The size range is not capped by the page size then, NewPool returns an error if a class can't fit one chunk in its page.
+func WithClassPageSize(pageSize func(chunkSize int) int) Option {
+	return func(cfg *config) {
+		cfg.pageSizes = pageSize
+	}
+}
+
+// classPageSize returns the page size of the slab class of chunkSize.
+func (cfg *config) classPageSize(chunkSize int) int {
+	if cfg.pageSizes != nil {
+		return cfg.pageSizes(chunkSize)
+	}
+	return cfg.pageSize
+}
+
+// WithClassSync set the way each slab class synchronizes its free list by its chunk size,
+// e.g. SyncLockFree for small hot classes and SyncMutex for rare huge ones. The default is SyncLockFree for every class.
+func WithClassSync(sync func(chunkSize int) Sync) Option {
+	return func(cfg *config) {
+		cfg.syncs = sync
+	}
+}
+
+// classSync returns the Sync of the slab class of chunkSize.
+func (cfg *config) classSync(chunkSize int) Sync {
+	if cfg.syncs != nil {
+		return cfg.syncs(chunkSize)
+	}
+	return SyncLockFree
+}
+
+// WithClassFreeCap set how many free chunks each slab class retains by its chunk size, 0 for no cap.
+// When the free chunks of a class exceed its cap by more than a page, the class releases its fully free pages
+// until they don't, so a burst doesn't hold its peak memory forever. ClassStats.Spills counts the chunks released this way,
+// ReleaseEvent and TrimEvent report them with TrimSpill. A page with any chunk in use can't be released, nor can the pages of a pinned class.
+func WithClassFreeCap(maxFree func(chunkSize int) int) Option {
+	return func(cfg *config) {
+		cfg.freeCaps = maxFree
+	}
+}
+
+// classFreeCap returns the free chunk cap of the slab class of chunkSize.
+func (cfg *config) classFreeCap(chunkSize int) int {
+	if cfg.freeCaps != nil {
+		return cfg.freeCaps(chunkSize)
+	}
+	return 0
+}
+
+// WithClasses set an explicit list of chunk sizes in ascending order,
+// replacing the classes derived from size range and growth factor and the tiny class of an earlier WithTinyClass.
+func WithClasses(sizes ...int) Option {
+	return func(cfg *config) {
+		cfg.classes = append([]int{}, sizes...)
+		cfg.tiny = 0
+	}
+}
+
+// WithTinyClass add a slab class of size-byte chunks below the smallest chunk size, so tiny requests of at most size bytes,
+// e.g. 8 or 16 byte keys, don't take a chunk of the smallest class each. Without it they are served by the smallest class.
+func WithTinyClass(size int) Option {
+	return func(cfg *config) {
+		cfg.tiny = size
+	}
+}
+
+// WithMaxPages set the maximum number of pages each slab class can own.
+// When a class runs out of free chunks it allocate one more page instead of falling back to make, until n pages exist.
+// The default is 1, which means a class never grows.
+// A class can own at most 2^32-1 chunks over all its pages, NewPool returns an error for larger configurations.
+func WithMaxPages(n int) Option {
+	return func(cfg *config) {
+		cfg.maxPages = n
+	}
+}
+
+// WithGrowIncrement make an exhausted slab class grow by pages pages at once instead of one,
+// so bursty classes grow less often. WithMaxPages and WithMaxMemory still cap the growth,
+// a class grows by fewer pages when it reaches them. ClassStats.Grows counts the grow operations.
+func WithGrowIncrement(pages int) Option {
+	return func(cfg *config) {
+		cfg.growBy = pages
+	}
+}
+
+// WithBackoff set how a goroutine waits after losing a compare-and-swap on the free list of a slab class to another one:
+// it retries spins times at once, then busy waits 1, 2, 4 ... up to pauses rounds before each retry, then yields the processor
+// before every further retry. The default is 0 and 0, yielding after every lost attempt, which may thrash the scheduler
+// on machines with many cores. ClassStats.Retries, ClassStats.Yields and ClassStats.Spins count the retries and the waiting.
+func WithBackoff(spins, pauses int) Option {
+	return func(cfg *config) {
+		cfg.spins = spins
+		cfg.pauses = pauses
+	}
+}
+
+// WithPrealloc set the number of pages each slab class allocate at construction.
+// It can not be larger than the maximum number of pages. The default is 1, 0 is the same as WithLazy.
+func WithPrealloc(n int) Option {
+	return func(cfg *config) {
+		cfg.prealloc = n
+	}
+}
+
+// WithLazy make each slab class allocate its first page on the first Alloc hitting the class instead of at construction,
+// so a wide size range doesn't cost memory for the classes the application never uses.
+func WithLazy() Option {
+	return func(cfg *config) {
+		cfg.prealloc = 0
+	}
+}
+
+// WithMaxMemory set the upper bound of memory in bytes held by all slab pages of the pool.
+// When the budget is reached no slab class can grow, allocations it can't serve fall back to make and are counted.
+// The default is 0, which means no limit.
+func WithMaxMemory(n int) Option {
+	return func(cfg *config) {
+		cfg.maxMemory = n
+	}
+}
+
+// WithStrictBudget make TryAlloc return ErrPoolExhausted instead of falling back to make when the memory budget is reached.
+func WithStrictBudget() Option {
+	return func(cfg *config) {
+		cfg.strict = true
+	}
+}
+
+// WithNoHeap make the pool never fall back to make when it can't serve a request from its slab classes or the buddy tier,
+// because the class is exhausted or the size is too large: TryAlloc returns ErrPoolExhausted and Alloc returns nil.
+// Only growing up to WithMaxPages still allocates slab pages, preallocate them to keep the hot path free of garbage collected allocations.
+// It can't be combined with WithFallback or WithOverflow, whose buffers come from the heap.
+func WithNoHeap() Option {
+	return func(cfg *config) {
+		cfg.noHeap = true
+	}
+}
+
+// WithRealTime bound the worst-case latency of Alloc, TryAlloc and AllocPooled for callers that can't be paused,
+// e.g. an audio or trading loop. It implies WithNoHeap, and every slab class preallocates and touches its WithMaxPages pages
+// at construction and never grows or releases them, so Trim and Reclaim keep the class pages.
+// Popping a chunk gives up after retries failed compare-and-swap attempts instead of looping until it wins:
+// TryAlloc returns ErrWouldBlock and Alloc returns nil, the caller decides to retry later or drop the work.
+// The default is 0, which turns the real-time mode off. It can't be combined with WithFallback, WithOverflow and the options
+// which run code or take locks while allocating: leak detection and sampling, profiles, alloc hooks and tracers,
+// watermarks, FIFO, policy chains, the buddy tier and classes synchronized by a lock with WithClassSync.
+func WithRealTime(retries int) Option {
+	return func(cfg *config) {
+		cfg.realTime = retries
+	}
+}
+
+// WithMmap back slab pages with anonymous mmap regions instead of make([]byte, pageSize),
+// keeping them out of the Go heap. Trim unmaps released pages, so no slice of them can be used after that.
+// On Windows the pages are allocated by VirtualAlloc, on other platforms without mmap by make.
+func WithMmap() Option {
+	return func(cfg *config) {
+		cfg.pages = mmapPages{}
+	}
+}
+
+// WithHugePages back slab pages with huge pages to reduce TLB misses of pools of hundreds of MB, it implies WithMmap.
+// On Linux a page whose size is a multiple of the huge page size is mapped from the reserved huge pages if there are any,
+// other pages are mapped normally and advised to be merged into transparent huge pages.
+// On Windows such a page is allocated from large pages if the process can enable SeLockMemoryPrivilege,
+// large pages are never paged out, other pages are allocated by VirtualAlloc.
+// Where huge pages aren't available the pages are used as if only WithMmap is set.
+func WithHugePages() Option {
+	return func(cfg *config) {
+		cfg.pages = hugePages{}
+	}
+}
+
+// WithPageAllocator set the allocator of the memory of slab pages and buddy arenas, e.g. a cgo allocator or a test fake.
+// The default allocates them by make. A child pool borrows its pages from its parent and doesn't use its allocator.
+func WithPageAllocator(pages PageAllocator) Option {
+	return func(cfg *config) {
+		cfg.pages = pages
+	}
+}
+
+// WithAlignment guarantee the start address of every chunk is a multiple of align,
+// e.g. 64 for cache lines or 4096 for O_DIRECT I/O. align must be a power of 2 and every class size must be a multiple of it.
+func WithAlignment(align int) Option {
+	return func(cfg *config) {
+		cfg.align = align
+	}
+}
+
+// WithLeakDetection record the time and the caller stack of every chunk allocation, so Leaks can report the chunks never freed.
+// It's a debug mode, recording the stack is much slower than the allocation itself.
+func WithLeakDetection() Option {
+	return func(cfg *config) {
+		cfg.leak = true
+	}
+}
+
+// WithDoubleFreeHandler make Free call handler and drop the buffer instead of panicking on double free.
+// The handler can log, count or report the error, e.g. log-and-drop in production.
+func WithDoubleFreeHandler(handler func(mem []byte)) Option {
+	return func(cfg *config) {
+		cfg.onDoubleFree = handler
+	}
+}
+
+// WithAllocHook make the pool call hook on every allocation, e.g. to wire in tracing, accounting or rate limiting.
+// The hook is called synchronously by the allocating goroutine and must be safe for concurrent use.
+// Setting a hook bypasses the magazines of Cache.
+func WithAllocHook(hook func(e AllocEvent)) Option {
+	return func(cfg *config) {
+		cfg.onAlloc = hook
+	}
+}
+
+// WithFreeHook make the pool call hook on every buffer passed to Free, except double frees.
+// Like WithAllocHook it's called synchronously, must be safe for concurrent use and bypasses the magazines of Cache.
+func WithFreeHook(hook func(e AllocEvent)) Option {
+	return func(cfg *config) {
+		cfg.onFree = hook
+	}
+}
+
+// WithReleaseHook make the pool call hook whenever it releases memory, for each slab class and tier,
+// with the bytes released and the reason, e.g. TrimTTL for a Reclaim, so caches built on the pool can update their bookkeeping.
+// The hook is called synchronously by the goroutine releasing the memory, e.g. a reclaimer, and must be safe for concurrent use.
+func WithReleaseHook(hook func(e ReleaseEvent)) Option {
+	return func(cfg *config) {
+		cfg.onRelease = hook
+	}
+}
+
+// WithPageHook set a hook called when a slab class adds a page, before its chunks can be alloc,
+// and when it's about to release a page, after its chunks can no longer be alloc, e.g. to register the pages of the pool
+// as io_uring fixed buffers and unregister them. The hook is called while the class can't grow, it must not alloc from the pool.
+func WithPageHook(hook func(e PageEvent)) Option {
+	return func(cfg *config) {
+		cfg.onPage = hook
+	}
+}
+
+// WithRouting register the slab pages of the pool in a package-level registry, so Route and FreeRouted find the pool
+// of a buffer from its address, e.g. when buffers cross subsystems using different pools. Pools created by a Manager are routed.
+// Adding and releasing a page take a lock shared by every routed pool, and the registry keeps the pool reachable
+// until its pages are released, so close a routed pool when it is no longer used.
+func WithRouting() Option {
+	return func(cfg *config) {
+		cfg.routing = true
+	}
+}
+
+// WithSampling record the allocation stack of one in every rate chunks alloc from each slab class,
+// so Sites can attribute the chunks in use to call sites at a cost low enough for production.
+// Stacks are recorded for every chunk in leak detection mode anyway. Sampling bypasses the magazines of Cache.
+func WithSampling(rate int) Option {
+	return func(cfg *config) {
+		cfg.sample = rate
+	}
+}
+
+// WithTracer make the pool record every allocation and free into tracer.
+// Like the hooks set by WithAllocHook, the tracer bypasses the magazines of Cache.
+func WithTracer(tracer *Tracer) Option {
+	return func(cfg *config) {
+		cfg.tracer = tracer
+	}
+}
+
+// WithoutDebug turn off the debug modes set by the options before it: leak detection, sampling, profiles, guards,
+// poisoning, quarantine, string guards and chaos, e.g. to give AtomPool.Reconfigure the options of a pool without them.
+func WithoutDebug() Option {
+	return func(cfg *config) {
+		cfg.leak = false
+		cfg.sample = 0
+		cfg.profile = nil
+		cfg.guards = false
+		cfg.onCorruption = nil
+		cfg.poison = false
+		cfg.quarantine = 0
+		cfg.stringGuard = false
+		cfg.chaos = false
+	}
+}
+
+// WithProfile record the allocation stack of every chunk in use into the runtime/pprof custom profile named name,
+// e.g. "slab.inuse", so `go tool pprof` can show where the outstanding chunks were allocated.
+// Pools configured with the same name share the profile. It's a debug mode, recording stacks is slow.
+func WithProfile(name string) Option {
+	return func(cfg *config) {
+		cfg.profile = pprof.Lookup(name)
+		if cfg.profile == nil {
+			cfg.profile = pprof.NewProfile(name)
+		}
+	}
+}
+
+// WithPretouch write one byte per OS page of every slab page when the page is allocated,
+// so the kernel materializes the memory up front instead of page faulting the first time each chunk is used.
+// Combine it with WithPrealloc to touch the pages at construction.
+func WithPretouch() Option {
+	return func(cfg *config) {
+		cfg.pretouch = true
+	}
+}
+
+// WithGuards reserve guard bytes before and after each chunk, fill them with a canary pattern and verify them on Free,
+// so writes out of the bounds of a chunk are detected. A trampled guard is reported as a *CorruptionError with the
+// allocation stack of the chunk, Free panics with it unless handler is not nil. It's a debug mode which implies WithLeakDetection.
+func WithGuards(handler func(err error)) Option {
+	return func(cfg *config) {
+		cfg.guards = true
+		cfg.leak = true
+		cfg.onCorruption = handler
+	}
+}
+
+// WithPoison fill every chunk with pattern, e.g. 0xDD, when it is freed,
+// so code reading a chunk after freeing it gets obviously wrong data instead of stale payload.
+// The chunks of new pages are filled too, so Audit can tell a free chunk written after it is freed.
+func WithPoison(pattern byte) Option {
+	return func(cfg *config) {
+		cfg.poison = true
+		cfg.poisonBy = pattern
+	}
+}
+
+// WithQuarantine hold the last n freed chunks of each slab class in a FIFO before they can be alloc again,
+// so code using a chunk after freeing it touches memory no other request owns yet, e.g. poisoned memory with WithPoison.
+// Chunks in quarantine count as free in the statistics, but the pages holding them are not trimmed until Close.
+// It's a debug mode and bypasses the magazines of Cache.
+func WithQuarantine(n int) Option {
+	return func(cfg *config) {
+		cfg.quarantine = n
+	}
+}
+
+// WithFIFO make each slab class reuse the freed chunks in the order they are freed instead of the most recently freed first,
+// so code using a chunk after freeing it is unlikely to read data of a new owner and the wear is spread over all chunks.
+// Freed chunks wait in a queue which is moved to the free list when the free list is empty.
+// It costs a lock on every Free and the cache locality of LIFO reuse, and bypasses the magazines of Cache.
+func WithFIFO() Option {
+	return func(cfg *config) {
+		cfg.fifo = true
+	}
+}
+
+// WithWatermarks make each slab class call fn when the ratio of its chunks in use to the chunks it can own with all its pages
+// rises to high, and again when it falls back to low, e.g. to shed load or Trim before the class is exhausted.
+// Between the two calls the ratio can move freely, so it doesn't fire on every Alloc and Free near a watermark.
+// fn is called synchronously by the goroutine crossing the watermark and must be safe for concurrent use.
+// low must be smaller than high, and high at most 1.
+func WithWatermarks(high, low float64, fn func(e WatermarkEvent)) Option {
+	return func(cfg *config) {
+		cfg.highMark = high
+		cfg.lowMark = low
+		cfg.onMark = fn
+	}
+}
+
+// WithPeaks track the high-water marks of the chunks in use reported by Peaks, which costs an atomic counter update
+// per Alloc and Free. The high-water marks of the resident pages are always tracked.
+func WithPeaks() Option {
+	return func(cfg *config) {
+		cfg.peaks = true
+	}
+}
+
+// WithStringGuard record the chunk and its generation behind every string view made by UnsafeString and AllocString,
+// so CheckString can tell a view whose chunk has been freed since. Combine it with WithPoison and WithQuarantine
+// so the stale view also reads obviously wrong data and its chunk isn't handed out again at once.
+// It's a debug mode, every view takes a lock.
+func WithStringGuard() Option {
+	return func(cfg *config) {
+		cfg.stringGuard = true
+	}
+}
+
+// WithMetadata reserve a word of user metadata for each chunk, set by AtomPool.SetMeta and read by AtomPool.Meta,
+// e.g. the connection owning a buffer, without a parallel map keyed by pointer. It costs 8 bytes per chunk.
+func WithMetadata() Option {
+	return func(cfg *config) {
+		cfg.meta = true
+	}
+}
+
+// WithPolicy set the chain of steps Alloc, TryAlloc and AllocPooled try in order when the slab class serving a request has no free chunk,
+// e.g. WithPolicy(PolicyLargerClass, PolicyGrow, PolicyHeap) to serve a 4KB request from the 8KB class rather than growing or touching the heap.
+// When every step fails, Alloc returns nil and TryAlloc returns ErrPoolExhausted. The default chain is PolicyGrow, PolicyHeap.
+// Other allocation methods like AllocBatch and Cache keep the default chain.
+func WithPolicy(steps ...Policy) Option {
+	return func(cfg *config) {
+		cfg.policy = append([]Policy{}, steps...)
+	}
+}
+
+// WithFit set how Alloc, TryAlloc and AllocPooled choose the slab class of a request, see Fit. The default is FitFirst.
+// FitBest is tried before the chain of WithPolicy. Other allocation methods like AllocBatch and Cache keep FitFirst.
+func WithFit(fit Fit) Option {
+	return func(cfg *config) {
+		cfg.fit = fit
+	}
+}
+
+// WithLatency record the time taken by each call of Alloc, TryAlloc, AllocPooled, Free and TryFree in histograms,
+// reported by AtomPool.Latency and Snapshot, to tell the tail latency of the allocator itself from the rest of a request.
+// It costs two reads of the clock per call.
+func WithLatency() Option {
+	return func(cfg *config) {
+		cfg.latency = true
+	}
+}
+
+// WithSizeHistogram count the sizes requested from the pool in a histogram, reported by AtomPool.SizeHistogram and Snapshot,
+// and used by Adapt to choose the sizes of the new classes. It costs an atomic add on a counter shared by all goroutines per call.
+func WithSizeHistogram() Option {
+	return func(cfg *config) {
+		cfg.sizeHistogram = true
+	}
+}
+
+// WithSecondary set the pool tried by PolicySecondary. Buffers passed to Free which don't belong to the pool are passed to the secondary pool.
+func WithSecondary(pool Pool) Option {
+	return func(cfg *config) {
+		cfg.secondary = pool
+	}
+}
+
+// WithChaos is a testing mode which links the chunks of every new or reset page into the free list in a random order
+// and randomly yields the processor before the CAS of Alloc and Free, so unit tests exercise chunk orders and interleavings
+// beyond the sequential chunk layout. The choices are derived from seed, a test can log it to reproduce a failure.
+func WithChaos(seed int64) Option {
+	return func(cfg *config) {
+		cfg.chaos = true
+		cfg.chaosSeed = seed
+	}
+}
+
+// WithShuffleSeed link the chunks of every new or reset page into the free list in an order shuffled by seed
+// instead of the sequential chunk layout, e.g. to spread the wear of persistent memory or flash backed pages.
+// The free list is still LIFO, combine it with WithFIFO to reuse the freed chunks in the order they are freed.
+func WithShuffleSeed(seed int64) Option {
+	return func(cfg *config) {
+		cfg.shuffle = true
+		cfg.shuffleSeed = seed
+	}
+}
+
+// WithOverflow recycle buffers larger than the largest chunk size, up to maxSize bytes, in an overflow tier
+// of sync.Pools bucketed by powers of 2, instead of making a new buffer for every such allocation.
+// Like any sync.Pool the tier is drained by the garbage collector, use OverflowStats to see how often it is hit.
+func WithOverflow(maxSize int) Option {
+	return func(cfg *config) {
+		cfg.overflow = maxSize
+	}
+}
+
+// WithOverflowCap keep at most n recycled buffers in each bucket of the overflow tier instead of sync.Pools,
+// so they survive garbage collections. Buffers freed into a full bucket are dropped, Trim releases the ones kept.
+func WithOverflowCap(n int) Option {
+	return func(cfg *config) {
+		cfg.overflowCap = n
+	}
+}
+
+// WithBuddy serve buffers larger than the largest chunk size, up to maxBlock bytes, from a buddy allocator tier.
+// The tier owns up to maxArenas arenas of maxBlock bytes, each one is split into power of 2 blocks on demand
+// and freed blocks are coalesced with their buddies, so large buffers of mixed sizes share the same memory.
+// maxBlock must be a power of 2, arenas are counted against the memory budget and released by Trim when they are all free.
+func WithBuddy(maxBlock, maxArenas int) Option {
+	return func(cfg *config) {
+		cfg.buddyMax = maxBlock
+		cfg.buddyArenas = maxArenas
+	}
+}
+
+// WithName register the pool into the package-level registry with name, see RegisterPool,
+// it's unregistered by Close. The shards of a ShardedPool are registered as name/0, name/1, ...
+// and a child pool doesn't inherit the name of its parent.
+func WithName(name string) Option {
+	return func(cfg *config) {
+		cfg.name = name
+	}
+}
+
+// WithNUMANodes set the number of shards NewNUMAPool creates to n instead of the number of NUMA nodes of the machine,
+// shard i is placed on node i modulo the number of nodes. It allows AllocOnNode to address a fixed set of nodes
+// on every machine, including the ones without NUMA information. Other constructors ignore it.
+func WithNUMANodes(n int) Option {
+	return func(cfg *config) {
+		cfg.numaNodes = n
+	}
+}
+
+// WithFile map the pages of the slab classes from the file path instead of allocating them, creating the file if needed.
+// Page n of each class always maps the same range of the file, so after a crash or restart a pool created with
+// the same options finds the data of the buffers at the offsets reported by FileOffset and recovers them with Recover.
+// The page sizes must be multiples of the OS page size. A child pool doesn't inherit the file of its parent.
+func WithFile(path string) Option {
+	return func(cfg *config) {
+		cfg.file = path
+	}
+}
+
+// WithFallback set the function used to alloc memory when the pool can't serve a request,
+// because the size is larger than the largest chunk size or the slab class is exhausted.
+// The default is make([]byte, size).
+func WithFallback(fallback func(size int) []byte) Option {
+	return func(cfg *config) {
+		cfg.fallback = fallback
+	}
+}
+
+// configure 返回 opts 配置的 config，用于在创建 pool 之前读取配置
+func configure(opts []Option) config {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// guardSize returns the number of guard bytes on each side of a chunk.
+func (cfg *config) guardSize() int {
+	if !cfg.guards {
+		return 0
+	}
+	if cfg.align > guardSize {
+		return cfg.align
+	}
+	return guardSize
+}
+
+// classSizes returns the chunk size of each slab class.
+func (cfg *config) classSizes() []int {
+	var sizes []int
+	if cfg.tiny > 0 {
+		sizes = append(sizes, cfg.tiny)
+	}
+	if cfg.classes != nil {
+		return append(sizes, cfg.classes...)
+	}
+	for chunkSize := cfg.minSize; chunkSize <= cfg.maxSize && (cfg.pageSizes != nil || chunkSize <= cfg.pageSize); {
+		sizes = append(sizes, chunkSize)
+		// 下一个大小超过 maxSize 时结束，先比较再计算，避免 maxSize 很大时溢出
+		if cfg.step > 0 && (cfg.linearMax == 0 || chunkSize < cfg.linearMax) {
+			if chunkSize > cfg.maxSize-cfg.step {
+				break
+			}
+			chunkSize += cfg.step
+		} else if cfg.ratio > 0 {
+			next := cfg.grow(chunkSize)
+			if next > cfg.maxSize {
+				break
+			}
+			chunkSize = next
+		} else {
+			if chunkSize > cfg.maxSize/cfg.factor {
+				break
+			}
+			chunkSize *= cfg.factor
+		}
+	}
+	return sizes
+}
+
+// grow 返回 chunkSize 之后按 ratio 增长的下一个大小，向上取整到 granule 的倍数，至少增长一个 granule。
+// validate 保证 maxSize 足够小，计算不会溢出
+func (cfg *config) grow(chunkSize int) int {
+	next := int(math.Ceil(float64(chunkSize) * cfg.ratio))
+	next = (next + cfg.granule - 1) / cfg.granule * cfg.granule
+	return max(next, chunkSize+cfg.granule)
+}
+
+func (cfg *config) validate() error {
+	if cfg.pageSize <= 0 {
+		return fmt.Errorf("slab: invalid page size %d", cfg.pageSize)
+	}
+	if cfg.classes != nil {
+		if len(cfg.classes) == 0 {
+			return fmt.Errorf("slab: empty class list")
+		}
+		for i, size := range cfg.classes {
+			if size <= 0 || size > cfg.classPageSize(size) {
+				return fmt.Errorf("slab: invalid class size %d with page size %d", size, cfg.classPageSize(size))
+			}
+			if i > 0 && size <= cfg.classes[i-1] {
+				return fmt.Errorf("slab: class sizes are not in ascending order")
+			}
+		}
+		cfg.minSize = cfg.classes[0]
+		cfg.maxSize = cfg.classes[len(cfg.classes)-1]
+	} else {
+		if cfg.minSize <= 0 {
+			return fmt.Errorf("slab: invalid min size %d", cfg.minSize)
+		}
+		if cfg.maxSize < cfg.minSize {
+			return fmt.Errorf("slab: max size %d is smaller than min size %d", cfg.maxSize, cfg.minSize)
+		}
+		if cfg.factor < 2 {
+			return fmt.Errorf("slab: invalid growth factor %d", cfg.factor)
+		}
+		if cfg.ratio != 0 && (!(cfg.ratio > 1) || cfg.ratio > 1<<20 || cfg.granule <= 0 || cfg.granule > cfg.maxSize) {
+			return fmt.Errorf("slab: invalid growth factor %g with granule %d", cfg.ratio, cfg.granule)
+		}
+		if cfg.ratio != 0 && float64(cfg.maxSize) > float64(math.MaxInt/2)/cfg.ratio-float64(cfg.granule) {
+			return fmt.Errorf("slab: max size %d is too large", cfg.maxSize)
+		}
+		if cfg.step < 0 || cfg.linearMax < 0 {
+			return fmt.Errorf("slab: invalid linear growth step %d up to %d", cfg.step, cfg.linearMax)
+		}
+		if cfg.maxSize > math.MaxInt/cfg.factor || cfg.maxSize > math.MaxInt-cfg.step {
+			return fmt.Errorf("slab: max size %d is too large", cfg.maxSize)
+		}
+		if cfg.pageSizes == nil && cfg.pageSize < cfg.minSize {
+			return fmt.Errorf("slab: page size %d is smaller than min size %d", cfg.pageSize, cfg.minSize)
+		}
+	}
+	if cfg.tiny < 0 || cfg.tiny > 0 && cfg.tiny >= cfg.minSize {
+		return fmt.Errorf("slab: tiny class size %d is not smaller than min size %d", cfg.tiny, cfg.minSize)
+	}
+	if cfg.align < 0 || cfg.align&(cfg.align-1) != 0 {
+		return fmt.Errorf("slab: alignment %d is not a power of 2", cfg.align)
+	}
+	if cfg.align > 1 {
+		for _, size := range cfg.classSizes() {
+			if size%cfg.align != 0 {
+				return fmt.Errorf("slab: class size %d is not a multiple of alignment %d", size, cfg.align)
+			}
+		}
+	}
+	for _, size := range cfg.classSizes() {
+		pageSize := cfg.classPageSize(size)
+		if cfg.guards && size+2*cfg.guardSize() > pageSize {
+			return fmt.Errorf("slab: class size %d with guards is larger than page size %d", size, pageSize)
+		}
+		if size > pageSize {
+			return fmt.Errorf("slab: class size %d is larger than page size %d", size, pageSize)
+		}
+	}
+	for _, size := range cfg.classSizes() {
+		if n := cfg.classFreeCap(size); n < 0 {
+			return fmt.Errorf("slab: invalid free chunk cap %d of class size %d", n, size)
+		}
+		if sync := cfg.classSync(size); sync < SyncLockFree || sync > SyncChannel {
+			return fmt.Errorf("slab: invalid sync %d of class size %d", sync, size)
+		} else if sync != SyncLockFree && cfg.realTime > 0 {
+			return fmt.Errorf("slab: real-time mode can't be used with a class synchronized by a lock")
+		}
+	}
+	if cfg.maxPages < 1 {
+		return fmt.Errorf("slab: invalid max pages %d", cfg.maxPages)
+	}
+	if cfg.file != "" {
+		if cfg.align > osPageSize {
+			return fmt.Errorf("slab: alignment %d is larger than the OS page size of a file-backed pool", cfg.align)
+		}
+		for _, size := range cfg.classSizes() {
+			if pageSize := cfg.classPageSize(size); pageSize%osPageSize != 0 {
+				return fmt.Errorf("slab: page size %d of a file-backed pool is not a multiple of the OS page size", pageSize)
+			}
+		}
+	}
+	if cfg.pages == nil {
+		return fmt.Errorf("slab: nil page allocator")
+	}
+	if cfg.numaNodes < 0 {
+		return fmt.Errorf("slab: invalid NUMA nodes %d", cfg.numaNodes)
+	}
+	if cfg.growBy < 0 {
+		return fmt.Errorf("slab: invalid grow increment %d", cfg.growBy)
+	}
+	// 链接值只用 32 位保存 chunk 下标，见 link.go
+	for _, size := range cfg.classSizes() {
+		perPage := cfg.classPageSize(size) / (size + 2*cfg.guardSize())
+		if uint64(perPage)*uint64(cfg.maxPages) > maxChunks {
+			return fmt.Errorf("slab: class size %d with %d pages of %d bytes has more than %d chunks", size, cfg.maxPages, cfg.classPageSize(size), uint64(maxChunks))
+		}
+	}
+	if cfg.policy != nil && len(cfg.policy) == 0 {
+		return fmt.Errorf("slab: empty policy chain")
+	}
+	for _, step := range cfg.policy {
+		if step < PolicyGrow || step > PolicyHeap {
+			return fmt.Errorf("slab: invalid policy %d", step)
+		}
+		if step == PolicySecondary && cfg.secondary == nil {
+			return fmt.Errorf("slab: policy chain tries a secondary pool but none is set")
+		}
+	}
+	if cfg.sample < 0 {
+		return fmt.Errorf("slab: invalid sampling rate %d", cfg.sample)
+	}
+	if cfg.onMark != nil && (cfg.lowMark < 0 || cfg.lowMark >= cfg.highMark || cfg.highMark > 1) {
+		return fmt.Errorf("slab: invalid watermarks %v and %v", cfg.highMark, cfg.lowMark)
+	}
+	if cfg.quarantine < 0 {
+		return fmt.Errorf("slab: invalid quarantine size %d", cfg.quarantine)
+	}
+	if cfg.maxMemory < 0 {
+		return fmt.Errorf("slab: invalid max memory %d", cfg.maxMemory)
+	}
+	if cfg.overflow < 0 || cfg.overflow > 0 && cfg.overflow <= cfg.maxSize {
+		return fmt.Errorf("slab: overflow size %d is not larger than max size %d", cfg.overflow, cfg.maxSize)
+	}
+	if cfg.buddyMax != 0 {
+		if cfg.buddyMax <= cfg.maxSize || cfg.buddyMax&(cfg.buddyMax-1) != 0 {
+			return fmt.Errorf("slab: buddy block size %d is not a power of 2 larger than max size %d", cfg.buddyMax, cfg.maxSize)
+		}
+		if cfg.buddyArenas < 1 {
+			return fmt.Errorf("slab: invalid buddy arenas %d", cfg.buddyArenas)
+		}
+	}
+	if cfg.overflowCap < 0 || cfg.overflowCap > 0 && cfg.overflow == 0 {
+		return fmt.Errorf("slab: invalid overflow cap %d without the overflow tier", cfg.overflowCap)
+	}
+	if cfg.noHeap && (cfg.fallback != nil || cfg.overflow > 0) {
+		return fmt.Errorf("slab: no heap mode can't be used with a fallback or the overflow tier")
+	}
+	if cfg.spins < 0 || cfg.pauses < 0 {
+		return fmt.Errorf("slab: invalid backoff %d spins and %d pauses", cfg.spins, cfg.pauses)
+	}
+	if cfg.fit < FitFirst || cfg.fit > FitBest {
+		return fmt.Errorf("slab: invalid fit %d", cfg.fit)
+	}
+	if cfg.realTime < 0 {
+		return fmt.Errorf("slab: invalid real-time retries %d", cfg.realTime)
+	}
+	if cfg.realTime > 0 && (cfg.fallback != nil || cfg.overflow > 0 || cfg.leak || cfg.sample > 0 || cfg.profile != nil || cfg.onAlloc != nil || cfg.tracer != nil ||
+		cfg.onMark != nil || cfg.fifo || cfg.policy != nil || cfg.fit == FitBest || cfg.buddyMax > 0) {
+		return fmt.Errorf("slab: real-time mode can't be used with options that run code or take locks on alloc")
+	}
+	if cfg.prealloc < 0 || cfg.prealloc > cfg.maxPages {
+		return fmt.Errorf("slab: invalid prealloc pages %d with max pages %d", cfg.prealloc, cfg.maxPages)
+	}
+	return nil
+}
@@ -0,0 +1,451 @@
+package slab
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_NewPool_Invalid(t *testing.T) {
+	invalids := [][]Option{
+		{WithGrowthFactor(1)},
+		{WithSizeRange(0, 1024)},
+		{WithSizeRange(1024, 128)},
+		{WithSizeRange(128, 1024), WithPageSize(64)},
+		{WithPageSize(0)},
+		{WithClasses()},
+		{WithClasses(256, 128)},
+		{WithClasses(128, 2048), WithPageSize(1024)},
+		{WithLinearGrowth(-1)},
+		{WithHybridGrowth(64, -1)},
+		{WithFractionalGrowth(1, 8)},
+		{WithFractionalGrowth(math.NaN(), 8)},
+		{WithFractionalGrowth(1.5, 0)},
+		{WithSizeRange(64, math.MaxInt/2), WithFractionalGrowth(1.5, 8), WithClassPageSize(func(size int) int { return size })},
+		{WithMaxPages(0)},
+		{WithClasses(1), WithPageSize(1 << 16), WithMaxPages(1 << 16)},
+		{WithMaxPages(2), WithPrealloc(3)},
+		{WithPrealloc(-1)},
+		{WithQuarantine(-1)},
+		{WithSampling(-1)},
+		{WithPolicy()},
+		{WithPolicy(PolicyHeap + 1)},
+		{WithPolicy(PolicySecondary, PolicyHeap)},
+		{WithSizeRange(128, 1024), WithClassPageSize(func(int) int { return 512 })},
+		{WithClasses(128, 1024), WithClassPageSize(func(size int) int { return size - 1 })},
+		{WithSizeRange(128, 1024), WithClassPageSize(func(size int) int { return size }), WithGuards(nil)},
+		{WithSizeRange(64, math.MaxInt), WithClassPageSize(func(size int) int { return size })},
+		{WithSizeRange(64, math.MaxInt-32), WithLinearGrowth(64), WithClassPageSize(func(size int) int { return size })},
+		{WithSizeRange(128, 1024), WithOverflow(1024)},
+		{WithSizeRange(128, 1024), WithBuddy(1024, 1)},
+		{WithSizeRange(128, 1024), WithBuddy(3000, 1)},
+		{WithSizeRange(128, 1024), WithBuddy(4096, 0)},
+		{WithNoHeap(), WithFallback(func(size int) []byte { return nil })},
+		{WithSizeRange(128, 1024), WithNoHeap(), WithOverflow(4096)},
+		{WithTinyClass(64)},
+		{WithTinyClass(-1)},
+		{WithClasses(16, 128), WithTinyClass(32)},
+	}
+	for _, opts := range invalids {
+		pool, err := NewPool(opts...)
+		utest.IsNilNow(t, pool)
+		utest.NotNilNow(t, err)
+	}
+
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	NewAtomPool(128, 1024, 1, 1024)
+}
+
+func Test_NewPool_Default(t *testing.T) {
+	pool, err := NewPool()
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.classes), 11)
+	utest.EqualNow(t, pool.classes[0].size, 64)
+	utest.EqualNow(t, pool.classes[10].size, 64*1024)
+}
+
+func Test_NewPool_Classes(t *testing.T) {
+	pool, err := NewPool(WithClasses(64, 1500, 9000), WithPageSize(64*1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.classes), 3)
+	utest.EqualNow(t, cap(pool.Alloc(1000)), 1500)
+	utest.EqualNow(t, cap(pool.Alloc(1501)), 9000)
+	utest.EqualNow(t, cap(pool.Alloc(9001)), 9001)
+}
+
+func Test_NewPool_TinyClass(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithTinyClass(16))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.classes), 5)
+	utest.EqualNow(t, cap(pool.Alloc(8)), 16)
+	utest.EqualNow(t, cap(pool.Alloc(17)), 128)
+
+	// without a tiny class tiny requests take a chunk of the smallest class
+	pool, err = NewPool(WithSizeRange(128, 1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, cap(pool.Alloc(8)), 128)
+
+	pool, err = NewPool(WithClasses(64, 1500), WithTinyClass(8))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, cap(pool.Alloc(1)), 8)
+	utest.EqualNow(t, pool.Stats()[1].Size, 64)
+}
+
+func Test_NewPool_Growth(t *testing.T) {
+	sizes := func(pool *AtomPool) []int {
+		var s []int
+		for _, c := range pool.Stats() {
+			s = append(s, c.Size)
+		}
+		return s
+	}
+
+	pool, err := NewPool(WithSizeRange(512, 2048), WithLinearGrowth(512))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[512 1024 1536 2048]")
+
+	pool, err = NewPool(WithSizeRange(256, 8192), WithHybridGrowth(256, 1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[256 512 768 1024 2048 4096 8192]")
+	utest.EqualNow(t, cap(pool.Alloc(700)), 768)
+
+	pool, err = NewPool(WithSizeRange(64, 256), WithFractionalGrowth(1.25, 16))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[64 80 112 144 192 240]")
+	utest.EqualNow(t, cap(pool.Alloc(100)), 112)
+
+	// 增长不足一个 granule 时至少增长一个 granule
+	pool, err = NewPool(WithSizeRange(64, 96), WithFractionalGrowth(1.01, 8))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[64 72 80 88 96]")
+
+	// 线性区间之后按非整数系数增长
+	pool, err = NewPool(WithSizeRange(256, 4096), WithHybridGrowth(256, 1024), WithFractionalGrowth(1.5, 64))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[256 512 768 1024 1536 2304 3456]")
+
+	// WithGrowthFactor 覆盖之前的非整数系数
+	pool, err = NewPool(WithSizeRange(64, 256), WithFractionalGrowth(1.25, 16), WithGrowthFactor(2))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, fmt.Sprint(sizes(pool)), "[64 128 256]")
+}
+
+func Test_NewPool_ClassPageSize(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(256, 64*1024), WithPageSize(4096), WithClassPageSize(func(size int) int {
+		if size <= 1024 {
+			return 64 * 1024
+		}
+		return 1024 * 1024
+	}))
+	utest.IsNilNow(t, err)
+	stats := pool.Stats()
+	utest.EqualNow(t, len(stats), 9)
+	utest.EqualNow(t, stats[0].PageSize, 64*1024)
+	utest.EqualNow(t, stats[2].PageSize, 64*1024)
+	utest.EqualNow(t, stats[3].PageSize, 1024*1024)
+	utest.EqualNow(t, stats[8].Size, 64*1024)
+	utest.EqualNow(t, stats[8].Resident, 1024*1024)
+
+	temp := pool.AllocBatch(64*1024, 16)
+	for _, mem := range temp {
+		utest.Assert(t, pool.Owns(mem))
+	}
+	utest.EqualNow(t, pool.Stats()[8].Free, 0)
+	pool.FreeBatch(temp)
+	utest.Assert(t, pool.Owns(pool.Alloc(256)))
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_config_classSizes(t *testing.T) {
+	// 接近 math.MaxInt 的 maxSize 不会让下一个大小溢出
+	cfg := config{minSize: 64, maxSize: math.MaxInt, factor: 2, pageSizes: func(size int) int { return size }}
+	sizes := cfg.classSizes()
+	utest.EqualNow(t, len(sizes), bits.UintSize-7)
+	utest.EqualNow(t, sizes[len(sizes)-1], math.MaxInt/2+1)
+
+	cfg = config{minSize: 64, maxSize: math.MaxInt - 32, step: 1 << (bits.UintSize - 4), factor: 2, pageSizes: cfg.pageSizes}
+	sizes = cfg.classSizes()
+	utest.EqualNow(t, len(sizes), 8)
+	utest.Assert(t, sizes[len(sizes)-1] > 0)
+}
+
+func Test_NewPool_Lazy(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithLazy())
+	utest.IsNilNow(t, err)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 0)
+		utest.EqualNow(t, s.Free, 0)
+	}
+	mem := pool.Alloc(200)
+	utest.Assert(t, pool.Owns(mem))
+	utest.EqualNow(t, pool.Stats()[1].Pages, 1)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+	utest.EqualNow(t, pool.TryFree(make([]byte, 128)), ErrNotPooled)
+	utest.IsNilNow(t, pool.TryFree(mem))
+}
+
+func Test_NewPool_MaxChunks(t *testing.T) {
+	// 65536*65535 chunks is just below the limit of a class
+	pool, err := NewPool(WithClasses(1), WithPageSize(1<<16), WithMaxPages(1<<16-1), WithLazy())
+	utest.IsNilNow(t, err)
+	mem := pool.Alloc(1)
+	utest.Assert(t, pool.Owns(mem))
+	utest.IsNilNow(t, pool.TryFree(mem))
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_NewPool_GrowIncrement(t *testing.T) {
+	_, err := NewPool(WithGrowIncrement(-1))
+	utest.NotNilNow(t, err)
+
+	// 每个 page 只有一个 chunk，第一次增长增加 3 个 page，第二次受 WithMaxPages 限制只增加 1 个
+	pool, err := NewPool(WithClasses(1024), WithPageSize(1024), WithMaxPages(5), WithGrowIncrement(3))
+	utest.IsNilNow(t, err)
+	var bufs [][]byte
+	for i := 0; i < 4; i++ {
+		bufs = append(bufs, pool.Alloc(1024))
+	}
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Pages, 4)
+	utest.EqualNow(t, s.Grows, uint64(1))
+	utest.EqualNow(t, s.Free, 0)
+
+	bufs = append(bufs, pool.Alloc(1024))
+	s = pool.Stats()[0]
+	utest.EqualNow(t, s.Pages, 5)
+	utest.EqualNow(t, s.Grows, uint64(2))
+	pool.Alloc(1024)
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(1))
+	pool.FreeBatch(bufs)
+	utest.IsNilNow(t, pool.Verify())
+
+	// 内存预算同样限制增长
+	pool, err = NewPool(WithClasses(1024), WithPageSize(1024), WithMaxPages(8), WithMaxMemory(2048), WithGrowIncrement(4))
+	utest.IsNilNow(t, err)
+	pool.Alloc(1024)
+	pool.Alloc(1024)
+	s = pool.Stats()[0]
+	utest.EqualNow(t, s.Pages, 2)
+	utest.EqualNow(t, s.Grows, uint64(1))
+}
+
+func Test_NewPool_ShuffleSeed(t *testing.T) {
+	order := func(pool *AtomPool) []int {
+		var idx []int
+		base := dataPtr(pool.classes[0].page(0).mem)
+		for i := 0; i < 16; i++ {
+			idx = append(idx, int(dataPtr(pool.Alloc(128))-base)/128)
+		}
+		return idx
+	}
+	newPool := func(opts ...Option) *AtomPool {
+		pool, err := NewPool(append([]Option{WithClasses(128), WithPageSize(16 * 128)}, opts...)...)
+		utest.IsNilNow(t, err)
+		return pool
+	}
+	sequential := order(newPool())
+	for i, v := range sequential {
+		utest.EqualNow(t, v, i)
+	}
+	pool := newPool(WithShuffleSeed(1))
+	shuffled := order(pool)
+	utest.Assert(t, fmt.Sprint(shuffled) != fmt.Sprint(sequential))
+	utest.EqualNow(t, fmt.Sprint(order(newPool(WithShuffleSeed(1)))), fmt.Sprint(shuffled))
+
+	// Reset 之后重新打乱
+	pool.Reset()
+	reset := order(pool)
+	utest.Assert(t, fmt.Sprint(reset) != fmt.Sprint(sequential))
+	utest.Assert(t, fmt.Sprint(reset) != fmt.Sprint(shuffled))
+
+	// LIFO 空闲链表总是复用同一个 chunk，打乱并经过隔离区之后每个 chunk 被复用的次数相同
+	wear := func(pool *AtomPool) (min, max int) {
+		count := map[uintptr]int{}
+		for i := 0; i < 1600; i++ {
+			mem := pool.Alloc(128)
+			count[dataPtr(mem)]++
+			pool.Free(mem)
+		}
+		min = 1600
+		for _, n := range count {
+			if n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+		}
+		if len(count) < 16 {
+			min = 0
+		}
+		return
+	}
+	min, max := wear(newPool())
+	utest.EqualNow(t, min, 0)
+	utest.EqualNow(t, max, 1600)
+	min, max = wear(newPool(WithShuffleSeed(1), WithQuarantine(15)))
+	utest.EqualNow(t, min, 100)
+	utest.EqualNow(t, max, 100)
+}
+
+func Test_NewPool_Prealloc(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithMaxPages(4), WithPrealloc(2))
+	utest.IsNilNow(t, err)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 2)
+	}
+}
+
+func Test_NewPool_Pretouch(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(4096), WithMaxPages(4), WithPrealloc(2), WithMmap(), WithPretouch())
+	utest.IsNilNow(t, err)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 2)
+	}
+	for i := range pool.classes {
+		utest.Assert(t, pool.classes[i].pretouch)
+	}
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, mem[0], byte(0))
+	utest.IsNilNow(t, pool.TryFree(mem))
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_NewPool_Fallback(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithFallback(func(size int) []byte {
+		return nil
+	}))
+	utest.IsNilNow(t, err)
+	utest.IsNilNow(t, pool.Alloc(2048))
+	utest.NotNilNow(t, pool.Alloc(1024))
+	utest.IsNilNow(t, pool.Alloc(1024))
+}
+
+func Test_NewPool_MaxMemory(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithMaxPages(4), WithMaxMemory(5*1024))
+	utest.IsNilNow(t, err)
+
+	// 4 classes own 4 pages, only one more page can be added
+	temp := make([][]byte, 16)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+		utest.EqualNow(t, cap(temp[i]), 128)
+	}
+	stats := pool.Stats()
+	utest.EqualNow(t, stats[0].Pages, 2)
+	utest.EqualNow(t, stats[0].Fallbacks, uint64(0))
+
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, len(mem), 128)
+	mem, err = pool.TryAlloc(128)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(mem), 128)
+
+	for i := 0; i < 2; i++ {
+		pool.Alloc(256)
+	}
+	stats = pool.Stats()
+	utest.EqualNow(t, stats[0].Fallbacks, uint64(2))
+	utest.EqualNow(t, stats[0].OverBudget, uint64(2))
+	utest.EqualNow(t, stats[1].Pages, 1)
+	utest.EqualNow(t, stats[1].OverBudget, uint64(0))
+
+	_, err = NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithMaxMemory(3*1024))
+	utest.NotNilNow(t, err)
+}
+
+func Test_NewPool_StrictBudget(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithMaxPages(4), WithMaxMemory(4*1024), WithStrictBudget())
+	utest.IsNilNow(t, err)
+	for i := 0; i < 8; i++ {
+		pool.Alloc(128)
+	}
+	mem, err := pool.TryAlloc(128)
+	utest.IsNilNow(t, mem)
+	utest.Assert(t, err == ErrPoolExhausted)
+
+	// Alloc still falls back to make
+	utest.EqualNow(t, len(pool.Alloc(128)), 128)
+
+	// sizes larger than the largest class are not limited by the budget
+	mem, err = pool.TryAlloc(2048)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(mem), 2048)
+}
+
+func Test_NewPool_NoHeap(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithNoHeap())
+	utest.IsNilNow(t, err)
+	temp := make([][]byte, 8)
+	for i := range temp {
+		temp[i] = pool.Alloc(128)
+		utest.EqualNow(t, len(temp[i]), 128)
+	}
+	utest.IsNilNow(t, pool.Alloc(128))
+	mem, err := pool.TryAlloc(128)
+	utest.IsNilNow(t, mem)
+	utest.Assert(t, err == ErrPoolExhausted)
+	mem, err = pool.TryAlloc(2048)
+	utest.IsNilNow(t, mem)
+	utest.Assert(t, err == ErrPoolExhausted)
+	utest.IsNilNow(t, pool.AllocAligned(2048, 8))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		pool.Free(temp[0])
+		temp[0] = pool.Alloc(100)
+		pool.Alloc(128)
+		pool.Alloc(4096)
+	})
+	utest.EqualNow(t, allocs, 0.0)
+}
+
+func Test_NewPool_Mmap(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(4096), WithMaxPages(2), WithMmap())
+	utest.IsNilNow(t, err)
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 128)
+	for i := range mem {
+		mem[i] = byte(i)
+	}
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Stats()[0].Frees, uint64(1))
+	utest.EqualNow(t, pool.Trim(), 4*4096)
+	utest.EqualNow(t, cap(pool.Alloc(128)), 128)
+}
+
+func Test_NewPool_HugePages(t *testing.T) {
+	// 有没有大页可用都能正常分配和释放
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(2<<20), WithMaxPages(2), WithHugePages())
+	utest.IsNilNow(t, err)
+	utest.Assert(t, isMmap(pool.src.pages))
+	mem := pool.Alloc(1024)
+	utest.Assert(t, pool.Owns(mem))
+	memset(mem, 1)
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Trim(), 4*(2<<20))
+	utest.IsNilNow(t, pool.Verify())
+
+	pool, err = NewPool(WithSizeRange(128, 1024), WithPageSize(4096), WithHugePages())
+	utest.IsNilNow(t, err)
+	utest.Assert(t, pool.Owns(pool.Alloc(128)))
+}
+
+func Test_NewPool_Alignment(t *testing.T) {
+	_, err := NewPool(WithAlignment(48))
+	utest.NotNilNow(t, err)
+	_, err = NewPool(WithClasses(64, 1500), WithAlignment(64))
+	utest.NotNilNow(t, err)
+
+	pool, err := NewPool(WithSizeRange(4096, 16384), WithPageSize(64*1024), WithAlignment(4096))
+	utest.IsNilNow(t, err)
+	for _, size := range []int{100, 4096, 5000, 16384} {
+		mem := pool.Alloc(size)
+		utest.Assert(t, isAligned(mem, 4096))
+		utest.Assert(t, pool.classes[0].size <= cap(mem))
+	}
+}
@@ -0,0 +1,147 @@
+package slab
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// overflow 为大于 maxSize 的请求提供按 2 的幂分级的 sync.Pool，使偶尔出现的大 buffer 也能被复用，
+// limit 大于 0 时每一级改为最多保存 limit 个 buffer 的栈，不会被 GC 清空，由 Trim 释放
+type overflow struct {
+	buckets []*overflowBucket
+	limit   int
+}
+
+type overflowBucket struct {
+	// 统计计数
+	allocs atomic.Uint64
+	misses atomic.Uint64
+	frees  atomic.Uint64
+	drops  atomic.Uint64
+
+	size int
+	pool sync.Pool
+	mu   sync.Mutex
+	free [][]byte // limit 大于 0 时回收的 buffer
+}
+
+// newOverflow 创建覆盖 (minSize, maxSize] 的 overflow 层，第一级是大于 minSize 的最小的 2 的幂
+func newOverflow(minSize, maxSize, limit int) *overflow {
+	o := &overflow{limit: limit}
+	size := 1
+	for size <= minSize {
+		size <<= 1
+	}
+	for ; size < maxSize*2 && size > 0; size <<= 1 {
+		o.buckets = append(o.buckets, &overflowBucket{size: size})
+	}
+	return o
+}
+
+// alloc 从能容纳 size 的最小一级分配，超出所有级别时返回 nil
+func (o *overflow) alloc(size int) []byte {
+	for _, b := range o.buckets {
+		if b.size >= size {
+			b.allocs.Add(1)
+			if o.limit > 0 {
+				if mem := b.pop(); mem != nil {
+					return mem[:size]
+				}
+			} else if mem, ok := b.pool.Get().(*[]byte); ok {
+				return (*mem)[:size]
+			}
+			b.misses.Add(1)
+			return make([]byte, size, b.size)
+		}
+	}
+	return nil
+}
+
+// free 回收容量恰好等于某一级大小的 buffer，返回是否回收
+func (o *overflow) free(mem []byte) bool {
+	size := cap(mem)
+	for _, b := range o.buckets {
+		if b.size == size {
+			mem = mem[:size]
+			b.frees.Add(1)
+			if o.limit == 0 {
+				b.pool.Put(&mem)
+				return true
+			}
+			b.mu.Lock()
+			if len(b.free) < o.limit {
+				b.free = append(b.free, mem)
+			} else {
+				// 已达上限，交给 GC
+				b.drops.Add(1)
+			}
+			b.mu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
+// pop 取出一个回收的 buffer，没有时返回 nil
+func (b *overflowBucket) pop() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.free)
+	if n == 0 {
+		return nil
+	}
+	mem := b.free[n-1]
+	b.free[n-1] = nil
+	b.free = b.free[:n-1]
+	return mem
+}
+
+// cached 返回保存着的回收的 buffer 数
+func (b *overflowBucket) cached() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.free)
+}
+
+// trim 丢弃所有回收的 buffer，返回丢弃的字节数
+func (o *overflow) trim() int {
+	released := 0
+	for _, b := range o.buckets {
+		b.mu.Lock()
+		released += len(b.free) * b.size
+		clear(b.free)
+		b.free = b.free[:0]
+		b.mu.Unlock()
+	}
+	return released
+}
+
+// OverflowStats is the statistics of a bucket of the overflow tier.
+type OverflowStats struct {
+	Size   int    // buffer size of the bucket
+	Allocs uint64 // allocations served by the bucket
+	Misses uint64 // allocations the bucket made because no recycled buffer was available
+	Frees  uint64 // buffers returned to the bucket by Free
+	Drops  uint64 // buffers returned by Free and dropped because the bucket is full, see WithOverflowCap
+	Cached int    // recycled buffers held by the bucket, 0 without WithOverflowCap
+}
+
+// OverflowStats returns the statistics of each bucket of the overflow tier,
+// or nil if the pool is created without WithOverflow.
+func (pool *AtomPool) OverflowStats() []OverflowStats {
+	if pool.overflow == nil {
+		return nil
+	}
+	stats := make([]OverflowStats, len(pool.overflow.buckets))
+	for i, b := range pool.overflow.buckets {
+		stats[i] = OverflowStats{
+			Size:   b.size,
+			Allocs: b.allocs.Load(),
+			Misses: b.misses.Load(),
+			Frees:  b.frees.Load(),
+			Drops:  b.drops.Load(),
+			Cached: b.cached(),
+		}
+	}
+	return stats
+}
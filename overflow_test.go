@@ -0,0 +1,63 @@
+package slab
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Overflow(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithOverflow(5000))
+	stats := pool.OverflowStats()
+	utest.EqualNow(t, len(stats), 3)
+	utest.EqualNow(t, stats[0].Size, 2048)
+	utest.EqualNow(t, stats[2].Size, 8192)
+
+	mem := pool.Alloc(3000)
+	utest.EqualNow(t, len(mem), 3000)
+	utest.EqualNow(t, cap(mem), 4096)
+	utest.IsNilNow(t, pool.TryFree(mem))
+
+	stats = pool.OverflowStats()
+	utest.EqualNow(t, stats[1].Allocs, uint64(1))
+	utest.EqualNow(t, stats[1].Misses, uint64(1))
+	utest.EqualNow(t, stats[1].Frees, uint64(1))
+
+	// larger than the overflow tier
+	mem = pool.Alloc(9000)
+	utest.EqualNow(t, cap(mem), 9000)
+	utest.EqualNow(t, pool.TryFree(mem), ErrNotPooled)
+}
+
+func Test_AtomPool_OverflowCap(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithOverflow(5000), WithOverflowCap(2))
+	bufs := [][]byte{pool.Alloc(3000), pool.Alloc(3000), pool.Alloc(4096)}
+	for _, mem := range bufs {
+		utest.IsNilNow(t, pool.TryFree(mem))
+	}
+	s := pool.OverflowStats()[1]
+	utest.EqualNow(t, s.Frees, uint64(3))
+	utest.EqualNow(t, s.Drops, uint64(1))
+	utest.EqualNow(t, s.Cached, 2)
+
+	// the kept buffers survive garbage collections
+	runtime.GC()
+	mem := pool.Alloc(2500)
+	utest.EqualNow(t, dataPtr(mem), dataPtr(bufs[1]))
+	utest.EqualNow(t, pool.OverflowStats()[1].Misses, uint64(3))
+
+	// the pages of the 4 slab classes and the buffer kept
+	utest.EqualNow(t, pool.Trim(), 4*1024+4096)
+	utest.EqualNow(t, pool.OverflowStats()[1].Cached, 0)
+	pool.Free(mem)
+
+	_, err := NewPool(WithOverflowCap(2))
+	utest.NotNilNow(t, err)
+}
+
+func Test_AtomPool_NoOverflow(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	utest.IsNilNow(t, pool.OverflowStats())
+	utest.EqualNow(t, pool.TryFree(make([]byte, 2048)), ErrNotPooled)
+}
@@ -0,0 +1,49 @@
+package slab
+
+// PageAllocator provides the memory of slab pages and buddy arenas to a pool, see WithPageAllocator.
+// AllocPage returns size bytes, FreePage gets back the pages released by Trim and Close,
+// which are no longer used by the pool. Both may be called concurrently.
+type PageAllocator interface {
+	AllocPage(size int) ([]byte, error)
+	FreePage(page []byte)
+}
+
+// heapPages 是默认的 PageAllocator，page 由 make 分配，释放后交给垃圾回收
+type heapPages struct{}
+
+func (heapPages) AllocPage(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+func (heapPages) FreePage(page []byte) {}
+
+// mmapPages 是 WithMmap 的 PageAllocator，page 是匿名映射的内存
+type mmapPages struct{}
+
+func (mmapPages) AllocPage(size int) ([]byte, error) {
+	return mmapPage(size)
+}
+
+func (mmapPages) FreePage(page []byte) {
+	munmapPage(page)
+}
+
+// hugePages 是 WithHugePages 的 PageAllocator
+type hugePages struct{}
+
+func (hugePages) AllocPage(size int) ([]byte, error) {
+	return mmapHuge(size)
+}
+
+func (hugePages) FreePage(page []byte) {
+	munmapPage(page)
+}
+
+// isMmap 判断 pages 分配的 page 是否在 Go 的堆以外
+func isMmap(pages PageAllocator) bool {
+	switch pages.(type) {
+	case mmapPages, hugePages, nodePages:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package slab
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+type fakePages struct {
+	mu    sync.Mutex
+	pages map[*byte]int
+	limit int
+}
+
+func (f *fakePages) AllocPage(size int) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.pages) >= f.limit {
+		return nil, errors.New("out of pages")
+	}
+	page := make([]byte, size)
+	f.pages[&page[0]] = size
+	return page, nil
+}
+
+func (f *fakePages) FreePage(page []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.pages, &page[0])
+}
+
+func Test_NewPool_PageAllocator(t *testing.T) {
+	_, err := NewPool(WithPageAllocator(nil))
+	utest.NotNilNow(t, err)
+
+	pages := &fakePages{pages: make(map[*byte]int), limit: 1}
+	_, err = NewPool(WithSizeRange(128, 256), WithPageSize(1024), WithPageAllocator(pages))
+	utest.NotNilNow(t, err)
+	utest.EqualNow(t, len(pages.pages), 0)
+
+	pages.limit = 3
+	pool, err := NewPool(WithClasses(128), WithPageSize(1024), WithMaxPages(4), WithPageAllocator(pages))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pages.pages), 1)
+
+	// the class grows with pages of the allocator until it fails, then falls back to the heap
+	bufs := pool.AllocBatch(128, 32)
+	utest.EqualNow(t, len(pages.pages), 3)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(8))
+
+	// released pages are returned to the allocator
+	pool.FreeBatch(bufs)
+	utest.EqualNow(t, pool.Trim(), 3*1024)
+	utest.EqualNow(t, len(pages.pages), 0)
+	utest.EqualNow(t, cap(pool.Alloc(100)), 128)
+	utest.EqualNow(t, len(pages.pages), 1)
+}
@@ -0,0 +1,121 @@
+package slab
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Peak is a high-water mark and when it was reached.
+type Peak struct {
+	Value int
+	Time  time.Time // zero if the value has never been above 0
+}
+
+// ClassPeaks is the high-water marks of a slab class since the pool is created or ResetPeaks is called.
+type ClassPeaks struct {
+	Size     int  // chunk size of the class
+	InUse    Peak // chunks in use, tracked only by a pool created WithPeaks
+	Resident Peak // bytes of pages owned by the class
+}
+
+// Peaks is the high-water marks of a pool since it's created or ResetPeaks is called, returned by AtomPool.Peaks.
+type Peaks struct {
+	InUse    Peak // bytes of the chunks in use in all slab classes, tracked only by a pool created WithPeaks
+	Resident Peak // bytes of the pages owned by all slab classes
+	Classes  []ClassPeaks
+}
+
+// peak 记录一个计数的最大值和达到最大值的时间
+type peak struct {
+	max atomic.Int64
+	at  atomic.Int64 // UnixNano，0 表示没有记录
+}
+
+// update 在 n 超过最大值时记录 n
+func (p *peak) update(n int64) {
+	for {
+		m := p.max.Load()
+		if n <= m {
+			return
+		}
+		if p.max.CompareAndSwap(m, n) {
+			p.at.Store(time.Now().UnixNano())
+			return
+		}
+	}
+}
+
+// reset 把最大值重置为当前值 n
+func (p *peak) reset(n int64) {
+	p.max.Store(n)
+	if n > 0 {
+		p.at.Store(time.Now().UnixNano())
+	} else {
+		p.at.Store(0)
+	}
+}
+
+func (p *peak) load() Peak {
+	v := Peak{Value: int(p.max.Load())}
+	if at := p.at.Load(); at != 0 {
+		v.Time = time.Unix(0, at)
+	}
+	return v
+}
+
+// usage 是 pool 所有 class 合计的已分配字节数和 page 字节数及其峰值
+type usage struct {
+	inUse        atomic.Int64
+	resident     atomic.Int64
+	peakInUse    peak
+	peakResident peak
+}
+
+// used 在 class 的已分配 chunk 数变化 delta 之后更新计数和峰值，并检查水位线
+func (c *class) used(delta int) {
+	if !c.counting {
+		return
+	}
+	n := c.inUse.Add(int64(delta))
+	bytes := c.usage.inUse.Add(int64(delta * c.size))
+	if delta > 0 {
+		c.peakInUse.update(n)
+		c.usage.peakInUse.update(bytes)
+	}
+	c.watermark(int(n))
+}
+
+// resized 在 class 的 page 数变化 delta 之后更新 page 字节数的峰值
+func (c *class) resized(delta int) {
+	n := atomic.AddInt32(&c.npages, int32(delta))
+	bytes := c.usage.resident.Add(int64(delta * c.pageSize))
+	if delta > 0 {
+		c.peakPages.update(int64(n) * int64(c.pageSize))
+		c.usage.peakResident.update(bytes)
+	}
+}
+
+// Peaks returns the high-water marks of the pool and each slab class.
+func (pool *AtomPool) Peaks() Peaks {
+	p := Peaks{
+		InUse:    pool.usage.peakInUse.load(),
+		Resident: pool.usage.peakResident.load(),
+		Classes:  make([]ClassPeaks, len(pool.classes)),
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		p.Classes[i] = ClassPeaks{Size: c.size, InUse: c.peakInUse.load(), Resident: c.peakPages.load()}
+	}
+	return p
+}
+
+// ResetPeaks starts the high-water marks again from the current values, e.g. at the start of a capacity planning window.
+func (pool *AtomPool) ResetPeaks() {
+	pool.usage.peakInUse.reset(pool.usage.inUse.Load())
+	pool.usage.peakResident.reset(pool.usage.resident.Load())
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		c.peakInUse.reset(c.inUse.Load())
+		c.peakPages.reset(int64(atomic.LoadInt32(&c.npages)) * int64(c.pageSize))
+	}
+}
@@ -0,0 +1,67 @@
+package slab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Peaks(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithPeaks())
+	start := time.Now()
+	p := pool.Peaks()
+	utest.EqualNow(t, p.InUse.Value, 0)
+	utest.Assert(t, p.InUse.Time.IsZero())
+	utest.EqualNow(t, p.Resident.Value, 4*1024)
+	utest.EqualNow(t, len(p.Classes), 4)
+
+	var bufs [][]byte
+	for i := 0; i < 20; i++ {
+		bufs = append(bufs, pool.Alloc(128))
+	}
+	bufs = append(bufs, pool.Alloc(1024))
+	pool.FreeBatch(bufs)
+	pool.Trim()
+
+	p = pool.Peaks()
+	utest.EqualNow(t, p.InUse.Value, 20*128+1024)
+	utest.Assert(t, !p.InUse.Time.Before(start))
+	utest.EqualNow(t, p.Resident.Value, 6*1024)
+	utest.EqualNow(t, p.Classes[0].Size, 128)
+	utest.EqualNow(t, p.Classes[0].InUse.Value, 20)
+	utest.EqualNow(t, p.Classes[0].Resident.Value, 3*1024)
+	utest.EqualNow(t, p.Classes[3].InUse.Value, 1)
+	utest.EqualNow(t, p.Classes[1].InUse.Value, 0)
+
+	// the peaks start again from the current values
+	mem := pool.Alloc(256)
+	pool.ResetPeaks()
+	p = pool.Peaks()
+	utest.EqualNow(t, p.InUse.Value, 256)
+	utest.EqualNow(t, p.Resident.Value, 1024)
+	utest.EqualNow(t, p.Classes[0].InUse.Value, 0)
+	utest.Assert(t, p.Classes[0].InUse.Time.IsZero())
+	utest.EqualNow(t, p.Classes[1].InUse.Value, 1)
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Peaks().InUse.Value, 256)
+}
+
+func Test_AtomPool_PeaksReset(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithPeaks())
+	pool.Alloc(128)
+	pool.Reset()
+	pool.ResetPeaks()
+	utest.EqualNow(t, pool.Peaks().InUse.Value, 0)
+	utest.EqualNow(t, pool.Peaks().Classes[0].InUse.Value, 0)
+}
+
+func Test_AtomPool_PeaksResident(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	bufs := pool.AllocBatch(128, 10)
+	pool.FreeBatch(bufs)
+	p := pool.Peaks()
+	utest.EqualNow(t, p.InUse.Value, 0)
+	utest.EqualNow(t, p.Resident.Value, 5*1024)
+	utest.EqualNow(t, p.Classes[0].Resident.Value, 2*1024)
+}
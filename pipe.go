@@ -0,0 +1,185 @@
+package slab
+
+import (
+	"io"
+	"sync"
+)
+
+// pipe 是 PipeReader 和 PipeWriter 共享的状态，缓冲的数据保存在从 pool 分配的 chunk 队列中
+type pipe struct {
+	mu        sync.Mutex
+	cond      sync.Cond
+	pool      Pool
+	chunkSize int
+	depth     int
+	chunks    [][]byte // 每个 chunk 的长度为已写入的字节数
+	off       int      // chunks[0] 中已读取的字节数
+	rerr      error    // 读端关闭的原因，写入时返回
+	werr      error    // 写端关闭的原因，缓冲的数据读完之后返回
+}
+
+// PipeReader is the read half of a pipe created by NewPipe.
+type PipeReader struct{ p *pipe }
+
+// PipeWriter is the write half of a pipe created by NewPipe.
+type PipeWriter struct{ p *pipe }
+
+// NewPipe create a synchronous in-memory pipe like io.Pipe, but buffered by up to depth chunks of chunkSize bytes
+// alloc from pool: Write returns once the data is copied into the chunks and only blocks when depth chunks are full,
+// and each chunk is returned to the pool as soon as the reader has consumed it.
+// Write returns ErrPoolExhausted if the pool returns nil. The halves are safe for concurrent use like io.Pipe.
+func NewPipe(pool Pool, chunkSize, depth int) (*PipeReader, *PipeWriter) {
+	p := &pipe{pool: pool, chunkSize: chunkSize, depth: max(depth, 1)}
+	p.cond.L = &p.mu
+	return &PipeReader{p}, &PipeWriter{p}
+}
+
+// Read reads the buffered data, blocking until some is written or the write half is closed.
+// At the end of the data it returns the error passed to CloseWithError of the writer, io.EOF when it's closed by Close.
+func (r *PipeReader) Read(b []byte) (int, error) {
+	p := r.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if p.rerr != nil {
+			return 0, io.ErrClosedPipe
+		}
+		if p.buffered() > 0 || len(b) == 0 {
+			break
+		}
+		if p.werr != nil {
+			p.release()
+			return 0, p.werr
+		}
+		p.cond.Wait()
+	}
+	n := 0
+	for n < len(b) && len(p.chunks) > 0 {
+		m := copy(b[n:], p.chunks[0][p.off:])
+		n += m
+		p.off += m
+		if p.off < len(p.chunks[0]) {
+			break
+		}
+		if len(p.chunks) == 1 && len(p.chunks[0]) < cap(p.chunks[0]) {
+			// 最后一个 chunk 还有空间，留给之后的写入
+			p.chunks[0] = p.chunks[0][:0]
+			p.off = 0
+			break
+		}
+		// 队列最多 depth 个 chunk，前移而不是重新切片，append 才不会重新分配
+		p.pool.Free(p.chunks[0])
+		k := copy(p.chunks, p.chunks[1:])
+		p.chunks[k] = nil
+		p.chunks = p.chunks[:k]
+		p.off = 0
+	}
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// Buffered returns the number of bytes written and not read yet.
+func (r *PipeReader) Buffered() int {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+	return r.p.buffered()
+}
+
+// Close closes the reader, later writes return io.ErrClosedPipe. The buffered chunks are returned to the pool.
+func (r *PipeReader) Close() error {
+	return r.CloseWithError(nil)
+}
+
+// CloseWithError closes the reader like Close, later writes return err, or io.ErrClosedPipe if err is nil.
+func (r *PipeReader) CloseWithError(err error) error {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p := r.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rerr == nil {
+		p.rerr = err
+	}
+	p.release()
+	p.cond.Broadcast()
+	return nil
+}
+
+// Write copies b into the chunks of the pipe, blocking while depth chunks are full.
+// It returns the error passed to CloseWithError of the reader, io.ErrClosedPipe if the reader or the writer is closed.
+func (w *PipeWriter) Write(b []byte) (int, error) {
+	p := w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for n < len(b) {
+		if p.werr != nil {
+			return n, io.ErrClosedPipe
+		}
+		if p.rerr != nil {
+			return n, p.rerr
+		}
+		last := len(p.chunks) - 1
+		if last < 0 || len(p.chunks[last]) == cap(p.chunks[last]) {
+			if len(p.chunks) >= p.depth {
+				p.cond.Wait()
+				continue
+			}
+			mem := p.pool.Alloc(p.chunkSize)
+			if cap(mem) == 0 {
+				return n, ErrPoolExhausted
+			}
+			p.chunks = append(p.chunks, mem[:0])
+			last++
+		}
+		c := p.chunks[last]
+		m := copy(c[len(c):cap(c)], b[n:])
+		p.chunks[last] = c[:len(c)+m]
+		n += m
+		p.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Close closes the writer, the reader gets io.EOF once it has read the buffered data.
+func (w *PipeWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError closes the writer like Close, the reader gets err instead of io.EOF, or io.EOF if err is nil.
+func (w *PipeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	p := w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.werr == nil {
+		p.werr = err
+	}
+	p.cond.Broadcast()
+	return nil
+}
+
+// buffered 返回尚未读取的字节数，调用者需持有 mu
+func (p *pipe) buffered() int {
+	n := -p.off
+	for _, c := range p.chunks {
+		n += len(c)
+	}
+	return n
+}
+
+// release 把所有 chunk 归还给 pool，调用者需持有 mu
+func (p *pipe) release() {
+	for i, c := range p.chunks {
+		p.pool.Free(c)
+		p.chunks[i] = nil
+	}
+	p.chunks = nil
+	p.off = 0
+}
+
+var _ io.ReadCloser = (*PipeReader)(nil)
+var _ io.WriteCloser = (*PipeWriter)(nil)
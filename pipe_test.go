@@ -0,0 +1,94 @@
+package slab
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Pipe_Copy(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	r, w := NewPipe(pool, 128, 2)
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	go func() {
+		w.Write(data[:3000])
+		w.Write(data[3000:])
+		w.Close()
+	}()
+	got, err := io.ReadAll(r)
+	utest.IsNilNow(t, err)
+	utest.Assert(t, bytes.Equal(got, data))
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_Pipe_Depth(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	r, w := NewPipe(pool, 128, 2)
+	done := make(chan int)
+	go func() {
+		n, _ := w.Write(make([]byte, 300))
+		done <- n
+	}()
+	// 写入 256 字节之后阻塞，直到读取释放了第一个 chunk
+	buf := make([]byte, 128)
+	n, err := io.ReadFull(r, buf)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 128)
+	utest.EqualNow(t, <-done, 300)
+	utest.EqualNow(t, r.Buffered(), 172)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+}
+
+func Test_Pipe_Close(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024)
+	r, w := NewPipe(pool, 128, 4)
+	w.Write([]byte("hello"))
+	boom := errors.New("boom")
+	w.CloseWithError(boom)
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, string(buf[:n]), "hello")
+	_, err = r.Read(buf)
+	utest.Assert(t, err == boom)
+	_, err = w.Write([]byte("x"))
+	utest.Assert(t, err == io.ErrClosedPipe)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	// 关闭读端时缓冲的 chunk 归还给 pool，阻塞的写入返回
+	r, w = NewPipe(pool, 128, 1)
+	done := make(chan error)
+	go func() {
+		_, err := w.Write(make([]byte, 500))
+		done <- err
+	}()
+	for r.Buffered() < 128 {
+	}
+	r.Close()
+	utest.Assert(t, <-done == io.ErrClosedPipe)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	_, err = r.Read(buf)
+	utest.Assert(t, err == io.ErrClosedPipe)
+}
+
+func Benchmark_Pipe_Copy(b *testing.B) {
+	pool := NewAtomPool(128, 64*1024, 2, 1024*1024)
+	r, w := NewPipe(pool, 16*1024, 4)
+	data := make([]byte, 32*1024)
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			w.Write(data)
+		}
+		w.Close()
+	}()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	io.Copy(io.Discard, r)
+}
@@ -0,0 +1,45 @@
+package slab
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrNotAllocated is the panic value of Pointer and FromPointer of a pool created WithLeakDetection
+// when the chunk at the address is free, e.g. a pointer kept by C code after the buffer is freed.
+var ErrNotAllocated = errors.New("slab: chunk not allocated")
+
+// Pointer returns the address of the first byte of mem, a buffer alloc from the pool, to pass it to C code, e.g. through cgo,
+// instead of copying the data in and out on every call. Slab pages never move and a page is not released while any of its chunks
+// is allocated, so the address stays valid until mem is freed, which must not happen while C code still uses it.
+// Pages allocated by make are in the Go heap and follow the cgo pointer passing rules: C code may use the address
+// during the call it's passed to. To keep it after the call, create the pool WithMmap so the pages are outside the Go heap.
+// With WithLeakDetection it panics with ErrNotAllocated if the chunk is free, or with a *FreeError if mem points into the middle of a chunk.
+func (pool *AtomPool) Pointer(mem []byte) unsafe.Pointer {
+	if cap(mem) == 0 {
+		return nil
+	}
+	if len(pool.classes) > 0 && pool.classes[0].leak {
+		if e := pool.freeError(mem, ErrNotPooled); e.Offset > 0 {
+			panic(e)
+		}
+		if chk := pool.chunkAt(dataPtr(mem)); chk != nil && chk.next.Load() != 0 {
+			panic(ErrNotAllocated)
+		}
+	}
+	return unsafe.Pointer(unsafe.SliceData(mem))
+}
+
+// FromPointer returns the chunk starting at ptr as a []byte of size bytes, e.g. to get back a buffer that was passed to C code by its address.
+// It returns nil if ptr is not the first byte of a chunk of the slab classes or size is larger than the chunk.
+// With WithLeakDetection it panics with ErrNotAllocated if the chunk is free.
+func (pool *AtomPool) FromPointer(ptr unsafe.Pointer, size int) []byte {
+	chk := pool.chunkAt(uintptr(ptr))
+	if chk == nil || dataPtr(chk.mem) != uintptr(ptr) || size < 0 || size > len(chk.mem) {
+		return nil
+	}
+	if pool.classes[0].leak && chk.next.Load() != 0 {
+		panic(ErrNotAllocated)
+	}
+	return chk.mem[:size]
+}
@@ -0,0 +1,51 @@
+package slab
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Pointer(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMmap())
+	mem := pool.Alloc(100)
+	ptr := pool.Pointer(mem)
+	utest.EqualNow(t, uintptr(ptr), dataPtr(mem))
+
+	back := pool.FromPointer(ptr, 100)
+	utest.EqualNow(t, len(back), 100)
+	utest.EqualNow(t, dataPtr(back), dataPtr(mem))
+	utest.Assert(t, pool.FromPointer(ptr, 129) == nil)
+	utest.Assert(t, pool.FromPointer(unsafe.Add(ptr, 1), 1) == nil)
+	utest.Assert(t, pool.Pointer(nil) == nil)
+
+	// heap fallback 不是 chunk
+	big := pool.Alloc(4096)
+	utest.NotNilNow(t, pool.Pointer(big))
+	utest.Assert(t, pool.FromPointer(pool.Pointer(big), 1) == nil)
+	pool.Free(back)
+}
+
+func Test_AtomPool_PointerDebug(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithLeakDetection())
+	mem := pool.Alloc(128)
+	ptr := pool.Pointer(mem)
+	pool.Free(mem)
+
+	recovered := func(f func()) (v interface{}) {
+		defer func() { v = recover() }()
+		f()
+		return nil
+	}
+	utest.EqualNow(t, recovered(func() { pool.Pointer(mem) }), ErrNotAllocated)
+	utest.EqualNow(t, recovered(func() { pool.FromPointer(ptr, 128) }), ErrNotAllocated)
+
+	mem = pool.Alloc(128)
+	err, ok := recovered(func() { pool.Pointer(mem[1:]) }).(error)
+	utest.Assert(t, ok)
+	utest.Assert(t, errors.Is(err, ErrNotPooled))
+	utest.EqualNow(t, recovered(func() { pool.Pointer(mem) }), nil)
+	pool.Free(mem)
+}
@@ -0,0 +1,72 @@
+package slab
+
+import "context"
+
+// Policy is a step of the chain set by WithPolicy, tried when the slab class serving a request has no free chunk.
+type Policy int
+
+const (
+	// PolicyGrow grows the slab class by a page, it fails when the class reaches the max pages or the memory budget.
+	PolicyGrow Policy = iota
+	// PolicyLargerClass takes a free chunk of the next larger slab class which has one, without growing any class.
+	PolicyLargerClass
+	// PolicySecondary allocates from the pool set by WithSecondary.
+	PolicySecondary
+	// PolicyBlock waits until another goroutine frees a chunk of the slab class, like AllocContext without a deadline.
+	PolicyBlock
+	// PolicyHeap allocates with the function set by WithFallback or make, it fails in no heap mode.
+	PolicyHeap
+)
+
+// allocChain 在 classes[i] 没有空闲 chunk 时依次尝试 WithPolicy 设置的策略
+func (pool *AtomPool) allocChain(i, size int, strict bool) (mem []byte, pooled bool, err error) {
+	c := &pool.classes[i]
+	if mem, _ := c.pop(size, false); mem != nil {
+		return c.served(mem, size), true, nil
+	}
+	for _, step := range pool.policy {
+		switch step {
+		case PolicyGrow:
+			mem, overBudget := c.pop(size, true)
+			if mem != nil {
+				return c.served(mem, size), true, nil
+			}
+			if overBudget {
+				c.overBudget.Add(1)
+				if strict {
+					return nil, false, ErrPoolExhausted
+				}
+			}
+		case PolicyLargerClass:
+			for j := i + 1; j < len(pool.classes); j++ {
+				l := &pool.classes[j]
+				if mem, _ := l.pop(size, false); mem != nil {
+					return l.served(mem, size), true, nil
+				}
+			}
+		case PolicySecondary:
+			if mem := pool.secondary.Alloc(size); mem != nil {
+				c.fallbacks.Add(1)
+				return mem, false, nil
+			}
+		case PolicyBlock:
+			if mem, _ := c.wait(context.Background(), size, false); mem != nil {
+				return c.served(mem, size), true, nil
+			}
+		case PolicyHeap:
+			if mem := pool.heap(size); mem != nil {
+				c.fallbacks.Add(1)
+				return mem, false, nil
+			}
+		}
+	}
+	c.fallbacks.Add(1)
+	return nil, false, ErrPoolExhausted
+}
+
+// served 记录 class 分配出的一个 chunk，返回长度为 size 的切片
+func (c *class) served(mem []byte, size int) []byte {
+	c.allocs.Add(1)
+	c.request(1, size)
+	return mem[:size]
+}
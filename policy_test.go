@@ -0,0 +1,78 @@
+package slab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_PolicyLargerClass(t *testing.T) {
+	pool := NewAtomPool(128, 512, 2, 1024, WithMaxPages(2), WithPolicy(PolicyLargerClass, PolicyGrow))
+	bufs := pool.AllocBatch(128, 8)
+
+	// served by the 256 class, then 512 class, without growing the 128 class
+	mem, pooled := pool.AllocPooled(128)
+	utest.Assert(t, pooled)
+	utest.EqualNow(t, len(mem), 128)
+	utest.EqualNow(t, cap(mem), 256)
+	for i := 0; i < 3; i++ {
+		pool.Alloc(128)
+	}
+	mem = pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 512)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+	utest.EqualNow(t, pool.Stats()[1].Allocs, uint64(4))
+
+	// the larger chunk goes back to its own class
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Stats()[2].Frees, uint64(1))
+
+	// all larger chunks are taken, the class grows
+	pool.Alloc(512)
+	pool.Alloc(512)
+	mem = pool.Alloc(128)
+	utest.EqualNow(t, cap(mem), 128)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 2)
+	pool.FreeBatch(bufs)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_PolicySecondary(t *testing.T) {
+	secondary := NewAtomPool(128, 1024, 2, 1024)
+	pool := NewAtomPool(128, 1024, 2, 128, WithMaxPages(1), WithSecondary(secondary), WithPolicy(PolicySecondary))
+	a := pool.Alloc(128)
+	b, pooled := pool.AllocPooled(128)
+	utest.Assert(t, !pooled)
+	utest.Assert(t, secondary.Owns(b))
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(1))
+
+	// the buffer of the secondary pool is returned to it
+	utest.IsNilNow(t, pool.TryFree(b))
+	utest.EqualNow(t, secondary.Stats()[0].Frees, uint64(1))
+	pool.Free(a)
+}
+
+func Test_AtomPool_PolicyExhausted(t *testing.T) {
+	pool := NewAtomPool(128, 256, 2, 256, WithMaxPages(1), WithPolicy(PolicyGrow))
+	pool.AllocBatch(128, 2)
+	utest.IsNilNow(t, pool.Alloc(128))
+	_, err := pool.TryAlloc(128)
+	utest.EqualNow(t, err, ErrPoolExhausted)
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(2))
+
+	// sizes larger than the largest chunk are not affected
+	utest.EqualNow(t, len(pool.Alloc(1024)), 1024)
+}
+
+func Test_AtomPool_PolicyBlock(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 128, WithMaxPages(1), WithPolicy(PolicyBlock))
+	held := pool.Alloc(128)
+	done := make(chan []byte)
+	go func() {
+		done <- pool.Alloc(128)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pool.Free(held)
+	utest.EqualNow(t, dataPtr(<-done), dataPtr(held))
+}
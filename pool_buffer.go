@@ -0,0 +1,244 @@
+package slab
+
+import (
+	"io"
+	"net"
+)
+
+// PoolBuffer is a variable-sized buffer of bytes with the method set of bytes.Buffer,
+// which grows by chaining more chunks of chunkSize bytes from Pool instead of reallocating one contiguous slice,
+// and Prepend inserts headers before the body.
+// WriteTo drains the chunks with one vectored write when w supports it, e.g. a net.Conn.
+// The chunks are returned to the pool when the PoolBuffer is closed.
+type PoolBuffer struct {
+	pool      Pool
+	chunkSize int
+	chunks    [][]byte // 每个 chunk 的长度为已写入的字节数
+	off       int      // chunks[0] 中已读取的字节数
+}
+
+// NewPoolBuffer create a PoolBuffer which alloc chunks of chunkSize bytes from pool as it grows.
+func NewPoolBuffer(pool Pool, chunkSize int) *PoolBuffer {
+	return &PoolBuffer{pool: pool, chunkSize: chunkSize}
+}
+
+// Len returns the number of bytes of the unread portion of the buffer.
+func (b *PoolBuffer) Len() int {
+	n := -b.off
+	for _, mem := range b.chunks {
+		n += len(mem)
+	}
+	return n
+}
+
+// Chunks returns the number of chunks currently held by the buffer.
+func (b *PoolBuffer) Chunks() int {
+	return len(b.chunks)
+}
+
+// Bytes returns the unread portion of the buffer. When it spans several chunks they are first merged into one chunk
+// of the pool, so Bytes is cheap only while the buffer fits one chunk. The slice is valid only until the next write, read or Close.
+func (b *PoolBuffer) Bytes() []byte {
+	if len(b.chunks) == 0 {
+		return nil
+	}
+	if len(b.chunks) > 1 {
+		mem := b.pool.Alloc(b.Len())
+		if mem == nil {
+			mem = make([]byte, b.Len())
+		}
+		n := copy(mem, b.chunks[0][b.off:])
+		for _, c := range b.chunks[1:] {
+			n += copy(mem[n:], c)
+		}
+		b.free(0, len(b.chunks))
+		b.chunks = append(b.chunks[:0], mem[:n])
+		b.off = 0
+	}
+	return b.chunks[0][b.off:]
+}
+
+// String returns a copy of the unread portion of the buffer as a string.
+func (b *PoolBuffer) String() string {
+	s := make([]byte, 0, b.Len())
+	if len(b.chunks) > 0 {
+		s = append(s, b.chunks[0][b.off:]...)
+		for _, c := range b.chunks[1:] {
+			s = append(s, c...)
+		}
+	}
+	return string(s)
+}
+
+// Reset empty the buffer but keep the first chunk for future writes, the others are returned to the pool.
+func (b *PoolBuffer) Reset() {
+	if len(b.chunks) == 0 {
+		return
+	}
+	b.free(1, len(b.chunks))
+	b.chunks = b.chunks[:1]
+	b.chunks[0] = b.chunks[0][:0]
+	b.off = 0
+}
+
+// free 归还 chunks[i:j]
+func (b *PoolBuffer) free(i, j int) {
+	for k := i; k < j; k++ {
+		b.pool.Free(b.chunks[k])
+		b.chunks[k] = nil
+	}
+}
+
+// tail 返回有剩余空间的最后一个 chunk 的下标，没有时分配一个新的 chunk
+func (b *PoolBuffer) tail() (int, error) {
+	if n := len(b.chunks); n > 0 && len(b.chunks[n-1]) < cap(b.chunks[n-1]) {
+		return n - 1, nil
+	}
+	mem := b.pool.Alloc(b.chunkSize)
+	if cap(mem) == 0 {
+		return 0, ErrPoolExhausted
+	}
+	b.chunks = append(b.chunks, mem[:0])
+	return len(b.chunks) - 1, nil
+}
+
+// Write appends the contents of p to the buffer, chaining chunks as needed.
+// It returns ErrPoolExhausted if the pool returns nil, e.g. a pool created with WithNoHeap is exhausted.
+func (b *PoolBuffer) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		i, err := b.tail()
+		if err != nil {
+			return n, err
+		}
+		c := b.chunks[i]
+		m := copy(c[len(c):cap(c)], p[n:])
+		b.chunks[i] = c[:len(c)+m]
+		n += m
+	}
+	return n, nil
+}
+
+// WriteString appends the contents of s to the buffer like Write.
+func (b *PoolBuffer) WriteString(s string) (int, error) {
+	n := 0
+	for n < len(s) {
+		i, err := b.tail()
+		if err != nil {
+			return n, err
+		}
+		c := b.chunks[i]
+		m := copy(c[len(c):cap(c)], s[n:])
+		b.chunks[i] = c[:len(c)+m]
+		n += m
+	}
+	return n, nil
+}
+
+// WriteByte appends the byte c to the buffer.
+func (b *PoolBuffer) WriteByte(c byte) error {
+	i, err := b.tail()
+	if err != nil {
+		return err
+	}
+	b.chunks[i] = append(b.chunks[i], c)
+	return nil
+}
+
+// Prepend inserts the contents of p before the unread portion of the buffer, e.g. a length or a header
+// encoded after the body it describes. It reuses the bytes already read from the first chunk when they are enough,
+// and chains new chunks in front otherwise, the body is never copied. It returns ErrPoolExhausted like Write.
+func (b *PoolBuffer) Prepend(p []byte) error {
+	if len(b.chunks) > 0 {
+		// 先用第一个 chunk 中已读取的部分放下 p 的末尾
+		n := min(b.off, len(p))
+		b.off -= n
+		copy(b.chunks[0][b.off:], p[len(p)-n:])
+		p = p[:len(p)-n]
+	}
+	// 剩下的部分从末尾开始，每次写满一个新 chunk 的尾部，挂到最前面
+	for len(p) > 0 {
+		mem := b.pool.Alloc(b.chunkSize)
+		if cap(mem) == 0 {
+			return ErrPoolExhausted
+		}
+		mem = mem[:cap(mem)]
+		n := copy(mem[max(len(mem)-len(p), 0):], p[max(len(p)-len(mem), 0):])
+		p = p[:len(p)-n]
+		b.chunks = append(b.chunks, nil)
+		copy(b.chunks[1:], b.chunks)
+		b.chunks[0] = mem
+		b.off = len(mem) - n
+	}
+	return nil
+}
+
+// Read reads the next len(p) bytes from the buffer or until the buffer is drained.
+// If the buffer has no data to return, err is io.EOF (unless len(p) is zero).
+func (b *PoolBuffer) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && len(b.chunks) > 0 {
+		m := copy(p[n:], b.chunks[0][b.off:])
+		n += m
+		b.consume(m)
+		if len(b.chunks) == 1 && b.off == len(b.chunks[0]) {
+			break
+		}
+	}
+	if n == 0 && len(p) > 0 {
+		b.Reset()
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// WriteTo writes the data of the buffer to w chunk by chunk until the buffer is drained or an error occurs,
+// with a single writev syscall when w is a net.Conn. The drained chunks are returned to the pool except the last one.
+func (b *PoolBuffer) WriteTo(w io.Writer) (int64, error) {
+	if b.Len() == 0 {
+		b.Reset()
+		return 0, nil
+	}
+	v := make(net.Buffers, 0, len(b.chunks))
+	v = append(v, b.chunks[0][b.off:])
+	v = append(v, b.chunks[1:]...)
+	n, err := v.WriteTo(w)
+	for left := int(n); left > 0; {
+		m := len(b.chunks[0]) - b.off
+		if m > left {
+			m = left
+		}
+		b.consume(m)
+		left -= m
+	}
+	if b.Len() == 0 {
+		b.Reset()
+	}
+	return n, err
+}
+
+// consume 丢弃 chunks[0] 中接下来的 n 个字节，读完的 chunk 除最后一个以外都归还给 pool
+func (b *PoolBuffer) consume(n int) {
+	b.off += n
+	if b.off == len(b.chunks[0]) && len(b.chunks) > 1 {
+		b.free(0, 1)
+		b.chunks = b.chunks[1:]
+		b.off = 0
+	}
+}
+
+// Close return all chunks to the pool.
+// The buffer must not be used after Close, closing it again is a no-op.
+func (b *PoolBuffer) Close() error {
+	b.free(0, len(b.chunks))
+	b.chunks = nil
+	b.off = 0
+	return nil
+}
+
+var _ io.Reader = (*PoolBuffer)(nil)
+var _ io.Writer = (*PoolBuffer)(nil)
+var _ io.StringWriter = (*PoolBuffer)(nil)
+var _ io.ByteWriter = (*PoolBuffer)(nil)
+var _ io.WriterTo = (*PoolBuffer)(nil)
+var _ io.Closer = (*PoolBuffer)(nil)
@@ -0,0 +1,120 @@
+package slab
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_PoolBuffer(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	b := NewPoolBuffer(pool, 128)
+
+	data := strings.Repeat("0123456789", 30)
+	n, err := b.WriteString(data)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 300)
+	b.Write([]byte("hello"))
+	b.WriteByte('!')
+	utest.EqualNow(t, b.Len(), 306)
+	utest.EqualNow(t, b.Chunks(), 3)
+	utest.EqualNow(t, b.String(), data+"hello!")
+
+	p := make([]byte, 200)
+	n, err = b.Read(p)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, 200)
+	utest.EqualNow(t, string(p), data[:200])
+	utest.EqualNow(t, b.Chunks(), 2)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 2)
+
+	// Bytes merges the chunks into one
+	utest.EqualNow(t, string(b.Bytes()), data[200:]+"hello!")
+	utest.EqualNow(t, b.Chunks(), 1)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+	utest.EqualNow(t, pool.Stats()[0].Frees, uint64(3))
+
+	b.Reset()
+	utest.EqualNow(t, b.Len(), 0)
+	_, err = b.Read(p)
+	utest.EqualNow(t, err, io.EOF)
+	utest.IsNilNow(t, b.Close())
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+}
+
+func Test_PoolBuffer_WriteTo(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	b := NewPoolBuffer(pool, 128)
+	data := strings.Repeat("0123456789", 50)
+	b.WriteString(data)
+	utest.EqualNow(t, b.Chunks(), 4)
+
+	var w bytes.Buffer
+	n, err := b.WriteTo(&w)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, n, int64(500))
+	utest.EqualNow(t, w.String(), data)
+	utest.EqualNow(t, b.Len(), 0)
+	utest.EqualNow(t, b.Chunks(), 1)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+
+	// a short write keeps the rest
+	b.WriteString(data)
+	lw := &limitedWriter{n: 300}
+	n, err = b.WriteTo(lw)
+	utest.EqualNow(t, err, io.ErrShortWrite)
+	utest.EqualNow(t, n, int64(300))
+	utest.EqualNow(t, b.String(), data[300:])
+	utest.EqualNow(t, b.Chunks(), 2)
+	b.Close()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_PoolBuffer_Prepend(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	b := NewPoolBuffer(pool, 128)
+	body := strings.Repeat("0123456789", 20)
+	b.WriteString(body)
+	utest.IsNilNow(t, b.Prepend([]byte("200:")))
+	utest.EqualNow(t, b.Chunks(), 3)
+	utest.EqualNow(t, b.String(), "200:"+body)
+
+	// a header larger than a chunk takes several chunks in front
+	header := strings.Repeat("h", 300)
+	utest.IsNilNow(t, b.Prepend([]byte(header)))
+	utest.EqualNow(t, b.String(), header+"200:"+body)
+
+	var w bytes.Buffer
+	b.WriteTo(&w)
+	utest.EqualNow(t, w.String(), header+"200:"+body)
+
+	// the bytes already read from the first chunk are reused
+	b.WriteString("abcdef")
+	p := make([]byte, 4)
+	b.Read(p)
+	chunks := b.Chunks()
+	utest.IsNilNow(t, b.Prepend([]byte("xy")))
+	utest.EqualNow(t, b.Chunks(), chunks)
+	utest.IsNilNow(t, b.Prepend([]byte("0123")))
+	utest.EqualNow(t, b.String(), "0123xyef")
+	b.Close()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+// limitedWriter 写入 n 个字节之后返回 io.ErrShortWrite
+type limitedWriter struct{ n int }
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
@@ -0,0 +1,52 @@
+package slab
+
+// NewNetworkPool create a pool for packet buffers, whose classes are centered on the Ethernet MTU of 1500 bytes
+// and the jumbo frame of 9000 bytes, up to 64KB for GSO/GRO batches.
+// Classes up to 4KB have 256KB pages, the larger ones 1MB pages. Pages are allocated lazily, up to 64 per class.
+// opts are applied after the preset, e.g. WithMaxMemory to bound the pool.
+func NewNetworkPool(opts ...Option) (*AtomPool, error) {
+	return NewPool(append([]Option{
+		WithClasses(128, 256, 512, 1024, 1500, 2048, 4096, 9000, 16384, 65536),
+		WithClassPageSize(func(chunkSize int) int {
+			if chunkSize <= 4096 {
+				return 256 * 1024
+			}
+			return 1024 * 1024
+		}),
+		WithLazy(),
+		WithMaxPages(64),
+	}, opts...)...)
+}
+
+// NewRPCPool create a pool for request and response messages of 128B to 64KB in power of 2 classes.
+// Classes up to 4KB have 64KB pages, so rarely used small classes cost little, the larger ones 1MB pages.
+// Pages are allocated lazily, up to 256 per class. opts are applied after the preset.
+func NewRPCPool(opts ...Option) (*AtomPool, error) {
+	return NewPool(append([]Option{
+		WithSizeRange(128, 64*1024),
+		WithClassPageSize(func(chunkSize int) int {
+			if chunkSize <= 4096 {
+				return 64 * 1024
+			}
+			return 1024 * 1024
+		}),
+		WithLazy(),
+		WithMaxPages(256),
+	}, opts...)...)
+}
+
+// NewBulkIOPool create a pool for file and bulk network I/O buffers of 64KB to 8MB in power of 2 classes,
+// aligned to 4KB for O_DIRECT. Each page holds 16 chunks, from 1MB pages of 64KB chunks to 128MB pages of 8MB chunks,
+// and pages are allocated lazily, up to 16 per class.
+// opts are applied after the preset, e.g. WithMmap to keep the large pages off the Go heap.
+func NewBulkIOPool(opts ...Option) (*AtomPool, error) {
+	return NewPool(append([]Option{
+		WithSizeRange(64*1024, 8*1024*1024),
+		WithClassPageSize(func(chunkSize int) int {
+			return 16 * chunkSize
+		}),
+		WithAlignment(4096),
+		WithLazy(),
+		WithMaxPages(16),
+	}, opts...)...)
+}
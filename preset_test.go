@@ -0,0 +1,33 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Presets(t *testing.T) {
+	pool, err := NewNetworkPool()
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Stats()), 10)
+	utest.EqualNow(t, cap(pool.Alloc(1400)), 1500)
+	utest.EqualNow(t, cap(pool.Alloc(9000)), 9000)
+	utest.EqualNow(t, pool.Stats()[4].PageSize, 256*1024)
+	utest.EqualNow(t, pool.Stats()[7].PageSize, 1024*1024)
+
+	pool, err = NewRPCPool(WithMaxMemory(1024 * 1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Stats()), 10)
+	utest.EqualNow(t, cap(pool.Alloc(100)), 128)
+	utest.EqualNow(t, pool.Stats()[0].PageSize, 64*1024)
+	utest.EqualNow(t, pool.Stats()[0].Resident, 64*1024)
+
+	pool, err = NewBulkIOPool()
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Stats()), 8)
+	mem := pool.Alloc(100 * 1024)
+	utest.EqualNow(t, cap(mem), 128*1024)
+	utest.Assert(t, isAligned(mem, 4096))
+	utest.EqualNow(t, pool.Stats()[7].PageSize, 128*1024*1024)
+	pool.Free(mem)
+}
@@ -0,0 +1,39 @@
+package slab
+
+import (
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// TrimOnMemoryPressure start a goroutine which checks the memory used by the Go runtime every interval
+// and calls Trim when it reaches ratio, e.g. 0.9, of the memory limit set by GOMEMLIMIT or debug.SetMemoryLimit,
+// so the free pages of the pool are given back to the heap instead of competing with it.
+// It never trims when no memory limit is set. Call Stop to end the goroutine.
+func (pool *AtomPool) TrimOnMemoryPressure(ratio float64, interval time.Duration) *Reclaimer {
+	samples := pressureSamples()
+	return pool.startReclaimer(interval, func() {
+		if underPressure(samples, ratio) {
+			pool.trim(TrimPressure)
+		}
+	})
+}
+
+func pressureSamples() []metrics.Sample {
+	return []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+}
+
+// underPressure 判断 runtime 占用的内存是否达到了内存限制的 ratio 倍，与 GOMEMLIMIT 的统计口径一致，不含已归还给操作系统的部分
+func underPressure(samples []metrics.Sample, ratio float64) bool {
+	limit := debug.SetMemoryLimit(-1)
+	if limit == math.MaxInt64 {
+		return false
+	}
+	metrics.Read(samples)
+	used := samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	return float64(used) >= ratio*float64(limit)
+}
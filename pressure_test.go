@@ -0,0 +1,33 @@
+package slab
+
+import (
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_TrimOnMemoryPressure(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	samples := pressureSamples()
+
+	limit := debug.SetMemoryLimit(math.MaxInt64)
+	defer debug.SetMemoryLimit(limit)
+	utest.Assert(t, !underPressure(samples, 0.9))
+
+	r := pool.TrimOnMemoryPressure(0.9, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+
+	// any usage is over a limit of 1KB
+	debug.SetMemoryLimit(1024)
+	utest.Assert(t, underPressure(samples, 0.9))
+	time.Sleep(10 * time.Millisecond)
+	r.Stop()
+	debug.SetMemoryLimit(math.MaxInt64)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 0)
+	}
+}
@@ -0,0 +1,98 @@
+package slab
+
+import "sync"
+
+// quarantine 是回收的 chunk 的 FIFO 隔离区，chunk 要等之后又有 len(ring) 个 chunk 被回收才挂回空闲链表
+type quarantine struct {
+	mu   sync.Mutex
+	ring []uint64 // 隔离中的 chunk 的链接值
+	pos  int      // 最早进入隔离区的 chunk 在 ring 中的位置
+	n    int      // 隔离中的 chunk 数
+}
+
+// release 回收以 first 为首、last 为尾且已经链接好的一串 chunk，
+// 开启隔离时它们依次进入隔离区，被挤出隔离区的 chunk 一起挂到空闲链表首部，开启 FIFO 时挂到后备链表尾部
+func (c *class) release(first uint64, last *chunk) {
+	q := c.quarantine
+	if q == nil {
+		c.pushFree(first, last)
+		return
+	}
+
+	var head uint64
+	var tail *chunk
+	q.mu.Lock()
+	for v := first; ; {
+		chk := c.chunk(linkIndex(v))
+		nxt := nextLink(chk.next.Load())
+		// 隔离中的 chunk 的 next 指向自己，保持非 0，重复回收仍能被识别
+		chk.next.Store(v)
+		if q.n < len(q.ring) {
+			q.ring[(q.pos+q.n)%len(q.ring)] = v
+			q.n++
+		} else {
+			old := q.ring[q.pos]
+			q.ring[q.pos] = v
+			q.pos = (q.pos + 1) % len(q.ring)
+			if tail == nil {
+				head = old
+			} else {
+				tail.next.Store(old)
+			}
+			tail = c.chunk(linkIndex(old))
+		}
+		if chk == last {
+			break
+		}
+		v = nxt
+	}
+	q.mu.Unlock()
+
+	if tail != nil {
+		c.pushFree(head, tail)
+	}
+}
+
+// drain 把隔离区中的 chunk 全部挂回空闲链表
+func (c *class) drain() {
+	q := c.quarantine
+	if q == nil {
+		return
+	}
+	var head uint64
+	var tail *chunk
+	q.mu.Lock()
+	for ; q.n > 0; q.n-- {
+		v := q.ring[q.pos]
+		q.pos = (q.pos + 1) % len(q.ring)
+		if tail == nil {
+			head = v
+		} else {
+			tail.next.Store(v)
+		}
+		tail = c.chunk(linkIndex(v))
+	}
+	q.mu.Unlock()
+
+	if tail != nil {
+		c.pushRun(head, tail)
+	}
+}
+
+// clear 丢弃隔离区中的 chunk，调用者负责重建空闲链表
+func (q *quarantine) clear() {
+	q.mu.Lock()
+	q.pos, q.n = 0, 0
+	q.mu.Unlock()
+}
+
+// quarantined 返回隔离中的 chunk 的链接值
+func (q *quarantine) quarantined() []uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	links := make([]uint64, 0, q.n)
+	for i := 0; i < q.n; i++ {
+		links = append(links, q.ring[(q.pos+i)%len(q.ring)])
+	}
+	return links
+}
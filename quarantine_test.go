@@ -0,0 +1,64 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Quarantine(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 1024, WithQuarantine(4), WithPoison(0xDD))
+	bufs := pool.AllocBatch(128, 8)
+	first := dataPtr(bufs[0])
+	pool.Free(bufs[0])
+	utest.EqualNow(t, pool.Stats()[0].Quarantined, 1)
+	utest.EqualNow(t, pool.TryFree(bufs[0]), ErrDoubleFree)
+	utest.IsNilNow(t, pool.Verify())
+
+	// the chunk stays poisoned and is not alloc again until 4 more chunks are freed
+	_, pooled := pool.AllocPooled(128)
+	utest.Assert(t, !pooled)
+	pool.FreeBatch(bufs[1:4])
+	_, pooled = pool.AllocPooled(128)
+	utest.Assert(t, !pooled)
+	utest.EqualNow(t, pool.Stats()[0].Quarantined, 4)
+	utest.EqualNow(t, bufs[0][0], byte(0xDD))
+
+	pool.Free(bufs[4])
+	utest.EqualNow(t, pool.Stats()[0].Quarantined, 4)
+	utest.IsNilNow(t, pool.Verify())
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, dataPtr(mem), first)
+	pool.Free(mem)
+	pool.FreeBatch(bufs[5:])
+	utest.IsNilNow(t, pool.Verify())
+
+	// closing the pool releases the quarantined chunks
+	utest.IsNilNow(t, pool.Close())
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+	utest.EqualNow(t, pool.Stats()[0].Quarantined, 0)
+}
+
+func Test_AtomPool_QuarantineConcurrent(t *testing.T) {
+	pool := NewAtomPool(128, 256, 2, 1024, WithMaxPages(4), WithQuarantine(8))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				bufs := pool.AllocBatch(128, 3)
+				mem := pool.Alloc(256)
+				pool.FreeBatch(bufs)
+				pool.Free(mem)
+			}
+		}()
+	}
+	wg.Wait()
+	utest.IsNilNow(t, pool.Verify())
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+		utest.EqualNow(t, s.Quarantined, 8)
+	}
+}
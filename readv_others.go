@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package slab
+
+import "io"
+
+func readv(r io.Reader, bufs [][]byte) (n int, ok bool, err error) {
+	return 0, false, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package slab
+
+import (
+	"io"
+	"syscall"
+	"unsafe"
+)
+
+// readv 用一次 readv 系统调用把 r 读入 bufs，r 不是 syscall.Conn 时 ok 为 false
+func readv(r io.Reader, bufs [][]byte) (n int, ok bool, err error) {
+	sc, ok := r.(syscall.Conn)
+	if !ok || len(bufs) == 0 {
+		return 0, false, nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+	iov := make([]syscall.Iovec, len(bufs))
+	for i, mem := range bufs {
+		if len(mem) > 0 {
+			iov[i].Base = &mem[0]
+		}
+		iov[i].SetLen(len(mem))
+	}
+	var errno syscall.Errno
+	err = rc.Read(func(fd uintptr) bool {
+		for {
+			m, _, e := syscall.Syscall(syscall.SYS_READV, fd, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)))
+			if e == syscall.EINTR {
+				continue
+			}
+			if e == syscall.EAGAIN {
+				// 等待可读之后 rc.Read 再次调用
+				return false
+			}
+			n, errno = int(m), e
+			return true
+		}
+	})
+	if err == nil && errno != 0 {
+		err = errno
+	}
+	if err != nil {
+		n = 0
+	}
+	return n, true, err
+}
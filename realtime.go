@@ -0,0 +1,51 @@
+package slab
+
+import "errors"
+
+// ErrWouldBlock is returned by TryAlloc of a pool created WithRealTime when popping a chunk lost every compare-and-swap
+// attempt to other goroutines, the class may still have free chunks.
+var ErrWouldBlock = errors.New("slab: alloc would block")
+
+// allocRealTime 是实时模式下从 class c 分配：不增长 page，不回退到堆，CAS 失败的次数有上限
+func (pool *AtomPool) allocRealTime(c *class, size int) ([]byte, bool, error) {
+	mem, busy := c.tryPop(pool.realTime)
+	if mem != nil {
+		c.allocs.Add(1)
+		c.request(1, size)
+		return mem[:size], true, nil
+	}
+	if busy {
+		return nil, false, ErrWouldBlock
+	}
+	c.fallbacks.Add(1)
+	return nil, false, ErrPoolExhausted
+}
+
+// tryPop 与 pop 相同，但不增长 page 也不取 FIFO 的后备链表，最多尝试 retries 次 CAS，都失败时 busy 为 true。
+// 实时模式下 page 不会被 reclaim 释放，chunk 总能找到，enter 也不会因 synchronize 推进周期而重试
+func (c *class) tryPop(retries int) (mem []byte, busy bool) {
+	for i := 0; i < retries; i++ {
+		old := c.head.Load()
+		if old == 0 {
+			return nil, false
+		}
+		e := c.epoch.enter()
+		chk := c.chunk(linkIndex(old))
+		if chk == nil {
+			c.epoch.exit(e)
+			continue
+		}
+		nxt := nextLink(chk.next.Load())
+		c.chaos.delay()
+		ok := c.head.CompareAndSwap(old, nxt)
+		c.epoch.exit(e)
+		if ok {
+			chk.next.Store(0)
+			c.used(1)
+			return chk.mem, false
+		}
+		// 不等待，重试的次数本身就有上限
+		c.retries.Add(1)
+	}
+	return nil, true
+}
@@ -0,0 +1,90 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_RealTime(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 256), WithPageSize(1024), WithMaxPages(2), WithLazy(), WithRealTime(4))
+	utest.IsNilNow(t, err)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.Pages, 2)
+	}
+
+	temp := make([][]byte, 16)
+	for i := range temp {
+		temp[i], err = pool.TryAlloc(128)
+		utest.IsNilNow(t, err)
+		utest.EqualNow(t, len(temp[i]), 128)
+	}
+	mem, err := pool.TryAlloc(128)
+	utest.IsNilNow(t, mem)
+	utest.Assert(t, err == ErrPoolExhausted)
+	utest.IsNilNow(t, pool.Alloc(1024))
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(1))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		pool.Free(temp[0])
+		temp[0] = pool.Alloc(100)
+		pool.Alloc(128)
+	})
+	utest.EqualNow(t, allocs, 0.0)
+
+	for _, mem := range temp {
+		pool.Free(mem)
+	}
+	utest.EqualNow(t, pool.Trim(), 0)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 2)
+}
+
+func Test_AtomPool_RealTimeWouldBlock(t *testing.T) {
+	// 随机化模式在 load 和 CAS 之间让出处理器，只尝试一次的 pop 很容易失败
+	pool, err := NewPool(WithSizeRange(128, 128), WithPageSize(4096), WithRealTime(1), WithChaos(1))
+	utest.IsNilNow(t, err)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		blocked int
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 0
+			for i := 0; i < 2000; i++ {
+				mem, err := pool.TryAlloc(128)
+				if err == ErrWouldBlock {
+					n++
+					continue
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				pool.Free(mem)
+			}
+			mu.Lock()
+			blocked += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	utest.Assert(t, blocked > 0)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_NewPool_RealTimeInvalid(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithRealTime(-1)},
+		{WithRealTime(1), WithLeakDetection()},
+		{WithRealTime(1), WithFIFO()},
+		{WithRealTime(1), WithFallback(func(size int) []byte { return make([]byte, size) })},
+		{WithRealTime(1), WithSizeRange(64, 1024), WithBuddy(4096, 1)},
+	} {
+		_, err := NewPool(opts...)
+		utest.NotNilNow(t, err)
+	}
+}
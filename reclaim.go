@@ -0,0 +1,187 @@
+package slab
+
+import (
+	"sync"
+	"time"
+)
+
+// Reclaim release the slab pages whose chunks have all been free for at least ttl and returns the number of bytes released.
+// The idle time of a page is measured from the first call of Reclaim which found it completely free,
+// so a page is released by a later call, at least ttl after that. Like Trim it can run concurrently with Alloc and Free.
+func (pool *AtomPool) Reclaim(ttl time.Duration) int {
+	now := time.Now()
+	released := 0
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if c.keeps(TrimTTL) {
+			continue
+		}
+		n := c.reclaim(now, ttl)
+		pool.releasedBy(TrimTTL, "class", c.size, n)
+		released += n
+	}
+	pool.trims.add(TrimTTL, ttl, released)
+	return released
+}
+
+// trim 和 Trim 相同，reason 是释放的原因
+func (pool *AtomPool) trim(reason TrimReason) int {
+	released := 0
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if c.keeps(reason) {
+			continue
+		}
+		n := c.Trim()
+		pool.releasedBy(reason, "class", c.size, n)
+		released += n
+	}
+	if pool.buddy != nil {
+		n := pool.buddy.trim()
+		pool.releasedBy(reason, "buddy", 0, n)
+		released += n
+	}
+	if pool.overflow != nil {
+		n := pool.overflow.trim()
+		pool.releasedBy(reason, "overflow", 0, n)
+		released += n
+	}
+	// 子 pool 归还的内存也一并释放
+	released += pool.src.drop()
+	pool.trims.add(reason, 0, released)
+	return released
+}
+
+// TrimReason is why the pool releases memory, reported by ReleaseEvent and TrimEvent.
+type TrimReason int
+
+const (
+	// TrimExplicit is a call of Trim, e.g. by the application or by Manager.Trim.
+	TrimExplicit TrimReason = iota
+	// TrimTTL is a call of Reclaim releasing pages idle for the ttl, e.g. by StartReclaimer.
+	TrimTTL
+	// TrimPressure is a trim by TrimOnMemoryPressure when the Go runtime is close to its memory limit.
+	TrimPressure
+	// TrimBudget is a trim by SetMaxMemory lowering the memory budget below the memory held by the pool.
+	TrimBudget
+	// TrimMigrate is the release of the pages drained by Migrate.
+	TrimMigrate
+	// TrimClose is the release of the free pages by Close.
+	TrimClose
+	// TrimSpill is the release of the free pages of a class whose free chunks exceed WithClassFreeCap.
+	TrimSpill
+)
+
+// ReleaseEvent is memory released by the pool, passed to the hook set by WithReleaseHook.
+type ReleaseEvent struct {
+	Reason   TrimReason
+	Tier     string // "class" for the pages of a slab class, "buddy" for the arenas of the buddy tier, "overflow" for the buffers cached by the overflow tier
+	Class    int    // chunk size of the slab class, 0 for the other tiers
+	Released int    // bytes released
+}
+
+// releasedBy 在释放了内存时调用 WithReleaseHook 设置的 hook
+func (pool *AtomPool) releasedBy(reason TrimReason, tier string, class, n int) {
+	if n > 0 && pool.onRelease != nil {
+		pool.onRelease(ReleaseEvent{reason, tier, class, n})
+	}
+}
+
+// TrimEvent is a call of Trim or Reclaim which released memory, reported by TrimEvents.
+type TrimEvent struct {
+	Time     time.Time     // when the call returned
+	Reason   TrimReason    // why the memory is released
+	TTL      time.Duration // ttl passed to Reclaim, 0 for Trim
+	Released int           // bytes released
+}
+
+// trimLog 保存最近 len(events) 次释放了内存的 Trim 和 Reclaim
+type trimLog struct {
+	mu       sync.Mutex
+	events   [16]TrimEvent
+	n        int // 记录过的总次数
+	released int // 记录过的释放字节数之和
+}
+
+func (l *trimLog) add(reason TrimReason, ttl time.Duration, released int) {
+	if released == 0 {
+		return
+	}
+	l.mu.Lock()
+	l.events[l.n%len(l.events)] = TrimEvent{time.Now(), reason, ttl, released}
+	l.n++
+	l.released += released
+	l.mu.Unlock()
+}
+
+// totals 返回记录过的总次数和释放字节数之和
+func (l *trimLog) totals() (n, released int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.n, l.released
+}
+
+// TrimEvents returns the last 16 calls of Trim and Reclaim which released memory, the most recent first.
+func (pool *AtomPool) TrimEvents() []TrimEvent {
+	l := &pool.trims
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.n
+	if n > len(l.events) {
+		n = len(l.events)
+	}
+	events := make([]TrimEvent, n)
+	for i := range events {
+		events[i] = l.events[(l.n-1-i)%len(l.events)]
+	}
+	return events
+}
+
+// Reclaimer is a goroutine releasing the idle slab pages of a pool, created by AtomPool.StartReclaimer.
+type Reclaimer struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// StartReclaimer start a goroutine calling Reclaim(ttl) every interval,
+// so the memory held by the pool follows the recent demand instead of the historical peak.
+// Call Stop to end the goroutine.
+func (pool *AtomPool) StartReclaimer(ttl, interval time.Duration) *Reclaimer {
+	return pool.startReclaimer(interval, func() {
+		pool.Reclaim(ttl)
+	})
+}
+
+// startReclaimer 启动一个每隔 interval 调用一次 fn 的 goroutine，
+// goroutine 运行期间计入 pool.watchers，Adapt 不会替换它正在读取的 class
+func (pool *AtomPool) startReclaimer(interval time.Duration, fn func()) *Reclaimer {
+	r := &Reclaimer{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	pool.watchers.Add(1)
+	go func() {
+		defer close(r.done)
+		defer pool.watchers.Add(-1)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// Stop end the reclaimer goroutine and wait for it to exit. It is safe to call Stop more than once.
+func (r *Reclaimer) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
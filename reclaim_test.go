@@ -0,0 +1,145 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Reclaim(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	c := &pool.classes[0]
+
+	temp := make([][]byte, c.perPage*2)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+	}
+	for i := 1; i < len(temp); i++ {
+		pool.Free(temp[i])
+	}
+
+	// the first call only starts the idle time
+	utest.EqualNow(t, pool.Reclaim(time.Hour), 0)
+	utest.EqualNow(t, pool.Reclaim(time.Hour), 0)
+	utest.EqualNow(t, int(c.npages), 2)
+
+	time.Sleep(20 * time.Millisecond)
+	utest.EqualNow(t, pool.Reclaim(10*time.Millisecond), 1024+3*1024)
+	utest.EqualNow(t, int(c.npages), 1)
+	pool.Free(temp[0])
+}
+
+func Test_AtomPool_ReclaimBusy(t *testing.T) {
+	const ttl = 200 * time.Millisecond
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	utest.EqualNow(t, pool.Reclaim(ttl), 0)
+	time.Sleep(ttl / 2)
+
+	// the page is busy in between, its idle time starts again
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, pool.Reclaim(ttl), 0)
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Reclaim(ttl), 0)
+
+	time.Sleep(ttl * 3 / 4)
+	utest.EqualNow(t, pool.Reclaim(ttl), 3*1024)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+}
+
+func Test_AtomPool_ReclaimParallel(t *testing.T) {
+	// 8 goroutines hold up to 40 chunks, enough pages that none fall back to make
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(6))
+	r := pool.StartReclaimer(0, time.Millisecond)
+	defer r.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				bufs := pool.AllocBatch(128, 4)
+				bufs = append(bufs, pool.Alloc(128))
+				pool.FreeBatch(bufs[:4])
+				pool.Free(bufs[4])
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		pool.Trim()
+	}
+	wg.Wait()
+	r.Stop()
+
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.InUse, 0)
+	utest.EqualNow(t, s.DoubleFrees, uint64(0))
+	utest.EqualNow(t, s.Rejects, uint64(0))
+	utest.EqualNow(t, s.Free, s.Pages*pool.classes[0].perPage)
+}
+
+func Test_AtomPool_TrimEvents(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	utest.EqualNow(t, len(pool.TrimEvents()), 0)
+	pool.Trim()
+	pool.Trim()
+	utest.EqualNow(t, len(pool.TrimEvents()), 1)
+	for i := 0; i < 20; i++ {
+		mem := pool.Alloc(128)
+		pool.Free(mem)
+		pool.Trim()
+	}
+	events := pool.TrimEvents()
+	utest.EqualNow(t, len(events), 16)
+	utest.EqualNow(t, events[0].Released, 1024)
+	utest.Assert(t, !events[0].Time.Before(events[15].Time))
+	utest.EqualNow(t, pool.Reclaim(0), 0)
+	utest.EqualNow(t, pool.TrimEvents()[0], events[0])
+}
+
+func Test_AtomPool_ReleaseHook(t *testing.T) {
+	var events []ReleaseEvent
+	pool, err := NewPool(WithSizeRange(128, 256), WithPageSize(1024), WithOverflow(4096), WithOverflowCap(4),
+		WithReleaseHook(func(e ReleaseEvent) {
+			events = append(events, e)
+		}))
+	utest.IsNilNow(t, err)
+	pool.Free(pool.Alloc(1024))
+	pool.Trim()
+	utest.EqualNow(t, len(events), 3)
+	utest.EqualNow(t, events[0], ReleaseEvent{TrimExplicit, "class", 128, 1024})
+	utest.EqualNow(t, events[1], ReleaseEvent{TrimExplicit, "class", 256, 1024})
+	utest.EqualNow(t, events[2], ReleaseEvent{TrimExplicit, "overflow", 0, 1024})
+	utest.EqualNow(t, pool.TrimEvents()[0].Reason, TrimExplicit)
+
+	// 没有释放内存时不调用
+	pool.Trim()
+	utest.EqualNow(t, len(events), 3)
+
+	pool.Free(pool.Alloc(128))
+	pool.Reclaim(0)
+	utest.EqualNow(t, events[3], ReleaseEvent{TrimTTL, "class", 128, 1024})
+	utest.EqualNow(t, pool.TrimEvents()[0].Reason, TrimTTL)
+
+	pool.Free(pool.Alloc(128))
+	utest.IsNilNow(t, pool.SetMaxMemory(1))
+	utest.EqualNow(t, events[4].Reason, TrimBudget)
+	utest.IsNilNow(t, pool.SetMaxMemory(0))
+
+	mem := pool.Alloc(128)
+	utest.IsNilNow(t, pool.Migrate(NewAtomPool(128, 256, 2, 1024)))
+	pool.Free(mem)
+	utest.EqualNow(t, events[5], ReleaseEvent{TrimMigrate, "class", 128, 1024})
+	utest.EqualNow(t, len(events), 6)
+}
+
+func Test_Reclaimer_Stop(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	r := pool.StartReclaimer(0, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	r.Stop()
+	r.Stop()
+	utest.EqualNow(t, int(pool.classes[0].npages), 0)
+}
@@ -0,0 +1,58 @@
+package slab
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetMaxMemory change the memory budget of the pool set by WithMaxMemory while it's in use, 0 means no limit.
+// Lowering it below the memory held by the pool releases the free pages like Trim, the slab classes can't grow
+// until the pool holds less than the new budget.
+func (pool *AtomPool) SetMaxMemory(n int) error {
+	if n < 0 {
+		return fmt.Errorf("slab: invalid max memory %d", n)
+	}
+	pool.src.budget.limit.Store(int64(n))
+	if n > 0 && pool.src.budget.used.Load() > int64(n) {
+		pool.trim(TrimBudget)
+	}
+	return nil
+}
+
+// SetSampling change the sampling rate set by WithSampling while the pool is in use, 0 turns sampling off.
+// The chunks sampled before stay attributed to their call sites by Sites until they are freed,
+// scaled by the new rate. A Cache created before keeps the choice it made at creation.
+func (pool *AtomPool) SetSampling(rate int) error {
+	if rate < 0 {
+		return fmt.Errorf("slab: invalid sampling rate %d", rate)
+	}
+	if rate > 0 && pool.realTime > 0 {
+		return errors.New("slab: real-time mode can't be used with sampling")
+	}
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].sample.Store(int64(rate))
+	}
+	return nil
+}
+
+// Reconfigure create a pool configured like the pool and then by opts, e.g. WithClasses to add a larger class,
+// WithLeakDetection or WithoutDebug, and migrates the pool to it with Migrate, so the change doesn't need a restart.
+// The new pool replaces the pool in the registry when it has a name, and doesn't reuse the file set by WithFile
+// unless opts set another one. It returns the new pool, or an error if opts are invalid or the pool is closed or already migrated.
+func (pool *AtomPool) Reconfigure(opts ...Option) (*AtomPool, error) {
+	if pool.src.closed.Load() {
+		return nil, ErrPoolClosed
+	}
+	if pool.migrated.Load() != nil {
+		return nil, errors.New("slab: pool already migrated")
+	}
+	dst, err := NewPool(append(append(append([]Option{}, pool.opts...), WithFile("")), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Migrate(dst); err != nil {
+		dst.Close()
+		return nil, err
+	}
+	return dst, nil
+}
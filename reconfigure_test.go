@@ -0,0 +1,75 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_SetMaxMemory(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 1024, WithMaxPages(4), WithStrictBudget())
+	bufs := pool.AllocBatch(128, 24)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+
+	// 降低预算释放空闲的 page，之后的增长受新的预算限制
+	pool.FreeBatch(bufs[16:])
+	utest.IsNilNow(t, pool.SetMaxMemory(2048))
+	utest.EqualNow(t, pool.Stats()[0].Pages, 2)
+	_, err := pool.TryAlloc(128)
+	utest.Assert(t, err == ErrPoolExhausted)
+
+	utest.IsNilNow(t, pool.SetMaxMemory(0))
+	mem, err := pool.TryAlloc(128)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+	pool.Free(mem)
+	pool.FreeBatch(bufs[:16])
+	utest.NotNilNow(t, pool.SetMaxMemory(-1))
+}
+
+func Test_AtomPool_SetSampling(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	mem := pool.Alloc(128)
+	utest.EqualNow(t, len(pool.Sites()), 0)
+
+	utest.IsNilNow(t, pool.SetSampling(1))
+	mem2 := pool.Alloc(100)
+	sites := pool.Sites()
+	utest.EqualNow(t, len(sites), 1)
+	for _, s := range sites {
+		utest.EqualNow(t, s, SiteStats{1, 1, 128, 100})
+	}
+
+	// 关闭采样之后回收的 chunk 不会再被统计
+	utest.IsNilNow(t, pool.SetSampling(0))
+	pool.Free(mem2)
+	pool.Free(mem)
+	utest.IsNilNow(t, pool.SetSampling(1))
+	utest.EqualNow(t, len(pool.Sites()), 0)
+	utest.NotNilNow(t, pool.SetSampling(-1))
+
+	rt, err := NewPool(WithRealTime(4))
+	utest.IsNilNow(t, err)
+	utest.NotNilNow(t, rt.SetSampling(1))
+}
+
+func Test_AtomPool_Reconfigure(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithLeakDetection())
+	mem := pool.Alloc(128)
+
+	dst, err := pool.Reconfigure(WithSizeRange(128, 4096), WithoutDebug())
+	utest.IsNilNow(t, err)
+	utest.Assert(t, pool.Migrated() == dst)
+	utest.EqualNow(t, len(dst.Stats()), 6)
+	utest.EqualNow(t, cap(pool.Alloc(4096)), 4096)
+	utest.EqualNow(t, len(dst.Leaks(0)), 0)
+	utest.EqualNow(t, len(pool.Leaks(0)), 1)
+
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 0)
+	_, err = pool.Reconfigure()
+	utest.NotNilNow(t, err)
+	_, err = dst.Reconfigure(WithGrowthFactor(0))
+	utest.NotNilNow(t, err)
+	utest.Assert(t, dst.Migrated() == nil)
+}
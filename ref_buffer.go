@@ -0,0 +1,73 @@
+package slab
+
+import "sync/atomic"
+
+// RefBuffer is a reference counted chunk of Pool which can be shared by several holders,
+// e.g. a message fanned out to many subscribers. It is returned to the pool when the last holder releases it.
+type RefBuffer struct {
+	pool Pool
+	buf  []byte
+	refs int32
+}
+
+// NewRefBuffer create a RefBuffer with a chunk of size bytes alloc from pool, holding one reference.
+func NewRefBuffer(pool Pool, size int) *RefBuffer {
+	return &RefBuffer{pool: pool, buf: pool.Alloc(size), refs: 1}
+}
+
+// Bytes returns the chunk. It must not be used after the caller's reference is released.
+func (b *RefBuffer) Bytes() []byte { return b.buf }
+
+// Refs returns the current number of references.
+func (b *RefBuffer) Refs() int { return int(atomic.LoadInt32(&b.refs)) }
+
+// Retain add a reference for a new holder and returns b.
+// It panics if the buffer has already been returned to the pool.
+func (b *RefBuffer) Retain() *RefBuffer {
+	for {
+		refs := atomic.LoadInt32(&b.refs)
+		if refs <= 0 {
+			panic("slab.RefBuffer: Retain after Release")
+		}
+		if atomic.CompareAndSwapInt32(&b.refs, refs, refs+1) {
+			return b
+		}
+	}
+}
+
+// Release drop a reference and returns true if it was the last one, then the chunk is returned to the pool.
+// It panics if the buffer is released more times than it is retained.
+func (b *RefBuffer) Release() bool {
+	refs := atomic.AddInt32(&b.refs, -1)
+	if refs < 0 {
+		panic("slab.RefBuffer: Release too many times")
+	}
+	if refs == 0 {
+		buf := b.buf
+		b.buf = nil
+		b.pool.Free(buf)
+		return true
+	}
+	return false
+}
+
+// View is a zero-copy part of a RefBuffer, e.g. a parsed field of a frame, which holds its own reference,
+// so the chunk stays alive until both the views and the other holders release it.
+type View struct {
+	ref  *RefBuffer
+	data []byte
+}
+
+// View returns the view of the bytes b.Bytes()[i:j] and retains b for it.
+// The capacity of the view is locked to its length, so append never overwrites the rest of the chunk.
+func (b *RefBuffer) View(i, j int) View {
+	data := b.buf[i:j:j]
+	return View{ref: b.Retain(), data: data}
+}
+
+// Bytes returns the bytes of the view. They must not be used after the view is released.
+func (v View) Bytes() []byte { return v.data }
+
+// Release drops the reference of the view and returns true if it was the last one of the RefBuffer.
+// Each view must be released exactly once.
+func (v View) Release() bool { return v.ref.Release() }
@@ -0,0 +1,73 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_RefBuffer(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewRefBuffer(pool, 100)
+	utest.EqualNow(t, len(b.Bytes()), 100)
+	utest.EqualNow(t, b.Retain(), b)
+	utest.EqualNow(t, b.Refs(), 2)
+
+	utest.Assert(t, !b.Release())
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+	utest.Assert(t, b.Release())
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	func() {
+		defer func() {
+			utest.NotNilNow(t, recover())
+		}()
+		b.Retain()
+	}()
+	func() {
+		defer func() {
+			utest.NotNilNow(t, recover())
+		}()
+		b.Release()
+	}()
+}
+
+func Test_RefBuffer_View(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewRefBuffer(pool, 11)
+	copy(b.Bytes(), "hello world")
+
+	hello := b.View(0, 5)
+	world := b.View(6, 11)
+	utest.EqualNow(t, b.Refs(), 3)
+	utest.EqualNow(t, string(hello.Bytes()), "hello")
+	utest.EqualNow(t, cap(hello.Bytes()), 5)
+	utest.EqualNow(t, &world.Bytes()[0], &b.Bytes()[6])
+
+	// the frame is released first, the fields keep the chunk alive
+	utest.Assert(t, !b.Release())
+	utest.Assert(t, !hello.Release())
+	utest.EqualNow(t, string(world.Bytes()), "world")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+	utest.Assert(t, world.Release())
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_RefBuffer_FanOut(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	b := NewRefBuffer(pool, 128)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(b *RefBuffer) {
+			defer wg.Done()
+			_ = b.Bytes()[0]
+			b.Release()
+		}(b.Retain())
+	}
+	b.Release()
+	wg.Wait()
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.Stats()[0].DoubleFrees, uint64(0))
+}
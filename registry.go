@@ -0,0 +1,45 @@
+package slab
+
+import "sync"
+
+var registry = struct {
+	sync.RWMutex
+	pools map[string]*AtomPool
+}{
+	pools: make(map[string]*AtomPool),
+}
+
+// RegisterPool add a pool into the package-level registry.
+// A pool already registered with the same name is replaced.
+func RegisterPool(name string, pool *AtomPool) {
+	registry.Lock()
+	registry.pools[name] = pool
+	registry.Unlock()
+}
+
+// UnregisterPool remove the pool registered with name.
+func UnregisterPool(name string) {
+	registry.Lock()
+	delete(registry.pools, name)
+	registry.Unlock()
+}
+
+// RegisteredPools returns a copy of the package-level registry.
+func RegisteredPools() map[string]*AtomPool {
+	registry.RLock()
+	defer registry.RUnlock()
+	pools := make(map[string]*AtomPool, len(registry.pools))
+	for name, pool := range registry.pools {
+		pools[name] = pool
+	}
+	return pools
+}
+
+// unregister 在 name 仍然注册为 pool 时才删除，避免删掉之后用同一个名字注册的另一个 pool
+func unregister(name string, pool *AtomPool) {
+	registry.Lock()
+	if registry.pools[name] == pool {
+		delete(registry.pools, name)
+	}
+	registry.Unlock()
+}
@@ -0,0 +1,52 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Registry(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	RegisterPool("test", pool)
+	utest.Assert(t, RegisteredPools()["test"] == pool)
+
+	pools := RegisteredPools()
+	delete(pools, "test")
+	utest.Assert(t, RegisteredPools()["test"] == pool)
+
+	UnregisterPool("test")
+	_, exists := RegisteredPools()["test"]
+	utest.Assert(t, !exists)
+}
+
+func Test_Registry_WithName(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithName("named"))
+	utest.IsNilNow(t, err)
+	utest.Assert(t, RegisteredPools()["named"] == pool)
+
+	child, err := pool.NewChild()
+	utest.IsNilNow(t, err)
+	utest.Assert(t, RegisteredPools()["named"] == pool)
+	utest.IsNilNow(t, child.Close())
+
+	utest.IsNilNow(t, pool.Close())
+	_, exists := RegisteredPools()["named"]
+	utest.Assert(t, !exists)
+
+	// 关闭被替换的 pool 不影响之后用同一个名字注册的 pool
+	old, _ := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithName("named"))
+	pool, _ = NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithName("named"))
+	utest.IsNilNow(t, old.Close())
+	utest.Assert(t, RegisteredPools()["named"] == pool)
+	utest.IsNilNow(t, pool.Close())
+
+	sharded, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(1024), WithName("sharded"))
+	utest.IsNilNow(t, err)
+	utest.Assert(t, RegisteredPools()["sharded/0"] == sharded.shards[0])
+	utest.Assert(t, RegisteredPools()["sharded/1"] == sharded.shards[1])
+	_, exists = RegisteredPools()["sharded"]
+	utest.Assert(t, !exists)
+	UnregisterPool("sharded/0")
+	UnregisterPool("sharded/1")
+}
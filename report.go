@@ -0,0 +1,114 @@
+package slab
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Report is a summary of the health of a pool over an interval, made by StartReporter.
+type Report struct {
+	Name         string        // name of the pool set by WithName
+	Time         time.Time     // when the report is made
+	Interval     time.Duration // time since the previous report, or since StartReporter for the first one
+	Requests     uint64        // requests to the slab classes and larger ones during the interval
+	Fallbacks    uint64        // requests which fell back to the heap during the interval
+	Trims        int           // calls of Trim, Reclaim and the automatic trims which released memory during the interval
+	Released     int           // bytes released by them
+	InUse        int           // bytes of the chunks in use in all slab classes
+	Resident     int           // bytes of the pages owned by all slab classes
+	PeakInUse    int           // high-water mark of InUse, tracked only by a pool created WithPeaks
+	PeakResident int           // high-water mark of Resident
+}
+
+// Utilization returns the fraction of the resident bytes which are in use, 0 if the pool owns no page.
+func (r Report) Utilization() float64 {
+	if r.Resident == 0 {
+		return 0
+	}
+	return float64(r.InUse) / float64(r.Resident)
+}
+
+// FallbackRate returns the fraction of the requests of the interval which fell back to the heap.
+func (r Report) FallbackRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Fallbacks) / float64(r.Requests)
+}
+
+// LogValue implements slog.LogValuer, logging the report as a group of snake_case attributes.
+func (r Report) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 13)
+	if r.Name != "" {
+		attrs = append(attrs, slog.String("name", r.Name))
+	}
+	attrs = append(attrs,
+		slog.Duration("interval", r.Interval),
+		slog.Uint64("requests", r.Requests),
+		slog.Uint64("fallbacks", r.Fallbacks),
+		slog.Float64("fallback_rate", r.FallbackRate()),
+		slog.Int("trims", r.Trims),
+		slog.Int("released", r.Released),
+		slog.Int("in_use", r.InUse),
+		slog.Int("resident", r.Resident),
+		slog.Float64("utilization", r.Utilization()),
+		slog.Int("peak_in_use", r.PeakInUse),
+		slog.Int("peak_resident", r.PeakResident),
+	)
+	return slog.GroupValue(attrs...)
+}
+
+// StartReporter start a goroutine which calls fn with a Report of the pool every interval,
+// e.g. to log the pool health where there is no metrics pipeline. Call Stop to end the goroutine.
+func (pool *AtomPool) StartReporter(interval time.Duration, fn func(Report)) *Reclaimer {
+	return pool.startReclaimer(interval, pool.reporter(fn))
+}
+
+// StartLogReporter is like StartReporter, logging each report to logger at level as a "slab pool report" message
+// with the report in the "pool" group attribute.
+func (pool *AtomPool) StartLogReporter(logger *slog.Logger, level slog.Level, interval time.Duration) *Reclaimer {
+	return pool.StartReporter(interval, func(r Report) {
+		logger.LogAttrs(context.Background(), level, "slab pool report", slog.Any("pool", r))
+	})
+}
+
+// reporter 返回 StartReporter 每个周期执行的函数，每次调用报告自上次调用以来的变化
+func (pool *AtomPool) reporter(fn func(Report)) func() {
+	var (
+		last                 = time.Now()
+		lastReqs, lastFalls  = pool.requestTotals()
+		lastTrims, lastBytes = pool.trims.totals()
+	)
+	return func() {
+		r := Report{Name: pool.name, Time: time.Now()}
+		r.Interval = r.Time.Sub(last)
+
+		reqs, falls := pool.requestTotals()
+		r.Requests, r.Fallbacks = reqs-lastReqs, falls-lastFalls
+		trims, released := pool.trims.totals()
+		r.Trims, r.Released = trims-lastTrims, released-lastBytes
+		last, lastReqs, lastFalls, lastTrims, lastBytes = r.Time, reqs, falls, trims, released
+
+		for _, s := range pool.Stats() {
+			r.InUse += s.InUse * s.Size
+			r.Resident += s.Resident
+		}
+		peaks := pool.Peaks()
+		r.PeakInUse, r.PeakResident = peaks.InUse.Value, peaks.Resident.Value
+		fn(r)
+	}
+}
+
+// requestTotals 返回所有 class 和超过最大 chunk 的请求的总次数，以及其中回退到 heap 的次数
+func (pool *AtomPool) requestTotals() (requests, fallbacks uint64) {
+	for _, s := range pool.Stats() {
+		requests += s.Allocs + s.Fallbacks
+		fallbacks += s.Fallbacks
+	}
+	for _, s := range pool.OversizeStats() {
+		requests += s.Requests
+		fallbacks += s.Fallbacks
+	}
+	return requests, fallbacks
+}
@@ -0,0 +1,95 @@
+package slab
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Reporter(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithPeaks(), WithName("report-test"))
+	defer UnregisterPool("report-test")
+
+	var reports []Report
+	report := pool.reporter(func(r Report) {
+		reports = append(reports, r)
+	})
+	mem := pool.Alloc(128)
+	pool.Alloc(1024)
+	pool.Alloc(1024) // 1024 的 class 只有一个 chunk，回退到 heap
+	pool.Alloc(4096)
+	report()
+	utest.EqualNow(t, len(reports), 1)
+	r := reports[0]
+	utest.EqualNow(t, r.Name, "report-test")
+	utest.EqualNow(t, r.Requests, uint64(4))
+	utest.EqualNow(t, r.Fallbacks, uint64(2))
+	utest.EqualNow(t, r.FallbackRate(), 0.5)
+	utest.EqualNow(t, r.InUse, 128+1024)
+	utest.EqualNow(t, r.Resident, 4096)
+	utest.EqualNow(t, r.Utilization(), float64(128+1024)/4096)
+	utest.EqualNow(t, r.PeakInUse, 128+1024)
+	utest.EqualNow(t, r.PeakResident, 4096)
+
+	// 报告的是自上次报告以来的变化
+	pool.Free(mem)
+	utest.EqualNow(t, pool.Trim(), 3072)
+	report()
+	r = reports[1]
+	utest.EqualNow(t, r.Requests, uint64(0))
+	utest.EqualNow(t, r.FallbackRate(), 0.0)
+	utest.EqualNow(t, r.Trims, 1)
+	utest.EqualNow(t, r.Released, 3072)
+	utest.EqualNow(t, r.Resident, 1024)
+	utest.EqualNow(t, r.PeakResident, 4096)
+}
+
+func Test_AtomPool_StartLogReporter(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	var buf syncBuffer
+	rep := pool.StartLogReporter(slog.New(slog.NewJSONHandler(&buf, nil)), slog.LevelInfo, time.Millisecond)
+	for buf.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	rep.Stop()
+
+	line, _, _ := bytes.Cut(buf.Bytes(), []byte("\n"))
+	var v struct {
+		Msg  string
+		Pool map[string]interface{}
+	}
+	utest.IsNilNow(t, json.Unmarshal(line, &v))
+	utest.EqualNow(t, v.Msg, "slab pool report")
+	utest.EqualNow(t, v.Pool["resident"], float64(4096))
+	_, ok := v.Pool["fallback_rate"]
+	utest.Assert(t, ok)
+}
+
+// syncBuffer 是可以被 reporter 的 goroutine 并发写入的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
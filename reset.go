@@ -0,0 +1,102 @@
+package slab
+
+import "sync/atomic"
+
+// Reset return every chunk of the pool to the free state and clears the statistics, keeping the pages,
+// so the pool can be reused e.g. between test cases or batch phases without faulting its memory in again.
+// Call Trim after Reset to release the pages too, the pages of a child pool are returned to its parent.
+// It must be called when no buffer alloc from the pool is used any more, including the ones held by a Cache,
+// and no other goroutine is calling Alloc or Free.
+func (pool *AtomPool) Reset() {
+	for i := 0; i < len(pool.classes); i++ {
+		pool.classes[i].reset()
+	}
+	if pool.buddy != nil {
+		pool.buddy.reset()
+	}
+	pool.tags.reset()
+	for k := range pool.oversize {
+		pool.oversize[k].requests.Store(0)
+		pool.oversize[k].fallbacks.Store(0)
+	}
+}
+
+// reset 把所有 page 的全部 chunk 按下标顺序重新链成空闲链表，随机化或打乱模式下每个 page 内按随机顺序
+func (c *class) reset() {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	if c.quarantine != nil {
+		c.quarantine.clear()
+	}
+	if c.fifo != nil {
+		c.fifo.clear()
+	}
+	c.usage.inUse.Add(-int64(c.inUse.Swap(0)) * int64(c.size))
+	if c.marks != nil {
+		c.marks.above.Store(false)
+	}
+	var first uint64
+	var last *chunk
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p == nil {
+			continue
+		}
+		for _, i := range c.order(len(p.chunks)) {
+			chk := &p.chunks[i]
+			if c.guard > 0 {
+				off := i*c.stride + c.guard
+				fillGuards(p.mem[off-c.guard:off+c.size+c.guard], c.guard)
+			}
+			c.clearInfo(chk)
+			if p.meta != nil {
+				p.meta[i].Store(0)
+			}
+			if c.profile != nil {
+				c.profile.Remove(chk)
+			}
+			if c.poison {
+				memset(chk.mem, c.poisonBy)
+			}
+
+			// 和回收时一样增加 ABA 计数
+			chk.aba++
+			e := makeLink(uint64(n*c.perPage+i), chk.aba)
+			if last == nil {
+				first = e
+			} else {
+				last.next.Store(e)
+			}
+			last = chk
+		}
+	}
+	if last != nil {
+		last.next.Store(linkEnd)
+	}
+	c.head.Store(first)
+
+	c.allocs.Store(0)
+	c.fallbacks.Store(0)
+	c.overBudget.Store(0)
+	c.frees.Store(0)
+	c.rejects.Store(0)
+	c.doubleFrees.Store(0)
+	c.requests.Store(0)
+	c.requested.Store(0)
+}
+
+// reset 把每个 arena 恢复成一整块空闲块
+func (b *buddy) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range b.arenas {
+		for k := range a.free {
+			a.free[k] = make(map[int]struct{})
+		}
+		a.free[b.levels-1][0] = struct{}{}
+		a.used = make(map[int]int)
+	}
+	b.allocs, b.fallbacks, b.overBudget, b.frees, b.rejects, b.inUse = 0, 0, 0, 0, 0, 0
+}
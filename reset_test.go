@@ -0,0 +1,54 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Reset(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithLeakDetection())
+	var held [][]byte
+	for i := 0; i < 20; i++ {
+		held = append(held, pool.Alloc(128))
+	}
+	pool.Free(held[3])
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+	utest.EqualNow(t, len(pool.Leaks(0)), 19)
+
+	pool.Reset()
+	utest.IsNilNow(t, pool.Verify())
+	utest.EqualNow(t, len(pool.Leaks(0)), 0)
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Pages, 3)
+	utest.EqualNow(t, s.Allocs, uint64(0))
+	utest.EqualNow(t, s.Frees, uint64(0))
+
+	// the pages are reused instead of growing the class
+	for i := 0; i < 24; i++ {
+		mem := pool.Alloc(128)
+		utest.Assert(t, pool.classes[0].Owns(dataPtr(mem)))
+	}
+	utest.EqualNow(t, pool.Stats()[0].Pages, 3)
+
+	pool.Reset()
+	utest.EqualNow(t, pool.Trim(), 6*1024)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_ResetBuddy(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithBuddy(16*1024, 2))
+	a := pool.Alloc(2048)
+	pool.Alloc(4096)
+	utest.EqualNow(t, pool.BuddyStats().Arenas, 1)
+
+	pool.Reset()
+	s := pool.BuddyStats()
+	utest.EqualNow(t, s.Arenas, 1)
+	utest.EqualNow(t, s.InUse, 0)
+
+	// the whole arena is one free block again
+	b := pool.Alloc(16 * 1024)
+	utest.EqualNow(t, dataPtr(b), dataPtr(a))
+	utest.EqualNow(t, pool.BuddyStats().Arenas, 1)
+}
@@ -0,0 +1,94 @@
+package slab
+
+import "sync"
+
+// Ring allocates buffers in order out of blocks alloc from a Pool for data with strictly FIFO lifetimes,
+// e.g. the frames of a streaming pipeline. Instead of freeing each buffer, the consumer passes the mark returned
+// with a buffer to Release once it's done with that buffer and every buffer before it, and the blocks behind the mark
+// are freed in bulk. A Ring is safe for concurrent use, e.g. by a producer and a consumer goroutine.
+type Ring struct {
+	mu        sync.Mutex
+	pool      Pool
+	blockSize int
+	blocks    []ringBlock // 按分配顺序排列的 block，Release 从头部释放
+	cur       []byte      // 最后一个 block 未被分配的部分
+	pos       uint64      // 已分配的字节数，即下一个缓冲区的起始位置
+}
+
+// ringBlock 是 Ring 从 pool 分配的一个 block，end 是其中最后一个缓冲区的结束位置
+type ringBlock struct {
+	mem []byte
+	end uint64
+}
+
+// NewRing create a Ring which alloc blocks of blockSize bytes from pool.
+func NewRing(pool Pool, blockSize int) *Ring {
+	return &Ring{pool: pool, blockSize: blockSize}
+}
+
+// Alloc returns a []byte of size bytes after the buffers alloc before, and the mark to pass to Release when
+// it and every buffer before it are no longer used. Like Arena, requests larger than a quarter of the block size
+// get their own buffer from the pool and the capacity of the returned slice is locked to size.
+// It returns nil when the pool returns nil, e.g. a pool created with WithNoHeap is exhausted.
+func (r *Ring) Alloc(size int) (mem []byte, mark uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if size > r.blockSize/4 {
+		// 大块单独占用一个 block，当前 block 剩余的空间留给之后的小块
+		mem = r.pool.Alloc(size)
+		if mem == nil {
+			return nil, r.pos
+		}
+		r.pos += uint64(size)
+		r.blocks = append(r.blocks, ringBlock{mem, r.pos})
+		if len(r.cur) > 0 {
+			// 当前 block 之后还会分配，它的 end 随之更新，因此保持在队尾
+			n := len(r.blocks)
+			r.blocks[n-2], r.blocks[n-1] = r.blocks[n-1], r.blocks[n-2]
+		}
+		return mem[:size:size], r.pos
+	}
+	if size > len(r.cur) {
+		block := r.pool.Alloc(r.blockSize)
+		if block == nil {
+			return nil, r.pos
+		}
+		r.blocks = append(r.blocks, ringBlock{block, r.pos})
+		r.cur = block[:cap(block)]
+	}
+	mem = r.cur[:size:size]
+	r.cur = r.cur[size:]
+	r.pos += uint64(size)
+	r.blocks[len(r.blocks)-1].end = r.pos
+	return mem, r.pos
+}
+
+// Release free every block whose buffers all end at or before mark, the buffers alloc before mark must not be used after that.
+func (r *Ring) Release(mark uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(r.blocks) && r.blocks[n].end <= mark {
+		r.pool.Free(r.blocks[n].mem)
+		n++
+	}
+	if n == len(r.blocks) {
+		// 当前 block 也被释放了，之后的分配从新的 block 开始
+		r.cur = nil
+	}
+	m := copy(r.blocks, r.blocks[n:])
+	clear(r.blocks[m:])
+	r.blocks = r.blocks[:m]
+}
+
+// Reset free every block like Release of the last mark, the ring can be reused after that.
+func (r *Ring) Reset() {
+	r.Release(^uint64(0))
+}
+
+// Blocks returns the number of blocks currently held by the ring.
+func (r *Ring) Blocks() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.blocks)
+}
@@ -0,0 +1,77 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Ring(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	ring := NewRing(pool, 1024)
+
+	a, ma := ring.Alloc(200)
+	b, mb := ring.Alloc(200)
+	utest.EqualNow(t, len(a), 200)
+	utest.EqualNow(t, cap(a), 200)
+	utest.EqualNow(t, ma, uint64(200))
+	utest.EqualNow(t, mb, uint64(400))
+	utest.EqualNow(t, &ring.blocks[0].mem[200], &b[0])
+
+	// the first block is full
+	var marks []uint64
+	for i := 0; i < 5; i++ {
+		_, m := ring.Alloc(200)
+		marks = append(marks, m)
+	}
+	utest.EqualNow(t, ring.Blocks(), 2)
+
+	// a large buffer gets its own block, the current block stays last
+	_, ml := ring.Alloc(512)
+	_, mc := ring.Alloc(100)
+	utest.EqualNow(t, ring.Blocks(), 3)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 2)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 1)
+
+	// the buffers in the first block are consumed
+	ring.Release(mb)
+	utest.EqualNow(t, ring.Blocks(), 3)
+	ring.Release(marks[2])
+	utest.EqualNow(t, ring.Blocks(), 2)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 1)
+
+	ring.Release(ml)
+	utest.EqualNow(t, ring.Blocks(), 1)
+	utest.EqualNow(t, pool.Stats()[2].InUse, 0)
+
+	ring.Release(mc)
+	utest.EqualNow(t, ring.Blocks(), 0)
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+	}
+
+	// a new block is alloc after the current one is released
+	ring.Alloc(10)
+	utest.EqualNow(t, ring.Blocks(), 1)
+	ring.Reset()
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+}
+
+func Test_Ring_Concurrent(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	ring := NewRing(pool, 1024)
+	marks := make(chan uint64, 16)
+	go func() {
+		defer close(marks)
+		for i := 0; i < 1000; i++ {
+			mem, m := ring.Alloc(100)
+			mem[0] = byte(i)
+			marks <- m
+		}
+	}()
+	for m := range marks {
+		ring.Release(m)
+	}
+	utest.EqualNow(t, ring.Blocks(), 0)
+	utest.EqualNow(t, pool.Stats()[3].InUse, 0)
+}
@@ -0,0 +1,78 @@
+package slab
+
+import (
+	"sort"
+	"sync"
+)
+
+// pageRange 是 WithRouting 的 pool 的一个 page 的地址范围 [begin, end)
+type pageRange struct {
+	begin, end uintptr
+	pool       *AtomPool
+}
+
+// routes 是所有 WithRouting 的 pool 的 page 按地址排序的全局登记表，page 不会重叠
+var routes = struct {
+	sync.RWMutex
+	pages []pageRange
+}{}
+
+// addRoute 登记 page，调用者持有 class 的 growMu
+func addRoute(r pageRange) {
+	routes.Lock()
+	i := sort.Search(len(routes.pages), func(i int) bool { return routes.pages[i].begin >= r.begin })
+	routes.pages = append(routes.pages, pageRange{})
+	copy(routes.pages[i+1:], routes.pages[i:])
+	routes.pages[i] = r
+	routes.Unlock()
+}
+
+// removeRoute 删除起始地址为 begin 的 page 的登记
+func removeRoute(begin uintptr) {
+	routes.Lock()
+	i := sort.Search(len(routes.pages), func(i int) bool { return routes.pages[i].begin >= begin })
+	if i < len(routes.pages) && routes.pages[i].begin == begin {
+		routes.pages = append(routes.pages[:i], routes.pages[i+1:]...)
+	}
+	routes.Unlock()
+}
+
+// Route returns the pool created WithRouting whose slab pages hold mem, or nil if there is none,
+// e.g. mem is a heap fallback or a block of the buddy tier, or the page has been released.
+func Route(mem []byte) *AtomPool {
+	if cap(mem) == 0 {
+		return nil
+	}
+	ptr := dataPtr(mem)
+	routes.RLock()
+	defer routes.RUnlock()
+	i := sort.Search(len(routes.pages), func(i int) bool { return routes.pages[i].end > ptr })
+	if i < len(routes.pages) && routes.pages[i].begin <= ptr {
+		return routes.pages[i].pool
+	}
+	return nil
+}
+
+// FreeRouted release mem to the pool Route returns, for buffers which cross subsystems using different pools
+// where the caller doesn't know the pool mem came from. It returns false and does nothing if Route returns nil.
+func FreeRouted(mem []byte) bool {
+	pool := Route(mem)
+	if pool == nil {
+		return false
+	}
+	pool.Free(mem)
+	return true
+}
+
+// route 在新增 page 之后或者摘下它时更新全局登记表
+func (c *class) route(p *page, added bool) {
+	if c.owner == nil {
+		return
+	}
+	begin := dataPtr(p.mem)
+	if added {
+		addRoute(pageRange{begin, begin + uintptr(len(p.mem)), c.owner})
+	} else {
+		removeRoute(begin)
+	}
+}
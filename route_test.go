@@ -0,0 +1,51 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_FreeRouted(t *testing.T) {
+	a := NewAtomPool(128, 1024, 2, 1024, WithRouting())
+	b := NewAtomPool(128, 1024, 2, 1024, WithRouting())
+	c := NewAtomPool(128, 1024, 2, 1024)
+
+	ma, mb, mc := a.Alloc(100), b.Alloc(1000), c.Alloc(100)
+	utest.Assert(t, Route(ma) == a)
+	utest.Assert(t, Route(mb[10:]) == b)
+	utest.Assert(t, Route(mc) == nil)
+	utest.Assert(t, Route(make([]byte, 10)) == nil)
+	utest.Assert(t, Route(nil) == nil)
+
+	utest.Assert(t, FreeRouted(ma))
+	utest.Assert(t, FreeRouted(mb))
+	utest.Assert(t, !FreeRouted(mc))
+	utest.EqualNow(t, a.Stats()[0].Frees, uint64(1))
+	utest.EqualNow(t, b.Stats()[3].Frees, uint64(1))
+	utest.EqualNow(t, c.Stats()[0].Frees, uint64(0))
+
+	// 释放的 page 不再登记
+	utest.EqualNow(t, a.Trim(), 4096)
+	utest.Assert(t, Route(ma) == nil)
+	utest.Assert(t, Route(mb) == b)
+	b.Close()
+	utest.Assert(t, Route(mb) == nil)
+}
+
+func Test_Manager_Free(t *testing.T) {
+	m := NewManager(0)
+	defer m.Close()
+	a, err := m.NewPool("a", WithSizeRange(128, 1024), WithPageSize(1024))
+	utest.IsNilNow(t, err)
+	b, err := m.NewPool("b", WithSizeRange(128, 1024), WithPageSize(1024))
+	utest.IsNilNow(t, err)
+
+	ma, mb := a.Alloc(200), b.Alloc(200)
+	utest.Assert(t, m.Free(mb))
+	utest.Assert(t, m.Free(ma))
+	utest.Assert(t, !m.Free(make([]byte, 200)))
+	utest.EqualNow(t, a.Stats()[1].InUse, 0)
+	utest.EqualNow(t, b.Stats()[1].InUse, 0)
+	utest.EqualNow(t, a.Stats()[1].Frees, uint64(1))
+}
@@ -0,0 +1,70 @@
+package slab
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// SafeBuffer is a handle of a chunk which checks on every access that the chunk has not been freed,
+// so a use after free panics where it happens instead of silently reading or writing another request's data.
+// It's meant for debug builds, production code can switch to the raw []byte of Alloc.
+// Buffers larger than the largest chunk size or falling back to make are not checked.
+type SafeBuffer struct {
+	pool *AtomPool
+	c    *class // 为 nil 时 mem 不是 chunk，不做检查
+	idx  uint64 // chunk 的全局下标
+	gen  uint32 // 分配时 chunk 的 ABA 计数，chunk 被回收时增加
+	mem  []byte
+}
+
+// AllocSafe alloc a []byte like Alloc and returns a SafeBuffer handle of it.
+func (pool *AtomPool) AllocSafe(size int) SafeBuffer {
+	mem := pool.Alloc(size)
+	b := SafeBuffer{pool: pool, mem: mem}
+	if size > pool.maxSize || cap(mem) == 0 {
+		return b
+	}
+	ptr := dataPtr(mem)
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if c.size == cap(mem) {
+			if chk, idx := c.find(ptr); chk != nil {
+				b.c, b.idx, b.gen = c, idx, chk.aba
+			}
+			break
+		}
+	}
+	return b
+}
+
+// Bytes returns the buffer. It panics if the chunk has been freed since the handle was created,
+// even if it has been alloc again by another request.
+func (b SafeBuffer) Bytes() []byte {
+	if b.c != nil {
+		if chk := b.c.chunk(b.idx); chk == nil || chk.aba != b.gen {
+			panic(fmt.Sprintf("slab.SafeBuffer: use after free of %d bytes chunk %d", b.c.size, b.idx))
+		}
+	}
+	return b.mem
+}
+
+// Free return the buffer to the pool like AtomPool.Free, it panics like Bytes if the chunk has already been freed.
+func (b SafeBuffer) Free() {
+	b.pool.Free(b.Bytes())
+}
+
+// find 返回首地址为 ptr 的 chunk 及其全局下标，ptr 不是本 class 某个 chunk 的开头时返回 nil
+func (c *class) find(ptr uintptr) (*chunk, uint64) {
+	nslots := int(atomic.LoadInt32(&c.nslots))
+	for n := 0; n < nslots; n++ {
+		p := c.page(n)
+		if p != nil && p.begin <= ptr && ptr <= p.end {
+			if (ptr-p.begin)%uintptr(c.stride) != 0 {
+				return nil, 0
+			}
+			i := int((ptr - p.begin) / uintptr(c.stride))
+			return &p.chunks[i], uint64(n*c.perPage + i)
+		}
+	}
+	return nil, 0
+}
@@ -0,0 +1,44 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_SafeBuffer(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(2))
+	stale := func(f func()) (msg interface{}) {
+		defer func() {
+			msg = recover()
+		}()
+		f()
+		return nil
+	}
+
+	b := pool.AllocSafe(100)
+	utest.EqualNow(t, len(b.Bytes()), 100)
+	b.Bytes()[0] = 1
+	b.Free()
+	utest.EqualNow(t, stale(func() { b.Bytes() }), "slab.SafeBuffer: use after free of 128 bytes chunk 0")
+	utest.NotNilNow(t, stale(b.Free))
+
+	// the chunk alloc again by another request is not accessible through the old handle
+	b2 := pool.AllocSafe(128)
+	utest.EqualNow(t, dataPtr(b2.Bytes()), dataPtr(b.mem))
+	utest.NotNilNow(t, stale(func() { b.Bytes() }))
+	b2.Free()
+
+	// a page released and grown again doesn't reuse old generations
+	b3 := pool.AllocSafe(128)
+	b3.Free()
+	pool.Trim()
+	pool.Alloc(128)
+	utest.NotNilNow(t, stale(func() { b3.Bytes() }))
+
+	// heap fallbacks are not checked
+	h := pool.AllocSafe(4096)
+	utest.EqualNow(t, len(h.Bytes()), 4096)
+	h.Free()
+	utest.EqualNow(t, len(h.Bytes()), 4096)
+}
@@ -0,0 +1,91 @@
+package slab
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// SiteStats is the chunks in use alloc from a call site, estimated from the sampled allocations.
+type SiteStats struct {
+	Samples   int // sampled chunks still in use
+	Chunks    int // estimated number of chunks in use, Samples scaled by the sampling rate
+	Bytes     int // estimated bytes of these chunks
+	Requested int // estimated bytes requested by the allocations of these chunks
+}
+
+// Waste returns the estimated bytes of the chunks beyond the requested sizes, the internal fragmentation caused by the call site,
+// e.g. a site asking for 1025 bytes and getting chunks of 2048 bytes wastes 1023 bytes per chunk.
+func (s SiteStats) Waste() int {
+	return s.Bytes - s.Requested
+}
+
+// Sites returns the chunks in use by call site, keyed by the function and file:line of the first caller outside the package,
+// e.g. to find which subsystem is hoarding chunks or wasting them with requests just above a class size. It only works when the pool is created with WithSampling or WithLeakDetection
+// and can be called periodically.
+func (pool *AtomPool) Sites() map[string]SiteStats {
+	sites := make(map[string]SiteStats)
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		rate := int(c.sample.Load())
+		if c.leak {
+			rate = 1
+		}
+		if rate == 0 {
+			continue
+		}
+		nslots := int(atomic.LoadInt32(&c.nslots))
+		for n := 0; n < nslots; n++ {
+			p := c.page(n)
+			if p == nil {
+				continue
+			}
+			for j := 0; j < len(p.chunks); j++ {
+				info := (*allocInfo)(atomic.LoadPointer(&p.chunks[j].info))
+				if info == nil {
+					continue
+				}
+				site := callSite(info.stack)
+				s := sites[site]
+				s.Samples++
+				s.Chunks += rate
+				s.Bytes += rate * c.size
+				s.Requested += rate * info.size
+				sites[site] = s
+			}
+		}
+	}
+	return sites
+}
+
+// sampled 判断是否记录本次分配的调用栈，每 sample 次分配记录一次
+func (c *class) sampled() bool {
+	rate := c.sample.Load()
+	return rate > 0 && c.samples.Add(1)%uint64(rate) == 0
+}
+
+// clearInfo 清除 chunk 的分配信息，采样可能已被 SetSampling 关闭，因此按 chk.info 而不是开关判断
+func (c *class) clearInfo(chk *chunk) {
+	if atomic.LoadPointer(&chk.info) != nil {
+		atomic.StorePointer(&chk.info, nil)
+	}
+}
+
+// pkgDir 是本包源文件所在的目录，用来跳过调用栈中本包的帧
+var pkgDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return path.Dir(file)
+}()
+
+// callSite 返回调用栈中第一个不属于本包的帧，测试代码不算本包
+func callSite(pcs []uintptr) string {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if path.Dir(frame.File) != pkgDir || strings.HasSuffix(frame.File, "_test.go") || !more {
+			return fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package slab
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func sampleA(pool *AtomPool, n int) (bufs [][]byte) {
+	for i := 0; i < n; i++ {
+		bufs = append(bufs, pool.Alloc(128))
+	}
+	return
+}
+
+func sampleB(pool *AtomPool, n int) [][]byte {
+	return pool.AllocBatch(256, n)
+}
+
+func Test_AtomPool_Sites(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 64*1024, WithSampling(4))
+	a := sampleA(pool, 400)
+	b := sampleB(pool, 200)
+
+	site := func(name string) SiteStats {
+		var found SiteStats
+		for k, s := range pool.Sites() {
+			if strings.HasPrefix(k, "github.com/funny/slab."+name+" ") {
+				found = s
+			}
+		}
+		return found
+	}
+	utest.EqualNow(t, site("sampleA"), SiteStats{100, 400, 400 * 128, 400 * 128})
+	utest.EqualNow(t, site("sampleB"), SiteStats{50, 200, 200 * 256, 200 * 256})
+
+	// freed chunks are no longer attributed
+	pool.FreeBatch(a)
+	utest.EqualNow(t, site("sampleA"), SiteStats{})
+	pool.FreeBatch(b[:100])
+	utest.EqualNow(t, site("sampleB").Chunks, 100)
+	utest.EqualNow(t, len(pool.Sites()), 1)
+}
+
+func Test_AtomPool_SitesLeakDetection(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithLeakDetection())
+	mem := pool.Alloc(128)
+	sites := pool.Sites()
+	utest.EqualNow(t, len(sites), 1)
+	for k, s := range sites {
+		utest.Assert(t, strings.HasPrefix(k, "github.com/funny/slab.Test_AtomPool_SitesLeakDetection "), k)
+		utest.EqualNow(t, s, SiteStats{1, 1, 128, 128})
+	}
+	pool.Free(mem)
+	utest.EqualNow(t, len(pool.Sites()), 0)
+}
+
+func sampleWaste(pool *AtomPool, n int) (bufs [][]byte) {
+	for i := 0; i < n; i++ {
+		bufs = append(bufs, pool.Alloc(1025))
+	}
+	return
+}
+
+func Test_AtomPool_SitesWaste(t *testing.T) {
+	pool := NewAtomPool(128, 2048, 2, 64*1024, WithSampling(2))
+	bufs := sampleWaste(pool, 10)
+	snap := pool.Snapshot()
+	var s SiteStats
+	for k, v := range snap.Sites {
+		if strings.HasPrefix(k, "github.com/funny/slab.sampleWaste ") {
+			s = v
+		}
+	}
+	utest.EqualNow(t, s, SiteStats{5, 10, 10 * 2048, 10 * 1025})
+	utest.EqualNow(t, s.Waste(), 10*1023)
+
+	data, err := snap.MarshalJSON()
+	utest.IsNilNow(t, err)
+	utest.Assert(t, strings.Contains(string(data), `"requested_bytes":10250`), string(data))
+	var back Snapshot
+	utest.IsNilNow(t, back.UnmarshalJSON(data))
+	utest.EqualNow(t, fmt.Sprint(back.Sites), fmt.Sprint(snap.Sites))
+	utest.EqualNow(t, fmt.Sprint(snap.Delta(snap).Sites), fmt.Sprint(snap.Sites))
+
+	pool.FreeBatch(bufs)
+	utest.IsNilNow(t, pool.Snapshot().Sites)
+}
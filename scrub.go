@@ -0,0 +1,127 @@
+package slab
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Audit walks the free list of every slab class like Verify and checks every free chunk on the way:
+// the guards of WithGuards and the pattern of WithPoison must be intact, a chunk written after it is freed is reported.
+// Guards are reported as a *CorruptionError and everything else as a *VerifyError, trampled guards and poison are restored.
+// Unlike Verify it can run concurrently with Alloc and Free, which wait for the slab class being checked like Trim.
+// A free list broken by a bad link is cut there and the chunks behind the link are lost.
+// The slab classes of a real-time pool are not checked.
+func (pool *AtomPool) Audit() []error {
+	var errs []error
+	for i := 0; i < len(pool.classes); i++ {
+		errs = append(errs, pool.classes[i].audit()...)
+	}
+	return errs
+}
+
+// audit 和 reclaim 一样先把整条空闲链表摘下来，在私有的链表上检查，再用新的 ABA 计数重建并挂回
+func (c *class) audit() []error {
+	if c.fixed {
+		return nil
+	}
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	// 期间空闲链表为空，让 grow 等待 growMu 而不是直接失败
+	atomic.StoreInt32(&c.reclaiming, 1)
+	defer atomic.StoreInt32(&c.reclaiming, 0)
+	c.takeFIFO()
+	head := c.head.Swap(0)
+
+	var errs []error
+	fail := func(idx int, format string, args ...interface{}) {
+		errs = append(errs, &VerifyError{Size: c.size, Chunk: idx, Reason: fmt.Sprintf(format, args...)})
+	}
+	total := int(atomic.LoadInt32(&c.nslots)) * c.perPage
+	seen := make([]bool, total)
+	var first uint64
+	var last *chunk
+	for v := head; v != 0; {
+		// 链接损坏时无法找到之后的 chunk，链表在这里截断
+		idx := int(linkIndex(v))
+		if idx >= total {
+			fail(idx, "index out of range [0, %d)", total)
+			break
+		}
+		chk := c.chunk(uint64(idx))
+		if chk == nil {
+			fail(idx, "in a released page")
+			break
+		}
+		if seen[idx] {
+			fail(idx, "free list has a cycle")
+			break
+		}
+		seen[idx] = true
+		if linkABA(v) != chk.aba {
+			fail(idx, "ABA counter %d in the link does not match %d of the chunk", linkABA(v), chk.aba)
+		}
+		if c.leak && atomic.LoadPointer(&chk.info) != nil {
+			fail(idx, "both free and allocated")
+		}
+		if c.guard > 0 {
+			if err := c.checkGuards(c.page(idx/c.perPage), idx%c.perPage, chk); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if c.poison {
+			if off := indexNot(chk.mem, c.poisonBy); off >= 0 {
+				fail(idx, "poison overwritten at byte %d, the chunk is written after free", off)
+				memset(chk.mem, c.poisonBy)
+			}
+		}
+
+		// 每个 chunk 的 ABA 计数都要增加，被挂起的 pop 持有的旧 head 值才不会再次匹配
+		nxt := nextLink(chk.next.Load())
+		chk.aba++
+		e := makeLink(uint64(idx), chk.aba)
+		if last == nil {
+			first = e
+		} else {
+			last.next.Store(e)
+		}
+		last = chk
+		v = nxt
+	}
+	if last != nil {
+		c.pushRun(first, last)
+	}
+	return errs
+}
+
+// indexNot 返回 b 中第一个不等于 v 的字节的下标，都等于 v 时返回 -1
+func indexNot(b []byte, v byte) int {
+	for i, x := range b {
+		if x != v {
+			return i
+		}
+	}
+	return -1
+}
+
+// Scrubber is a goroutine checking the free chunks of a pool, created by AtomPool.StartScrubber.
+type Scrubber struct {
+	r *Reclaimer
+}
+
+// StartScrubber start a goroutine calling Audit every interval and passing each error it finds to onCorruption,
+// so memory corrupted by a bug is detected soon after it happens rather than by its effects much later.
+// Call Stop to end the goroutine.
+func (pool *AtomPool) StartScrubber(interval time.Duration, onCorruption func(err error)) *Scrubber {
+	return &Scrubber{pool.startReclaimer(interval, func() {
+		for _, err := range pool.Audit() {
+			onCorruption(err)
+		}
+	})}
+}
+
+// Stop end the scrubber goroutine and wait for it to exit. It is safe to call Stop more than once.
+func (s *Scrubber) Stop() {
+	s.r.Stop()
+}
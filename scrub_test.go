@@ -0,0 +1,98 @@
+package slab
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Audit(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096, WithPoison(0xDD), WithGuards(func(error) {}))
+	bufs := pool.AllocBatch(128, 4)
+	pool.FreeBatch(bufs)
+	utest.EqualNow(t, len(pool.Audit()), 0)
+
+	// 释放之后写入
+	bufs[1][5] = 1
+	outOfBounds(bufs[2], 128, 1)[0] = 1
+	errs := pool.Audit()
+	utest.EqualNow(t, len(errs), 2)
+	var poisoned *VerifyError
+	var trampled *CorruptionError
+	for _, err := range errs {
+		switch err := err.(type) {
+		case *VerifyError:
+			poisoned = err
+		case *CorruptionError:
+			trampled = err
+		}
+	}
+	utest.NotNilNow(t, poisoned)
+	utest.Assert(t, strings.Contains(poisoned.Reason, "at byte 5"), poisoned.Reason)
+	utest.NotNilNow(t, trampled)
+	utest.Assert(t, trampled.Overflow)
+
+	// 毒化的内容和 guard 都已修复，空闲链表依然完整
+	utest.EqualNow(t, len(pool.Audit()), 0)
+	utest.EqualNow(t, bufs[1][5], byte(0xDD))
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_AuditBrokenLink(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	c := &pool.classes[0]
+	c.chunk(3).next.Store(100 << 32)
+	errs := pool.Audit()
+	utest.EqualNow(t, len(errs), 1)
+	utest.EqualNow(t, errs[0].(*VerifyError).Chunk, 99)
+
+	// 链表在损坏处截断，之前的 chunk 仍然可以分配
+	for i := 0; i < 4; i++ {
+		utest.Assert(t, pool.Owns(pool.Alloc(128)))
+	}
+	utest.Assert(t, !pool.Owns(pool.Alloc(128)))
+}
+
+func Test_AtomPool_Scrubber(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithPoison(0xDD))
+	var mu sync.Mutex
+	var reported []error
+	s := pool.StartScrubber(time.Millisecond, func(err error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				mem := pool.Alloc(128)
+				mem[0] = 1
+				pool.Free(mem)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Stop()
+	s.Stop()
+	utest.EqualNow(t, len(reported), 0)
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(0))
+	utest.IsNilNow(t, pool.Verify())
+
+	mem := pool.Alloc(128)
+	pool.Free(mem)
+	mem[0] = 1
+	s = pool.StartScrubber(time.Millisecond, func(err error) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	})
+	time.Sleep(20 * time.Millisecond)
+	s.Stop()
+	utest.EqualNow(t, len(reported), 1)
+}
@@ -0,0 +1,178 @@
+package slab
+
+import (
+	"runtime"
+	"strconv"
+	"unsafe"
+)
+
+// ShardedPool is a lock-free slab allocation memory pool split into shards,
+// each shard has its own pages and free lists to reduce CAS contention on hot classes.
+// A goroutine allocates from the shard chosen by its stack address and steals from the other shards when its shard is empty.
+type ShardedPool struct {
+	shards  []*AtomPool
+	maxSize int
+	pick    func() int
+}
+
+// NewShardedPool create a sharded pool, every shard is an AtomPool configured by opts.
+// If n <= 0 the number of shards is GOMAXPROCS.
+// Limits like WithMaxPages and WithMaxMemory apply to each shard.
+func NewShardedPool(n int, opts ...Option) (*ShardedPool, error) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	pool := &ShardedPool{shards: make([]*AtomPool, n)}
+	for i := 0; i < n; i++ {
+		shard, err := NewPool(shardOpts(opts, i)...)
+		if err != nil {
+			return nil, err
+		}
+		pool.shards[i] = shard
+	}
+	pool.maxSize = pool.shards[0].maxSize
+	return pool, nil
+}
+
+// shardOpts 返回第 i 个 shard 的配置，设置了 WithName 时 shard 注册为 name/i
+func shardOpts(opts []Option, i int) []Option {
+	cfg := configure(opts)
+	if cfg.name == "" {
+		return opts
+	}
+	return append(append([]Option{}, opts...), WithName(cfg.name+"/"+strconv.Itoa(i)))
+}
+
+// shard 选择当前 goroutine 使用的 shard
+func (pool *ShardedPool) shard() int {
+	if pool.pick != nil {
+		return pool.pick() % len(pool.shards)
+	}
+	return pool.stackShard()
+}
+
+// stackShard 根据当前 goroutine 的栈地址选择 shard，同一个 goroutine 通常落在同一个 shard 上
+func (pool *ShardedPool) stackShard() int {
+	var x byte
+	h := uintptr(unsafe.Pointer(&x)) >> 13
+	h ^= h >> 7
+	return int(h % uintptr(len(pool.shards)))
+}
+
+// Alloc try alloc a []byte from the free chunks of the slab class of the local shard, then from other shards,
+// then grows the class of the local shard, if it can't grow Alloc will make one. Alloc(0) returns an empty slice like AtomPool.Alloc.
+func (pool *ShardedPool) Alloc(size int) (mem []byte) {
+	local := pool.shard()
+	pooled := true
+	if shard := pool.shards[local]; shard.hooks {
+		defer func() { shard.allocated(size, mem, pooled) }()
+	}
+	pool.shards[local].recordSize(1, size)
+	if size == 0 {
+		pooled = false
+		return emptyBuf
+	}
+	if i := pool.shards[local].classIndex(size); i >= 0 {
+		// 先取本 shard 和其它 shard 已有的空闲 chunk，都没有时才增长本 shard
+		for k := 0; k <= len(pool.shards); k++ {
+			c := &pool.shards[(local+k)%len(pool.shards)].classes[i]
+			if mem, _ := c.pop(size, k == len(pool.shards)); mem != nil {
+				c.allocs.Add(1)
+				c.request(1, size)
+				return mem[:size]
+			}
+		}
+		pool.shards[local].classes[i].fallbacks.Add(1)
+	}
+	var over *oversizeBucket
+	if size > pool.maxSize {
+		over = pool.shards[local].oversized(size)
+		over.requests.Add(1)
+	}
+	if b := pool.shards[local].buddy; size > pool.maxSize && b != nil && size <= b.maxBlock {
+		if mem, _ = b.alloc(size); mem != nil {
+			return mem
+		}
+	}
+	if o := pool.shards[local].overflow; size > pool.maxSize && o != nil {
+		if mem = o.alloc(size); mem != nil {
+			return mem
+		}
+	}
+	if over != nil {
+		over.fallbacks.Add(1)
+	}
+	pooled = false
+	return pool.shards[local].heap(size)
+}
+
+// Free release a []byte that alloc from ShardedPool.Alloc, the chunk is returned to the shard owning it.
+func (pool *ShardedPool) Free(mem []byte) {
+	if cap(mem) == 0 && dataPtr(mem) == dataPtr(emptyBuf) {
+		pool.shards[pool.shard()].freed(mem, 0, false)
+		return
+	}
+	// 和 AtomPool.Free 一样按首指针查找，重新切片过的 mem 只可能属于 chunk 大小不小于 cap(mem) 的 class
+	size := cap(mem)
+	classes := pool.shards[0].classes
+	exact := -1
+	for i := 0; i < len(classes); i++ {
+		if classes[i].size < size {
+			continue
+		}
+		for _, shard := range pool.shards {
+			c := &shard.classes[i]
+			ok, err := c.Push(mem)
+			if err == ErrDoubleFree {
+				c.doubleFrees.Add(1)
+				if shard.onDoubleFree != nil {
+					shard.onDoubleFree(mem)
+					return
+				}
+				panic(shard.freeError(mem, ErrDoubleFree))
+			}
+			if ok {
+				c.frees.Add(1)
+				shard.freed(mem, c.size, true)
+				// guard 被改写时和 AtomPool.Free 一样报告
+				shard.report(mem, err)
+				return
+			}
+		}
+		if classes[i].size == size {
+			exact = i
+		}
+	}
+	if exact >= 0 {
+		classes[exact].rejects.Add(1)
+	}
+	for _, shard := range pool.shards {
+		if shard.buddy == nil {
+			break
+		}
+		if ok, err := shard.buddy.free(mem); ok {
+			if err == ErrDoubleFree {
+				if shard.onDoubleFree != nil {
+					shard.onDoubleFree(mem)
+					return
+				}
+				panic(shard.freeError(mem, ErrDoubleFree))
+			}
+			if err == nil {
+				shard.freed(mem, 0, true)
+			}
+			return
+		}
+	}
+	shard := pool.shards[pool.shard()]
+	if shard.overflow != nil && shard.overflow.free(mem) {
+		shard.freed(mem, 0, true)
+		return
+	}
+	shard.freed(mem, 0, false)
+}
+
+// Shards returns the shards of the pool, e.g. to read their Stats.
+func (pool *ShardedPool) Shards() []*AtomPool {
+	return pool.shards
+}
@@ -0,0 +1,178 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_ShardedPool_AllocAndFree(t *testing.T) {
+	pool, err := NewShardedPool(4, WithSizeRange(128, 1024), WithPageSize(1024))
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(pool.Shards()), 4)
+
+	mem := pool.Alloc(100)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, cap(mem), 128)
+	pool.Free(mem)
+
+	mem = pool.Alloc(256)
+	pool.Free(mem[:128:128])
+	for _, shard := range pool.Shards() {
+		for _, s := range shard.Stats() {
+			utest.EqualNow(t, s.InUse, 0)
+			utest.EqualNow(t, s.Rejects, uint64(0))
+		}
+	}
+
+	mem = pool.Alloc(2048)
+	utest.EqualNow(t, cap(mem), 2048)
+	pool.Free(mem)
+}
+
+func Test_ShardedPool_Steal(t *testing.T) {
+	pool, err := NewShardedPool(4, WithSizeRange(128, 1024), WithPageSize(1024))
+	utest.IsNilNow(t, err)
+
+	// all chunks of all shards can be allocated from a single goroutine
+	temp := make([][]byte, 4*8)
+	for i := 0; i < len(temp); i++ {
+		temp[i] = pool.Alloc(128)
+	}
+	for _, shard := range pool.Shards() {
+		utest.EqualNow(t, shard.Stats()[0].InUse, 8)
+	}
+	utest.EqualNow(t, cap(pool.Alloc(128)), 128)
+
+	for i := 0; i < len(temp); i++ {
+		pool.Free(temp[i])
+	}
+	for _, shard := range pool.Shards() {
+		utest.EqualNow(t, shard.Stats()[0].InUse, 0)
+	}
+}
+
+func Test_ShardedPool_StealBeforeGrow(t *testing.T) {
+	pool, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(1024), WithMaxPages(2))
+	utest.IsNilNow(t, err)
+	local := 0
+	pool.pick = func() int { return local }
+
+	// 本 shard 用完之后先取另一个 shard 的空闲 chunk，不会让它增长
+	var temp [][]byte
+	for i := 0; i < 16; i++ {
+		temp = append(temp, pool.Alloc(128))
+	}
+	utest.EqualNow(t, pool.shards[0].Stats()[0].Pages, 1)
+	utest.EqualNow(t, pool.shards[1].Stats()[0].Pages, 1)
+	utest.EqualNow(t, pool.shards[1].Stats()[0].InUse, 8)
+
+	// 都没有空闲 chunk 时增长本 shard
+	temp = append(temp, pool.Alloc(128))
+	utest.EqualNow(t, pool.shards[0].Stats()[0].Pages, 2)
+	utest.EqualNow(t, pool.shards[1].Stats()[0].Pages, 1)
+	for _, mem := range temp {
+		pool.Free(mem)
+	}
+
+	mem := pool.Alloc(0)
+	utest.EqualNow(t, dataPtr(mem), dataPtr(emptyBuf))
+	pool.Free(mem)
+	for _, shard := range pool.Shards() {
+		utest.EqualNow(t, shard.Stats()[0].InUse, 0)
+		utest.EqualNow(t, shard.Stats()[0].Rejects, uint64(0))
+	}
+}
+
+func Test_ShardedPool_Parallel(t *testing.T) {
+	pool, err := NewShardedPool(0, WithSizeRange(128, 1024), WithPageSize(64*1024))
+	utest.IsNilNow(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				pool.Free(pool.Alloc(256))
+			}
+		}()
+	}
+	wg.Wait()
+	for _, shard := range pool.Shards() {
+		utest.EqualNow(t, shard.Stats()[1].InUse, 0)
+	}
+}
+
+func Test_ShardedPool_DoubleFree(t *testing.T) {
+	pool, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(1024))
+	utest.IsNilNow(t, err)
+	mem := pool.Alloc(128)
+	pool.Alloc(128)
+	pool.Free(mem)
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	pool.Free(mem)
+}
+
+func Test_ShardedPool_Guards(t *testing.T) {
+	var reported []*CorruptionError
+	pool, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(4096), WithGuards(func(err error) {
+		reported = append(reported, err.(*CorruptionError))
+	}))
+	utest.IsNilNow(t, err)
+	mem := pool.Alloc(128)
+	pool.Free(mem)
+	utest.EqualNow(t, len(reported), 0)
+
+	mem = pool.Alloc(128)
+	outOfBounds(mem, 128, 1)[0] = 1
+	pool.Free(mem)
+	utest.EqualNow(t, len(reported), 1)
+	utest.Assert(t, reported[0].Overflow)
+
+	pool, err = NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(4096), WithGuards(nil))
+	utest.IsNilNow(t, err)
+	mem = pool.Alloc(128)
+	outOfBounds(mem, -1, 1)[0] = 1
+	defer func() {
+		_, ok := recover().(*CorruptionError)
+		utest.Assert(t, ok)
+	}()
+	pool.Free(mem)
+}
+
+func Benchmark_ShardedPool_AllocAndFree_128(b *testing.B) {
+	pool, _ := NewShardedPool(0, WithSizeRange(128, 1024), WithPageSize(64*1024))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Free(pool.Alloc(128))
+		}
+	})
+}
+
+func Benchmark_ShardedPool_AllocAndFree_256(b *testing.B) {
+	pool, _ := NewShardedPool(0, WithSizeRange(128, 1024), WithPageSize(64*1024))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Free(pool.Alloc(256))
+		}
+	})
+}
+
+func Test_ShardedPool_Overflow(t *testing.T) {
+	pool, err := NewShardedPool(2, WithSizeRange(128, 1024), WithPageSize(1024), WithOverflow(4096))
+	utest.IsNilNow(t, err)
+	mem := pool.Alloc(3000)
+	utest.EqualNow(t, cap(mem), 4096)
+	pool.Free(mem)
+
+	frees := uint64(0)
+	for _, shard := range pool.Shards() {
+		frees += shard.OverflowStats()[1].Frees
+	}
+	utest.EqualNow(t, frees, uint64(1))
+}
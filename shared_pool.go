@@ -0,0 +1,208 @@
+package slab
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// sharedMagic 标记共享内存文件已经初始化完成，并用于识别文件格式
+const sharedMagic = 0x736c616273686d31 // "slabshm1"
+
+// sharedHeader 位于共享内存文件的开头，之后依次是 chunks 个 sharedChunk 和 chunk 的内存
+type sharedHeader struct {
+	magic     atomic.Uint64
+	chunkSize uint64
+	chunks    uint64
+	head      atomic.Uint64
+}
+
+// sharedChunk 和 objectChunk 一样，但位于共享内存中，所有进程都通过它分配和回收 chunk
+type sharedChunk struct {
+	next atomic.Uint64
+	aba  uint32 // resolve ABA problem, see link.go
+	_    uint32
+}
+
+// SharedPool is a lock-free pool of fixed size chunks in a shared memory file, e.g. a file in /dev/shm,
+// which several processes map to pass chunks to each other as offsets instead of copying the payloads.
+// The free list lives in the file too, so every process allocates from and frees to the same chunks.
+type SharedPool struct {
+	file      *os.File
+	mem       []byte
+	hdr       *sharedHeader
+	chunks    []sharedChunk
+	data      []byte
+	chunkSize int
+}
+
+// sharedLayout 返回 chunks 个 chunk 的元数据所占的字节数，chunk 的内存从这之后按 64 字节对齐开始
+func sharedLayout(chunks int) int {
+	n := int(unsafe.Sizeof(sharedHeader{})) + chunks*int(unsafe.Sizeof(sharedChunk{}))
+	return (n + 63) &^ 63
+}
+
+// CreateSharedPool create the shared memory file path with chunks chunks of chunkSize bytes, replacing an existing one,
+// and map it. Other processes map it with OpenSharedPool.
+func CreateSharedPool(path string, chunkSize, chunks int) (*SharedPool, error) {
+	if chunkSize <= 0 || chunks <= 0 || uint64(chunks) > maxChunks {
+		return nil, fmt.Errorf("slab: invalid shared pool of %d chunks of %d bytes", chunks, chunkSize)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(int64(sharedLayout(chunks) + chunks*chunkSize)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	pool, err := mapSharedPool(f, chunkSize, chunks)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// 把所有 chunk 按下标顺序链成空闲链表，最后才写入 magic，其它进程看到 magic 时链表已经就绪
+	pool.hdr.chunkSize = uint64(chunkSize)
+	pool.hdr.chunks = uint64(chunks)
+	for i := 0; i < chunks-1; i++ {
+		pool.chunks[i].next.Store(makeLink(uint64(i+1), 0))
+	}
+	pool.chunks[chunks-1].next.Store(linkEnd)
+	pool.hdr.head.Store(makeLink(0, 0))
+	pool.hdr.magic.Store(sharedMagic)
+	return pool, nil
+}
+
+// OpenSharedPool map the shared memory file path created by CreateSharedPool.
+func OpenSharedPool(path string) (*SharedPool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	var hdr sharedHeader
+	_, err = f.ReadAt(unsafe.Slice((*byte)(unsafe.Pointer(&hdr)), unsafe.Sizeof(hdr)), 0)
+	info, _ := f.Stat()
+	if err != nil || hdr.magic.Load() != sharedMagic || hdr.chunkSize == 0 || hdr.chunks == 0 || hdr.chunks > maxChunks ||
+		info == nil || info.Size() < int64(sharedLayout(int(hdr.chunks))+int(hdr.chunks*hdr.chunkSize)) {
+		f.Close()
+		return nil, fmt.Errorf("slab: invalid shared pool file %s", path)
+	}
+	pool, err := mapSharedPool(f, int(hdr.chunkSize), int(hdr.chunks))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+func mapSharedPool(f *os.File, chunkSize, chunks int) (*SharedPool, error) {
+	meta := sharedLayout(chunks)
+	mem, err := mmapFile(f, 0, meta+chunks*chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	pool := &SharedPool{
+		file:      f,
+		mem:       mem,
+		hdr:       (*sharedHeader)(unsafe.Pointer(&mem[0])),
+		chunks:    unsafe.Slice((*sharedChunk)(unsafe.Pointer(&mem[unsafe.Sizeof(sharedHeader{})])), chunks),
+		data:      mem[meta:],
+		chunkSize: chunkSize,
+	}
+	return pool, nil
+}
+
+// Alloc returns a chunk of the shared memory sliced to size bytes,
+// or nil if size is larger than the chunk size or there is no free chunk.
+// Unlike the other pools it never falls back to make, since the buffer must be visible to the other processes.
+func (pool *SharedPool) Alloc(size int) []byte {
+	if size > pool.chunkSize {
+		return nil
+	}
+	for {
+		old := pool.hdr.head.Load()
+		if old == 0 {
+			return nil
+		}
+		i := linkIndex(old)
+		chk := &pool.chunks[i]
+		nxt := nextLink(chk.next.Load())
+		if pool.hdr.head.CompareAndSwap(old, nxt) {
+			chk.next.Store(0)
+			off := int(i) * pool.chunkSize
+			return pool.data[off : off+size : off+pool.chunkSize]
+		}
+		runtime.Gosched()
+	}
+}
+
+// Free release a chunk alloc by Alloc of any process mapping the same file, it panics on double free.
+func (pool *SharedPool) Free(mem []byte) {
+	if err := pool.TryFree(mem); err == ErrDoubleFree {
+		panic("slab.SharedPool: Double Free")
+	}
+}
+
+// TryFree is like Free, but returns ErrDoubleFree if the chunk is already free,
+// and ErrNotPooled if mem doesn't start at a chunk of the pool.
+func (pool *SharedPool) TryFree(mem []byte) error {
+	off, ok := pool.Offset(mem)
+	if !ok || off%pool.chunkSize != 0 {
+		return ErrNotPooled
+	}
+	chk := &pool.chunks[off/pool.chunkSize]
+	if chk.next.Load() != 0 {
+		return ErrDoubleFree
+	}
+	chk.aba++
+	link := makeLink(uint64(off/pool.chunkSize), chk.aba)
+	for {
+		old := pool.hdr.head.Load()
+		chk.next.Store(tailLink(old))
+		if pool.hdr.head.CompareAndSwap(old, link) {
+			return nil
+		}
+		runtime.Gosched()
+	}
+}
+
+// Offset returns the offset of the first byte of mem in the shared memory, which another process turns back into
+// the same bytes with At. ok is false if mem is not inside the chunks of the pool.
+func (pool *SharedPool) Offset(mem []byte) (off int, ok bool) {
+	if cap(mem) == 0 {
+		return 0, false
+	}
+	begin := dataPtr(pool.data)
+	ptr := dataPtr(mem)
+	if ptr < begin || ptr >= begin+uintptr(len(pool.data)) {
+		return 0, false
+	}
+	return int(ptr - begin), true
+}
+
+// At returns the n bytes at offset off of the shared memory, as passed by the process calling Offset.
+// It panics if the bytes are not inside a chunk of the pool.
+func (pool *SharedPool) At(off, n int) []byte {
+	if off < 0 || n < 0 || off/pool.chunkSize >= len(pool.chunks) || off%pool.chunkSize+n > pool.chunkSize {
+		panic("slab.SharedPool: offset out of range")
+	}
+	end := off - off%pool.chunkSize + pool.chunkSize
+	return pool.data[off : off+n : end]
+}
+
+// ChunkSize returns the size of each chunk.
+func (pool *SharedPool) ChunkSize() int {
+	return pool.chunkSize
+}
+
+// Close unmap the shared memory and close the file, the buffers alloc from the pool must not be used after that.
+// The file and the chunks alloc by this process stay, remove the file when no process uses it any more.
+func (pool *SharedPool) Close() error {
+	err := munmapPage(pool.mem)
+	pool.mem, pool.data, pool.chunks, pool.hdr = nil, nil, nil, nil
+	return errors.Join(err, pool.file.Close())
+}
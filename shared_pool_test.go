@@ -0,0 +1,72 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package slab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_SharedPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slab.shm")
+	_, err := CreateSharedPool(path, 0, 4)
+	utest.NotNilNow(t, err)
+	_, err = OpenSharedPool(path)
+	utest.NotNilNow(t, err)
+
+	a, err := CreateSharedPool(path, 128, 4)
+	utest.IsNilNow(t, err)
+	defer a.Close()
+	// the other process maps the same file
+	b, err := OpenSharedPool(path)
+	utest.IsNilNow(t, err)
+	defer b.Close()
+	utest.EqualNow(t, b.ChunkSize(), 128)
+
+	mem := a.Alloc(5)
+	utest.EqualNow(t, len(mem), 5)
+	utest.EqualNow(t, cap(mem), 128)
+	copy(mem, "hello")
+	off, ok := a.Offset(mem)
+	utest.Assert(t, ok)
+	view := b.At(off, 5)
+	utest.EqualNow(t, string(view), "hello")
+	_, ok = a.Offset(make([]byte, 1))
+	utest.Assert(t, !ok)
+
+	// both processes alloc from the same free list
+	var bufs [][]byte
+	for i := 0; i < 3; i++ {
+		buf := b.Alloc(128)
+		utest.NotNilNow(t, buf)
+		bufs = append(bufs, buf)
+	}
+	utest.IsNilNow(t, a.Alloc(1))
+	utest.IsNilNow(t, a.Alloc(129))
+
+	// the chunk freed by the other process is reused
+	b.Free(view)
+	utest.EqualNow(t, a.TryFree(mem), ErrDoubleFree)
+	utest.EqualNow(t, a.TryFree(make([]byte, 128)), ErrNotPooled)
+	mem = a.Alloc(128)
+	utest.EqualNow(t, string(mem[:5]), "hello")
+	a.Free(mem)
+	for _, buf := range bufs {
+		b.Free(buf)
+	}
+	n := 0
+	for buf := a.Alloc(128); buf != nil; buf = a.Alloc(128) {
+		n++
+	}
+	utest.EqualNow(t, n, 4)
+
+	// the file isn't a shared pool
+	other := filepath.Join(t.TempDir(), "other")
+	utest.IsNilNow(t, os.WriteFile(other, make([]byte, 4096), 0600))
+	_, err = OpenSharedPool(other)
+	utest.NotNilNow(t, err)
+}
@@ -1,5 +1,12 @@
 package slab
 
+import (
+	"os"
+	"unsafe"
+)
+
+var osPageSize = os.Getpagesize()
+
 type Pool interface {
 	Alloc(int) []byte
 	Free([]byte)
@@ -15,5 +22,49 @@ func (p *NoPool) Free(_ []byte) {}
 
 var _ Pool = (*NoPool)(nil)
 var _ Pool = (*ChanPool)(nil)
+var _ Pool = (*LockPool)(nil)
+var _ Pool = (*LocalPool)(nil)
 var _ Pool = (*SyncPool)(nil)
 var _ Pool = (*AtomPool)(nil)
+var _ Pool = (*ShardedPool)(nil)
+var _ Pool = (*SharedPool)(nil)
+var _ Pool = (*Group)(nil)
+
+// memclr zeroes b, the compiler turns this loop into a single memclr call.
+func memclr(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// memset fill b with v.
+func memset(b []byte, v byte) {
+	for i := range b {
+		b[i] = v
+	}
+}
+
+// dataPtr returns the address of the backing array of b, it works for slices of zero length or capacity too.
+func dataPtr(b []byte) uintptr {
+	return uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+}
+
+// alignSlice returns the part of b which starts at an address aligned to align.
+func alignSlice(b []byte, align int) []byte {
+	if align <= 1 || len(b) == 0 {
+		return b
+	}
+	off := int(uintptr(unsafe.Pointer(&b[0])) & uintptr(align-1))
+	if off == 0 {
+		return b
+	}
+	return b[align-off:]
+}
+
+// isAligned reports whether the first byte address of b is a multiple of align.
+func isAligned(b []byte, align int) bool {
+	if align <= 1 || cap(b) == 0 {
+		return true
+	}
+	return dataPtr(b)&uintptr(align-1) == 0
+}
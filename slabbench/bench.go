@@ -0,0 +1,157 @@
+// Package slabbench runs the same allocation workload against several pools, e.g. AtomPool, sync.Pool and plain make,
+// and reports their throughput, heap allocations and garbage collection cost side by side.
+package slabbench
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/funny/slab"
+)
+
+// Workload describes the allocations made by each goroutine of a run.
+type Workload struct {
+	Goroutines int                    // goroutines allocating concurrently, the default is GOMAXPROCS
+	Ops        int                    // allocations of each goroutine, the default is 100000
+	Hold       int                    // buffers each goroutine holds, the oldest is freed when one more is allocated, the default is 16
+	Sizes      func(r *rand.Rand) int // requested size of each allocation, the default is Uniform(64, 4096)
+	Seed       int64                  // seed of the random source of each goroutine, which adds its index
+}
+
+// Uniform returns a size distribution picking sizes in [minSize, maxSize] uniformly.
+func Uniform(minSize, maxSize int) func(r *rand.Rand) int {
+	return func(r *rand.Rand) int {
+		return minSize + r.Intn(maxSize-minSize+1)
+	}
+}
+
+// Choice returns a size distribution picking sizes[i] with probability weights[i] over the total of weights.
+func Choice(sizes []int, weights []int) func(r *rand.Rand) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	return func(r *rand.Rand) int {
+		n := r.Intn(total)
+		for i, w := range weights {
+			if n < w {
+				return sizes[i]
+			}
+			n -= w
+		}
+		return sizes[len(sizes)-1]
+	}
+}
+
+// Allocator is a named pool to run a workload against.
+type Allocator struct {
+	Name string
+	Pool slab.Pool
+}
+
+// Defaults returns the allocators compared by default: an AtomPool whose classes can grow to 64 pages,
+// a SyncPool and make, all with classes from 64B to 64KB.
+func Defaults() []Allocator {
+	atom, _ := slab.NewPool(slab.WithMaxPages(64), slab.WithLazy())
+	return []Allocator{
+		{"AtomPool", atom},
+		{"SyncPool", slab.NewSyncPool(64, 64*1024, 2)},
+		{"make", &slab.NoPool{}},
+	}
+}
+
+// Result is how an allocator served a workload.
+type Result struct {
+	Name     string
+	Ops      int           // allocations of all goroutines
+	Duration time.Duration // wall time of the run
+	Mallocs  uint64        // heap objects allocated during the run
+	Bytes    uint64        // heap bytes allocated during the run
+	GCs      uint32        // garbage collections during the run
+	GCPause  time.Duration // total stop-the-world pause of these collections
+}
+
+// OpsPerSec returns the allocations per second.
+func (r Result) OpsPerSec() float64 {
+	return float64(r.Ops) / r.Duration.Seconds()
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%s: %.0f ops/s, %d mallocs, %d bytes, %d GCs paused %v", r.Name, r.OpsPerSec(), r.Mallocs, r.Bytes, r.GCs, r.GCPause)
+}
+
+// Compare runs w against each allocator in turn, Defaults if none is given, and returns their results in the same order.
+func Compare(w Workload, allocators ...Allocator) []Result {
+	if len(allocators) == 0 {
+		allocators = Defaults()
+	}
+	results := make([]Result, len(allocators))
+	for i, a := range allocators {
+		results[i] = Run(w, a)
+	}
+	return results
+}
+
+// Run runs w against one allocator. The heap is collected before the run so the runs of Compare start alike.
+func Run(w Workload, a Allocator) Result {
+	if w.Goroutines <= 0 {
+		w.Goroutines = runtime.GOMAXPROCS(0)
+	}
+	if w.Ops <= 0 {
+		w.Ops = 100000
+	}
+	if w.Hold <= 0 {
+		w.Hold = 16
+	}
+	if w.Sizes == nil {
+		w.Sizes = Uniform(64, 4096)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for g := 0; g < w.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(w.Seed + int64(g)))
+			held := make([][]byte, w.Hold)
+			for i := 0; i < w.Ops; i++ {
+				// 环形持有最近分配的 Hold 个缓冲区，写入一个字节模拟使用
+				k := i % w.Hold
+				if held[k] != nil {
+					a.Pool.Free(held[k])
+				}
+				mem := a.Pool.Alloc(w.Sizes(r))
+				if len(mem) > 0 {
+					mem[0] = byte(i)
+				}
+				held[k] = mem
+			}
+			for _, mem := range held {
+				if mem != nil {
+					a.Pool.Free(mem)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return Result{
+		Name:     a.Name,
+		Ops:      w.Goroutines * w.Ops,
+		Duration: elapsed,
+		Mallocs:  after.Mallocs - before.Mallocs,
+		Bytes:    after.TotalAlloc - before.TotalAlloc,
+		GCs:      after.NumGC - before.NumGC,
+		GCPause:  time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+}
@@ -0,0 +1,36 @@
+package slabbench
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Compare(t *testing.T) {
+	w := Workload{Goroutines: 2, Ops: 2000, Hold: 8, Sizes: Choice([]int{100, 1500}, []int{3, 1})}
+	results := Compare(w)
+	utest.EqualNow(t, len(results), 3)
+	for _, r := range results {
+		utest.EqualNow(t, r.Ops, 4000)
+		utest.Assert(t, r.Duration > 0)
+		utest.Assert(t, r.OpsPerSec() > 0)
+	}
+	utest.EqualNow(t, results[0].Name, "AtomPool")
+	utest.EqualNow(t, results[2].Name, "make")
+
+	// make allocates every buffer on the heap, the AtomPool almost never does
+	utest.Assert(t, results[2].Mallocs >= 4000)
+	utest.Assert(t, results[0].Mallocs < results[2].Mallocs/2)
+}
+
+func Test_Sizes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	u := Uniform(10, 20)
+	c := Choice([]int{1, 2}, []int{0, 1})
+	for i := 0; i < 100; i++ {
+		n := u(r)
+		utest.Assert(t, n >= 10 && n <= 20)
+		utest.EqualNow(t, c(r), 2)
+	}
+}
@@ -0,0 +1,86 @@
+// Package slabprom exports the statistics of slab pools as Prometheus metrics.
+package slabprom
+
+import (
+	"strconv"
+
+	"github.com/funny/slab"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector that reads the per-class statistics of an AtomPool on each scrape.
+type Collector struct {
+	pool *slab.AtomPool
+
+	inUse     *prometheus.Desc
+	free      *prometheus.Desc
+	resident  *prometheus.Desc
+	pages     *prometheus.Desc
+	allocs    *prometheus.Desc
+	fallbacks *prometheus.Desc
+	frees     *prometheus.Desc
+	rejects   *prometheus.Desc
+	retries   *prometheus.Desc
+	yields    *prometheus.Desc
+	spins     *prometheus.Desc
+	spills    *prometheus.Desc
+}
+
+// NewCollector create a Collector for pool, labels are attached to every metric, e.g. to tell pools apart.
+func NewCollector(pool *slab.AtomPool, labels prometheus.Labels) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("slab_"+name, help, []string{"class"}, labels)
+	}
+	return &Collector{
+		pool:      pool,
+		inUse:     desc("in_use_chunks", "Number of chunks currently allocated."),
+		free:      desc("free_chunks", "Number of chunks currently free."),
+		resident:  desc("resident_bytes", "Bytes of slab pages owned by the class."),
+		pages:     desc("pages", "Number of slab pages owned by the class."),
+		allocs:    desc("allocs_total", "Allocations served from the class."),
+		fallbacks: desc("fallbacks_total", "Allocations fall back to the heap because the class is exhausted."),
+		frees:     desc("frees_total", "Chunks returned to the class."),
+		rejects:   desc("rejects_total", "Buffers passed to Free that do not belong to the class."),
+		retries:   desc("cas_retries_total", "Compare-and-swap attempts on the free list lost to other goroutines and retried."),
+		yields:    desc("yields_total", "Retries which yield the processor first."),
+		spins:     desc("spin_rounds_total", "Rounds of busy waiting before retries."),
+		spills:    desc("spilled_chunks_total", "Free chunks beyond the free chunk cap released with their pages."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inUse
+	ch <- c.free
+	ch <- c.resident
+	ch <- c.pages
+	ch <- c.allocs
+	ch <- c.fallbacks
+	ch <- c.frees
+	ch <- c.rejects
+	ch <- c.retries
+	ch <- c.yields
+	ch <- c.spins
+	ch <- c.spills
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.pool.Stats() {
+		class := strconv.Itoa(s.Size)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse), class)
+		ch <- prometheus.MustNewConstMetric(c.free, prometheus.GaugeValue, float64(s.Free), class)
+		ch <- prometheus.MustNewConstMetric(c.resident, prometheus.GaugeValue, float64(s.Resident), class)
+		ch <- prometheus.MustNewConstMetric(c.pages, prometheus.GaugeValue, float64(s.Pages), class)
+		ch <- prometheus.MustNewConstMetric(c.allocs, prometheus.CounterValue, float64(s.Allocs), class)
+		ch <- prometheus.MustNewConstMetric(c.fallbacks, prometheus.CounterValue, float64(s.Fallbacks), class)
+		ch <- prometheus.MustNewConstMetric(c.frees, prometheus.CounterValue, float64(s.Frees), class)
+		ch <- prometheus.MustNewConstMetric(c.rejects, prometheus.CounterValue, float64(s.Rejects), class)
+		ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(s.Retries), class)
+		ch <- prometheus.MustNewConstMetric(c.yields, prometheus.CounterValue, float64(s.Yields), class)
+		ch <- prometheus.MustNewConstMetric(c.spins, prometheus.CounterValue, float64(s.Spins), class)
+		ch <- prometheus.MustNewConstMetric(c.spills, prometheus.CounterValue, float64(s.Spills), class)
+	}
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
@@ -0,0 +1,25 @@
+package slabprom
+
+import (
+	"testing"
+
+	"github.com/funny/slab"
+	"github.com/funny/utest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_Collector(t *testing.T) {
+	pool := slab.NewAtomPool(128, 1024, 2, 1024)
+	pool.Alloc(128)
+	c := NewCollector(pool, prometheus.Labels{"pool": "test"})
+
+	descs := make(chan *prometheus.Desc, 100)
+	c.Describe(descs)
+	close(descs)
+	utest.EqualNow(t, len(descs), 12)
+
+	metrics := make(chan prometheus.Metric, 100)
+	c.Collect(metrics)
+	close(metrics)
+	utest.EqualNow(t, len(metrics), 12*4)
+}
@@ -0,0 +1,253 @@
+// Package slabsafe is a slab allocation memory pool written without package unsafe, syscalls or pointer arithmetic,
+// for platforms and sandboxes which don't allow them, e.g. wasm or appengine-style environments.
+// Its Pool has the Alloc and Free methods of slab.Pool, so code written against that interface can switch
+// to it by configuration, but this package doesn't import slab and can be built wherever plain Go can.
+// Chunks are found by the address of their first byte in a map instead of by address arithmetic,
+// which makes Free slower than the one of slab.AtomPool.
+// It provides only a small subset of the slab API and is not a drop-in replacement selected by a build tag.
+package slabsafe
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDoubleFree is returned by TryFree when the chunk is already free.
+var ErrDoubleFree = errors.New("slabsafe: double free")
+
+// ErrNotPooled is returned by TryFree when the buffer doesn't start at a chunk of the pool.
+var ErrNotPooled = errors.New("slabsafe: buffer not pooled")
+
+// Pool is a slab allocation memory pool safe for concurrent use, each slab class has its own lock.
+type Pool struct {
+	classes []class
+	maxSize int
+}
+
+type class struct {
+	size     int
+	perPage  int
+	maxPages int
+
+	mu     sync.Mutex
+	pages  [][]byte      // 下标为 page 号，Trim 之后中间可能为 nil
+	free   []int         // 空闲 chunk 全局下标组成的栈，全局下标为 page 号*perPage+page 内下标
+	used   []bool        // 按全局下标记录 chunk 是否已分配
+	chunks map[*byte]int // chunk 首字节的地址 → 全局下标
+	npages int           // 已分配的 page 数
+	stats  ClassStats    // 统计计数，Pages、InUse 和 Free 在 Stats 中计算
+}
+
+// New create a pool whose chunk sizes go from minSize up to maxSize by factor, like slab.NewAtomPool.
+// Each slab class grows by pages of pageSize bytes up to maxPages pages when it runs out of chunks,
+// requests it can't serve and requests larger than maxSize fall back to make.
+func New(minSize, maxSize, factor, pageSize, maxPages int) *Pool {
+	if minSize <= 0 || factor < 2 || maxPages < 1 {
+		panic("slabsafe: invalid pool configuration")
+	}
+	pool := &Pool{maxSize: maxSize}
+	for chunkSize := minSize; chunkSize <= maxSize && chunkSize <= pageSize; chunkSize *= factor {
+		pool.classes = append(pool.classes, class{
+			size:     chunkSize,
+			perPage:  pageSize / chunkSize,
+			maxPages: maxPages,
+			pages:    make([][]byte, maxPages),
+			used:     make([]bool, maxPages*(pageSize/chunkSize)),
+			chunks:   make(map[*byte]int),
+		})
+	}
+	if len(pool.classes) > 0 {
+		pool.maxSize = pool.classes[len(pool.classes)-1].size
+	}
+	return pool
+}
+
+// Alloc try alloc a []byte from internal slab class if no free chunk in slab class Alloc will make one.
+// Alloc(0) returns an empty slice which takes no chunk.
+func (pool *Pool) Alloc(size int) []byte {
+	if size == 0 {
+		return []byte{}
+	}
+	if size <= pool.maxSize {
+		for i := 0; i < len(pool.classes); i++ {
+			c := &pool.classes[i]
+			if c.size >= size {
+				if mem := c.pop(); mem != nil {
+					return mem[:size]
+				}
+				break
+			}
+		}
+	}
+	return make([]byte, size)
+}
+
+// Free release a []byte that alloc from Pool.Alloc, it panics on double free.
+// The chunk is found by the address of the first byte of mem, buffers with zero capacity are ignored.
+func (pool *Pool) Free(mem []byte) {
+	if pool.TryFree(mem) == ErrDoubleFree {
+		panic("slabsafe.Pool: Double Free")
+	}
+}
+
+// TryFree is like Free but reports what happened to the buffer.
+// It returns nil if the buffer is returned to a slab class, ErrNotPooled if it doesn't start at a chunk,
+// or ErrDoubleFree instead of panicking if the chunk is already free.
+func (pool *Pool) TryFree(mem []byte) error {
+	if cap(mem) == 0 {
+		return ErrNotPooled
+	}
+	first := &mem[:1][0]
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		if c.size < cap(mem) {
+			continue
+		}
+		if ok, err := c.push(first); ok {
+			return err
+		}
+	}
+	return ErrNotPooled
+}
+
+// Owns reports whether mem starts at a chunk of the pool.
+func (pool *Pool) Owns(mem []byte) bool {
+	if cap(mem) == 0 {
+		return false
+	}
+	first := &mem[:1][0]
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		c.mu.Lock()
+		_, ok := c.chunks[first]
+		c.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pop 弹出一个空闲 chunk，没有时增加一个 page，达到 page 数上限时返回 nil
+func (c *class) pop() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.free) == 0 && !c.grow() {
+		c.stats.Fallbacks++
+		return nil
+	}
+	idx := c.free[len(c.free)-1]
+	c.free = c.free[:len(c.free)-1]
+	c.used[idx] = true
+	c.stats.Allocs++
+	off := idx % c.perPage * c.size
+	return c.pages[idx/c.perPage][off : off+c.size : off+c.size]
+}
+
+// grow 在第一个空闲的 page 号上增加一个 page，调用者需持有 mu
+func (c *class) grow() bool {
+	if c.npages == c.maxPages {
+		return false
+	}
+	n := 0
+	for c.pages[n] != nil {
+		n++
+	}
+	page := make([]byte, c.perPage*c.size)
+	c.pages[n] = page
+	c.npages++
+	// 倒序入栈，page 内的 chunk 按地址顺序分配
+	for i := c.perPage - 1; i >= 0; i-- {
+		idx := n*c.perPage + i
+		c.chunks[&page[i*c.size]] = idx
+		c.free = append(c.free, idx)
+	}
+	return true
+}
+
+// push 回收首字节地址为 first 的 chunk，返回 chunk 是否属于本 class
+func (c *class) push(first *byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx, ok := c.chunks[first]
+	if !ok {
+		return false, nil
+	}
+	if !c.used[idx] {
+		c.stats.DoubleFrees++
+		return true, ErrDoubleFree
+	}
+	c.used[idx] = false
+	c.free = append(c.free, idx)
+	c.stats.Frees++
+	return true, nil
+}
+
+// ClassStats is the statistics of a slab class.
+type ClassStats struct {
+	Size        int    // chunk size of the class
+	Pages       int    // number of pages owned by the class
+	Allocs      uint64 // allocations served from the class
+	Fallbacks   uint64 // allocations fall back to make because the class is exhausted
+	Frees       uint64 // chunks returned to the class by Free
+	DoubleFrees uint64 // chunks passed to Free while they are already free
+	InUse       int    // chunks currently allocated
+	Free        int    // chunks currently free
+}
+
+// Stats returns the statistics of each slab class.
+func (pool *Pool) Stats() []ClassStats {
+	stats := make([]ClassStats, len(pool.classes))
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		c.mu.Lock()
+		s := c.stats
+		s.Size = c.size
+		s.Pages = c.npages
+		s.Free = len(c.free)
+		s.InUse = c.npages*c.perPage - len(c.free)
+		c.mu.Unlock()
+		stats[i] = s
+	}
+	return stats
+}
+
+// Trim release the slab pages whose chunks are all free and returns the number of bytes released.
+func (pool *Pool) Trim() int {
+	released := 0
+	for i := 0; i < len(pool.classes); i++ {
+		released += pool.classes[i].trim()
+	}
+	return released
+}
+
+func (c *class) trim() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	free := make([]int, len(c.pages))
+	for _, idx := range c.free {
+		free[idx/c.perPage]++
+	}
+	released := 0
+	for n, page := range c.pages {
+		if page == nil || free[n] != c.perPage {
+			continue
+		}
+		for i := 0; i < c.perPage; i++ {
+			delete(c.chunks, &page[i*c.size])
+		}
+		c.pages[n] = nil
+		c.npages--
+		released += len(page)
+	}
+	// 保留其余 page 的空闲 chunk 的顺序
+	k := 0
+	for _, idx := range c.free {
+		if c.pages[idx/c.perPage] != nil {
+			c.free[k] = idx
+			k++
+		}
+	}
+	c.free = c.free[:k]
+	return released
+}
@@ -0,0 +1,117 @@
+package slabsafe
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Pool_AllocAndFree(t *testing.T) {
+	pool := New(128, 1024, 2, 1024, 2)
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		temp := make([][]byte, c.perPage*2)
+		for j := 0; j < len(temp); j++ {
+			mem := pool.Alloc(c.size)
+			utest.EqualNow(t, cap(mem), c.size)
+			utest.Assert(t, pool.Owns(mem))
+			temp[j] = mem
+		}
+		utest.EqualNow(t, cap(pool.Alloc(c.size)), c.size)
+		utest.EqualNow(t, pool.Stats()[i].Fallbacks, uint64(1))
+		for j := 0; j < len(temp); j++ {
+			pool.Free(temp[j])
+		}
+		s := pool.Stats()[i]
+		utest.EqualNow(t, s.InUse, 0)
+		utest.EqualNow(t, s.Free, c.perPage*2)
+	}
+}
+
+func Test_Pool_TryFree(t *testing.T) {
+	pool := New(128, 1024, 2, 1024, 1)
+	mem := pool.Alloc(100)
+	utest.EqualNow(t, cap(mem), 128)
+
+	// a resliced buffer is found by its first byte
+	utest.IsNilNow(t, pool.TryFree(mem[:10:10]))
+	utest.EqualNow(t, pool.TryFree(mem), ErrDoubleFree)
+	utest.EqualNow(t, pool.TryFree(mem[1:]), ErrNotPooled)
+	utest.EqualNow(t, pool.TryFree(make([]byte, 128)), ErrNotPooled)
+	utest.EqualNow(t, pool.TryFree(pool.Alloc(0)), ErrNotPooled)
+	utest.EqualNow(t, cap(pool.Alloc(2048)), 2048)
+	utest.EqualNow(t, pool.Stats()[0].DoubleFrees, uint64(1))
+
+	defer func() {
+		utest.NotNilNow(t, recover())
+	}()
+	pool.Free(mem)
+}
+
+func Test_Pool_Trim(t *testing.T) {
+	pool := New(128, 1024, 2, 1024, 2)
+	var bufs [][]byte
+	for i := 0; i < 12; i++ {
+		bufs = append(bufs, pool.Alloc(128))
+	}
+	for _, mem := range bufs[1:] {
+		pool.Free(mem)
+	}
+	// the second page is all free, the first one still holds bufs[0]
+	utest.EqualNow(t, pool.Trim(), 1024)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 1)
+	utest.Assert(t, !pool.Owns(bufs[11]))
+	utest.EqualNow(t, pool.TryFree(bufs[11]), ErrNotPooled)
+	pool.Free(bufs[0])
+	utest.EqualNow(t, pool.Trim(), 1024)
+	utest.EqualNow(t, pool.Stats()[0].Free, 0)
+}
+
+func Test_Pool_Parallel(t *testing.T) {
+	pool := New(128, 1024, 2, 4096, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				pool.Free(pool.Alloc(128 << (j % 4)))
+			}
+		}()
+	}
+	wg.Wait()
+	for _, s := range pool.Stats() {
+		utest.EqualNow(t, s.InUse, 0)
+		utest.EqualNow(t, s.DoubleFrees, uint64(0))
+	}
+}
+
+// the package must build where unsafe and syscalls are not allowed
+func Test_Imports(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	utest.IsNilNow(t, err)
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		utest.IsNilNow(t, err)
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if path == "unsafe" || path == "syscall" || path == "github.com/funny/slab" {
+				t.Fatalf("%s imports %s", file, path)
+			}
+		}
+	}
+}
+
+func Benchmark_Pool_AllocAndFree_128(b *testing.B) {
+	pool := New(128, 1024, 2, 64*1024, 1)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.Free(pool.Alloc(128))
+		}
+	})
+}
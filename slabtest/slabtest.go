@@ -0,0 +1,231 @@
+// Package slabtest checks the invariants of a slab.Pool under concurrent use: goroutines Alloc, Realloc and Free
+// buffers of random sizes, fill every buffer they hold with a pattern of their own and verify it before letting go of it,
+// so a chunk handed out twice, a write across chunks or a Realloc losing data is reported.
+// It's meant for the tests of pools wrapping or embedding a slab pool as much as for the pools of package slab.
+package slabtest
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/funny/slab"
+)
+
+// Config describes the operations made by each goroutine of Run.
+type Config struct {
+	Goroutines int   // goroutines using the pool concurrently, the default is GOMAXPROCS
+	Ops        int   // operations of each goroutine, the default is 10000
+	Hold       int   // buffers each goroutine holds at most, the default is 16
+	MaxSize    int   // largest requested size, sizes are picked in [0, MaxSize], the default is 4096
+	Seed       int64 // seed of the random source of each goroutine, which adds its index
+}
+
+// Reallocator is implemented by pools which resize buffers themselves, e.g. slab.AtomPool.
+// Run Realloc without it by Alloc, copy and Free.
+type Reallocator interface {
+	Realloc(mem []byte, newSize int) []byte
+}
+
+// Statser is implemented by pools reporting their slab classes, e.g. slab.AtomPool.
+// With it Run checks that no chunk is lost, that is every class has as many chunks in use after the run as before.
+type Statser interface {
+	Stats() []slab.ClassStats
+}
+
+// Violation is a broken invariant found by Run.
+type Violation struct {
+	Goroutine int    // goroutine which found it
+	Op        int    // operation of the goroutine which found it, -1 after the run
+	Reason    string // what is broken
+}
+
+func (v *Violation) Error() string {
+	if v.Op < 0 {
+		return "slabtest: " + v.Reason
+	}
+	return fmt.Sprintf("slabtest: goroutine %d op %d: %s", v.Goroutine, v.Op, v.Reason)
+}
+
+// held 是一个 goroutine 持有的 buffer 和填入它的图案
+type held struct {
+	mem []byte
+	tag byte
+}
+
+// Run runs the operations of cfg against pool and returns the first broken invariant as a *Violation, or nil.
+// The pool must not be used by anything else during the run.
+func Run(pool slab.Pool, cfg Config) error {
+	if cfg.Goroutines <= 0 {
+		cfg.Goroutines = runtime.GOMAXPROCS(0)
+	}
+	if cfg.Ops <= 0 {
+		cfg.Ops = 10000
+	}
+	if cfg.Hold <= 0 {
+		cfg.Hold = 16
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 4096
+	}
+	var before []slab.ClassStats
+	if s, ok := pool.(Statser); ok {
+		before = s.Stats()
+	}
+
+	// live 记录所有 goroutine 持有的 buffer 的首字节，同一个 chunk 同时交给两个持有者即为重复分配
+	var (
+		mu   sync.Mutex
+		live = make(map[*byte]int)
+		errs = make([]error, cfg.Goroutines)
+		wg   sync.WaitGroup
+	)
+	acquire := func(g int, mem []byte) string {
+		if cap(mem) == 0 {
+			return ""
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		first := &mem[:1][0]
+		if owner, ok := live[first]; ok {
+			return fmt.Sprintf("buffer of %d bytes handed out while goroutine %d holds it", cap(mem), owner)
+		}
+		live[first] = g
+		return ""
+	}
+	release := func(mem []byte) {
+		if cap(mem) > 0 {
+			mu.Lock()
+			delete(live, &mem[:1][0])
+			mu.Unlock()
+		}
+	}
+
+	for g := 0; g < cfg.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(cfg.Seed + int64(g)))
+			bufs := make([]held, 0, cfg.Hold)
+			fail := func(op int, format string, args ...interface{}) {
+				errs[g] = &Violation{Goroutine: g, Op: op, Reason: fmt.Sprintf(format, args...)}
+			}
+			defer func() {
+				for _, b := range bufs {
+					release(b.mem)
+					pool.Free(b.mem)
+				}
+			}()
+			for op := 0; op < cfg.Ops; op++ {
+				switch {
+				case len(bufs) < cfg.Hold && (len(bufs) == 0 || r.Intn(3) > 0):
+					size := r.Intn(cfg.MaxSize + 1)
+					mem := pool.Alloc(size)
+					if len(mem) != size {
+						fail(op, "Alloc(%d) returned %d bytes", size, len(mem))
+						return
+					}
+					if reason := acquire(g, mem); reason != "" {
+						fail(op, "%s", reason)
+						return
+					}
+					b := held{mem, byte(r.Intn(256))}
+					fill(b.mem, b.tag)
+					bufs = append(bufs, b)
+				case r.Intn(4) == 0:
+					i := r.Intn(len(bufs))
+					b := &bufs[i]
+					if n := corrupted(b.mem, b.tag); n >= 0 {
+						fail(op, "byte %d of a buffer of %d bytes overwritten before Realloc", n, len(b.mem))
+						return
+					}
+					size := r.Intn(cfg.MaxSize + 1)
+					old := len(b.mem)
+					release(b.mem)
+					mem := realloc(pool, b.mem, size)
+					if len(mem) != size {
+						fail(op, "Realloc to %d bytes returned %d bytes", size, len(mem))
+						return
+					}
+					if old > size {
+						old = size
+					}
+					if n := corrupted(mem[:old], b.tag); n >= 0 {
+						fail(op, "byte %d lost by Realloc from %d to %d bytes", n, len(b.mem), size)
+						return
+					}
+					if reason := acquire(g, mem); reason != "" {
+						fail(op, "%s", reason)
+						return
+					}
+					b.mem = mem
+					fill(b.mem, b.tag)
+				default:
+					i := r.Intn(len(bufs))
+					b := bufs[i]
+					if n := corrupted(b.mem, b.tag); n >= 0 {
+						fail(op, "byte %d of a buffer of %d bytes overwritten before Free", n, len(b.mem))
+						return
+					}
+					bufs[i] = bufs[len(bufs)-1]
+					bufs = bufs[:len(bufs)-1]
+					release(b.mem)
+					pool.Free(b.mem)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if s, ok := pool.(Statser); ok {
+		for i, after := range s.Stats() {
+			if i < len(before) && after.Size == before[i].Size && after.InUse != before[i].InUse {
+				return &Violation{Op: -1, Reason: fmt.Sprintf("class %d has %d chunks in use after the run, %d before", after.Size, after.InUse, before[i].InUse)}
+			}
+		}
+	}
+	return nil
+}
+
+// Test is Run reporting the broken invariant as a fatal error of t.
+func Test(t testing.TB, pool slab.Pool, cfg Config) {
+	t.Helper()
+	if err := Run(pool, cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// realloc 用 pool 的 Realloc 调整 mem 的大小，pool 没有 Realloc 时分配新的 buffer 并复制
+func realloc(pool slab.Pool, mem []byte, size int) []byte {
+	if r, ok := pool.(Reallocator); ok {
+		return r.Realloc(mem, size)
+	}
+	mem2 := pool.Alloc(size)
+	copy(mem2, mem)
+	pool.Free(mem)
+	return mem2
+}
+
+// fill 用以 tag 开始的图案填满 mem
+func fill(mem []byte, tag byte) {
+	for i := range mem {
+		mem[i] = tag + byte(i)
+	}
+}
+
+// corrupted 返回 mem 中第一个不符合图案的字节的下标，都符合时返回 -1
+func corrupted(mem []byte, tag byte) int {
+	for i := range mem {
+		if mem[i] != tag+byte(i) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package slabtest
+
+import (
+	"testing"
+
+	"github.com/funny/slab"
+	"github.com/funny/slab/slabsafe"
+	"github.com/funny/utest"
+)
+
+func Test_Run(t *testing.T) {
+	cfg := Config{Goroutines: 4, Ops: 2000, MaxSize: 2048}
+	fifo, _ := slab.NewPool(slab.WithMaxPages(4), slab.WithFIFO(), slab.WithQuarantine(8))
+	pools := []slab.Pool{
+		slab.NewAtomPool(64, 1024, 2, 16*1024),
+		fifo,
+		slab.NewLockPool(64, 1024, 2, 16*1024),
+		slab.NewSyncPool(64, 1024, 2),
+		slabsafe.New(64, 1024, 2, 16*1024, 4),
+	}
+	for _, pool := range pools {
+		Test(t, pool, cfg)
+	}
+}
+
+// twice hands out the same chunk to every Alloc of 64 bytes or less
+type twice struct {
+	slab.NoPool
+	mem []byte
+}
+
+func (p *twice) Alloc(size int) []byte {
+	if size > 0 && size <= len(p.mem) {
+		return p.mem[:size]
+	}
+	return make([]byte, size)
+}
+
+func Test_Run_DoubleHandOut(t *testing.T) {
+	err := Run(&twice{mem: make([]byte, 64)}, Config{Goroutines: 2, Ops: 1000, MaxSize: 128})
+	v, ok := err.(*Violation)
+	utest.Assert(t, ok)
+	utest.Assert(t, v.Op >= 0)
+}
+
+// leaky never takes a buffer back
+type leaky struct {
+	*slab.AtomPool
+}
+
+func (p leaky) Free([]byte) {}
+
+func (p leaky) Realloc(mem []byte, newSize int) []byte {
+	mem2 := p.Alloc(newSize)
+	copy(mem2, mem)
+	return mem2
+}
+
+func Test_Run_LostChunks(t *testing.T) {
+	pool := leaky{slab.NewAtomPool(64, 1024, 2, 64*1024)}
+	err := Run(pool, Config{Goroutines: 1, Ops: 100})
+	v, ok := err.(*Violation)
+	utest.Assert(t, ok)
+	utest.EqualNow(t, v.Op, -1)
+}
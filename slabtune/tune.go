@@ -0,0 +1,220 @@
+// Package slabtune replays allocation traces recorded by slab.Tracer against candidate pool configurations
+// and recommends the configuration serving the trace best.
+package slabtune
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/funny/slab"
+)
+
+// Config is a candidate configuration of an AtomPool, the arguments of slab.NewAtomPool.
+type Config struct {
+	MinSize  int
+	MaxSize  int
+	Factor   int
+	PageSize int
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("minSize=%d maxSize=%d factor=%d pageSize=%d", c.MinSize, c.MaxSize, c.Factor, c.PageSize)
+}
+
+// Result is how a configuration served a replayed trace.
+type Result struct {
+	Config        Config
+	Allocs        int     // allocations replayed
+	Hits          int     // allocations served by the slab classes
+	PeakResident  int     // peak bytes of slab pages owned by the pool
+	Fragmentation float64 // fraction of the bytes handed out by the slab classes which were not requested
+}
+
+// HitRate returns the fraction of the allocations served by the slab classes.
+func (r Result) HitRate() float64 {
+	if r.Allocs == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Allocs)
+}
+
+// Replay replays the allocations and frees of records against a pool configured by cfg and then by opts.
+// Frees are paired with their allocations by address, frees of buffers allocated before the trace starts are skipped.
+// The slab classes may grow as much as the trace needs, opts can limit them, e.g. with slab.WithMaxMemory.
+func Replay(records []slab.TraceRecord, cfg Config, opts ...slab.Option) (Result, error) {
+	// 每个 class 的 page 数上限按所有同时存活的分配都落在最大的 class 上估算
+	live, peakLive := 0, 0
+	for _, r := range records {
+		switch r.Op {
+		case slab.TraceAlloc:
+			live++
+		case slab.TraceFree:
+			live--
+		}
+		if live > peakLive {
+			peakLive = live
+		}
+	}
+	perPage := 1
+	if cfg.MaxSize > 0 && cfg.PageSize > cfg.MaxSize {
+		perPage = cfg.PageSize / cfg.MaxSize
+	}
+	maxPages := peakLive/perPage + 1
+
+	opts = append([]slab.Option{
+		slab.WithSizeRange(cfg.MinSize, cfg.MaxSize),
+		slab.WithGrowthFactor(cfg.Factor),
+		slab.WithPageSize(cfg.PageSize),
+		slab.WithMaxPages(maxPages),
+	}, opts...)
+	pool, err := slab.NewPool(opts...)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
+
+	res := Result{Config: cfg}
+	resident := residentBytes(pool)
+	res.PeakResident = resident
+	bufs := make(map[uint64][]byte)
+	for _, r := range records {
+		switch r.Op {
+		case slab.TraceAlloc:
+			mem, pooled := pool.AllocPooled(r.Size)
+			res.Allocs++
+			if pooled && r.Size <= cfg.MaxSize {
+				res.Hits++
+			}
+			bufs[r.Addr] = mem
+			// 只有 class 增长时常驻内存才会变化
+			if pooled {
+				if resident = residentBytes(pool); resident > res.PeakResident {
+					res.PeakResident = resident
+				}
+			}
+		case slab.TraceFree:
+			if mem, ok := bufs[r.Addr]; ok {
+				pool.Free(mem)
+				delete(bufs, r.Addr)
+			}
+		}
+	}
+
+	var handed, requested uint64
+	for _, s := range pool.Stats() {
+		handed += s.Requests * uint64(s.Size)
+		requested += s.Requested
+	}
+	if handed > 0 {
+		res.Fragmentation = 1 - float64(requested)/float64(handed)
+	}
+	for _, mem := range bufs {
+		pool.Free(mem)
+	}
+	return res, nil
+}
+
+func residentBytes(pool *slab.AtomPool) int {
+	n := 0
+	for _, s := range pool.Stats() {
+		n += s.Resident
+	}
+	return n
+}
+
+// Tune replays records against every candidate and returns the results from the best to the worst,
+// ordered by hit rate, then by peak resident memory, then by fragmentation.
+func Tune(records []slab.TraceRecord, candidates []Config, opts ...slab.Option) ([]Result, error) {
+	results := make([]Result, 0, len(candidates))
+	for _, cfg := range candidates {
+		res, err := Replay(records, cfg, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("slabtune: %s: %w", cfg, err)
+		}
+		results = append(results, res)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Hits != b.Hits {
+			return a.Hits > b.Hits
+		}
+		if a.PeakResident != b.PeakResident {
+			return a.PeakResident < b.PeakResident
+		}
+		return a.Fragmentation < b.Fragmentation
+	})
+	return results, nil
+}
+
+// Candidates returns a grid of configurations around the allocation sizes of records:
+// the smallest chunk sizes at or below the small requests, the largest chunk sizes covering the large requests,
+// growth factors of 2 and 4, and page sizes from 64KB to 1MB that hold at least one largest chunk.
+func Candidates(records []slab.TraceRecord) []Config {
+	var sizes []int
+	for _, r := range records {
+		if r.Op == slab.TraceAlloc && r.Size > 0 {
+			sizes = append(sizes, r.Size)
+		}
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+	sort.Ints(sizes)
+	quantile := func(q float64) int {
+		return sizes[int(q*float64(len(sizes)-1))]
+	}
+
+	var configs []Config
+	for _, minSize := range distinct(pow2(quantile(0)), pow2(quantile(0.1))) {
+		for _, maxSize := range distinct(pow2(quantile(0.9)), pow2(quantile(0.99)), pow2(quantile(1))) {
+			if maxSize < minSize {
+				continue
+			}
+			for _, factor := range []int{2, 4} {
+				for _, pageSize := range []int{64 * 1024, 256 * 1024, 1024 * 1024} {
+					if pageSize < maxSize {
+						continue
+					}
+					configs = append(configs, Config{minSize, maxSize, factor, pageSize})
+				}
+			}
+		}
+	}
+	return configs
+}
+
+// Recommend replays records against the Candidates and returns the best result.
+func Recommend(records []slab.TraceRecord, opts ...slab.Option) (Result, error) {
+	results, err := Tune(records, Candidates(records), opts...)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(results) == 0 {
+		return Result{}, fmt.Errorf("slabtune: no allocation in the trace")
+	}
+	return results[0], nil
+}
+
+// pow2 返回不小于 n 的最小的 2 的幂
+func pow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// distinct 返回去掉重复值之后的 sizes
+func distinct(sizes ...int) []int {
+	var out []int
+	for _, size := range sizes {
+		dup := false
+		for _, s := range out {
+			dup = dup || s == size
+		}
+		if !dup {
+			out = append(out, size)
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package slabtune
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/funny/slab"
+	"github.com/funny/utest"
+)
+
+// record 用一个只有一个很小的 class 的 pool 记录 trace，大部分请求都落到 heap
+func record(t *testing.T) []slab.TraceRecord {
+	var buf bytes.Buffer
+	tracer := slab.NewTracer(&buf)
+	pool := slab.NewAtomPool(64, 64, 2, 1024, slab.WithTracer(tracer))
+	var held [][]byte
+	for i := 0; i < 200; i++ {
+		held = append(held, pool.Alloc(100+i%4*1000))
+		if len(held) == 20 {
+			for _, mem := range held {
+				pool.Free(mem)
+			}
+			held = held[:0]
+		}
+	}
+	records, err := slab.ReadTrace(&buf)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(records), 400)
+	return records
+}
+
+func Test_Replay(t *testing.T) {
+	records := record(t)
+	res, err := Replay(records, Config{64, 4096, 2, 64 * 1024})
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, res.Allocs, 200)
+	utest.EqualNow(t, res.Hits, 200)
+	utest.EqualNow(t, res.HitRate(), 1.0)
+	utest.Assert(t, res.Fragmentation > 0 && res.Fragmentation < 1)
+	utest.Assert(t, res.PeakResident >= 7*64*1024)
+
+	// chunks too small for most requests
+	res, err = Replay(records, Config{64, 1024, 2, 64 * 1024})
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, res.Hits, 50)
+
+	_, err = Replay(records, Config{64, 4096, 1, 64 * 1024})
+	utest.NotNilNow(t, err)
+}
+
+func Test_Recommend(t *testing.T) {
+	records := record(t)
+	configs := Candidates(records)
+	utest.EqualNow(t, len(configs), 6)
+	for _, cfg := range configs {
+		utest.EqualNow(t, cfg.MinSize, 128)
+		utest.EqualNow(t, cfg.MaxSize, 4096)
+	}
+
+	results, err := Tune(records, configs)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(results), len(configs))
+	for i := 1; i < len(results); i++ {
+		utest.Assert(t, results[i-1].Hits >= results[i].Hits)
+	}
+
+	best, err := Recommend(records)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, best, results[0])
+	utest.EqualNow(t, best.HitRate(), 1.0)
+	utest.EqualNow(t, best.Config.MaxSize, 4096)
+
+	_, err = Recommend(nil)
+	utest.NotNilNow(t, err)
+}
@@ -0,0 +1,76 @@
+package slab
+
+import (
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// Number is the element types that AllocSlice can carve out of a chunk.
+// They contain no pointers, so keeping them in []byte memory hides nothing from the garbage collector.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~complex64 | ~complex128
+}
+
+// AllocSlice alloc a []T of length n from a chunk of pool, its first element is aligned for T.
+// If the chunk is not aligned, e.g. with odd sizes from WithClasses, it is freed and the slice falls back to make.
+// The slice must be released by FreeSlice.
+func AllocSlice[T Number](pool Pool, n int) []T {
+	size := int(unsafe.Sizeof(*new(T)))
+	align := int(unsafe.Alignof(*new(T)))
+	mem := pool.Alloc(n * size)
+	if cap(mem) < size || !isAligned(mem, align) {
+		pool.Free(mem)
+		return make([]T, n)
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(unsafe.SliceData(mem))), cap(mem)/size)[:n]
+}
+
+// FreeSlice release a []T that alloc from AllocSlice.
+// The capacity of the slice is rounded down to whole elements, so pools finding chunks by capacity,
+// unlike AtomPool, can only take it back when their chunk sizes are multiples of the element size.
+func FreeSlice[T Number](pool Pool, s []T) {
+	if cap(s) == 0 {
+		return
+	}
+	size := int(unsafe.Sizeof(*new(T)))
+	mem := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(s))), cap(s)*size)
+	pool.Free(mem)
+}
+
+// vecPools 按容量 1<<k 缓存 FreeVec 归还的 [][]byte，保存的是首元素的指针，放回时不需要分配
+var vecPools [maxVecShift + 1]sync.Pool
+
+const (
+	minVecShift = 2  // 最小的缓存容量为 4
+	maxVecShift = 16 // 容量超过 65536 的 [][]byte 不缓存
+)
+
+// AllocVec alloc a [][]byte of length n with every element nil, e.g. an iovec-style frame list,
+// reusing one released by FreeVec instead of making a new one each time. Its capacity is n rounded up to a power of 2.
+// Unlike AllocSlice the headers are not carved out of a chunk: they point to memory, which the garbage collector
+// must see, so the vector lives in the Go heap and is recycled. The vector should be released by FreeVec.
+func AllocVec(n int) [][]byte {
+	k := max(bits.Len(uint(n-1)), minVecShift)
+	if n <= 0 || k > maxVecShift {
+		return make([][]byte, n)
+	}
+	if p, ok := vecPools[k].Get().(*[]byte); ok {
+		return unsafe.Slice(p, 1<<k)[:n]
+	}
+	return make([][]byte, n, 1<<k)
+}
+
+// FreeVec release a [][]byte that alloc from AllocVec, the buffers it refers to are not freed.
+// The vector must not be used after that.
+func FreeVec(vec [][]byte) {
+	k := bits.Len(uint(cap(vec) - 1))
+	if cap(vec) < 1<<minVecShift || cap(vec) != 1<<k || k > maxVecShift {
+		return
+	}
+	vec = vec[:cap(vec)]
+	clear(vec)
+	vecPools[k].Put(unsafe.SliceData(vec))
+}
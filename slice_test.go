@@ -0,0 +1,80 @@
+package slab
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/funny/utest"
+)
+
+func Test_AllocSlice(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	s := AllocSlice[float64](pool, 20)
+	utest.EqualNow(t, len(s), 20)
+	utest.EqualNow(t, cap(s), 32)
+	utest.EqualNow(t, uintptr(unsafe.Pointer(&s[0]))%unsafe.Alignof(s[0]), uintptr(0))
+	for i := range s {
+		s[i] = float64(i) / 2
+	}
+	utest.EqualNow(t, s[19], 9.5)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 1)
+	FreeSlice(pool, s)
+	utest.EqualNow(t, pool.Stats()[1].InUse, 0)
+
+	u := AllocSlice[uint32](pool, 0)
+	utest.EqualNow(t, len(u), 0)
+	FreeSlice(pool, u)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_AllocSlice_Unaligned(t *testing.T) {
+	pool, err := NewPool(WithClasses(100, 1500), WithPageSize(3000))
+	utest.IsNilNow(t, err)
+
+	// the second chunk of the class starts at offset 100, which is not aligned for uint64
+	a := pool.Alloc(100)
+	s := AllocSlice[uint64](pool, 10)
+	utest.EqualNow(t, len(s), 10)
+	utest.EqualNow(t, uintptr(unsafe.Pointer(&s[0]))%unsafe.Alignof(s[0]), uintptr(0))
+	FreeSlice(pool, s)
+	pool.Free(a)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	// an aligned chunk of 100 bytes holds 12 elements
+	s = AllocSlice[uint64](pool, 10)
+	utest.EqualNow(t, cap(s), 12)
+	FreeSlice(pool, s)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+}
+
+func Test_AllocVec(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	vec := AllocVec(5)
+	utest.EqualNow(t, len(vec), 5)
+	utest.EqualNow(t, cap(vec), 8)
+	for i := range vec {
+		utest.Assert(t, vec[i] == nil)
+		vec[i] = pool.Alloc(100)
+	}
+	vec = append(vec, pool.Alloc(100))
+	utest.EqualNow(t, pool.Stats()[0].InUse, 6)
+
+	for _, mem := range vec {
+		pool.Free(mem)
+	}
+	FreeVec(vec)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	// 重用的 vector 已经清空，不再引用之前的缓冲区
+	vec = AllocVec(8)
+	utest.EqualNow(t, cap(vec), 8)
+	for i := range vec {
+		utest.Assert(t, vec[i] == nil)
+	}
+	FreeVec(vec)
+	utest.EqualNow(t, cap(AllocVec(1)), 4)
+	utest.EqualNow(t, len(AllocVec(0)), 0)
+	FreeVec(AllocVec(0))
+	FreeVec(make([][]byte, 3))
+	utest.EqualNow(t, cap(AllocVec(1<<17)), 1<<17)
+}
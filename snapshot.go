@@ -0,0 +1,310 @@
+package slab
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Snapshot is the statistics of a pool at a point in time, taken by AtomPool.Snapshot.
+// It's encoded to JSON with stable snake_case field names, e.g. {"time": ..., "classes": [{"size": 128, "in_use": 3, ...}]},
+// which don't change when the Go fields are renamed.
+type Snapshot struct {
+	Time     time.Time
+	Interval time.Duration // time since the previous snapshot in the result of Delta, 0 otherwise
+	Classes  []ClassStats
+	Oversize []OversizeStats
+	Buddy    BuddyStats // zero value if the pool is created without WithBuddy
+	Overflow []OverflowStats
+	Sizes    []SizeBucket         // histogram of requested sizes, recorded WithSizeHistogram
+	Sites    map[string]SiteStats // chunks in use by call site, see AtomPool.Sites, nil without WithSampling or WithLeakDetection
+	Latency  LatencyStats         // zero value if the pool is created without WithLatency
+}
+
+// Snapshot returns the statistics of all slab classes and tiers of the pool.
+func (pool *AtomPool) Snapshot() Snapshot {
+	return Snapshot{
+		Time:     time.Now(),
+		Classes:  pool.Stats(),
+		Oversize: pool.OversizeStats(),
+		Buddy:    pool.BuddyStats(),
+		Overflow: pool.OverflowStats(),
+		Sizes:    pool.SizeHistogram(),
+		Sites:    pool.sites(),
+		Latency:  pool.Latency(),
+	}
+}
+
+// sites 和 Sites 相同，但没有记录调用栈时返回 nil
+func (pool *AtomPool) sites() map[string]SiteStats {
+	if sites := pool.Sites(); len(sites) > 0 {
+		return sites
+	}
+	return nil
+}
+
+// Delta returns the difference between s and an earlier snapshot prev of the same pool,
+// the counters like Allocs and Fallbacks are what happened since prev and Interval is the time between them,
+// so rates are the counters divided by Interval. The gauges like InUse and Resident are the values of s.
+func (s Snapshot) Delta(prev Snapshot) Snapshot {
+	d := Snapshot{
+		Time:     s.Time,
+		Interval: s.Time.Sub(prev.Time),
+		Classes:  make([]ClassStats, len(s.Classes)),
+		Buddy:    s.Buddy,
+		Sites:    s.Sites,
+	}
+	for i, c := range s.Classes {
+		for _, p := range prev.Classes {
+			if p.Size == c.Size {
+				c.Allocs -= p.Allocs
+				c.Fallbacks -= p.Fallbacks
+				c.OverBudget -= p.OverBudget
+				c.Frees -= p.Frees
+				c.Rejects -= p.Rejects
+				c.DoubleFrees -= p.DoubleFrees
+				c.Requests -= p.Requests
+				c.Requested -= p.Requested
+				c.Grows -= p.Grows
+				c.Retries -= p.Retries
+				c.Yields -= p.Yields
+				c.Spins -= p.Spins
+				c.Spills -= p.Spills
+				break
+			}
+		}
+		d.Classes[i] = c
+	}
+	// 没有请求的 oversize 桶不会出现在快照里，因此只保留区间内有请求的桶
+	for _, o := range s.Oversize {
+		for _, p := range prev.Oversize {
+			if p.Size == o.Size {
+				o.Requests -= p.Requests
+				o.Fallbacks -= p.Fallbacks
+				break
+			}
+		}
+		if o.Requests > 0 {
+			d.Oversize = append(d.Oversize, o)
+		}
+	}
+	d.Buddy.Allocs -= prev.Buddy.Allocs
+	d.Buddy.Fallbacks -= prev.Buddy.Fallbacks
+	d.Buddy.OverBudget -= prev.Buddy.OverBudget
+	d.Buddy.Frees -= prev.Buddy.Frees
+	d.Buddy.Rejects -= prev.Buddy.Rejects
+	for _, o := range s.Overflow {
+		for _, p := range prev.Overflow {
+			if p.Size == o.Size {
+				o.Allocs -= p.Allocs
+				o.Misses -= p.Misses
+				o.Frees -= p.Frees
+				o.Drops -= p.Drops
+				break
+			}
+		}
+		d.Overflow = append(d.Overflow, o)
+	}
+	for _, b := range s.Sizes {
+		for _, p := range prev.Sizes {
+			if p.Size == b.Size {
+				b.Count -= p.Count
+				break
+			}
+		}
+		if b.Count > 0 {
+			d.Sizes = append(d.Sizes, b)
+		}
+	}
+	d.Latency = LatencyStats{
+		AllocHit:      s.Latency.AllocHit.delta(prev.Latency.AllocHit),
+		AllocFallback: s.Latency.AllocFallback.delta(prev.Latency.AllocFallback),
+		FreeHit:       s.Latency.FreeHit.delta(prev.Latency.FreeHit),
+		FreeFallback:  s.Latency.FreeFallback.delta(prev.Latency.FreeFallback),
+	}
+	return d
+}
+
+// 以下类型和对应的统计结构字段完全相同，只是加上了 JSON 名字，Go 字段改名时转换会编译失败，JSON 名字因此保持不变
+type (
+	classJSON struct {
+		Size        int    `json:"size"`
+		Pages       int    `json:"pages"`
+		Allocs      uint64 `json:"allocs"`
+		Fallbacks   uint64 `json:"fallbacks"`
+		OverBudget  uint64 `json:"over_budget"`
+		Frees       uint64 `json:"frees"`
+		Rejects     uint64 `json:"rejects"`
+		DoubleFrees uint64 `json:"double_frees"`
+		Requests    uint64 `json:"requests"`
+		Requested   uint64 `json:"requested_bytes"`
+		Grows       uint64 `json:"grows"`
+		InUse       int    `json:"in_use"`
+		Free        int    `json:"free"`
+		Quarantined int    `json:"quarantined"`
+		Resident    int    `json:"resident_bytes"`
+		PageSize    int    `json:"page_size"`
+		TailWaste   int    `json:"tail_waste_bytes"`
+		Retries     uint64 `json:"retries"`
+		Yields      uint64 `json:"yields"`
+		Spins       uint64 `json:"spins"`
+		Spills      uint64 `json:"spills"`
+		Disabled    bool   `json:"disabled,omitempty"`
+		Pinned      bool   `json:"pinned,omitempty"`
+	}
+	oversizeJSON struct {
+		Size      int    `json:"size"`
+		Requests  uint64 `json:"requests"`
+		Fallbacks uint64 `json:"fallbacks"`
+	}
+	buddyJSON struct {
+		MinBlock   int    `json:"min_block"`
+		MaxBlock   int    `json:"max_block"`
+		Arenas     int    `json:"arenas"`
+		Allocs     uint64 `json:"allocs"`
+		Fallbacks  uint64 `json:"fallbacks"`
+		OverBudget uint64 `json:"over_budget"`
+		Frees      uint64 `json:"frees"`
+		Rejects    uint64 `json:"rejects"`
+		InUse      int    `json:"in_use_bytes"`
+		Resident   int    `json:"resident_bytes"`
+	}
+	overflowJSON struct {
+		Size   int    `json:"size"`
+		Allocs uint64 `json:"allocs"`
+		Misses uint64 `json:"misses"`
+		Frees  uint64 `json:"frees"`
+		Drops  uint64 `json:"drops"`
+		Cached int    `json:"cached"`
+	}
+	sizeJSON struct {
+		Size  int    `json:"size"`
+		Count uint64 `json:"count"`
+	}
+	siteJSON struct {
+		Samples   int `json:"samples"`
+		Chunks    int `json:"chunks"`
+		Bytes     int `json:"bytes"`
+		Requested int `json:"requested_bytes"`
+	}
+	latencyJSON struct {
+		Latency time.Duration `json:"latency_ns"`
+		Count   uint64        `json:"count"`
+	}
+	latencyStatsJSON struct {
+		AllocHit      []latencyJSON `json:"alloc_hit,omitempty"`
+		AllocFallback []latencyJSON `json:"alloc_fallback,omitempty"`
+		FreeHit       []latencyJSON `json:"free_hit,omitempty"`
+		FreeFallback  []latencyJSON `json:"free_fallback,omitempty"`
+	}
+	snapshotJSON struct {
+		Time     time.Time           `json:"time"`
+		Interval int64               `json:"interval_ns,omitempty"`
+		Classes  []classJSON         `json:"classes"`
+		Oversize []oversizeJSON      `json:"oversize,omitempty"`
+		Buddy    *buddyJSON          `json:"buddy,omitempty"`
+		Overflow []overflowJSON      `json:"overflow,omitempty"`
+		Sizes    []sizeJSON          `json:"sizes,omitempty"`
+		Sites    map[string]siteJSON `json:"sites,omitempty"`
+		Latency  *latencyStatsJSON   `json:"latency,omitempty"`
+	}
+)
+
+// MarshalJSON implements json.Marshaler.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	v := snapshotJSON{
+		Time:     s.Time,
+		Interval: int64(s.Interval),
+		Classes:  make([]classJSON, len(s.Classes)),
+	}
+	for i, c := range s.Classes {
+		v.Classes[i] = classJSON(c)
+	}
+	for _, o := range s.Oversize {
+		v.Oversize = append(v.Oversize, oversizeJSON(o))
+	}
+	if s.Buddy != (BuddyStats{}) {
+		b := buddyJSON(s.Buddy)
+		v.Buddy = &b
+	}
+	for _, o := range s.Overflow {
+		v.Overflow = append(v.Overflow, overflowJSON(o))
+	}
+	for _, b := range s.Sizes {
+		v.Sizes = append(v.Sizes, sizeJSON(b))
+	}
+	if s.Sites != nil {
+		v.Sites = make(map[string]siteJSON, len(s.Sites))
+		for site, st := range s.Sites {
+			v.Sites[site] = siteJSON(st)
+		}
+	}
+	if l := s.Latency; l.AllocHit != nil || l.AllocFallback != nil || l.FreeHit != nil || l.FreeFallback != nil {
+		v.Latency = &latencyStatsJSON{
+			AllocHit:      l.AllocHit.json(),
+			AllocFallback: l.AllocFallback.json(),
+			FreeHit:       l.FreeHit.json(),
+			FreeFallback:  l.FreeFallback.json(),
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var v snapshotJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Snapshot{
+		Time:     v.Time,
+		Interval: time.Duration(v.Interval),
+		Classes:  make([]ClassStats, len(v.Classes)),
+	}
+	for i, c := range v.Classes {
+		s.Classes[i] = ClassStats(c)
+	}
+	for _, o := range v.Oversize {
+		s.Oversize = append(s.Oversize, OversizeStats(o))
+	}
+	if v.Buddy != nil {
+		s.Buddy = BuddyStats(*v.Buddy)
+	}
+	for _, o := range v.Overflow {
+		s.Overflow = append(s.Overflow, OverflowStats(o))
+	}
+	for _, b := range v.Sizes {
+		s.Sizes = append(s.Sizes, SizeBucket(b))
+	}
+	if v.Sites != nil {
+		s.Sites = make(map[string]SiteStats, len(v.Sites))
+		for site, st := range v.Sites {
+			s.Sites[site] = SiteStats(st)
+		}
+	}
+	if l := v.Latency; l != nil {
+		s.Latency = LatencyStats{
+			AllocHit:      latencyFromJSON(l.AllocHit),
+			AllocFallback: latencyFromJSON(l.AllocFallback),
+			FreeHit:       latencyFromJSON(l.FreeHit),
+			FreeFallback:  latencyFromJSON(l.FreeFallback),
+		}
+	}
+	return nil
+}
+
+// json 把直方图转换为 JSON 的形式
+func (h LatencyHistogram) json() []latencyJSON {
+	var v []latencyJSON
+	for _, b := range h {
+		v = append(v, latencyJSON(b))
+	}
+	return v
+}
+
+func latencyFromJSON(v []latencyJSON) LatencyHistogram {
+	var h LatencyHistogram
+	for _, b := range v {
+		h = append(h, LatencyBucket(b))
+	}
+	return h
+}
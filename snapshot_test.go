@@ -0,0 +1,63 @@
+package slab
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Snapshot(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithBuddy(4096, 1), WithOverflow(8192))
+	pool.Alloc(100)
+	prev := pool.Snapshot()
+	time.Sleep(time.Millisecond)
+	for i := 0; i < 9; i++ {
+		pool.Alloc(100)
+	}
+	pool.Free(pool.Alloc(3000))
+	pool.Alloc(6000)
+
+	s := pool.Snapshot()
+	utest.EqualNow(t, s.Classes[0].Allocs, uint64(8))
+	utest.EqualNow(t, s.Classes[0].Fallbacks, uint64(2))
+	utest.EqualNow(t, s.Buddy.Allocs, uint64(1))
+	utest.EqualNow(t, s.Overflow[len(s.Overflow)-1].Size, 8192)
+
+	d := s.Delta(prev)
+	utest.Assert(t, d.Interval >= time.Millisecond)
+	utest.EqualNow(t, d.Classes[0].Allocs, uint64(7))
+	utest.EqualNow(t, d.Classes[0].Fallbacks, uint64(2))
+	utest.EqualNow(t, d.Classes[0].InUse, 8)
+	utest.EqualNow(t, len(d.Oversize), 2)
+	utest.EqualNow(t, d.Buddy.Frees, uint64(1))
+	utest.EqualNow(t, d.Buddy.Resident, 4096)
+	utest.EqualNow(t, d.Overflow[len(d.Overflow)-1].Misses, uint64(1))
+
+	data, err := json.Marshal(d)
+	utest.IsNilNow(t, err)
+	js := string(data)
+	utest.Assert(t, strings.Contains(js, `"interval_ns":`))
+	utest.Assert(t, strings.Contains(js, `{"size":128,"pages":1,"allocs":7,"fallbacks":2,`))
+	utest.Assert(t, strings.Contains(js, `"in_use":8,"free":0,`))
+	utest.Assert(t, strings.Contains(js, `"buddy":{"min_block":2048,`))
+
+	var back Snapshot
+	utest.IsNilNow(t, json.Unmarshal(data, &back))
+	utest.Assert(t, back.Time.Equal(d.Time))
+	utest.EqualNow(t, back.Interval, d.Interval)
+	utest.EqualNow(t, len(back.Classes), len(d.Classes))
+	for i := range back.Classes {
+		utest.EqualNow(t, back.Classes[i], d.Classes[i])
+	}
+	utest.EqualNow(t, back.Buddy, d.Buddy)
+	utest.EqualNow(t, back.Overflow[len(d.Overflow)-1], d.Overflow[len(d.Overflow)-1])
+	utest.EqualNow(t, back.Oversize[1], d.Oversize[1])
+
+	// pools without the buddy tier omit it
+	data, err = json.Marshal(NewAtomPool(128, 1024, 2, 1024).Snapshot())
+	utest.IsNilNow(t, err)
+	utest.Assert(t, !strings.Contains(string(data), "buddy"))
+}
@@ -0,0 +1,23 @@
+package slab
+
+import "time"
+
+// spilled 在 class 回收了 chunk 之后调用，开启 WithClassFreeCap 时空闲 chunk 超过上限一个 page 以上，
+// 且上次检查以来又回收了一个 page 的 chunk，就释放完全空闲的 page 直到空闲 chunk 不再超过上限，
+// 没有完全空闲的 page 时每回收一个 page 的 chunk 重试一次，不会每次回收都遍历空闲链表
+func (pool *AtomPool) spilled(c *class) {
+	if c.freeCap == 0 || c.freeChunks() <= c.freeCap+c.perPage || c.keeps(TrimSpill) {
+		return
+	}
+	mark := c.spillMark.Load()
+	frees := c.frees.Load()
+	if frees < mark+uint64(c.perPage) || !c.spillMark.CompareAndSwap(mark, frees) {
+		return
+	}
+	released := c.reclaimKeep(time.Now(), 0, c.freeCap)
+	if released > 0 {
+		c.spills.Add(uint64(released / c.pageSize * c.perPage))
+		pool.releasedBy(TrimSpill, "class", c.size, released)
+		pool.trims.add(TrimSpill, 0, released)
+	}
+}
@@ -0,0 +1,59 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_FreeCap(t *testing.T) {
+	var events []ReleaseEvent
+	pool, err := NewPool(WithClasses(128, 256), WithPageSize(1024), WithMaxPages(8), WithLazy(),
+		WithClassFreeCap(func(chunkSize int) int {
+			if chunkSize == 128 {
+				return 8
+			}
+			return 0
+		}),
+		WithReleaseHook(func(e ReleaseEvent) {
+			events = append(events, e)
+		}))
+	utest.IsNilNow(t, err)
+
+	// 突发分配 6 个 page 的 chunk 之后全部回收，只保留上限附近的空闲 chunk
+	bufs := pool.AllocBatch(128, 48)
+	utest.EqualNow(t, pool.Stats()[0].Pages, 6)
+	pool.FreeBatch(bufs)
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.Pages, 1)
+	utest.EqualNow(t, s.Spills, uint64(40))
+	utest.EqualNow(t, len(events), 1)
+	utest.EqualNow(t, events[0].Reason, TrimSpill)
+	utest.EqualNow(t, events[0].Released, 5*1024)
+	utest.EqualNow(t, pool.TrimEvents()[0].Reason, TrimSpill)
+
+	// 逐个回收时也一样
+	for i := 0; i < 32; i++ {
+		bufs[i] = pool.Alloc(128)
+	}
+	utest.EqualNow(t, pool.Stats()[0].Pages, 4)
+	for i := 0; i < 32; i++ {
+		pool.Free(bufs[i])
+	}
+	s = pool.Stats()[0]
+	utest.Assert(t, s.Free <= 8+8, s.Free)
+	utest.EqualNow(t, s.Spills, uint64(40+8*(4-s.Pages)))
+
+	// 没有上限的 class 保留所有 page，固定的 class 也不释放
+	pool.FreeBatch(pool.AllocBatch(256, 16))
+	utest.EqualNow(t, pool.Stats()[1].Pages, 4)
+	utest.EqualNow(t, pool.Stats()[1].Spills, uint64(0))
+	utest.IsNilNow(t, pool.SetClassPinned(128, true))
+	pool.FreeBatch(pool.AllocBatch(128, 32))
+	utest.EqualNow(t, pool.Stats()[0].Pages, 4)
+}
+
+func Test_NewPool_InvalidFreeCap(t *testing.T) {
+	_, err := NewPool(WithClassFreeCap(func(int) int { return -1 }))
+	utest.NotNilNow(t, err)
+}
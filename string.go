@@ -0,0 +1,93 @@
+package slab
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ErrUseAfterFree is returned by CheckString when the chunk behind a string view has been freed since the view was made.
+var ErrUseAfterFree = errors.New("slab: string used after free")
+
+// stringView 是 UnsafeString 创建视图时视图所在的 chunk 和 chunk 当时的 ABA 计数，chunk 每次回收 ABA 计数都会加一
+type stringView struct {
+	chk *chunk
+	aba uint32
+}
+
+// stringViews 记录 WithStringGuard 模式下创建的字符串视图，同一地址上只保留最新的视图
+type stringViews struct {
+	mu    sync.Mutex
+	views map[uintptr]stringView // 字符串首地址 → 视图
+}
+
+// UnsafeString returns a string sharing the bytes of mem without copying, e.g. to key a map by pooled bytes.
+// The string is only valid until the chunk holding mem is freed and mem must not be modified while the string is used.
+// With WithStringGuard the view is recorded, so CheckString can detect it is used after the chunk is freed.
+func (pool *AtomPool) UnsafeString(mem []byte) string {
+	if len(mem) == 0 {
+		return ""
+	}
+	if pool.views != nil {
+		if chk := pool.chunkAt(dataPtr(mem)); chk != nil {
+			pool.views.mu.Lock()
+			pool.views.views[dataPtr(mem)] = stringView{chk, chk.aba}
+			pool.views.mu.Unlock()
+		}
+	}
+	return unsafe.String(unsafe.SliceData(mem), len(mem))
+}
+
+// AllocString copies s into a chunk alloc from the pool and returns the copy as a string view of the chunk, see UnsafeString.
+// The string must be released by FreeString.
+func (pool *AtomPool) AllocString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return pool.UnsafeString(pool.CloneString(s))
+}
+
+// FreeString release a string that alloc from AllocString, the string must not be used after that.
+func (pool *AtomPool) FreeString(s string) {
+	if s == "" {
+		return
+	}
+	pool.Free(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// CheckString returns ErrUseAfterFree if s is a view made by UnsafeString or AllocString of a pool created WithStringGuard
+// and its chunk has been freed since, nil otherwise, including for the strings the pool doesn't know.
+// A view made after its chunk is alloc again at the same address replaces the stale one, WithQuarantine makes that rare.
+func (pool *AtomPool) CheckString(s string) error {
+	if pool.views == nil || s == "" {
+		return nil
+	}
+	pool.views.mu.Lock()
+	v, ok := pool.views.views[uintptr(unsafe.Pointer(unsafe.StringData(s)))]
+	pool.views.mu.Unlock()
+	if ok && (v.chk.next.Load() != 0 || v.chk.aba != v.aba) {
+		return ErrUseAfterFree
+	}
+	return nil
+}
+
+// chunkAt 返回包含地址 ptr 的 chunk，ptr 不在任何 class 的 chunk 中时返回 nil
+func (pool *AtomPool) chunkAt(ptr uintptr) *chunk {
+	for i := 0; i < len(pool.classes); i++ {
+		c := &pool.classes[i]
+		nslots := int(atomic.LoadInt32(&c.nslots))
+		for n := 0; n < nslots; n++ {
+			p := c.page(n)
+			if p == nil || ptr < p.begin || ptr >= p.end+uintptr(c.size) {
+				continue
+			}
+			if (ptr-p.begin)%uintptr(c.stride) >= uintptr(c.size) {
+				// ptr 在两个 chunk 之间的 guard 中
+				return nil
+			}
+			return &p.chunks[(ptr-p.begin)/uintptr(c.stride)]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package slab
+
+import (
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_AllocString(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+
+	s := pool.AllocString("hello")
+	utest.EqualNow(t, s, "hello")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 1)
+	m := map[string]int{s: 1}
+	utest.EqualNow(t, m["hello"], 1)
+	utest.IsNilNow(t, pool.CheckString(s))
+
+	pool.FreeString(s)
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+	utest.EqualNow(t, pool.AllocString(""), "")
+	utest.EqualNow(t, pool.Stats()[0].InUse, 0)
+
+	mem := pool.Alloc(16)
+	copy(mem, "key=value")
+	utest.EqualNow(t, pool.UnsafeString(mem[4:9]), "value")
+	pool.Free(mem)
+}
+
+func Test_AtomPool_StringGuard(t *testing.T) {
+	pool, err := NewPool(WithSizeRange(128, 1024), WithPageSize(1024), WithStringGuard(), WithQuarantine(2))
+	utest.IsNilNow(t, err)
+
+	s := pool.AllocString("hello")
+	mem := pool.Alloc(16)
+	copy(mem, "key=value")
+	key := pool.UnsafeString(mem[4:9])
+	utest.IsNilNow(t, pool.CheckString(s))
+	utest.IsNilNow(t, pool.CheckString(key))
+	utest.IsNilNow(t, pool.CheckString("not pooled"))
+
+	pool.FreeString(s)
+	utest.EqualNow(t, pool.CheckString(s), ErrUseAfterFree)
+	utest.IsNilNow(t, pool.CheckString(key))
+
+	// chunk 被重新分配之后旧的视图仍然被检测到
+	pool.Free(mem)
+	for i := 0; i < 8; i++ {
+		pool.Alloc(16)
+	}
+	utest.EqualNow(t, pool.CheckString(key), ErrUseAfterFree)
+}
@@ -0,0 +1,164 @@
+package slab
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrQuotaExceeded is returned by AllocTagged when the chunk would exceed the quota of its tag.
+var ErrQuotaExceeded = errors.New("slab: tag quota exceeded")
+
+// tag 是一个标签的配额和用量
+type tag struct {
+	name      string
+	maxBytes  int // 0 表示不限制
+	maxChunks int // 0 表示不限制
+	bytes     int
+	chunks    int
+	allocs    uint64
+	rejects   uint64
+}
+
+// owner 是 AllocTagged 分配出去的一块内存所属的标签和记账的字节数
+type owner struct {
+	tag   *tag
+	bytes int
+}
+
+// tags 记录 AllocTagged 分配出去的内存属于哪个标签
+type tags struct {
+	mu     sync.Mutex
+	byName map[string]*tag
+	owners map[uintptr]owner // chunk 首地址 → 标签
+	n      atomic.Int64      // owners 的个数，为 0 时 Free 不需要加锁查找
+}
+
+// get 返回名为 name 的标签，调用者需持有 mu
+func (t *tags) get(name string) *tag {
+	if t.byName == nil {
+		t.byName = make(map[string]*tag)
+		t.owners = make(map[uintptr]owner)
+	}
+	tg := t.byName[name]
+	if tg == nil {
+		tg = &tag{name: name}
+		t.byName[name] = tg
+	}
+	return tg
+}
+
+// charge 把 mem 记到标签 name 上，超出配额时返回 false
+func (t *tags) charge(name string, mem []byte) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tg := t.get(name)
+	if (tg.maxBytes > 0 && tg.bytes+cap(mem) > tg.maxBytes) || (tg.maxChunks > 0 && tg.chunks+1 > tg.maxChunks) {
+		tg.rejects++
+		return false
+	}
+	tg.bytes += cap(mem)
+	tg.chunks++
+	tg.allocs++
+	t.owners[dataPtr(mem)] = owner{tg, cap(mem)}
+	t.n.Add(1)
+	return true
+}
+
+// release 在 mem 被回收之前把它从所属标签的用量中扣除，必须在 chunk 放回空闲链表之前调用，
+// 否则 chunk 可能已被重新分配并记到另一个标签上
+func (t *tags) release(mem []byte) {
+	if t.n.Load() == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ptr := dataPtr(mem)
+	o, ok := t.owners[ptr]
+	if !ok {
+		return
+	}
+	delete(t.owners, ptr)
+	t.n.Add(-1)
+	o.tag.chunks--
+	o.tag.bytes -= o.bytes
+}
+
+// reset 清空所有标签的用量，保留配额
+func (t *tags) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ptr := range t.owners {
+		delete(t.owners, ptr)
+	}
+	t.n.Store(0)
+	for _, tg := range t.byName {
+		tg.bytes, tg.chunks = 0, 0
+	}
+}
+
+// SetTagQuota limit the memory the buffers alloc by AllocTagged with tag can hold at the same time,
+// to maxBytes bytes of chunks and maxChunks chunks, 0 means no limit.
+// Lowering a quota below the current usage doesn't free anything, it only rejects the next allocations.
+func (pool *AtomPool) SetTagQuota(tag string, maxBytes, maxChunks int) {
+	pool.tags.mu.Lock()
+	defer pool.tags.mu.Unlock()
+	tg := pool.tags.get(tag)
+	tg.maxBytes, tg.maxChunks = 0, 0
+	if maxBytes > 0 {
+		tg.maxBytes = maxBytes
+	}
+	if maxChunks > 0 {
+		tg.maxChunks = maxChunks
+	}
+}
+
+// AllocTagged is like TryAlloc, but the pooled memory is accounted to tag until it's freed,
+// it returns ErrQuotaExceeded if the chunk would exceed the quota set by SetTagQuota.
+// Heap fallbacks don't hold pool memory and are not accounted.
+// The buffer must be passed to Free or FreeBatch with its first byte, like Free requires, to release the quota.
+func (pool *AtomPool) AllocTagged(tag string, size int) ([]byte, error) {
+	mem, pooled, err := pool.alloc(size, true)
+	if err != nil || !pooled {
+		return mem, err
+	}
+	if !pool.tags.charge(tag, mem) {
+		pool.Free(mem)
+		return nil, ErrQuotaExceeded
+	}
+	return mem, nil
+}
+
+// TagStats is the usage and quota of a tag.
+type TagStats struct {
+	Tag       string // tag passed to AllocTagged or SetTagQuota
+	Bytes     int    // bytes of the chunks currently held by the tag
+	Chunks    int    // chunks currently held by the tag
+	MaxBytes  int    // quota of bytes, 0 means no limit
+	MaxChunks int    // quota of chunks, 0 means no limit
+	Allocs    uint64 // allocations accounted to the tag
+	Rejects   uint64 // allocations rejected with ErrQuotaExceeded
+}
+
+// TagStats returns the statistics of each tag, sorted by tag.
+func (pool *AtomPool) TagStats() []TagStats {
+	pool.tags.mu.Lock()
+	defer pool.tags.mu.Unlock()
+	stats := make([]TagStats, 0, len(pool.tags.byName))
+	for _, tg := range pool.tags.byName {
+		stats = append(stats, TagStats{
+			Tag:       tg.name,
+			Bytes:     tg.bytes,
+			Chunks:    tg.chunks,
+			MaxBytes:  tg.maxBytes,
+			MaxChunks: tg.maxChunks,
+			Allocs:    tg.allocs,
+			Rejects:   tg.rejects,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats
+}
@@ -0,0 +1,87 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_AllocTagged(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 4096)
+	pool.SetTagQuota("a", 512, 0)
+	pool.SetTagQuota("b", 0, 2)
+
+	var a [][]byte
+	for i := 0; i < 4; i++ {
+		mem, err := pool.AllocTagged("a", 100)
+		utest.IsNilNow(t, err)
+		utest.EqualNow(t, len(mem), 100)
+		a = append(a, mem)
+	}
+	mem, err := pool.AllocTagged("a", 100)
+	utest.IsNilNow(t, mem)
+	utest.EqualNow(t, err, ErrQuotaExceeded)
+
+	// other tags are not affected
+	b1, err := pool.AllocTagged("b", 1000)
+	utest.IsNilNow(t, err)
+	_, err = pool.AllocTagged("b", 10)
+	utest.IsNilNow(t, err)
+	_, err = pool.AllocTagged("b", 10)
+	utest.EqualNow(t, err, ErrQuotaExceeded)
+	_, err = pool.AllocTagged("c", 1000)
+	utest.IsNilNow(t, err)
+
+	// heap fallbacks are not accounted
+	big, err := pool.AllocTagged("a", 4096)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(big), 4096)
+
+	stats := pool.TagStats()
+	utest.EqualNow(t, len(stats), 3)
+	utest.EqualNow(t, stats[0], TagStats{Tag: "a", Bytes: 512, Chunks: 4, MaxBytes: 512, Allocs: 4, Rejects: 1})
+	utest.EqualNow(t, stats[1], TagStats{Tag: "b", Bytes: 1024 + 128, Chunks: 2, MaxChunks: 2, Allocs: 2, Rejects: 1})
+	utest.EqualNow(t, stats[2], TagStats{Tag: "c", Bytes: 1024, Chunks: 1, Allocs: 1})
+
+	pool.Free(a[0])
+	pool.FreeBatch(a[1:3])
+	pool.Free(b1)
+	stats = pool.TagStats()
+	utest.EqualNow(t, stats[0].Bytes, 128)
+	utest.EqualNow(t, stats[0].Chunks, 1)
+	utest.EqualNow(t, stats[1].Bytes, 128)
+	_, err = pool.AllocTagged("b", 10)
+	utest.IsNilNow(t, err)
+
+	pool.Reset()
+	for _, s := range pool.TagStats() {
+		utest.EqualNow(t, s.Bytes, 0)
+		utest.EqualNow(t, s.Chunks, 0)
+	}
+	utest.EqualNow(t, pool.TagStats()[0].MaxBytes, 512)
+}
+
+func Test_AtomPool_AllocTaggedConcurrent(t *testing.T) {
+	pool := NewAtomPool(128, 128, 2, 4096, WithMaxPages(4))
+	pool.SetTagQuota("a", 0, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				mem, err := pool.AllocTagged("a", 128)
+				if err == nil {
+					pool.Free(mem)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	s := pool.TagStats()[0]
+	utest.EqualNow(t, s.Chunks, 0)
+	utest.EqualNow(t, s.Bytes, 0)
+	utest.EqualNow(t, s.Allocs+s.Rejects, uint64(8*500))
+	utest.IsNilNow(t, pool.Verify())
+}
@@ -0,0 +1,173 @@
+package slab
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// TraceOp is the operation of a TraceRecord.
+type TraceOp uint8
+
+const (
+	TraceAlloc TraceOp = iota + 1 // an allocation
+	TraceFree                     // a buffer passed to Free
+)
+
+// TraceRecordSize is the size of an encoded TraceRecord.
+const TraceRecordSize = 40
+
+// TraceRecord is an allocation or a free recorded by a Tracer.
+type TraceRecord struct {
+	Time      int64   // unix time in nanoseconds
+	Goroutine uint64  // id of the goroutine
+	Addr      uint64  // address of the first byte of the buffer, which pairs a free with its allocation
+	Size      int     // size of the allocation, or capacity of the buffer passed to Free
+	Class     int     // chunk size of the slab class, 0 if the buffer is not a chunk
+	Op        TraceOp // TraceAlloc or TraceFree
+	Pooled    bool    // whether the buffer is alloc from or returned to the pool
+}
+
+// AppendBinary appends the TraceRecordSize bytes little endian encoding of r to b.
+func (r TraceRecord) AppendBinary(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(r.Time))
+	b = binary.LittleEndian.AppendUint64(b, r.Goroutine)
+	b = binary.LittleEndian.AppendUint64(b, r.Addr)
+	b = binary.LittleEndian.AppendUint64(b, uint64(r.Size))
+	b = binary.LittleEndian.AppendUint32(b, uint32(r.Class))
+	var pooled byte
+	if r.Pooled {
+		pooled = 1
+	}
+	return append(b, byte(r.Op), pooled, 0, 0)
+}
+
+// ErrTraceCorrupted is returned by ReadTrace when the trace is not made of valid records.
+var ErrTraceCorrupted = errors.New("slab: trace corrupted")
+
+// ReadTrace decodes the records written by a Tracer from r until io.EOF.
+func ReadTrace(r io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+	var b [TraceRecordSize]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				err = ErrTraceCorrupted
+			}
+			return records, err
+		}
+		op := TraceOp(b[36])
+		if op != TraceAlloc && op != TraceFree || b[37] > 1 {
+			return records, ErrTraceCorrupted
+		}
+		records = append(records, TraceRecord{
+			Time:      int64(binary.LittleEndian.Uint64(b[0:])),
+			Goroutine: binary.LittleEndian.Uint64(b[8:]),
+			Addr:      binary.LittleEndian.Uint64(b[16:]),
+			Size:      int(binary.LittleEndian.Uint64(b[24:])),
+			Class:     int(binary.LittleEndian.Uint32(b[32:])),
+			Op:        op,
+			Pooled:    b[37] == 1,
+		})
+	}
+}
+
+// Tracer records every allocation and free of the pools created with WithTracer,
+// either encoded to an io.Writer or into a ring buffer keeping the latest records.
+// Recording takes a lock and looks up the goroutine id, it's meant for capturing traces rather than for the hot path.
+type Tracer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	buf  []byte
+	err  error
+	ring []TraceRecord
+	pos  int // ring 中最早的记录的位置
+	n    int // ring 中的记录数
+}
+
+// NewTracer create a Tracer writing TraceRecordSize bytes per record to w, which can be decoded by ReadTrace.
+// w should be buffered, e.g. a bufio.Writer flushed by the caller after the pool is done.
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{w: w, buf: make([]byte, 0, TraceRecordSize)}
+}
+
+// NewRingTracer create a Tracer keeping the latest n records in memory.
+func NewRingTracer(n int) *Tracer {
+	if n < 1 {
+		n = 1
+	}
+	return &Tracer{ring: make([]TraceRecord, n)}
+}
+
+// Records returns the records kept by a ring tracer from the oldest to the latest one, or nil for a tracer writing to an io.Writer.
+func (t *Tracer) Records() []TraceRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ring == nil {
+		return nil
+	}
+	records := make([]TraceRecord, 0, t.n)
+	for i := 0; i < t.n; i++ {
+		records = append(records, t.ring[(t.pos+i)%len(t.ring)])
+	}
+	return records
+}
+
+// Err returns the first error writing to the io.Writer, the tracer stops writing after an error.
+func (t *Tracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *Tracer) record(op TraceOp, e AllocEvent, mem []byte) {
+	r := TraceRecord{
+		Time:      time.Now().UnixNano(),
+		Goroutine: goid(),
+		Size:      e.Size,
+		Class:     e.Class,
+		Op:        op,
+		Pooled:    e.Pooled,
+	}
+	if cap(mem) > 0 {
+		r.Addr = uint64(dataPtr(mem))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ring != nil {
+		if t.n < len(t.ring) {
+			t.ring[(t.pos+t.n)%len(t.ring)] = r
+			t.n++
+		} else {
+			t.ring[t.pos] = r
+			t.pos = (t.pos + 1) % len(t.ring)
+		}
+		return
+	}
+	if t.err == nil {
+		t.buf = r.AppendBinary(t.buf[:0])
+		_, t.err = t.w.Write(t.buf)
+	}
+}
+
+// goid 从调用栈的第一行 "goroutine 123 [running]:" 中解析当前 goroutine 的 id
+func goid() uint64 {
+	var buf [32]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
@@ -0,0 +1,80 @@
+package slab
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_Tracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf)
+	pool := NewAtomPool(128, 1024, 2, 1024, WithTracer(tracer))
+	a := pool.Alloc(100)
+	b := pool.Alloc(4096)
+	pool.Free(a)
+	pool.Free(b)
+	utest.IsNilNow(t, tracer.Err())
+	utest.IsNilNow(t, tracer.Records())
+	utest.EqualNow(t, buf.Len(), 4*TraceRecordSize)
+
+	records, err := ReadTrace(&buf)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(records), 4)
+	r := records[0]
+	utest.EqualNow(t, r.Op, TraceAlloc)
+	utest.EqualNow(t, r.Size, 100)
+	utest.EqualNow(t, r.Class, 128)
+	utest.Assert(t, r.Pooled)
+	utest.EqualNow(t, r.Addr, uint64(dataPtr(a)))
+	utest.Assert(t, r.Goroutine > 0)
+	utest.Assert(t, r.Time > 0)
+
+	utest.EqualNow(t, records[1], TraceRecord{records[1].Time, r.Goroutine, uint64(dataPtr(b)), 4096, 0, TraceAlloc, false})
+	utest.EqualNow(t, records[2], TraceRecord{records[2].Time, r.Goroutine, r.Addr, 128, 128, TraceFree, true})
+	utest.EqualNow(t, records[3].Op, TraceFree)
+	utest.Assert(t, !records[3].Pooled)
+	utest.Assert(t, records[0].Time <= records[3].Time)
+
+	// a record cut short or with an unknown op
+	_, err = ReadTrace(bytes.NewReader(make([]byte, TraceRecordSize-1)))
+	utest.EqualNow(t, err, ErrTraceCorrupted)
+	_, err = ReadTrace(bytes.NewReader(make([]byte, TraceRecordSize)))
+	utest.EqualNow(t, err, ErrTraceCorrupted)
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errors.New("full") }
+
+func Test_Tracer_Error(t *testing.T) {
+	tracer := NewTracer(errWriter{})
+	pool := NewAtomPool(128, 1024, 2, 1024, WithTracer(tracer))
+	pool.Free(pool.Alloc(128))
+	utest.EqualNow(t, tracer.Err().Error(), "full")
+}
+
+func Test_Tracer_Ring(t *testing.T) {
+	tracer := NewRingTracer(3)
+	pool := NewAtomPool(128, 1024, 2, 1024, WithTracer(tracer))
+	bufs := pool.AllocBatch(128, 2)
+	pool.FreeBatch(bufs)
+	records := tracer.Records()
+	utest.EqualNow(t, len(records), 3)
+	utest.EqualNow(t, records[0].Op, TraceAlloc)
+	utest.EqualNow(t, records[0].Addr, uint64(dataPtr(bufs[1])))
+	utest.EqualNow(t, records[1].Op, TraceFree)
+	utest.EqualNow(t, records[2].Addr, uint64(dataPtr(bufs[1])))
+
+	// goroutines are told apart
+	done := make(chan struct{})
+	go func() {
+		pool.Free(pool.Alloc(128))
+		close(done)
+	}()
+	<-done
+	records = tracer.Records()
+	utest.Assert(t, records[2].Goroutine != records[0].Goroutine)
+}
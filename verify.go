@@ -0,0 +1,123 @@
+package slab
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// VerifyError is reported by Verify when the free list of a slab class is inconsistent,
+// which means the pool itself is corrupted rather than misused by the application.
+type VerifyError struct {
+	Size   int    // chunk size of the class
+	Chunk  int    // global index of the chunk where the problem is found, or -1
+	Reason string // what is wrong
+}
+
+func (e *VerifyError) Error() string {
+	if e.Chunk < 0 {
+		return fmt.Sprintf("slab: class %d: %s", e.Size, e.Reason)
+	}
+	return fmt.Sprintf("slab: class %d: chunk %d: %s", e.Size, e.Chunk, e.Reason)
+}
+
+// Verify walks the free list of every slab class and returns a *VerifyError for the first inconsistency found:
+// a cycle, an index out of range or into a released page, an ABA counter not matching the chunk,
+// a free chunk not linked in the list, a chunk both free and allocated in leak tracking mode,
+// or a free list whose length does not match the statistics.
+// It must be called in a quiescent window when no other goroutine is calling Alloc or Free.
+func (pool *AtomPool) Verify() error {
+	for i := 0; i < len(pool.classes); i++ {
+		if err := pool.classes[i].verify(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *class) verify() error {
+	c.growMu.Lock()
+	defer c.growMu.Unlock()
+
+	fail := func(idx int, format string, args ...interface{}) error {
+		return &VerifyError{Size: c.size, Chunk: idx, Reason: fmt.Sprintf(format, args...)}
+	}
+	total := int(atomic.LoadInt32(&c.nslots)) * c.perPage
+	seen := make([]bool, total)
+	length := 0
+	for v := c.head.Load(); v != 0; {
+		if linkIndex(v) >= uint64(total) {
+			return fail(int(linkIndex(v)), "index out of range [0, %d)", total)
+		}
+		idx := int(linkIndex(v))
+		chk := c.chunk(uint64(idx))
+		if chk == nil {
+			return fail(idx, "in a released page")
+		}
+		if seen[idx] {
+			return fail(idx, "free list has a cycle")
+		}
+		if linkABA(v) != chk.aba {
+			return fail(idx, "ABA counter %d in the link does not match %d of the chunk", linkABA(v), chk.aba)
+		}
+		if c.leak && atomic.LoadPointer(&chk.info) != nil {
+			return fail(idx, "both free and allocated")
+		}
+		seen[idx] = true
+		length++
+		v = nextLink(chk.next.Load())
+	}
+
+	// 隔离中的 chunk 也是空闲的，它们的 next 指向自己
+	if c.quarantine != nil {
+		for _, v := range c.quarantine.quarantined() {
+			idx := int(linkIndex(v))
+			if idx >= total || c.chunk(uint64(idx)) == nil {
+				return fail(idx, "quarantined in a released page")
+			}
+			chk := c.chunk(uint64(idx))
+			if seen[idx] {
+				return fail(idx, "both in the free list and quarantined")
+			}
+			if chk.next.Load() != v {
+				return fail(idx, "quarantined but not linked to itself")
+			}
+			seen[idx] = true
+			length++
+		}
+	}
+
+	// FIFO 后备链表中的 chunk 也是空闲的
+	if f := c.fifo; f != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for v := f.head; v != 0; {
+			idx := int(linkIndex(v))
+			if idx >= total || c.chunk(uint64(idx)) == nil {
+				return fail(idx, "queued in a released page")
+			}
+			chk := c.chunk(uint64(idx))
+			if seen[idx] {
+				return fail(idx, "queued twice or also in the free list")
+			}
+			if linkABA(v) != chk.aba {
+				return fail(idx, "ABA counter %d in the link does not match %d of the chunk", linkABA(v), chk.aba)
+			}
+			seen[idx] = true
+			length++
+			v = nextLink(chk.next.Load())
+		}
+	}
+
+	// 不在空闲链表、隔离区和后备链表中的 chunk 都是已分配的，它们的 next 必须为 0
+	for idx := 0; idx < total; idx++ {
+		if chk := c.chunk(uint64(idx)); chk != nil && !seen[idx] && chk.next.Load() != 0 {
+			return fail(idx, "marked free but not in the free list")
+		}
+	}
+
+	inUse := int(c.allocs.Load() - c.frees.Load())
+	if free := int(atomic.LoadInt32(&c.npages))*c.perPage - inUse; free != length {
+		return fail(-1, "free list holds %d chunks but %d are free by the statistics", length, free)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package slab
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_Verify(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024, WithMaxPages(4), WithLeakDetection())
+	r := pool.StartReclaimer(0, time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				bufs := pool.AllocBatch(128, 3)
+				bufs = append(bufs, pool.Alloc(256))
+				pool.FreeBatch(bufs[:3])
+				pool.Free(bufs[3])
+			}
+		}()
+	}
+	wg.Wait()
+	r.Stop()
+	utest.IsNilNow(t, pool.Verify())
+
+	held := pool.Alloc(128)
+	utest.IsNilNow(t, pool.Verify())
+	pool.Free(held)
+	utest.IsNilNow(t, pool.Verify())
+}
+
+func Test_AtomPool_VerifyCorrupted(t *testing.T) {
+	corrupt := func(f func(c *class)) error {
+		pool := NewAtomPool(128, 1024, 2, 1024)
+		f(&pool.classes[0])
+		return pool.Verify()
+	}
+	verr := func(err error) *VerifyError {
+		e, ok := err.(*VerifyError)
+		utest.Assert(t, ok)
+		utest.EqualNow(t, e.Size, 128)
+		return e
+	}
+
+	// the last chunk links back to the first one
+	e := verr(corrupt(func(c *class) {
+		c.chunk(7).next.Store(c.head.Load())
+	}))
+	utest.EqualNow(t, e.Reason, "free list has a cycle")
+
+	e = verr(corrupt(func(c *class) {
+		c.chunk(3).next.Store(100 << 32)
+	}))
+	utest.EqualNow(t, e.Chunk, 99)
+
+	e = verr(corrupt(func(c *class) {
+		c.chunk(0).aba++
+	}))
+	utest.EqualNow(t, e.Chunk, 0)
+
+	// the chunk is popped without updating the statistics
+	e = verr(corrupt(func(c *class) {
+		c.Pop()
+	}))
+	utest.EqualNow(t, e.Chunk, -1)
+
+	// the chunk is unlinked but still looks free
+	e = verr(corrupt(func(c *class) {
+		c.chunk(1).next.Store(c.chunk(2).next.Load())
+	}))
+	utest.EqualNow(t, e.Chunk, 2)
+	utest.EqualNow(t, e.Error(), "slab: class 128: chunk 2: marked free but not in the free list")
+}
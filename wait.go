@@ -0,0 +1,78 @@
+package slab
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// AllocContext is like TryAlloc, but when the slab class of size is exhausted it waits until another goroutine frees a chunk of the class
+// instead of falling back to make, which gives backpressure to producers outrunning their consumers.
+// It returns ctx.Err() if ctx is done before a chunk is freed, or ErrPoolClosed if the pool is closed.
+// Sizes larger than the largest chunk size are served like TryAlloc does.
+func (pool *AtomPool) AllocContext(ctx context.Context, size int) ([]byte, error) {
+	if dst := pool.migrated.Load(); dst != nil {
+		return dst.AllocContext(ctx, size)
+	}
+	if size > pool.maxSize || pool.src.closed.Load() {
+		return pool.TryAlloc(size)
+	}
+	if i := pool.classIndex(size); i >= 0 {
+		// 和 AtomPool.Alloc 一样调用栈在 class.pop 之上有两层，profile 记录的调用栈跳过的层数相同
+		c := &pool.classes[i]
+		pool.recordSize(1, size)
+		mem, err := c.wait(ctx, size, true)
+		if mem == nil {
+			return nil, err
+		}
+		c.allocs.Add(1)
+		c.request(1, size)
+		pool.allocated(size, mem, true)
+		return mem[:size], nil
+	}
+	return nil, ErrPoolExhausted
+}
+
+// wait 弹出一个空闲 chunk，size 是请求的大小，grow 表示是否可以增长，没有空闲 chunk 且无法增长时等待 pushRun 的唤醒信号、ctx 结束或 pool 关闭
+func (c *class) wait(ctx context.Context, size int, grow bool) ([]byte, error) {
+	for {
+		if c.src.closed.Load() {
+			return nil, ErrPoolClosed
+		}
+		if mem, _ := c.pop(size, grow); mem != nil {
+			return mem, nil
+		}
+
+		// 登记为等待者之后再试一次，登记之前回收的 chunk 不会发出信号
+		atomic.AddInt32(&c.waiters, 1)
+		mem, _ := c.pop(size, grow)
+		if mem == nil {
+			select {
+			case <-c.wake:
+				mem, _ = c.pop(size, grow)
+			case <-ctx.Done():
+				atomic.AddInt32(&c.waiters, -1)
+				return nil, ctx.Err()
+			case <-c.src.done:
+				atomic.AddInt32(&c.waiters, -1)
+				return nil, ErrPoolClosed
+			}
+		}
+		atomic.AddInt32(&c.waiters, -1)
+
+		if mem != nil {
+			// 一次信号可能对应多个被回收的 chunk，还有空闲 chunk 时把信号传给下一个等待者
+			if atomic.LoadInt32(&c.waiters) > 0 && c.head.Load() != 0 {
+				c.signal()
+			}
+			return mem, nil
+		}
+	}
+}
+
+// signal 唤醒一个等待者，已有信号未被接收时不再重复发送
+func (c *class) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
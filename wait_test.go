@@ -0,0 +1,82 @@
+package slab
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/funny/utest"
+)
+
+func Test_AtomPool_AllocContext(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	temp := make([][]byte, 8)
+	for i := range temp {
+		temp[i] = pool.Alloc(128)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mem, err := pool.AllocContext(ctx, 100)
+	utest.IsNilNow(t, mem)
+	utest.EqualNow(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Free(temp[0])
+	}()
+	mem, err = pool.AllocContext(context.Background(), 100)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(mem), 100)
+	utest.EqualNow(t, &mem[0], &temp[0][0])
+	utest.EqualNow(t, pool.Stats()[0].Fallbacks, uint64(0))
+	utest.EqualNow(t, pool.Stats()[0].InUse, 8)
+
+	mem, err = pool.AllocContext(context.Background(), 2048)
+	utest.IsNilNow(t, err)
+	utest.EqualNow(t, len(mem), 2048)
+}
+
+func Test_AtomPool_AllocContextWaiters(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	temp := pool.AllocBatch(128, 8)
+
+	// one FreeBatch wakes up all the waiters, each of them gets a chunk
+	var wg sync.WaitGroup
+	got := make([][]byte, 4)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], _ = pool.AllocContext(context.Background(), 128)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	pool.FreeBatch(temp[:4])
+	wg.Wait()
+	for _, mem := range got {
+		utest.Assert(t, pool.Owns(mem))
+	}
+	utest.EqualNow(t, pool.Stats()[0].InUse, 8)
+}
+
+func Test_AtomPool_AllocContextParallel(t *testing.T) {
+	pool := NewAtomPool(128, 1024, 2, 1024)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				mem, err := pool.AllocContext(context.Background(), 128)
+				utest.IsNilNow(t, err)
+				pool.Free(mem)
+			}
+		}()
+	}
+	wg.Wait()
+	s := pool.Stats()[0]
+	utest.EqualNow(t, s.InUse, 0)
+	utest.EqualNow(t, s.Fallbacks, uint64(0))
+}
@@ -0,0 +1,33 @@
+package slab
+
+import "sync/atomic"
+
+// WatermarkEvent reports a slab class whose in-use ratio crossed a watermark set by WithWatermarks.
+type WatermarkEvent struct {
+	Size     int  // chunk size of the class
+	InUse    int  // chunks in use when the watermark is crossed
+	Capacity int  // chunks the class can own with all its pages, see WithMaxPages
+	High     bool // true when the ratio rose to the high watermark, false when it fell back to the low watermark
+}
+
+// watermarks 记录一个 class 的水位线
+type watermarks struct {
+	high, low float64
+	fn        func(e WatermarkEvent)
+	above     atomic.Bool // 达到高水位之后、回落到低水位之前为 true
+}
+
+// watermark 在 class 的已分配 chunk 数变为 n 之后检查水位线，越过时调用回调
+func (c *class) watermark(n int) {
+	w := c.marks
+	if w == nil {
+		return
+	}
+	capacity := len(c.pages) * c.perPage
+	ratio := float64(n) / float64(capacity)
+	if ratio >= w.high && w.above.CompareAndSwap(false, true) {
+		w.fn(WatermarkEvent{Size: c.size, InUse: n, Capacity: capacity, High: true})
+	} else if ratio <= w.low && w.above.CompareAndSwap(true, false) {
+		w.fn(WatermarkEvent{Size: c.size, InUse: n, Capacity: capacity, High: false})
+	}
+}
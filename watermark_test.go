@@ -0,0 +1,40 @@
+package slab
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/funny/utest"
+)
+
+func Test_NewPool_Watermarks(t *testing.T) {
+	var events []WatermarkEvent
+	pool, err := NewPool(WithClasses(128, 256), WithPageSize(1024), WithMaxPages(2),
+		WithWatermarks(0.75, 0.25, func(e WatermarkEvent) {
+			events = append(events, e)
+		}))
+	utest.IsNilNow(t, err)
+
+	// 128B 的 class 最多有 16 个 chunk，分配到第 12 个时达到高水位
+	var bufs [][]byte
+	for i := 0; i < 11; i++ {
+		bufs = append(bufs, pool.Alloc(100))
+	}
+	utest.EqualNow(t, len(events), 0)
+	bufs = append(bufs, pool.AllocBatch(100, 2)...)
+	utest.EqualNow(t, fmt.Sprint(events), "[{128 13 16 true}]")
+
+	// 在两条水位线之间不再触发
+	for len(bufs) > 5 {
+		pool.Free(bufs[len(bufs)-1])
+		bufs = bufs[:len(bufs)-1]
+	}
+	bufs = append(bufs, pool.Alloc(100))
+	utest.EqualNow(t, len(events), 1)
+
+	pool.FreeBatch(bufs[2:])
+	utest.EqualNow(t, fmt.Sprint(events), "[{128 13 16 true} {128 4 16 false}]")
+
+	_, err = NewPool(WithWatermarks(0.5, 0.5, func(WatermarkEvent) {}))
+	utest.NotNilNow(t, err)
+}